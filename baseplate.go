@@ -83,6 +83,20 @@ type Baseplate interface {
 
 	EdgeContextImpl() ecinterface.Interface
 	Secrets() *secrets.Store
+
+	// OnStart registers a hook to be run when the server built on this
+	// Baseplate starts serving.
+	//
+	// Start hooks are run in the order they are registered.
+	OnStart(hook LifecycleHook)
+
+	// OnShutdown registers a hook to be run after the server built on this
+	// Baseplate is stopped gracefully.
+	//
+	// Shutdown hooks are run in the reverse order they are registered.
+	//
+	// It complements batchcloser for resources that are not io.Closers.
+	OnShutdown(hook LifecycleHook)
 }
 
 // Server is the primary interface for baseplate servers.
@@ -107,6 +121,30 @@ type Server interface {
 	Serve() error
 }
 
+// Shutdowner is an optional interface a Server can implement to drain
+// in-flight requests gracefully on shutdown.
+//
+// When the Server implements Shutdowner, Serve calls Shutdown instead of Close,
+// with a context object that's canceled after StopTimeout,
+// so the Server can drain in-flight requests up to that deadline.
+type Shutdowner interface {
+	// Shutdown should stop the server gracefully, waiting for the in-flight
+	// requests to finish until ctx is done,
+	// and only return after the server has finished shutting down.
+	Shutdown(ctx context.Context) error
+}
+
+// DrainStarter is an optional interface a Server can implement to be notified
+// as soon as the shutdown signal is received.
+//
+// When the Server implements DrainStarter, Serve calls StartDraining before
+// sleeping for StopDelay, so the Server can start failing its health checks and the load
+// balancer can deregister it before it stops accepting new connections.
+type DrainStarter interface {
+	// StartDraining should mark the server as draining without blocking.
+	StartDraining()
+}
+
 // ServeArgs provides a list of arguments to Serve.
 type ServeArgs struct {
 	// Server is the Server that should be run until receiving a shutdown signal.
@@ -127,9 +165,14 @@ type ServeArgs struct {
 // It uses runtimebp.HandleShutdown to handle the signal and gracefully shut
 // down, in order:
 //
+// * the Server is marked as draining if it implements DrainStarter,
+//
+// * StopDelay is waited,
+//
 // * any provided PreShutdown closers,
 //
-// * the Server, and
+// * the Server (via Shutdowner.Shutdown if it's implemented, Close otherwise),
+// and
 //
 // * any provided PostShutdown closers.
 //
@@ -182,6 +225,10 @@ func Serve(ctx context.Context, args ServeArgs) error {
 				delay = DefaultStopDelay
 			}
 
+			if d, ok := server.(DrainStarter); ok {
+				d.StartDraining()
+			}
+
 			// Initialize a channel to pass the result of server.Close().
 			//
 			// It's buffered with size 1 to avoid blocking the goroutine forever.
@@ -199,7 +246,13 @@ func Serve(ctx context.Context, args ServeArgs) error {
 					}))
 				}
 				bc.Add(args.PreShutdown...)
-				bc.Add(server)
+				if s, ok := server.(Shutdowner); ok {
+					bc.Add(batchcloser.Wrap(func() error {
+						return s.Shutdown(ctx)
+					}))
+				} else {
+					bc.Add(server)
+				}
 				bc.Add(args.PostShutdown...)
 				closeChannel <- bc.Close()
 			}()
@@ -309,7 +362,7 @@ type NewArgs struct {
 // The returned context will be cancelled when the Baseplate is closed.
 func New(ctx context.Context, args NewArgs) (context.Context, Baseplate, error) {
 	cfg := args.Config.GetConfig()
-	bp := impl{cfg: cfg, closers: batchcloser.New()}
+	bp := impl{cfg: cfg, closers: batchcloser.New(), lifecycle: new(lifecycle)}
 
 	if info, ok := debug.ReadBuildInfo(); ok {
 		prometheusbp.RecordModuleVersions(info)
@@ -372,10 +425,11 @@ func New(ctx context.Context, args NewArgs) (context.Context, Baseplate, error)
 }
 
 type impl struct {
-	closers *batchcloser.BatchCloser
-	cfg     Config
-	ecImpl  ecinterface.Interface
-	secrets *secrets.Store
+	closers   *batchcloser.BatchCloser
+	cfg       Config
+	ecImpl    ecinterface.Interface
+	secrets   *secrets.Store
+	lifecycle *lifecycle
 }
 
 func (bp impl) GetConfig() Config {
@@ -390,6 +444,22 @@ func (bp impl) EdgeContextImpl() ecinterface.Interface {
 	return bp.ecImpl
 }
 
+func (bp impl) OnStart(hook LifecycleHook) {
+	bp.lifecycle.onStart(hook)
+}
+
+func (bp impl) OnShutdown(hook LifecycleHook) {
+	bp.lifecycle.onShutdown(hook)
+}
+
+func (bp impl) runStartHooks(ctx context.Context) error {
+	return bp.lifecycle.runStart(ctx)
+}
+
+func (bp impl) runShutdownHooks(ctx context.Context) error {
+	return bp.lifecycle.runShutdown(ctx)
+}
+
 func (bp impl) Close() error {
 	err := bp.closers.Close()
 	if err != nil {
@@ -415,14 +485,16 @@ type NewTestBaseplateArgs struct {
 // the monitoring or logging frameworks.
 func NewTestBaseplate(args NewTestBaseplateArgs) Baseplate {
 	return &impl{
-		cfg:     args.Config,
-		secrets: args.Store,
-		ecImpl:  args.EdgeContextImpl,
-		closers: batchcloser.New(),
+		cfg:       args.Config,
+		secrets:   args.Store,
+		ecImpl:    args.EdgeContextImpl,
+		closers:   batchcloser.New(),
+		lifecycle: new(lifecycle),
 	}
 }
 
 var (
-	_ Baseplate = impl{}
-	_ Baseplate = (*impl)(nil)
+	_ Baseplate       = impl{}
+	_ Baseplate       = (*impl)(nil)
+	_ lifecycleRunner = impl{}
 )
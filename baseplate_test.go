@@ -396,3 +396,87 @@ anotherbackend:
 		}
 	})
 }
+
+type shutdownServer struct {
+	testServer
+
+	hasDeadline bool
+	closed      bool
+	drainedAt   time.Time
+	shutdownAt  time.Time
+}
+
+func (s *shutdownServer) StartDraining() {
+	s.drainedAt = time.Now()
+}
+
+func (s *shutdownServer) Close() error {
+	s.closed = true
+	return s.testServer.Close()
+}
+
+func (s *shutdownServer) Shutdown(ctx context.Context) error {
+	_, s.hasDeadline = ctx.Deadline()
+	s.shutdownAt = time.Now()
+	s.wg.Done()
+	return nil
+}
+
+var (
+	_ baseplate.Shutdowner   = (*shutdownServer)(nil)
+	_ baseplate.DrainStarter = (*shutdownServer)(nil)
+)
+
+func TestServeShutdowner(t *testing.T) {
+	t.Parallel()
+
+	const stopDelay = 50 * time.Millisecond
+
+	store := newSecretsStore(t)
+	defer store.Close()
+
+	bp := baseplate.NewTestBaseplate(baseplate.NewTestBaseplateArgs{
+		Config: baseplate.Config{
+			StopTimeout: testTimeout,
+			StopDelay:   stopDelay,
+		},
+		Store:           store,
+		EdgeContextImpl: ecinterface.Mock(),
+	})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	server := &shutdownServer{
+		testServer: testServer{
+			bp: bp,
+			wg: &wg,
+		},
+	}
+
+	ch := make(chan error)
+	go func() {
+		ch <- baseplate.Serve(context.Background(), baseplate.ServeArgs{Server: server})
+	}()
+
+	time.Sleep(time.Millisecond)
+	p, err := os.FindProcess(syscall.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Signal(os.Interrupt)
+	if err := <-ch; err != nil {
+		t.Fatalf("Serve returned error: %v", err)
+	}
+
+	if server.closed {
+		t.Error("Expected Shutdown to be called instead of Close")
+	}
+	if !server.hasDeadline {
+		t.Error("Expected Shutdown to be called with a deadline from StopTimeout")
+	}
+	if server.drainedAt.IsZero() {
+		t.Fatal("Expected StartDraining to be called")
+	}
+	if d := server.shutdownAt.Sub(server.drainedAt); d < stopDelay {
+		t.Errorf("Expected StartDraining to be called StopDelay (%v) before Shutdown, got %v", stopDelay, d)
+	}
+}
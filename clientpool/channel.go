@@ -3,11 +3,15 @@ package clientpool
 import (
 	"context"
 	"errors"
+	"fmt"
 	"sync/atomic"
 	"time"
 
-	"github.com/reddit/baseplate.go/log"
+	"github.com/prometheus/client_golang/prometheus"
 	"golang.org/x/time/rate"
+
+	"github.com/reddit/baseplate.go/log"
+	"github.com/reddit/baseplate.go/prometheusbp"
 )
 
 type channelPool struct {
@@ -15,13 +19,59 @@ type channelPool struct {
 	opener     ClientOpener
 	numActive  atomic.Int32
 	maxClients int
+	validator  Validator
+
+	// freed is signaled when Release frees up capacity without putting a client
+	// back into pool, e.g. when it failed to reopen a closed client,
+	// so that the waiters in GetWithTimeout can open a new client.
+	freed chan struct{}
 }
 
-// Make sure channelPool implements Pool interface.
-var _ Pool = (*channelPool)(nil)
+// Make sure channelPool implements BlockingPool interface.
+var _ BlockingPool = (*channelPool)(nil)
+
+var errGetAfterClose = errors.New("clientpool: Get called after Close")
+
+// Validator checks whether an idle client from the pool is still usable.
+//
+// It's called by the pool before handing out an idle client,
+// after the client reported IsOpen as true.
+// When it returns false, the client is closed and discarded.
+type Validator func(Client) bool
+
+type channelPoolOpts struct {
+	validator Validator
+}
+
+// ChannelPoolOption is the optional configuration used in NewChannelPool.
+type ChannelPoolOption func(*channelPoolOpts)
+
+// WithValidator sets the Validator used by the pool to check the idle clients
+// before handing them out in Get and GetWithTimeout.
+//
+// When an idle client fails the validation, it's closed and the next idle
+// client is tried, and a new client is opened when there's no idle client
+// left. The newly opened clients are not validated.
+//
+// The validator is called on the hot path of every Get,
+// so it adds its latency to every request using the pool,
+// and it should be cheap (e.g. checking the age or a local state of the
+// client, rather than doing a network round trip).
+//
+// By default (or when validator is nil) there's no validation beyond IsOpen.
+func WithValidator(validator Validator) ChannelPoolOption {
+	return func(o *channelPoolOpts) {
+		o.validator = validator
+	}
+}
 
 // NewChannelPool creates a new client pool implemented via channel.
-func NewChannelPool(ctx context.Context, requiredInitialClients, bestEffortInitialClients, maxClients int, opener ClientOpener) (_ Pool, err error) {
+func NewChannelPool(ctx context.Context, requiredInitialClients, bestEffortInitialClients, maxClients int, opener ClientOpener, options ...ChannelPoolOption) (_ Pool, err error) {
+	var opts channelPoolOpts
+	for _, opt := range options {
+		opt(&opts)
+	}
+
 	if !(requiredInitialClients <= bestEffortInitialClients && bestEffortInitialClients <= maxClients) {
 		return nil, &ConfigError{
 			BestEffortInitialClients: bestEffortInitialClients,
@@ -86,39 +136,128 @@ func NewChannelPool(ctx context.Context, requiredInitialClients, bestEffortIniti
 		pool:       pool,
 		opener:     opener,
 		maxClients: maxClients,
+		validator:  opts.validator,
+		freed:      make(chan struct{}, 1),
 	}, nil
 }
 
 // Get returns a client from the pool.
+//
+// Get never blocks waiting for other clients to be released.
+// When there's no idle client in the pool it opens a new one,
+// unless the pool is exhausted, in which case ErrExhausted is returned
+// immediately.
+// Use GetWithTimeout to wait for a client to be released instead.
+//
+// The time spent in Get is reported to the clientpool_get_wait_seconds
+// histogram, which is mostly the time spent in opening new clients.
 func (cp *channelPool) Get() (client Client, err error) {
+	start := time.Now()
 	defer func() {
-		if err == nil {
-			cp.numActive.Add(1)
-		}
+		cp.onGet(start, err)
 	}()
+	return cp.get()
+}
 
-	select {
-	case c, ok := <-cp.pool:
-		if !ok {
-			return nil, errors.New("clientpool: Get called after Close")
+// GetWithTimeout returns a client from the pool.
+//
+// Unlike Get, when the pool is exhausted it waits for a client to be released
+// back to the pool, or for the capacity to be freed up by a release,
+// until ctx is done.
+// If ctx is done before any client is released, the returned error wraps both
+// ErrExhausted and ctx.Err().
+//
+// The time spent in GetWithTimeout, including the time spent waiting, is
+// reported to the clientpool_get_wait_seconds histogram.
+func (cp *channelPool) GetWithTimeout(ctx context.Context) (client Client, err error) {
+	start := time.Now()
+	defer func() {
+		cp.onGet(start, err)
+	}()
+
+	client, err = cp.get()
+	if !errors.Is(err, ErrExhausted) {
+		return client, err
+	}
+
+	for {
+		select {
+		case c, ok := <-cp.pool:
+			if !ok {
+				return nil, errGetAfterClose
+			}
+			if cp.usable(c) {
+				return c, nil
+			}
+			// See the comment in get.
+			c.Close()
+			return cp.opener()
+		case <-cp.freed:
+			client, err = cp.get()
+			if !errors.Is(err, ErrExhausted) {
+				if err == nil && cp.NumActiveClients()+1 < int32(cp.maxClients) {
+					// Multiple releases could be coalesced into a single signal,
+					// pass it on to the next waiter if there's still capacity left.
+					cp.notifyFreed()
+				}
+				return client, err
+			}
+			// Another caller took the freed capacity first, keep waiting.
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%w: %w", ErrExhausted, ctx.Err())
 		}
-		if c.IsOpen() {
-			return c, nil
+	}
+}
+
+func (cp *channelPool) onGet(start time.Time, err error) {
+	getWaitHisto.With(prometheus.Labels{
+		successLabel: prometheusbp.BoolString(err == nil),
+	}).Observe(time.Since(start).Seconds())
+	if err == nil {
+		cp.numActive.Add(1)
+	}
+}
+
+// usable returns true when c is open and passes the validator, if any.
+func (cp *channelPool) usable(c Client) bool {
+	if !c.IsOpen() {
+		return false
+	}
+	return cp.validator == nil || cp.validator(c)
+}
+
+func (cp *channelPool) get() (client Client, err error) {
+	// Without a validator, at most one idle client is tried to keep the
+	// original behavior.
+	tries := 1
+	if cp.validator != nil {
+		tries = cp.maxClients
+	}
+	for i := 0; i < tries; i++ {
+		select {
+		case c, ok := <-cp.pool:
+			if !ok {
+				return nil, errGetAfterClose
+			}
+			if cp.usable(c) {
+				return c, nil
+			}
+			// For thrift connections, IsOpen could return false in both explicit and
+			// implicit closed situations.
+			// In implicit closed situation, IsOpen does a connectivity check and
+			// returns false if that check fails. In such case we should still close the
+			// connection explicitly to avoid resource leak.
+			// In explicit situation, calling Close again will just return an already
+			// closed error, which is harmless here.
+			c.Close()
+			continue
+		default:
 		}
-		// For thrift connections, IsOpen could return false in both explicit and
-		// implicit closed situations.
-		// In implicit closed situation, IsOpen does a connectivity check and
-		// returns false if that check fails. In such case we should still close the
-		// connection explicitly to avoid resource leak.
-		// In explicit situation, calling Close again will just return an already
-		// closed error, which is harmless here.
-		c.Close()
-	default:
+		break
 	}
 
 	if cp.IsExhausted() {
-		err = ErrExhausted
-		return
+		return nil, ErrExhausted
 	}
 	return cp.opener()
 }
@@ -135,7 +274,15 @@ func (cp *channelPool) Release(c Client) error {
 
 	// As long as c is not nil, we always need to decrease numActive by 1,
 	// even if we encounter errors here, either due to close or opener.
-	defer cp.numActive.Add(-1)
+	var pooled bool
+	defer func() {
+		cp.numActive.Add(-1)
+		if !pooled {
+			// No client was put back for the waiters in GetWithTimeout,
+			// wake one of them up to open a new one instead.
+			cp.notifyFreed()
+		}
+	}()
 
 	if !c.IsOpen() {
 		// Even when c.IsOpen reported false, still call Close explicitly to avoid
@@ -152,6 +299,7 @@ func (cp *channelPool) Release(c Client) error {
 
 	select {
 	case cp.pool <- c:
+		pooled = true
 		return nil
 	default:
 		// Pool is full, just close it instead.
@@ -159,6 +307,15 @@ func (cp *channelPool) Release(c Client) error {
 	}
 }
 
+// notifyFreed wakes up one of the waiters in GetWithTimeout without blocking.
+func (cp *channelPool) notifyFreed() {
+	select {
+	case cp.freed <- struct{}{}:
+	default:
+		// A notification is already pending.
+	}
+}
+
 // Close closes the pool, and all allocated clients.
 func (cp *channelPool) Close() error {
 	var lastErr error
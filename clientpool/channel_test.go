@@ -5,6 +5,7 @@ import (
 	"errors"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/reddit/baseplate.go/clientpool"
 )
@@ -113,3 +114,145 @@ func TestChannelPoolWithOpenerFailure(t *testing.T) {
 		},
 	)
 }
+
+func TestChannelPoolGetWithTimeout(t *testing.T) {
+	var failNext atomic.Bool
+	opener := func() (clientpool.Client, error) {
+		if failNext.CompareAndSwap(true, false) {
+			return nil, errors.New("failed")
+		}
+		return &testClient{}, nil
+	}
+
+	const min, init, max = 0, 1, 1
+	p, err := clientpool.NewChannelPool(context.Background(), min, init, max, opener)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := p.(clientpool.BlockingPool)
+
+	c, err := pool.GetWithTimeout(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkActiveAndAllocated(t, pool, 1, 0)
+
+	t.Run("timeout", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err := pool.GetWithTimeout(ctx)
+		if !errors.Is(err, clientpool.ErrExhausted) {
+			t.Errorf("Expected error to wrap ErrExhausted, got %v", err)
+		}
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected error to wrap context.DeadlineExceeded, got %v", err)
+		}
+		checkActiveAndAllocated(t, pool, 1, 0)
+	})
+
+	t.Run("released", func(t *testing.T) {
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			if err := pool.Release(c); err != nil {
+				t.Errorf("Release returned error: %v", err)
+			}
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		got, err := pool.GetWithTimeout(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != c {
+			t.Errorf("Expected the released client %p, got %p", c, got)
+		}
+		checkActiveAndAllocated(t, pool, 1, 0)
+	})
+
+	t.Run("release-reopen-failed", func(t *testing.T) {
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			// Release fails to reopen the closed client,
+			// the waiter should still be woken up to open a new one.
+			c.Close()
+			failNext.Store(true)
+			if err := pool.Release(c); err == nil {
+				t.Error("Expected Release to return error")
+			}
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		got, err := pool.GetWithTimeout(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got == c {
+			t.Error("Expected a newly opened client, got the closed one")
+		}
+		checkActiveAndAllocated(t, pool, 1, 0)
+	})
+}
+
+func TestChannelPoolWithValidator(t *testing.T) {
+	var clients []*testClient
+	opener := func() (clientpool.Client, error) {
+		c := &testClient{}
+		clients = append(clients, c)
+		return c, nil
+	}
+	stale := make(map[clientpool.Client]bool)
+	validator := func(c clientpool.Client) bool {
+		return !stale[c]
+	}
+
+	const min, init, max = 3, 3, 5
+	pool, err := clientpool.NewChannelPool(
+		context.Background(),
+		min,
+		init,
+		max,
+		opener,
+		clientpool.WithValidator(validator),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	stale[clients[0]] = true
+	stale[clients[1]] = true
+
+	c, err := pool.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c != clients[2] {
+		t.Errorf("Expected the only valid idle client, got %p", c)
+	}
+	if len(clients) != init {
+		t.Errorf("Expected no new clients opened, got %d clients", len(clients))
+	}
+	for i := 0; i < 2; i++ {
+		if !clients[i].closed {
+			t.Errorf("Expected stale client #%d to be closed", i)
+		}
+	}
+	checkActiveAndAllocated(t, pool, 1, 0)
+
+	stale[clients[2]] = true
+	if err := pool.Release(c); err != nil {
+		t.Fatal(err)
+	}
+	c, err = pool.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c != clients[3] {
+		t.Errorf("Expected a newly opened client, got %p", c)
+	}
+	if !clients[2].closed {
+		t.Error("Expected stale client #2 to be closed")
+	}
+}
@@ -1,6 +1,7 @@
 package clientpool
 
 import (
+	"context"
 	"io"
 )
 
@@ -28,3 +29,13 @@ type Pool interface {
 	NumAllocated() int32
 	IsExhausted() bool
 }
+
+// BlockingPool defines a Pool that can also wait for a client to be released
+// when it's exhausted.
+//
+// The pool returned by NewChannelPool implements BlockingPool.
+type BlockingPool interface {
+	Pool
+
+	GetWithTimeout(ctx context.Context) (Client, error)
+}
@@ -0,0 +1,25 @@
+package clientpool
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/reddit/baseplate.go/internal/prometheusbpint"
+	"github.com/reddit/baseplate.go/prometheusbp"
+)
+
+const (
+	successLabel = "clientpool_success"
+)
+
+var (
+	getWaitLabels = []string{
+		successLabel,
+	}
+
+	getWaitHisto = promauto.With(prometheusbpint.GlobalRegistry).NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "clientpool_get_wait_seconds",
+		Help:    "The time spent in Get before a client is obtained or the attempt fails",
+		Buckets: prometheusbp.DefaultLatencyBuckets,
+	}, getWaitLabels)
+)
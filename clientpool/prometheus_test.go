@@ -0,0 +1,50 @@
+package clientpool
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/reddit/baseplate.go/prometheusbp/promtest"
+)
+
+type fakeClient struct{}
+
+func (fakeClient) IsOpen() bool { return true }
+
+func (fakeClient) Close() error { return nil }
+
+func TestGetWaitHisto(t *testing.T) {
+	getWaitHisto.Reset()
+	defer getWaitHisto.Reset()
+
+	successMetric := promtest.NewPrometheusMetricTest(t, "get wait success", getWaitHisto, prometheus.Labels{
+		successLabel: "true",
+	})
+	failureMetric := promtest.NewPrometheusMetricTest(t, "get wait failure", getWaitHisto, prometheus.Labels{
+		successLabel: "false",
+	})
+
+	const max = 2
+	pool, err := NewChannelPool(context.Background(), 0, 1, max, func() (Client, error) {
+		return fakeClient{}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	for i := 0; i < max; i++ {
+		if _, err := pool.Get(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := pool.Get(); !errors.Is(err, ErrExhausted) {
+		t.Errorf("Expected ErrExhausted, got %v", err)
+	}
+
+	successMetric.CheckSampleCountDelta(max)
+	failureMetric.CheckSampleCountDelta(1)
+}
@@ -8,9 +8,11 @@ import (
 	"context"
 	"crypto/sha1"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"math/big"
+	"sort"
 	"strings"
 	"time"
 
@@ -30,6 +32,22 @@ const (
 	targetAllOverride = `{"OVERRIDE": true}`
 )
 
+// OverrideGroupsKey is the key of the system entry in the experiments file
+// declaring the mutually-exclusive experiment groups.
+//
+// Its value is a map from the group name to the names of the experiments in
+// the group, for example:
+//
+//	"$override_groups": {
+//	  "home_feed": ["experiment_a", "experiment_b"]
+//	}
+//
+// Users are bucketed into exactly one experiment of each group, using the
+// group name as the seed, so a user eligible for one of the experiments in the
+// group is always excluded from the others.
+// All the experiments in the same group should use the same bucket_val.
+const OverrideGroupsKey = "$override_groups"
+
 var variantTotalRequests = promauto.With(prometheusbpint.GlobalRegistry).NewCounter(prometheus.CounterOpts{
 	Name: "experiments_go_variant_requests_total",
 	Help: "Total experiments.go Variant() request count",
@@ -59,6 +77,28 @@ func (e MissingBucketKeyError) Error() string {
 	)
 }
 
+// InvalidInputError is a special error returned by Variant functions,
+// to indicate that a value from the args map is of a type that is not
+// supported by bucketing and targeting.
+//
+// Only the bucketing value and the fields compared by the targeting and
+// overrides of the experiment are checked.
+// Supported types for them are string, bool, int, float64, json.Number and nil.
+type InvalidInputError struct {
+	ExperimentName string
+	ArgsKey        string
+	Value          interface{}
+}
+
+func (e InvalidInputError) Error() string {
+	return fmt.Sprintf(
+		"experiment: unsupported type %T for %q in call to variant for experiment %q",
+		e.Value,
+		e.ArgsKey,
+		e.ExperimentName,
+	)
+}
+
 // Experiments offers access to the experiment framework with automatic refresh
 // when there are change.
 //
@@ -70,24 +110,21 @@ type Experiments struct {
 	eventLogger EventLogger
 }
 
+var _ io.Closer = (*Experiments)(nil)
+
 // NewExperiments returns a new instance of the experiments clients. The path
 // points to the experiments file that will be parsed.
 //
+// The experiments file is watched for changes until Close is called.
+//
 // Context should come with a timeout otherwise this might block forever, i.e.
 // if the path never becomes available.
 func NewExperiments(ctx context.Context, path string, eventLogger EventLogger, logger log.Wrapper) (*Experiments, error) {
-	parser := func(r io.Reader) (document, error) {
-		var doc document
-		err := json.NewDecoder(r).Decode(&doc)
-		if err != nil {
-			return nil, err
-		}
-		return doc, nil
-	}
+	var parser documentParser
 	result, err := filewatcher.New(
 		ctx,
 		path,
-		parser,
+		parser.parse,
 	)
 	if err != nil {
 		return nil, err
@@ -98,6 +135,18 @@ func NewExperiments(ctx context.Context, path string, eventLogger EventLogger, l
 	}, nil
 }
 
+// Close stops watching the experiments file, releasing the resources used by
+// the underlying filewatcher.
+//
+// After Close is called, the experiments client keeps using the last loaded
+// experiments file: Variant, Explain, and Expose still work, but won't see any
+// further changes to the file.
+//
+// It's OK to call Close multiple times. Close never returns an error.
+func (e *Experiments) Close() error {
+	return e.watcher.Close()
+}
+
 // Variant determines the variant, if any, of this experiment is active.
 //
 // All arguments needed for bucketing, targeting, and variant overrides should
@@ -110,6 +159,9 @@ func NewExperiments(ctx context.Context, path string, eventLogger EventLogger, l
 // This function might return MissingBucketKeyError as the error.
 // Caller usually want to check for that and handle it differently from other
 // errors. See its documentation for more details.
+//
+// If any of the values in args used by bucketing or targeting is of an
+// unsupported type, InvalidInputError will be returned.
 func (e *Experiments) Variant(name string, args map[string]interface{}, bucketingEventOverride bool) (string, error) {
 	variantTotalRequests.Inc()
 
@@ -120,13 +172,23 @@ func (e *Experiments) Variant(name string, args map[string]interface{}, bucketin
 	return experiment.Variant(args)
 }
 
+// Explain is the same as Variant, but also returns the reason the variant was
+// chosen, for example the override that matched.
+func (e *Experiments) Explain(name string, args map[string]interface{}) (Explanation, error) {
+	experiment, err := e.experiment(name)
+	if err != nil {
+		return Explanation{OverrideIndex: NoOverride}, err
+	}
+	return experiment.Explain(args)
+}
+
 // Expose logs an event to indicate that a user has been exposed to an
 // experimental treatment.
 func (e *Experiments) Expose(ctx context.Context, experimentName string, event ExperimentEvent) error {
 	exposeTotalRequests.Inc()
 
 	doc := e.watcher.Get()
-	experiment, ok := doc[experimentName]
+	experiment, ok := doc.experiments[experimentName]
 	if !ok {
 		return UnknownExperimentError(experimentName)
 	}
@@ -137,19 +199,82 @@ func (e *Experiments) Expose(ctx context.Context, experimentName string, event E
 	return e.eventLogger.Log(ctx, event)
 }
 
+// AllVariants determines the variants of all the loaded experiments for the
+// given args in one pass, for debugging and internal tooling purposes, e.g. to
+// show which experiments a user is in.
+//
+// The returned map has an entry for every experiment, with the variant
+// as the value, or an empty string if no variant is active.
+// The experiments that are not simple experiments (e.g. dynamic configs) are
+// skipped.
+// MissingBucketKeyError is not treated as an error, as the experiments
+// bucketing on other keys simply don't apply to the args.
+// The other errors of the experiments are joined and returned along with the
+// variants of the rest of the experiments.
+//
+// Unlike Variant, AllVariants does not report the variant request metrics.
+func (e *Experiments) AllVariants(args map[string]interface{}) (map[string]string, error) {
+	doc := e.watcher.Get()
+	args = lowerArguments(args)
+	variants := make(map[string]string, len(doc.compiled))
+	var errs []error
+	// scratch is reused for every experiment, see the comment in experiment for
+	// why the copy is needed.
+	var scratch SimpleExperiment
+	for name, compiled := range doc.compiled {
+		if compiled.experiment == nil {
+			if config := doc.experiments[name]; config == nil || !isSimpleExperiment(config.Type) {
+				continue
+			}
+			errs = append(errs, fmt.Errorf("experiments: experiment %q: %w", name, compiled.err))
+			continue
+		}
+		scratch = *compiled.experiment
+		scratch.group = doc.groups[name]
+		explanation, err := scratch.explain(args)
+		if err != nil && !errors.As(err, new(MissingBucketKeyError)) {
+			errs = append(errs, err)
+		}
+		variants[name] = explanation.Variant
+	}
+	return variants, errors.Join(errs...)
+}
+
 func (e *Experiments) experiment(name string) (*SimpleExperiment, error) {
 	doc := e.watcher.Get()
-	experiment, ok := doc[name]
+	compiled, ok := doc.compiled[name]
 	if !ok {
 		return nil, UnknownExperimentError(name)
 	}
-	if isSimpleExperiment(experiment.Type) {
-		return NewSimpleExperiment(experiment)
+	if compiled.err != nil {
+		return nil, compiled.err
 	}
-	return nil, fmt.Errorf(
-		"experiments.Experiments.Variant: unknown experiment %q",
-		experiment.Type,
-	)
+	// The compiled experiment is shared across reloads, so make a shallow copy
+	// before setting the group, which could change without the experiment
+	// itself being changed.
+	experiment := *compiled.experiment
+	experiment.group = doc.groups[name]
+	return &experiment, nil
+}
+
+// documentParser parses the experiments file and compiles the experiments in
+// it.
+//
+// It keeps the last parsed document, so experiments with unchanged config
+// reuse the previously compiled SimpleExperiment, including the targeting
+// trees, instead of being compiled again on every reload.
+type documentParser struct {
+	prev document
+}
+
+func (p *documentParser) parse(r io.Reader) (document, error) {
+	var doc document
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return document{}, err
+	}
+	doc.compile(p.prev)
+	p.prev = doc
+	return doc, nil
 }
 
 // Experiment represents the experiment and configures the available
@@ -162,9 +287,158 @@ type Experiment struct {
 	BucketSeed        string                       `json:"bucket_seed"`
 	Targeting         json.RawMessage              `json:"targeting"`
 	Overrides         []map[string]json.RawMessage `json:"overrides"`
+	Rollout           *RolloutSchedule             `json:"rollout"`
+}
+
+// RolloutSchedule ramps the size of the variant of a feature_rollout
+// experiment linearly from 0 to the configured size,
+// between StartTimestamp and EndTimestamp.
+//
+// The users are included in the order of their buckets,
+// so a user getting the variant keeps getting it as the ramp grows.
+type RolloutSchedule struct {
+	// StartTimestamp is a float of seconds since the epoch of the date and time
+	// when the ramp starts from 0.
+	StartTimestamp timebp.TimestampSecondF `json:"start_ts"`
+	// EndTimestamp is a float of seconds since the epoch of the date and time
+	// when the ramp reaches the configured size of the variant.
+	EndTimestamp timebp.TimestampSecondF `json:"end_ts"`
+}
+
+// document is the parsed experiments file.
+type document struct {
+	experiments map[string]*ExperimentConfig
+	// hashes are the hashes of the raw config of the experiments.
+	hashes map[string][sha1.Size]byte
+	// compiled are the compiled experiments, populated by compile.
+	compiled map[string]*compiledExperiment
+	// groups maps the experiment names to their memberships of the override
+	// groups they belong to.
+	groups map[string]*exclusionGroupMember
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+//
+// Entries with keys starting with "$" are system entries instead of
+// experiments. OverrideGroupsKey is the only supported system entry,
+// other system entries are ignored.
+func (d *document) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	d.experiments = make(map[string]*ExperimentConfig, len(raw))
+	d.hashes = make(map[string][sha1.Size]byte, len(raw))
+	d.groups = nil
+	for key, value := range raw {
+		if key == OverrideGroupsKey {
+			var groups map[string][]string
+			if err := json.Unmarshal(value, &groups); err != nil {
+				return fmt.Errorf("experiments: failed to parse %q: %w", OverrideGroupsKey, err)
+			}
+			d.groups = make(map[string]*exclusionGroupMember)
+			for name, experiments := range groups {
+				group := &exclusionGroup{
+					name: name,
+					size: len(experiments),
+				}
+				for i, experiment := range experiments {
+					if other, ok := d.groups[experiment]; ok {
+						return fmt.Errorf(
+							"experiments: experiment %q is in both override groups %q and %q",
+							experiment,
+							other.group.name,
+							name,
+						)
+					}
+					d.groups[experiment] = &exclusionGroupMember{
+						group: group,
+						index: i,
+					}
+				}
+			}
+			continue
+		}
+		if strings.HasPrefix(key, "$") {
+			continue
+		}
+		var experiment *ExperimentConfig
+		if err := json.Unmarshal(value, &experiment); err != nil {
+			return fmt.Errorf("experiments: failed to parse experiment %q: %w", key, err)
+		}
+		d.experiments[key] = experiment
+		d.hashes[key] = sha1.Sum(value)
+	}
+	return nil
+}
+
+// compiledExperiment is an experiment compiled from its config.
+type compiledExperiment struct {
+	hash       [sha1.Size]byte
+	experiment *SimpleExperiment
+	// err is the error compiling the experiment, returned by
+	// Experiments.Variant.
+	err error
+}
+
+// compile compiles all the experiments in the document.
+//
+// The compiled experiments from prev are reused if their config hashes are
+// unchanged.
+func (d *document) compile(prev document) {
+	d.compiled = make(map[string]*compiledExperiment, len(d.experiments))
+	for name, config := range d.experiments {
+		hash := d.hashes[name]
+		if old, ok := prev.compiled[name]; ok && old.hash == hash {
+			d.compiled[name] = old
+			continue
+		}
+		compiled := &compiledExperiment{hash: hash}
+		if config != nil && isSimpleExperiment(config.Type) {
+			compiled.experiment, compiled.err = NewSimpleExperiment(config)
+		} else {
+			var experimentType string
+			if config != nil {
+				experimentType = config.Type
+			}
+			compiled.err = fmt.Errorf(
+				"experiments.Experiments.Variant: unknown experiment %q",
+				experimentType,
+			)
+		}
+		d.compiled[name] = compiled
+	}
+}
+
+// exclusionGroup is a group of mutually-exclusive experiments declared in the
+// OverrideGroupsKey system entry.
+type exclusionGroup struct {
+	name string
+	// size is the number of the experiments in the group.
+	size int
+}
+
+// exclusionGroupMember is the membership of an experiment in an
+// exclusionGroup.
+//
+// The experiments are identified by their keys in the experiments file,
+// both in the group declaration and when looking up their memberships,
+// so it does not depend on the names in their configs.
+type exclusionGroupMember struct {
+	group *exclusionGroup
+	// index is the index of the experiment in the group declaration.
+	index int
 }
 
-type document map[string]*ExperimentConfig
+// includes returns true if the user identified by bucketKey is bucketed into
+// the experiment within the group.
+//
+// The buckets are split evenly among the experiments in the group, in the
+// order they are declared.
+func (m *exclusionGroupMember) includes(bucketKey string) bool {
+	bucket := calculateBucket(m.group.name, bucketKey, numBuckets)
+	return bucket*m.group.size/numBuckets == m.index
+}
 
 // ExperimentConfig holds the information for the experiment plus additional
 // data around the experiment.
@@ -232,7 +506,14 @@ type SimpleExperiment struct {
 	// both AND and OR based logical grouping.
 	targeting Targeting
 	// overrides if matched allow to force a particular variant.
-	overrides []map[string]Targeting
+	// They are evaluated in order and the first match wins.
+	overrides []override
+	// targetedFields are the args keys compared by targeting and overrides.
+	targetedFields []string
+	// group is the override group this experiment belongs to, if any.
+	// Users bucketed into other experiments of the group are excluded from
+	// this experiment.
+	group *exclusionGroupMember
 }
 
 // NewSimpleExperiment returns a new instance of SimpleExperiment. Default
@@ -254,6 +535,15 @@ func NewSimpleExperiment(experiment *ExperimentConfig) (*SimpleExperiment, error
 	if err != nil {
 		return nil, err
 	}
+	if schedule := experiment.Experiment.Rollout; schedule != nil {
+		rollout, ok := variantSet.(*RolloutVariantSet)
+		if !ok {
+			return nil, VariantValidationError("rollout schedule is only supported by feature_rollout experiments")
+		}
+		if err := rollout.setSchedule(schedule.StartTimestamp.ToTime(), schedule.EndTimestamp.ToTime()); err != nil {
+			return nil, err
+		}
+	}
 
 	targetingConfig := experiment.Experiment.Targeting
 	if len(targetingConfig) == 0 {
@@ -263,19 +553,37 @@ func NewSimpleExperiment(experiment *ExperimentConfig) (*SimpleExperiment, error
 	if err != nil {
 		return nil, err
 	}
-	overrides := make([]map[string]Targeting, len(experiment.Experiment.Overrides))
-	for i, override := range experiment.Experiment.Overrides {
-		for variant, overrideConfig := range override {
-			override, err := NewTargeting(overrideConfig)
+	var overrides []override
+	for i, entry := range experiment.Experiment.Overrides {
+		// JSON object key order is not preserved in maps, so variants within the
+		// same entry are sorted by name to keep the evaluation order deterministic.
+		variants := make([]string, 0, len(entry))
+		for variant := range entry {
+			variants = append(variants, variant)
+		}
+		sort.Strings(variants)
+		for _, variant := range variants {
+			targeting, err := NewTargeting(entry[variant])
 			if err != nil {
 				return nil, err
 			}
-			if overrides[i] == nil {
-				overrides[i] = make(map[string]Targeting)
-			}
-			overrides[i][variant] = override
+			overrides = append(overrides, override{
+				index:     i,
+				variant:   variant,
+				targeting: targeting,
+			})
 		}
 	}
+	fields := make(map[string]bool)
+	collectTargetedFields(targeting, fields)
+	for _, o := range overrides {
+		collectTargetedFields(o.targeting, fields)
+	}
+	targetedFields := make([]string, 0, len(fields))
+	for field := range fields {
+		targetedFields = append(targetedFields, field)
+	}
+	sort.Strings(targetedFields)
 	return &SimpleExperiment{
 		id:         experiment.ID,
 		name:       experiment.Name,
@@ -288,6 +596,8 @@ func NewSimpleExperiment(experiment *ExperimentConfig) (*SimpleExperiment, error
 		variantSet: variantSet,
 		targeting:  targeting,
 		overrides:  overrides,
+
+		targetedFields: targetedFields,
 	}, nil
 }
 
@@ -297,38 +607,88 @@ func NewSimpleExperiment(experiment *ExperimentConfig) (*SimpleExperiment, error
 // This function might return MissingBucketKeyError as the error.
 // Caller usually want to check for that and handle it differently from other
 // errors. See its documentation for more details.
+//
+// If any of the values in args used by bucketing or targeting is of an
+// unsupported type, InvalidInputError will be returned.
+//
+// Overrides are evaluated in the order they are defined in the config, and the
+// first matching one wins.
+// When a single override entry has multiple variants,
+// they are evaluated in the lexical order of the variant names.
 func (e *SimpleExperiment) Variant(args map[string]interface{}) (string, error) {
+	explanation, err := e.Explain(args)
+	return explanation.Variant, err
+}
+
+// NoOverride is the Explanation.OverrideIndex when no override matched.
+const NoOverride = -1
+
+// Explanation explains the variant returned by Variant.
+type Explanation struct {
+	// Variant is the same variant returned by Variant.
+	Variant string
+
+	// OverrideIndex is the index of the matched entry in the overrides of the
+	// experiment config, or NoOverride if the variant was not chosen by an
+	// override.
+	OverrideIndex int
+}
+
+// Explain is the same as Variant,
+// but also returns the reason the variant was chosen.
+func (e *SimpleExperiment) Explain(args map[string]interface{}) (Explanation, error) {
+	return e.explain(lowerArguments(args))
+}
+
+// explain is the implementation of Explain with args already lowered.
+func (e *SimpleExperiment) explain(args map[string]interface{}) (Explanation, error) {
+	explanation := Explanation{OverrideIndex: NoOverride}
 	if !e.isEnabled() {
-		return "", nil
+		return explanation, nil
+	}
+	if err := e.validateArguments(args); err != nil {
+		return explanation, err
 	}
-	args = lowerArguments(args)
 	if value := args[e.bucketVal]; value == nil || value == "" {
-		return "", MissingBucketKeyError{
+		return explanation, MissingBucketKeyError{
 			ExperimentName: e.name,
 			ArgsKey:        e.bucketVal,
 		}
 	}
 
 	for _, override := range e.overrides {
-		for variant, targeting := range override {
-			if targeting.Evaluate(args) {
-				return variant, nil
-			}
+		if override.targeting.Evaluate(args) {
+			explanation.Variant = override.variant
+			explanation.OverrideIndex = override.index
+			return explanation, nil
 		}
 	}
 	if !e.targeting.Evaluate(args) {
-		return "", nil
+		return explanation, nil
 	}
 	bucketVal, ok := args[e.bucketVal].(string)
 	if !ok {
-		return "", fmt.Errorf(
-			"experiment.SimpleExperiment.Variant: expected bucket val to be a string, actual: %T",
-			args[e.bucketVal],
-		)
+		return explanation, InvalidInputError{
+			ExperimentName: e.name,
+			ArgsKey:        e.bucketVal,
+			Value:          args[e.bucketVal],
+		}
+	}
+	if e.group != nil && !e.group.includes(bucketVal) {
+		return explanation, nil
 	}
 
 	bucket := e.calculateBucket(bucketVal)
-	return e.variantSet.ChooseVariant(bucket), nil
+	explanation.Variant = e.variantSet.ChooseVariant(bucket)
+	return explanation, nil
+}
+
+// override is a single variant override of an experiment.
+type override struct {
+	// index is the index of the entry in the overrides config.
+	index     int
+	variant   string
+	targeting Targeting
 }
 
 func lowerArguments(args map[string]interface{}) map[string]interface{} {
@@ -339,12 +699,36 @@ func lowerArguments(args map[string]interface{}) map[string]interface{} {
 	return lowered
 }
 
+// validateArguments checks that the values in args compared by targeting are
+// of the supported types.
+//
+// Other values are not used by the experiment and are not checked.
+func (e *SimpleExperiment) validateArguments(args map[string]interface{}) error {
+	for _, key := range e.targetedFields {
+		value := args[key]
+		switch value.(type) {
+		case nil, string, bool, int, float64, json.Number:
+			continue
+		}
+		return InvalidInputError{
+			ExperimentName: e.name,
+			ArgsKey:        key,
+			Value:          value,
+		}
+	}
+	return nil
+}
+
 func (e *SimpleExperiment) calculateBucket(bucketKey string) int {
+	return calculateBucket(e.bucketSeed, bucketKey, e.numBuckets)
+}
+
+func calculateBucket(seed, bucketKey string, numBuckets int) int {
 	target := new(big.Int)
 	bucket := new(big.Int)
-	hashed := sha1.Sum([]byte(e.bucketSeed + bucketKey))
+	hashed := sha1.Sum([]byte(seed + bucketKey))
 	target.SetBytes(hashed[:])
-	bucket.Mod(target, big.NewInt(int64(e.numBuckets)))
+	bucket.Mod(target, big.NewInt(int64(numBuckets)))
 	return int(bucket.Int64())
 }
 
@@ -1,10 +1,16 @@
 package experiments
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
 	"testing"
 	"time"
 
@@ -443,6 +449,66 @@ func TestOverride(t *testing.T) {
 	}
 }
 
+func TestOverrideOrder(t *testing.T) {
+	t.Parallel()
+
+	override := func(userIDs ...string) json.RawMessage {
+		data, err := json.Marshal(map[string]interface{}{
+			"EQ": map[string]interface{}{
+				"field":  "user_id",
+				"values": userIDs,
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	config := makeTestConfig(
+		"single_variant",
+		Variant{Name: "variant_1", Size: 0},
+		Variant{Name: "variant_2", Size: 0},
+	)
+	// Both overrides match t2_1, and the later one sorts first by name.
+	config.Experiment.Overrides = []map[string]json.RawMessage{
+		{"variant_2": override("t2_1")},
+		{"variant_1": override("t2_1", "t2_2")},
+	}
+	experiment, err := NewSimpleExperiment(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		userID   string
+		expected Explanation
+	}{
+		{
+			userID:   "t2_1",
+			expected: Explanation{Variant: "variant_2", OverrideIndex: 0},
+		},
+		{
+			userID:   "t2_2",
+			expected: Explanation{Variant: "variant_1", OverrideIndex: 1},
+		},
+		{
+			userID:   "t2_3",
+			expected: Explanation{OverrideIndex: NoOverride},
+		},
+	} {
+		// Run multiple times to catch any nondeterministic order.
+		for i := 0; i < 10; i++ {
+			explanation, err := experiment.Explain(map[string]interface{}{"user_id": c.userID})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if explanation != c.expected {
+				t.Errorf("%s: expected %+v, got %+v", c.userID, c.expected, explanation)
+			}
+		}
+	}
+}
+
 // TestRegression250 tests distribution of users into buckets.
 // GitHub issue: https://github.com/reddit/baseplate.go/issues/250
 func TestRegression250(t *testing.T) {
@@ -597,6 +663,53 @@ func TestRegression250(t *testing.T) {
 	})
 }
 
+func TestSimpleExperimentRolloutSchedule(t *testing.T) {
+	schedule := &RolloutSchedule{
+		StartTimestamp: timebp.TimestampSecondF(time.Now().Add(-time.Hour)),
+		EndTimestamp:   timebp.TimestampSecondF(time.Now().Add(time.Hour)),
+	}
+	userIDs := make([]string, 1000)
+	for i := 0; i < len(userIDs); i++ {
+		userIDs[i] = fmt.Sprintf("t2_%03d", i)
+	}
+
+	t.Run("feature_rollout", func(t *testing.T) {
+		config := makeTestConfig("feature_rollout", Variant{Name: "variant_1", Size: 1})
+		config.Experiment.Rollout = schedule
+		experiment, err := NewSimpleExperiment(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var count int
+		for _, userID := range userIDs {
+			variant, err := experiment.Variant(map[string]interface{}{"user_id": userID})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if variant != "" {
+				count++
+			}
+		}
+		// Half way through the ramp, so roughly half of the users should have the
+		// variant.
+		if count < len(userIDs)*4/10 || count > len(userIDs)*6/10 {
+			t.Errorf("Expected about half of the %d users to have the variant, got %d", len(userIDs), count)
+		}
+	})
+
+	t.Run("unsupported-type", func(t *testing.T) {
+		config := makeTestConfig("multi_variant",
+			Variant{Name: "variant_1", Size: 0.1},
+			Variant{Name: "variant_2", Size: 0.1},
+		)
+		config.Experiment.Rollout = schedule
+		var validationErr VariantValidationError
+		if _, err := NewSimpleExperiment(config); !errors.As(err, &validationErr) {
+			t.Errorf("Expected VariantValidationError, got %v", err)
+		}
+	})
+}
+
 func makeTestConfig(experimentType string, variants ...Variant) *ExperimentConfig {
 	return &ExperimentConfig{
 		ID:             1,
@@ -633,3 +746,360 @@ func roundTo(num float64, digits int) float64 {
 	shift := math.Pow(10, float64(digits))
 	return math.Round(num*shift) / shift
 }
+
+func TestVariantInvalidInput(t *testing.T) {
+	config := *simpleConfig
+	config.Experiment.Targeting = json.RawMessage(`{"ALL": [
+		{"EQ": {"field": "logged_in", "value": true}},
+		{"GT": {"field": "score", "value": 1}}
+	]}`)
+	experiment, err := NewSimpleExperiment(&config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		name string
+		args map[string]interface{}
+		key  string
+	}{
+		{
+			name: "float32",
+			args: map[string]interface{}{"user_id": "t2_1", "logged_in": true, "Score": float32(1.5)},
+			key:  "score",
+		},
+		{
+			name: "slice",
+			args: map[string]interface{}{"user_id": "t2_1", "logged_in": []bool{true}, "score": 1.5},
+			key:  "logged_in",
+		},
+		{
+			name: "bucket-val",
+			args: map[string]interface{}{"user_id": 1, "logged_in": true, "score": 1.5},
+			key:  "user_id",
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			variant, err := experiment.Variant(c.args)
+			var iie InvalidInputError
+			if !errors.As(err, &iie) {
+				t.Fatalf("Expected InvalidInputError, got %v", err)
+			}
+			if iie.ArgsKey != c.key {
+				t.Errorf("Expected ArgsKey %q, got %q", c.key, iie.ArgsKey)
+			}
+			if iie.ExperimentName != config.Name {
+				t.Errorf("Expected ExperimentName %q, got %q", config.Name, iie.ExperimentName)
+			}
+			if variant != "" {
+				t.Errorf("Expected empty variant, got %q", variant)
+			}
+		})
+	}
+
+	t.Run("untargeted", func(t *testing.T) {
+		// Values not compared by targeting are not validated.
+		if _, err := experiment.Variant(map[string]interface{}{
+			"user_id":   "t2_1",
+			"logged_in": true,
+			"score":     1.5,
+			"age":       int64(1),
+			"roles":     []string{"admin"},
+			"device":    map[string]string{"os": "ios"},
+		}); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		if _, err := experiment.Variant(map[string]interface{}{
+			"user_id":   "t2_1",
+			"logged_in": nil,
+			"score":     json.Number("42"),
+		}); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	})
+}
+
+func TestOverrideGroups(t *testing.T) {
+	t.Parallel()
+
+	now := float64(time.Now().Unix())
+	experiment := func(id int, name string) map[string]interface{} {
+		return map[string]interface{}{
+			"id":       id,
+			"name":     name,
+			"owner":    "test",
+			"type":     "feature_rollout",
+			"version":  "1",
+			"start_ts": now - 86400,
+			"stop_ts":  now + 86400,
+			"experiment": map[string]interface{}{
+				"variants": []map[string]interface{}{
+					{"name": "active", "size": 1.0},
+				},
+			},
+		}
+	}
+	data, err := json.Marshal(map[string]interface{}{
+		"experiment_a": experiment(1, "experiment_a"),
+		// The override groups refer to the experiments by their keys,
+		// not the names in their configs.
+		"experiment_b": experiment(2, "Experiment B"),
+		"experiment_c": experiment(3, "experiment_c"),
+		OverrideGroupsKey: map[string][]string{
+			"group": {"experiment_a", "experiment_b"},
+		},
+		"$unknown_system_entry": []string{"foo"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "experiments.json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	e, err := NewExperiments(ctx, path, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+
+	counts := make(map[string]int)
+	for i := 0; i < 1000; i++ {
+		args := map[string]interface{}{"user_id": fmt.Sprintf("t2_%d", i)}
+		var active []string
+		for _, name := range []string{"experiment_a", "experiment_b", "experiment_c"} {
+			variant, err := e.Variant(name, args, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if variant != "" {
+				active = append(active, name)
+				counts[name]++
+			}
+		}
+		if slices.Contains(active, "experiment_a") && slices.Contains(active, "experiment_b") {
+			t.Errorf("%v: expected to be in at most one experiment in the group, got %v", args, active)
+		}
+	}
+	if counts["experiment_c"] != 1000 {
+		t.Errorf("expected all users in experiment_c, got %d", counts["experiment_c"])
+	}
+	if counts["experiment_a"]+counts["experiment_b"] != 1000 {
+		t.Errorf("expected all users in one of the experiments in the group, got %v", counts)
+	}
+	if counts["experiment_a"] < 400 || counts["experiment_b"] < 400 {
+		t.Errorf("expected users to be split evenly in the group, got %v", counts)
+	}
+}
+
+func TestOverrideGroupsDuplicated(t *testing.T) {
+	t.Parallel()
+
+	var doc document
+	err := json.Unmarshal([]byte(`{"$override_groups": {"a": ["foo"], "b": ["foo"]}}`), &doc)
+	if err == nil {
+		t.Error("expected error for experiment in multiple override groups, got nil")
+	}
+}
+
+func TestDocumentParserReusesCompiledExperiments(t *testing.T) {
+	t.Parallel()
+
+	experiment := func(id int, name string, values ...string) map[string]interface{} {
+		return map[string]interface{}{
+			"id":      id,
+			"name":    name,
+			"owner":   "test",
+			"type":    "feature_rollout",
+			"version": "1",
+			"experiment": map[string]interface{}{
+				"variants": []map[string]interface{}{
+					{"name": "active", "size": 1.0},
+				},
+				"targeting": map[string]interface{}{
+					"EQ": map[string]interface{}{
+						"field":  "user_id",
+						"values": values,
+					},
+				},
+			},
+		}
+	}
+	parse := func(t *testing.T, p *documentParser, v interface{}) document {
+		t.Helper()
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc, err := p.parse(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return doc
+	}
+
+	var p documentParser
+	first := parse(t, &p, map[string]interface{}{
+		"experiment_a": experiment(1, "experiment_a", "t2_1"),
+		"experiment_b": experiment(2, "experiment_b", "t2_2"),
+	})
+	second := parse(t, &p, map[string]interface{}{
+		"experiment_a": experiment(1, "experiment_a", "t2_1"),
+		"experiment_b": experiment(2, "experiment_b", "t2_3"),
+	})
+
+	if first.compiled["experiment_a"].experiment.targeting != second.compiled["experiment_a"].experiment.targeting {
+		t.Error("expected targeting of unchanged experiment_a to be reused")
+	}
+	if first.compiled["experiment_b"].experiment.targeting == second.compiled["experiment_b"].experiment.targeting {
+		t.Error("expected targeting of changed experiment_b to be rebuilt")
+	}
+
+	if !second.compiled["experiment_b"].experiment.targeting.Evaluate(map[string]interface{}{"user_id": "t2_3"}) {
+		t.Error("expected rebuilt targeting of experiment_b to use the new config")
+	}
+}
+
+func TestExperimentsClose(t *testing.T) {
+	t.Parallel()
+
+	now := float64(time.Now().Unix())
+	data, err := json.Marshal(map[string]interface{}{
+		"experiment_a": map[string]interface{}{
+			"id":       1,
+			"name":     "experiment_a",
+			"owner":    "test",
+			"type":     "feature_rollout",
+			"version":  "1",
+			"start_ts": now - 86400,
+			"stop_ts":  now + 86400,
+			"experiment": map[string]interface{}{
+				"variants": []map[string]interface{}{
+					{"name": "active", "size": 1.0},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "experiments.json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	e, err := NewExperiments(ctx, path, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Close(); err != nil {
+		t.Errorf("Close returned error: %v", err)
+	}
+	// Calling Close again should be no-op.
+	if err := e.Close(); err != nil {
+		t.Errorf("Second Close returned error: %v", err)
+	}
+
+	// Changes after Close should not be picked up.
+	if err := os.WriteFile(path, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	variant, err := e.Variant("experiment_a", map[string]interface{}{"user_id": "t2_1"}, false)
+	if err != nil {
+		t.Fatalf("Expected the last loaded experiments to be used after Close, got error: %v", err)
+	}
+	if variant != "active" {
+		t.Errorf("Expected variant %q, got %q", "active", variant)
+	}
+}
+
+func TestAllVariants(t *testing.T) {
+	t.Parallel()
+
+	now := float64(time.Now().Unix())
+	experiment := func(id int, name string, config map[string]interface{}) map[string]interface{} {
+		experiment := map[string]interface{}{
+			"variants": []map[string]interface{}{
+				{"name": "active", "size": 1.0},
+			},
+		}
+		for k, v := range config {
+			experiment[k] = v
+		}
+		return map[string]interface{}{
+			"id":         id,
+			"name":       name,
+			"owner":      "test",
+			"type":       "feature_rollout",
+			"version":    "1",
+			"start_ts":   now - 86400,
+			"stop_ts":    now + 86400,
+			"experiment": experiment,
+		}
+	}
+	disabled := experiment(3, "experiment_c", nil)
+	disabled["enabled"] = false
+	data, err := json.Marshal(map[string]interface{}{
+		"experiment_a": experiment(1, "experiment_a", nil),
+		"experiment_b": experiment(2, "experiment_b", map[string]interface{}{"bucket_val": "device_id"}),
+		"experiment_c": disabled,
+		"dynamic_config": map[string]interface{}{
+			"id":   4,
+			"name": "dynamic_config",
+			"type": "dynamic_config",
+		},
+		"$unknown_system_entry": []string{"foo"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "experiments.json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	e, err := NewExperiments(ctx, path, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+
+	t.Run("valid", func(t *testing.T) {
+		variants, err := e.AllVariants(map[string]interface{}{"User_ID": "t2_1"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expected := map[string]string{
+			"experiment_a": "active",
+			"experiment_b": "",
+			"experiment_c": "",
+		}
+		if !reflect.DeepEqual(variants, expected) {
+			t.Errorf("Expected %v, got %v", expected, variants)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		variants, err := e.AllVariants(map[string]interface{}{
+			"user_id": 1,
+		})
+		if !errors.As(err, new(InvalidInputError)) {
+			t.Errorf("Expected InvalidInputError, got %v", err)
+		}
+		if variants["experiment_c"] != "" {
+			t.Errorf("Expected no variant for disabled experiment, got %q", variants["experiment_c"])
+		}
+	})
+}
@@ -291,11 +291,34 @@ func (n *ComparisonNode) Evaluate(inputs map[string]interface{}) bool {
 	case int:
 		return n.comparer(float64(cv), value)
 	case float64:
-		n.comparer(cv, value)
+		return n.comparer(cv, value)
 	}
 	return false
 }
 
+// collectTargetedFields adds the input fields compared by the targeting tree
+// t into fields.
+//
+// Custom Targeting implementations are skipped.
+func collectTargetedFields(t Targeting, fields map[string]bool) {
+	switch n := t.(type) {
+	case *AnyNode:
+		for _, child := range n.children {
+			collectTargetedFields(child, fields)
+		}
+	case *AllNode:
+		for _, child := range n.children {
+			collectTargetedFields(child, fields)
+		}
+	case *NotNode:
+		collectTargetedFields(n.child, fields)
+	case *EqualNode:
+		fields[n.fieldName] = true
+	case *ComparisonNode:
+		fields[n.field] = true
+	}
+}
+
 type less func(float64, float64) bool
 
 func greaterThan(i, j float64) bool   { return i > j }
@@ -466,6 +466,16 @@ func TestComparisonNode(t *testing.T) {
 			targetConfig: []byte(`{"GT": {"field": "num_field", "value": 6}}`),
 			expected:     false,
 		},
+		{
+			name:         "gt node float",
+			targetConfig: []byte(`{"GT": {"field": "float_field", "value": 5}}`),
+			expected:     true,
+		},
+		{
+			name:         "le node float",
+			targetConfig: []byte(`{"LE": {"field": "float_field", "value": 5}}`),
+			expected:     false,
+		},
 		{
 			name:         "lt node equal",
 			targetConfig: []byte(`{"LT": {"field": "num_field", "value": 5}}`),
@@ -617,6 +627,7 @@ func inputSet() map[string]interface{} {
 	inputs["bool_field"] = true
 	inputs["str_field"] = "string_value"
 	inputs["num_field"] = 5
+	inputs["float_field"] = 5.5
 	inputs["explicit_nil_field"] = nil
 	return inputs
 }
@@ -1,6 +1,9 @@
 package experiments
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 // VariantSet is the base interface for variant sets. A variant set contains a
 // set of experimental variants, as well as their distributions. It is used by
@@ -140,9 +143,17 @@ func (v *MultiVariantSet) ChooseVariant(bucket int) string {
 // instance, going from 45% to 55% will result in only the new 10% of users
 // changing treatments. The initial 45% will not change. Conversely, going from
 // 55% to 45% will result in only 10% of users losing the treatment.
+//
+// The size of the variant can also be ramped linearly from 0 over a time
+// window, see RolloutSchedule.
 type RolloutVariantSet struct {
 	variant Variant
 	buckets int
+
+	// rampStart and rampEnd are the optional window to ramp the size of the
+	// variant over, set by setSchedule.
+	rampStart time.Time
+	rampEnd   time.Time
 }
 
 // NewRolloutVariantSet returns a new instance of RolloutVariantSet based on
@@ -173,15 +184,53 @@ func (v *RolloutVariantSet) validate(variants []Variant) error {
 	return nil
 }
 
+// setSchedule sets the window to ramp the size of the variant over.
+func (v *RolloutVariantSet) setSchedule(start, end time.Time) error {
+	if start.IsZero() || end.IsZero() {
+		return VariantValidationError("rollout schedule requires both start_ts and end_ts")
+	}
+	if !end.After(start) {
+		return VariantValidationError("rollout schedule end_ts must be after start_ts")
+	}
+	v.rampStart = start
+	v.rampEnd = end
+	return nil
+}
+
 // ChooseVariant deterministically choose a percentage-based variant. Every
 // call with the same bucket and variants will result in the same answer.
+//
+// When the variant set is ramped over a schedule, the answer also depends on
+// the current time, but a bucket having the variant keeps having it as the
+// ramp grows.
 func (v *RolloutVariantSet) ChooseVariant(bucket int) string {
-	if bucket < int(v.variant.Size*float64(v.buckets)) {
+	return v.chooseVariant(bucket, time.Now())
+}
+
+func (v *RolloutVariantSet) chooseVariant(bucket int, now time.Time) string {
+	if bucket < int(v.size(now)*float64(v.buckets)) {
 		return v.variant.Name
 	}
 	return ""
 }
 
+// size returns the effective size of the variant at now.
+//
+// Without a schedule it's always the configured size.
+// With a schedule it's 0 before the window,
+// linearly interpolated from 0 to the configured size within the window,
+// and the configured size after the window.
+func (v *RolloutVariantSet) size(now time.Time) float64 {
+	if v.rampEnd.IsZero() || !now.Before(v.rampEnd) {
+		return v.variant.Size
+	}
+	if !now.After(v.rampStart) {
+		return 0
+	}
+	progress := float64(now.Sub(v.rampStart)) / float64(v.rampEnd.Sub(v.rampStart))
+	return v.variant.Size * progress
+}
+
 // RangeVariantSet is designed to take fixed bucket ranges.
 //
 // This VariantSet allows manually setting bucketing ranges. It takes in a
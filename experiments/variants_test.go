@@ -3,6 +3,7 @@ package experiments
 import (
 	"errors"
 	"testing"
+	"time"
 )
 
 func singleVariantConfig() []Variant {
@@ -675,3 +676,119 @@ func rangeVariantConfig() []Variant {
 		},
 	}
 }
+
+func TestRolloutVariantSetSchedule(t *testing.T) {
+	const numBuckets = 1000
+	variantSet, err := NewRolloutVariantSet(
+		[]Variant{
+			{
+				Name: "variant_1",
+				Size: 0.5,
+			},
+		},
+		numBuckets,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Unix(1700000000, 0)
+	end := start.Add(100 * time.Hour)
+	if err := variantSet.setSchedule(start, end); err != nil {
+		t.Fatal(err)
+	}
+
+	included := make([]bool, numBuckets)
+	for _, tt := range []struct {
+		name     string
+		now      time.Time
+		expected int
+	}{
+		{
+			name:     "before-start",
+			now:      start.Add(-time.Hour),
+			expected: 0,
+		},
+		{
+			name:     "start",
+			now:      start,
+			expected: 0,
+		},
+		{
+			name:     "10%",
+			now:      start.Add(10 * time.Hour),
+			expected: 50,
+		},
+		{
+			name:     "50%",
+			now:      start.Add(50 * time.Hour),
+			expected: 250,
+		},
+		{
+			name:     "90%",
+			now:      start.Add(90 * time.Hour),
+			expected: 450,
+		},
+		{
+			name:     "end",
+			now:      end,
+			expected: 500,
+		},
+		{
+			name:     "after-end",
+			now:      end.Add(time.Hour),
+			expected: 500,
+		},
+	} {
+		// Not parallel as the subtests verify the ramp progression in order.
+		t.Run(tt.name, func(t *testing.T) {
+			var count int
+			for i := 0; i < numBuckets; i++ {
+				if variantSet.chooseVariant(i, tt.now) == "" {
+					if included[i] {
+						t.Errorf("Bucket %d was included before but not anymore", i)
+					}
+					continue
+				}
+				count++
+				included[i] = true
+			}
+			if count != tt.expected {
+				t.Errorf("Expected %d buckets to have the variant, got %d", tt.expected, count)
+			}
+		})
+	}
+}
+
+func TestRolloutVariantSetScheduleValidation(t *testing.T) {
+	now := time.Now()
+	for _, tt := range []struct {
+		name  string
+		start time.Time
+		end   time.Time
+	}{
+		{
+			name: "missing-start",
+			end:  now,
+		},
+		{
+			name:  "missing-end",
+			start: now,
+		},
+		{
+			name:  "end-before-start",
+			start: now,
+			end:   now.Add(-time.Hour),
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			variantSet, err := NewRolloutVariantSet(rolloutVariantConfig(), 1000)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var validationErr VariantValidationError
+			if err := variantSet.setSchedule(tt.start, tt.end); !errors.As(err, &validationErr) {
+				t.Errorf("Expected VariantValidationError, got %v", err)
+			}
+		})
+	}
+}
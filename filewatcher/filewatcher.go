@@ -2,6 +2,7 @@ package filewatcher
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"io/fs"
 	"time"
@@ -113,6 +114,15 @@ type Config struct {
 	// The parser to parse the data load, required.
 	Parser Parser
 
+	// Optional. When non-nil, it will be called with the data returned by
+	// Parser, before the data is made available via Get.
+	//
+	// If Validator returns an error, the new data is discarded and Get keeps
+	// returning the previous data, and the error will be logged.
+	// The errors from the first Validator call will be returned by New
+	// directly.
+	Validator func(data any) error `yaml:"-"`
+
 	// Optional. When non-nil, it will be used to log errors,
 	// either returned by parser or by the underlying file system watcher.
 	// Please note that this does not include errors returned by the first parser
@@ -182,13 +192,34 @@ func New(ctx context.Context, cfg Config) (*Result, error) {
 	if cfg.FSEventsDelay > 0 {
 		opts = append(opts, v2.WithFSEventsDelay(cfg.FSEventsDelay))
 	}
-	result, err := v2.New(ctx, cfg.Path, cfg.Parser, opts...)
+	parser := cfg.Parser
+	if cfg.Validator != nil {
+		parser = validatedParser(cfg.Parser, cfg.Validator)
+	}
+	result, err := v2.New(ctx, cfg.Path, parser, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return &Result{result: result}, nil
 }
 
+// validatedParser wraps parser to also run validator on the parsed data.
+//
+// As the errors returned by the parser prevents the data being swapped,
+// the previous data will be kept when validator fails.
+func validatedParser(parser Parser, validator func(any) error) Parser {
+	return func(f io.Reader) (any, error) {
+		data, err := parser(f)
+		if err != nil {
+			return nil, err
+		}
+		if err := validator(data); err != nil {
+			return nil, fmt.Errorf("filewatcher: validator error: %w", err)
+		}
+		return data, nil
+	}
+}
+
 // MockFileWatcher is an implementation of FileWatcher that does not actually read
 // from a file, it simply returns the data given to it when it was initialized
 // with NewMockFilewatcher. It provides an additional Update method that allows
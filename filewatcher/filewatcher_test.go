@@ -279,6 +279,59 @@ func TestParserFailure(t *testing.T) {
 	}
 }
 
+func TestValidatorFailure(t *testing.T) {
+	errInvalid := errors.New("invalid")
+	validator := func(data any) error {
+		if bytes.Equal(data.([]byte), []byte("bad")) {
+			return errInvalid
+		}
+		return nil
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "foo")
+
+	writeFile(t, path, []byte("bad"))
+	_, err := filewatcher.New(
+		context.Background(),
+		filewatcher.Config{
+			Path:      path,
+			Parser:    parser,
+			Validator: validator,
+		},
+	)
+	if !errors.Is(err, errInvalid) {
+		t.Errorf("Expected the initial validator error to be returned, got %v", err)
+	}
+
+	writeFile(t, path, []byte("good"))
+	data, err := filewatcher.New(
+		context.Background(),
+		filewatcher.Config{
+			Path:            path,
+			Parser:          parser,
+			Validator:       validator,
+			PollingInterval: -1,
+			FSEventsDelay:   fsEventsDelayForTests,
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(data.Stop)
+	compareBytesData(t, data.Get(), []byte("good"))
+
+	writeFile(t, path, []byte("bad"))
+	// Give it some time to handle the file content change
+	time.Sleep(500 * time.Millisecond)
+	compareBytesData(t, data.Get(), []byte("good"))
+
+	writeFile(t, path, []byte("better"))
+	// Give it some time to handle the file content change
+	time.Sleep(500 * time.Millisecond)
+	compareBytesData(t, data.Get(), []byte("better"))
+}
+
 func updateDirWithContents(tb testing.TB, dst string, contents map[string]string) {
 	tb.Helper()
 
@@ -3,6 +3,8 @@ package grpcbp
 import (
 	"context"
 	"errors"
+	"io"
+	"sync"
 	"time"
 
 	"github.com/opentracing/opentracing-go"
@@ -56,8 +58,10 @@ func MonitorInterceptorUnary(args MonitorInterceptorArgs) grpc.UnaryClientInterc
 // MonitorInterceptorStreaming is a client middleware that provides tracing and
 // metrics by starting or continuing a span.
 //
-// This is not implemented yet.
+// The span is finished when the stream ends, see newFinishingClientStream for
+// details.
 func MonitorInterceptorStreaming(args MonitorInterceptorArgs) grpc.StreamClientInterceptor {
+	prefix := args.ServiceSlug + "."
 	return func(
 		ctx context.Context,
 		desc *grpc.StreamDesc,
@@ -66,7 +70,27 @@ func MonitorInterceptorStreaming(args MonitorInterceptorArgs) grpc.StreamClientI
 		streamer grpc.Streamer,
 		opts ...grpc.CallOption,
 	) (grpc.ClientStream, error) {
-		return nil, errors.New("grpc.MonitorInterceptorStreaming: not implemented")
+		m := methodSlug(method)
+		span, ctx := opentracing.StartSpanFromContext(
+			ctx,
+			prefix+m,
+			tracing.SpanTypeOption{
+				Type: tracing.SpanTypeClient,
+			},
+		)
+		ctx = CreateGRPCContextFromSpan(ctx, tracing.AsSpan(span))
+		finish := func(err error) {
+			span.FinishWithOptions(tracing.FinishOptions{
+				Ctx: ctx,
+				Err: err,
+			}.Convert())
+		}
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			finish(err)
+			return nil, err
+		}
+		return newFinishingClientStream(stream, desc, finish), nil
 	}
 }
 
@@ -91,8 +115,6 @@ func ForwardEdgeContextUnary(ecImpl ecinterface.Interface) grpc.UnaryClientInter
 // ForwardEdgeContextStreaming is a client middleware that forwards the
 // EdgeRequestContext set on the context object to the gRPC service being
 // called if one is set.
-//
-// This is not implemented yet.
 func ForwardEdgeContextStreaming(ecImpl ecinterface.Interface) grpc.StreamClientInterceptor {
 	return func(
 		ctx context.Context,
@@ -102,7 +124,8 @@ func ForwardEdgeContextStreaming(ecImpl ecinterface.Interface) grpc.StreamClient
 		streamer grpc.Streamer,
 		opts ...grpc.CallOption,
 	) (grpc.ClientStream, error) {
-		return nil, errors.New("grpc.ForwardEdgeContextStreaming: not implemented")
+		ctx = AttachEdgeRequestContext(ctx, ecImpl)
+		return streamer(ctx, desc, cc, method, opts...)
 	}
 }
 
@@ -179,16 +202,113 @@ func PrometheusUnaryClientInterceptor(serverSlug string) grpc.UnaryClientInterce
 // PrometheusStreamClientInterceptor is a client-side interceptor that provides Prometheus
 // monitoring for Streaming RPCs.
 //
-// This is not implemented yet.
+// It emits the same metrics as PrometheusUnaryClientInterceptor,
+// with grpc_type label being client_stream, server_stream or bidi_stream.
+// The latency is measured until the stream ends,
+// see newFinishingClientStream for details.
 func PrometheusStreamClientInterceptor(serverSlug string) grpc.StreamClientInterceptor {
 	return func(
 		ctx context.Context,
 		desc *grpc.StreamDesc,
 		conn *grpc.ClientConn,
-		method string,
+		fullMethod string,
 		streamer grpc.Streamer,
 		opts ...grpc.CallOption,
-	) (_ grpc.ClientStream, err error) {
-		return nil, errors.New("PrometheusStreamClientInterceptor: not implemented")
+	) (grpc.ClientStream, error) {
+		start := time.Now()
+		serviceName, method := serviceAndMethodSlug(fullMethod)
+		streamType := streamTypeOf(desc.ClientStreams, desc.ServerStreams)
+
+		activeRequestLabels := prometheus.Labels{
+			serviceLabel:    serviceName,
+			methodLabel:     method,
+			typeLabel:       streamType,
+			clientNameLabel: serverSlug,
+		}
+		clientActiveRequests.With(activeRequestLabels).Inc()
+
+		finish := func(err error) {
+			success := prometheusbp.BoolString(err == nil)
+			status, _ := status.FromError(err)
+
+			latencyLabels := prometheus.Labels{
+				serviceLabel:    serviceName,
+				methodLabel:     method,
+				typeLabel:       streamType,
+				successLabel:    success,
+				clientNameLabel: serverSlug,
+			}
+
+			clientLatencyDistribution.With(latencyLabels).Observe(time.Since(start).Seconds())
+
+			totalRequestLabels := prometheus.Labels{
+				serviceLabel:    serviceName,
+				methodLabel:     method,
+				typeLabel:       streamType,
+				successLabel:    success,
+				clientNameLabel: serverSlug,
+				codeLabel:       status.Code().String(),
+			}
+			clientTotalRequests.With(totalRequestLabels).Inc()
+			clientActiveRequests.With(activeRequestLabels).Dec()
+		}
+		stream, err := streamer(ctx, desc, conn, fullMethod, opts...)
+		if err != nil {
+			finish(err)
+			return nil, err
+		}
+		return newFinishingClientStream(stream, desc, finish), nil
 	}
 }
+
+// streamTypeOf returns the grpc_type label value of a stream.
+func streamTypeOf(clientStreams, serverStreams bool) string {
+	switch {
+	case clientStreams && serverStreams:
+		return bidiStream
+	case clientStreams:
+		return clientStream
+	default:
+		return serverStream
+	}
+}
+
+// finishingClientStream is a grpc.ClientStream calling finish once when the
+// stream ends.
+type finishingClientStream struct {
+	grpc.ClientStream
+
+	serverStreams bool
+	once          sync.Once
+	finish        func(error)
+}
+
+// newFinishingClientStream wraps stream to call finish once when the stream
+// ends, which is when RecvMsg returns an error (io.EOF being treated as a
+// successful end), or when RecvMsg returns the only response of a stream that
+// is not server streaming.
+//
+// If the caller abandons the stream without reading it to the end, finish is
+// never called.
+func newFinishingClientStream(stream grpc.ClientStream, desc *grpc.StreamDesc, finish func(error)) grpc.ClientStream {
+	return &finishingClientStream{
+		ClientStream:  stream,
+		serverStreams: desc.ServerStreams,
+		finish:        finish,
+	}
+}
+
+func (s *finishingClientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err == nil && s.serverStreams {
+		return err
+	}
+	s.once.Do(func() {
+		if errors.Is(err, io.EOF) {
+			s.finish(nil)
+		} else {
+			s.finish(err)
+		}
+	})
+	return err
+}
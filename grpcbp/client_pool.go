@@ -0,0 +1,392 @@
+package grpcbp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+
+	"github.com/reddit/baseplate.go/ecinterface"
+	"github.com/reddit/baseplate.go/log"
+	"github.com/reddit/baseplate.go/randbp"
+)
+
+// Default values for ClientPoolConfig.
+const (
+	DefaultPoolSize               = 1
+	DefaultMaxConnectionAge       = 5 * time.Minute
+	DefaultMaxConnectionAgeJitter = 0.1
+	DefaultMaxConnectionAgeGrace  = 30 * time.Second
+	DefaultUnhealthyReplaceAge    = 10 * time.Second
+)
+
+// The reasons for a pooled connection to be replaced,
+// used as the grpc_replace_reason label.
+const (
+	ReplaceReasonMaxAge    = "max_age"
+	ReplaceReasonUnhealthy = "unhealthy"
+)
+
+// ErrClientPoolClosed is the error returned by the calls made through a
+// ClientPool after it's closed.
+var ErrClientPoolClosed = errors.New("grpcbp: client pool is closed")
+
+// ClientPoolConfig is the configuration struct for creating a new ClientPool.
+type ClientPoolConfig struct {
+	// ServiceSlug is a short identifier for the gRPC service you are creating
+	// clients for, used in the spans and metrics.
+	//
+	// See thriftbp.ClientPoolConfig.ServiceSlug for the naming convention.
+	ServiceSlug string `yaml:"serviceSlug"`
+
+	// Addr is the target of the gRPC service, in the format accepted by
+	// grpc.DialContext (e.g. "${host}:${port}" or "dns:///${host}:${port}").
+	Addr string `yaml:"addr"`
+
+	// PoolSize is the number of grpc.ClientConns maintained by the pool.
+	//
+	// A grpc.ClientConn multiplexes concurrent calls already, so this is only
+	// needed when a single connection becomes a bottleneck (e.g. when hitting
+	// the HTTP/2 max concurrent streams limit of the server).
+	//
+	// Optional. Default to 1 (see DefaultPoolSize).
+	PoolSize int `yaml:"poolSize"`
+
+	// MaxConnectionAge is the maximum duration that a pooled connection will be
+	// kept before being replaced by a new one, so that the calls are rebalanced
+	// among the server instances periodically.
+	//
+	// If this is not set, the default duration is 5 minutes
+	// (see DefaultMaxConnectionAge).
+	// To disable this and keep connections in the pool indefinitely, set this to
+	// a negative value.
+	//
+	// MaxConnectionAgeJitter is the ratio of random jitter +/- on top of
+	// MaxConnectionAge. Default to 10% (see DefaultMaxConnectionAgeJitter).
+	//
+	// The replaced connections are closed after MaxConnectionAgeGrace, to give
+	// the in-flight calls on them time to finish.
+	// Default to 30 seconds (see DefaultMaxConnectionAgeGrace).
+	MaxConnectionAge       time.Duration `yaml:"maxConnectionAge"`
+	MaxConnectionAgeJitter *float64      `yaml:"maxConnectionAgeJitter"`
+	MaxConnectionAgeGrace  time.Duration `yaml:"maxConnectionAgeGrace"`
+
+	// UnhealthyReplaceAge is the minimum age of a pooled connection in
+	// TRANSIENT_FAILURE state before it's replaced by a new one.
+	//
+	// gRPC keeps reconnecting the unhealthy connections by itself,
+	// this only helps when a connection is stuck on a bad backend,
+	// and the minimum age keeps the pool from redialing on every call during an
+	// outage of the whole service.
+	//
+	// Optional. Default to 10 seconds (see DefaultUnhealthyReplaceAge).
+	// The replaced connections are also closed after MaxConnectionAgeGrace.
+	UnhealthyReplaceAge time.Duration `yaml:"unhealthyReplaceAge"`
+
+	// EdgeContextImpl is the edge context implementation to be forwarded.
+	//
+	// Optional. If it's not set, the global one from ecinterface.Get will be
+	// used instead.
+	EdgeContextImpl ecinterface.Interface `yaml:"-"`
+}
+
+// ClientPool maintains a pool of grpc.ClientConns to the same target.
+//
+// It implements grpc.ClientConnInterface,
+// so it can be used with the generated gRPC clients directly:
+//
+//	pool, err := grpcbp.NewBaseplateClientPool(ctx, cfg, grpc.WithTransportCredentials(creds))
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer pool.Close()
+//	client := pb.NewMyServiceClient(pool)
+//
+// The calls are distributed among the pooled connections in round-robin.
+// When a picked connection is in TRANSIENT_FAILURE state for longer than
+// UnhealthyReplaceAge, or it's older than MaxConnectionAge,
+// it will be replaced by a new one before being used.
+// While the replacement is being dialed, the other calls keep using the old
+// connection.
+//
+// The pool emits the following prometheus metrics,
+// all with a grpc_client_pool label with the value of ServiceSlug:
+//
+//   - grpcbp_client_pool_size gauge
+//   - grpcbp_client_pool_replaced_connections_total counter,
+//     with an additional grpc_replace_reason label
+//   - grpcbp_client_pool_dial_errors_total counter
+type ClientPool struct {
+	cfg  ClientPoolConfig
+	dial func(ctx context.Context) (*grpc.ClientConn, error)
+
+	next atomic.Uint64
+
+	lock   sync.Mutex
+	conns  []*pooledConn
+	closed bool
+}
+
+var _ grpc.ClientConnInterface = (*ClientPool)(nil)
+
+type pooledConn struct {
+	*grpc.ClientConn
+
+	createdAt time.Time
+	expiresAt time.Time
+
+	// replacing is set when a replacement of this connection is being dialed,
+	// guarded by ClientPool.lock.
+	replacing bool
+}
+
+// BaseplateDefaultClientInterceptorsUnary returns the default unary client
+// interceptors that should be used by a baseplate gRPC client.
+//
+// Currently they are (in order):
+//
+// 1. MonitorInterceptorUnary
+//
+// 2. ForwardEdgeContextUnary
+//
+// 3. PrometheusUnaryClientInterceptor
+func BaseplateDefaultClientInterceptorsUnary(cfg ClientPoolConfig) []grpc.UnaryClientInterceptor {
+	ecImpl := cfg.EdgeContextImpl
+	if ecImpl == nil {
+		ecImpl = ecinterface.Get()
+	}
+	return []grpc.UnaryClientInterceptor{
+		MonitorInterceptorUnary(MonitorInterceptorArgs{
+			ServiceSlug: cfg.ServiceSlug,
+		}),
+		ForwardEdgeContextUnary(ecImpl),
+		PrometheusUnaryClientInterceptor(cfg.ServiceSlug),
+	}
+}
+
+// BaseplateDefaultClientInterceptorsStream returns the default stream client
+// interceptors that should be used by a baseplate gRPC client.
+//
+// They are the streaming equivalents of
+// BaseplateDefaultClientInterceptorsUnary, in the same order:
+//
+// 1. MonitorInterceptorStreaming
+//
+// 2. ForwardEdgeContextStreaming
+//
+// 3. PrometheusStreamClientInterceptor
+func BaseplateDefaultClientInterceptorsStream(cfg ClientPoolConfig) []grpc.StreamClientInterceptor {
+	ecImpl := cfg.EdgeContextImpl
+	if ecImpl == nil {
+		ecImpl = ecinterface.Get()
+	}
+	return []grpc.StreamClientInterceptor{
+		MonitorInterceptorStreaming(MonitorInterceptorArgs{
+			ServiceSlug: cfg.ServiceSlug,
+		}),
+		ForwardEdgeContextStreaming(ecImpl),
+		PrometheusStreamClientInterceptor(cfg.ServiceSlug),
+	}
+}
+
+// NewBaseplateClientPool creates a new ClientPool with the interceptors from
+// BaseplateDefaultClientInterceptorsUnary and
+// BaseplateDefaultClientInterceptorsStream applied.
+//
+// The opts are applied after the default interceptors, so additional
+// interceptors added via grpc.WithChainUnaryInterceptor or
+// grpc.WithChainStreamInterceptor in opts run after them.
+// The transport credentials must be provided in opts.
+func NewBaseplateClientPool(ctx context.Context, cfg ClientPoolConfig, opts ...grpc.DialOption) (*ClientPool, error) {
+	opts = append([]grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(BaseplateDefaultClientInterceptorsUnary(cfg)...),
+		grpc.WithChainStreamInterceptor(BaseplateDefaultClientInterceptorsStream(cfg)...),
+	}, opts...)
+	return NewCustomClientPool(ctx, cfg, opts...)
+}
+
+// NewCustomClientPool creates a new ClientPool without any default
+// interceptors applied.
+//
+// Most services should use NewBaseplateClientPool instead.
+func NewCustomClientPool(ctx context.Context, cfg ClientPoolConfig, opts ...grpc.DialOption) (*ClientPool, error) {
+	if cfg.Addr == "" {
+		return nil, errors.New("grpcbp.NewCustomClientPool: Addr is required")
+	}
+	if cfg.PoolSize <= 0 {
+		cfg.PoolSize = DefaultPoolSize
+	}
+	if cfg.MaxConnectionAge == 0 {
+		cfg.MaxConnectionAge = DefaultMaxConnectionAge
+	}
+	if cfg.MaxConnectionAgeJitter == nil {
+		jitter := DefaultMaxConnectionAgeJitter
+		cfg.MaxConnectionAgeJitter = &jitter
+	}
+	if cfg.MaxConnectionAgeGrace <= 0 {
+		cfg.MaxConnectionAgeGrace = DefaultMaxConnectionAgeGrace
+	}
+	if cfg.UnhealthyReplaceAge <= 0 {
+		cfg.UnhealthyReplaceAge = DefaultUnhealthyReplaceAge
+	}
+
+	p := &ClientPool{
+		cfg: cfg,
+		dial: func(ctx context.Context) (*grpc.ClientConn, error) {
+			return grpc.DialContext(ctx, cfg.Addr, opts...)
+		},
+		conns: make([]*pooledConn, 0, cfg.PoolSize),
+	}
+	for i := 0; i < cfg.PoolSize; i++ {
+		pc, err := p.newConn(ctx)
+		if err != nil {
+			p.Close()
+			return nil, fmt.Errorf("grpcbp.NewCustomClientPool: failed to dial %q: %w", cfg.Addr, err)
+		}
+		p.conns = append(p.conns, pc)
+	}
+	clientPoolSizeGauge.With(prometheus.Labels{
+		clientPoolLabel: cfg.ServiceSlug,
+	}).Set(float64(cfg.PoolSize))
+	return p, nil
+}
+
+// Invoke implements grpc.ClientConnInterface.
+func (p *ClientPool) Invoke(ctx context.Context, method string, args interface{}, reply interface{}, opts ...grpc.CallOption) error {
+	conn, err := p.pick(ctx)
+	if err != nil {
+		return err
+	}
+	return conn.Invoke(ctx, method, args, reply, opts...)
+}
+
+// NewStream implements grpc.ClientConnInterface.
+func (p *ClientPool) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	conn, err := p.pick(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return conn.NewStream(ctx, desc, method, opts...)
+}
+
+// Close closes all the connections in the pool.
+//
+// The in-flight calls are canceled.
+// The calls made through the pool after it's closed fail with
+// ErrClientPoolClosed.
+func (p *ClientPool) Close() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.closed {
+		return nil
+	}
+	p.closed = true
+	errs := make([]error, 0, len(p.conns))
+	for _, pc := range p.conns {
+		errs = append(errs, pc.Close())
+	}
+	p.conns = nil
+	clientPoolSizeGauge.With(prometheus.Labels{
+		clientPoolLabel: p.cfg.ServiceSlug,
+	}).Set(0)
+	return errors.Join(errs...)
+}
+
+// pick returns the next connection in the pool, replacing it first if it's
+// unhealthy or expired.
+//
+// The replacement is dialed without holding the lock, and only by one caller
+// at a time, the other callers keep using the old connection meanwhile.
+func (p *ClientPool) pick(ctx context.Context) (*grpc.ClientConn, error) {
+	p.lock.Lock()
+	if p.closed {
+		p.lock.Unlock()
+		return nil, ErrClientPoolClosed
+	}
+	i := int(p.next.Add(1) % uint64(len(p.conns)))
+	pc := p.conns[i]
+	reason := p.replaceReason(pc)
+	if reason == "" || pc.replacing {
+		p.lock.Unlock()
+		return pc.ClientConn, nil
+	}
+	pc.replacing = true
+	p.lock.Unlock()
+
+	replacement, err := p.newConn(ctx)
+	if err != nil {
+		// Keep using the old connection,
+		// the replacement will be retried on the next pick.
+		log.C(ctx).Warnw(
+			"grpcbp: failed to replace pooled connection",
+			"err", err,
+			"addr", p.cfg.Addr,
+			"reason", reason,
+		)
+		p.lock.Lock()
+		pc.replacing = false
+		p.lock.Unlock()
+		return pc.ClientConn, nil
+	}
+
+	p.lock.Lock()
+	if p.closed {
+		p.lock.Unlock()
+		replacement.Close()
+		return nil, ErrClientPoolClosed
+	}
+	p.conns[i] = replacement
+	p.lock.Unlock()
+
+	clientPoolReplacedCounter.With(prometheus.Labels{
+		clientPoolLabel:    p.cfg.ServiceSlug,
+		replaceReasonLabel: reason,
+	}).Inc()
+	// Give the in-flight calls on the old connection time to finish.
+	time.AfterFunc(p.cfg.MaxConnectionAgeGrace, func() {
+		pc.Close()
+	})
+	return replacement.ClientConn, nil
+}
+
+// replaceReason returns the reason pc should be replaced,
+// or an empty string if it shouldn't.
+func (p *ClientPool) replaceReason(pc *pooledConn) string {
+	now := time.Now()
+	switch {
+	case pc.GetState() == connectivity.TransientFailure && now.Sub(pc.createdAt) >= p.cfg.UnhealthyReplaceAge:
+		return ReplaceReasonUnhealthy
+	case !pc.expiresAt.IsZero() && now.After(pc.expiresAt):
+		return ReplaceReasonMaxAge
+	default:
+		return ""
+	}
+}
+
+func (p *ClientPool) newConn(ctx context.Context) (*pooledConn, error) {
+	conn, err := p.dial(ctx)
+	if err != nil {
+		clientPoolDialErrorsCounter.With(prometheus.Labels{
+			clientPoolLabel: p.cfg.ServiceSlug,
+		}).Inc()
+		return nil, err
+	}
+	pc := &pooledConn{
+		ClientConn: conn,
+		createdAt:  time.Now(),
+	}
+	if p.cfg.MaxConnectionAge > 0 {
+		pc.expiresAt = pc.createdAt.Add(randbp.JitterDuration(
+			p.cfg.MaxConnectionAge,
+			*p.cfg.MaxConnectionAgeJitter,
+		))
+	}
+	return pc, nil
+}
@@ -0,0 +1,280 @@
+package grpcbp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	pb "github.com/grpc-ecosystem/go-grpc-middleware/testing/testproto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/reddit/baseplate.go/ecinterface"
+	"github.com/reddit/baseplate.go/prometheusbp/promtest"
+)
+
+// ping calls the Ping endpoint of the test service through cc.
+//
+// The generated client of the test service requires *grpc.ClientConn,
+// so the call is made with grpc.ClientConnInterface directly.
+func ping(cc grpc.ClientConnInterface) error {
+	return cc.Invoke(
+		context.Background(),
+		"/mwitkow.testproto.TestService/Ping",
+		&pb.PingRequest{},
+		new(pb.PingResponse),
+	)
+}
+
+func TestClientPool(t *testing.T) {
+	l, _ := setupServer(t)
+	bufDialer := func(ctx context.Context, _ string) (net.Conn, error) {
+		return l.DialContext(ctx)
+	}
+
+	t.Run("round-robin", func(t *testing.T) {
+		const slug = "test-pool-round-robin"
+		pool, err := NewBaseplateClientPool(
+			context.Background(),
+			ClientPoolConfig{
+				ServiceSlug: slug,
+				Addr:        "bufnet",
+				PoolSize:    3,
+			},
+			grpc.WithContextDialer(bufDialer),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+		)
+		if err != nil {
+			t.Fatalf("NewBaseplateClientPool: %v", err)
+		}
+		defer pool.Close()
+
+		size := testutil.ToFloat64(clientPoolSizeGauge.With(prometheus.Labels{
+			clientPoolLabel: slug,
+		}))
+		if size != 3 {
+			t.Errorf("Expected pool size gauge to be 3, got %v", size)
+		}
+
+		picked := make(map[*grpc.ClientConn]bool)
+		for i := 0; i < 6; i++ {
+			if err := ping(pool); err != nil {
+				t.Fatalf("Ping: %v", err)
+			}
+			conn, err := pool.pick(context.Background())
+			if err != nil {
+				t.Fatalf("pick: %v", err)
+			}
+			picked[conn] = true
+		}
+		if len(picked) != 3 {
+			t.Errorf("Expected all 3 connections to be used, got %d", len(picked))
+		}
+	})
+
+	t.Run("max-age", func(t *testing.T) {
+		const slug = "test-pool-max-age"
+		defer promtest.NewPrometheusMetricTest(t, "replaced", clientPoolReplacedCounter, prometheus.Labels{
+			clientPoolLabel:    slug,
+			replaceReasonLabel: ReplaceReasonMaxAge,
+		}).CheckDelta(1)
+
+		jitter := 0.0
+		pool, err := NewCustomClientPool(
+			context.Background(),
+			ClientPoolConfig{
+				ServiceSlug:            slug,
+				Addr:                   "bufnet",
+				MaxConnectionAge:       time.Millisecond,
+				MaxConnectionAgeJitter: &jitter,
+				MaxConnectionAgeGrace:  time.Millisecond,
+			},
+			grpc.WithContextDialer(bufDialer),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+		)
+		if err != nil {
+			t.Fatalf("NewCustomClientPool: %v", err)
+		}
+		defer pool.Close()
+
+		old := pool.conns[0].ClientConn
+		time.Sleep(5 * time.Millisecond)
+		// Disable max age for the replacement.
+		pool.cfg.MaxConnectionAge = -1
+		if err := ping(pool); err != nil {
+			t.Fatalf("Ping: %v", err)
+		}
+		if pool.conns[0].ClientConn == old {
+			t.Error("Expected the expired connection to be replaced")
+		}
+	})
+
+	t.Run("replace-without-lock", func(t *testing.T) {
+		jitter := 0.0
+		pool, err := NewCustomClientPool(
+			context.Background(),
+			ClientPoolConfig{
+				ServiceSlug:            "test-pool-replace-without-lock",
+				Addr:                   "bufnet",
+				MaxConnectionAge:       time.Millisecond,
+				MaxConnectionAgeJitter: &jitter,
+				MaxConnectionAgeGrace:  time.Millisecond,
+			},
+			grpc.WithContextDialer(bufDialer),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+		)
+		if err != nil {
+			t.Fatalf("NewCustomClientPool: %v", err)
+		}
+		defer pool.Close()
+
+		old := pool.conns[0].ClientConn
+		dial := pool.dial
+		dialing := make(chan struct{})
+		release := make(chan struct{})
+		pool.dial = func(ctx context.Context) (*grpc.ClientConn, error) {
+			close(dialing)
+			<-release
+			return dial(ctx)
+		}
+		time.Sleep(5 * time.Millisecond)
+
+		replaced := make(chan *grpc.ClientConn)
+		go func() {
+			conn, err := pool.pick(context.Background())
+			if err != nil {
+				t.Errorf("pick: %v", err)
+			}
+			replaced <- conn
+		}()
+		<-dialing
+
+		// The other picks should not be blocked by the dial in progress.
+		conn, err := pool.pick(context.Background())
+		if err != nil {
+			t.Fatalf("pick: %v", err)
+		}
+		if conn != old {
+			t.Error("Expected the old connection to be used while dialing its replacement")
+		}
+
+		close(release)
+		if conn := <-replaced; conn == old {
+			t.Error("Expected the expired connection to be replaced")
+		}
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		pool, err := NewCustomClientPool(
+			context.Background(),
+			ClientPoolConfig{
+				ServiceSlug: "test-pool-closed",
+				Addr:        "bufnet",
+			},
+			grpc.WithContextDialer(bufDialer),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+		)
+		if err != nil {
+			t.Fatalf("NewCustomClientPool: %v", err)
+		}
+		if err := pool.Close(); err != nil {
+			t.Errorf("Close: %v", err)
+		}
+		if err := ping(pool); !errors.Is(err, ErrClientPoolClosed) {
+			t.Errorf("Expected ErrClientPoolClosed, got %v", err)
+		}
+	})
+
+	t.Run("no-addr", func(t *testing.T) {
+		if _, err := NewCustomClientPool(context.Background(), ClientPoolConfig{}); err == nil {
+			t.Error("Expected error when Addr is not set")
+		}
+	})
+}
+
+// listService implements PingList on top of mockService.
+type listService struct {
+	mockService
+}
+
+func (s *listService) PingList(req *pb.PingRequest, stream pb.TestService_PingListServer) error {
+	s.ctx = stream.Context()
+	for i := 0; i < 2; i++ {
+		if err := stream.Send(&pb.PingResponse{Counter: int32(i)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestClientPoolStream(t *testing.T) {
+	const slug = "test-pool-stream"
+
+	l := bufconn.Listen(1024 * 1024)
+	s := grpc.NewServer()
+	pb.RegisterTestServiceServer(s, &listService{})
+	go s.Serve(l)
+	defer s.Stop()
+
+	defer promtest.NewPrometheusMetricTest(t, "requests", clientTotalRequests, prometheus.Labels{
+		serviceLabel:    "mwitkow.testproto.TestService",
+		methodLabel:     "PingList",
+		typeLabel:       serverStream,
+		successLabel:    "true",
+		clientNameLabel: slug,
+		codeLabel:       codes.OK.String(),
+	}).CheckDelta(1)
+
+	pool, err := NewBaseplateClientPool(
+		context.Background(),
+		ClientPoolConfig{
+			ServiceSlug:     slug,
+			Addr:            "bufnet",
+			EdgeContextImpl: ecinterface.Mock(),
+		},
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return l.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("NewBaseplateClientPool: %v", err)
+	}
+	defer pool.Close()
+
+	stream, err := pool.NewStream(
+		context.Background(),
+		&grpc.StreamDesc{StreamName: "PingList", ServerStreams: true},
+		"/mwitkow.testproto.TestService/PingList",
+	)
+	if err != nil {
+		t.Fatalf("NewStream: %v", err)
+	}
+	if err := stream.SendMsg(&pb.PingRequest{}); err != nil {
+		t.Fatalf("SendMsg: %v", err)
+	}
+	if err := stream.CloseSend(); err != nil {
+		t.Fatalf("CloseSend: %v", err)
+	}
+	var count int
+	for {
+		err := stream.RecvMsg(new(pb.PingResponse))
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("RecvMsg: %v", err)
+		}
+		count++
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 responses, got %d", count)
+	}
+}
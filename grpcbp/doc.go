@@ -6,9 +6,22 @@
 // propagation or initialization as well as forwarding EdgeRequestContext
 // according to the Baseplate specification.
 //
+// NewBaseplateClientPool creates a pool of connections with those middlewares
+// applied, similar to thriftbp.NewBaseplateClientPool.
+//
 // # Servers
 //
 // On the server side, this package provides middleware implementations for
 // EdgeRequestContext handling and tracing propagation according to Baseplate
-// specification.
+// specification, as well as panic recovery.
+//
+// BaseplateDefaultUnaryServerInterceptors and
+// BaseplateDefaultStreamServerInterceptors return those interceptors in the
+// recommended order:
+//
+//	args := grpcbp.DefaultServerInterceptorsArgs{}
+//	server := grpc.NewServer(
+//		grpc.ChainUnaryInterceptor(grpcbp.BaseplateDefaultUnaryServerInterceptors(args)...),
+//		grpc.ChainStreamInterceptor(grpcbp.BaseplateDefaultStreamServerInterceptors(args)...),
+//	)
 package grpcbp
@@ -0,0 +1,116 @@
+package grpcbp
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+
+	"github.com/reddit/baseplate.go/log"
+	"github.com/reddit/baseplate.go/randbp"
+)
+
+// The keys used in the log lines emitted by the logging interceptors.
+const (
+	LogKeyMethod   = "grpc.method"
+	LogKeyCode     = "grpc.code"
+	LogKeyDuration = "grpc.duration"
+	LogKeyUser     = "user"
+)
+
+const healthServicePrefix = "/grpc.health.v1.Health/"
+
+// SkipHealthCheck is a LoggingInterceptorArgs.Skip predicate that skips the
+// RPCs to the standard gRPC health service (grpc.health.v1.Health).
+func SkipHealthCheck(fullMethod string) bool {
+	return strings.HasPrefix(fullMethod, healthServicePrefix)
+}
+
+// LoggingInterceptorArgs are the args to be passed into
+// LoggingInterceptorUnary and LoggingInterceptorStreaming functions.
+type LoggingInterceptorArgs struct {
+	// SampleRate is the rate of the successful RPCs to be logged,
+	// in the range of [0, 1].
+	//
+	// RPCs with non-OK status codes are always logged.
+	//
+	// Optional. Default to 1 (log all RPCs) when nil.
+	SampleRate *float64
+
+	// Skip, when returns true, skips logging the RPC with the full method name,
+	// regardless of its status code.
+	//
+	// Optional. SkipHealthCheck can be used to skip health checks.
+	Skip func(fullMethod string) bool
+
+	// UserFunc extracts the user from the edge request context injected into ctx,
+	// for example the user id.
+	//
+	// As the edge request context is implementation specific, it should be
+	// provided by the ecinterface.Interface implementation used by the service.
+	// When UserFunc is nil or returns false, the user will be omitted from the
+	// log line.
+	//
+	// Optional.
+	UserFunc func(ctx context.Context) (user string, ok bool)
+}
+
+// LoggingInterceptorUnary is a server middleware that logs one structured line
+// per RPC, with the full method name, the status code, the duration, and the
+// edge request context user when present, at info level.
+//
+// The status code is extracted from the error returned by the handler via
+// status.FromError.
+//
+// It should be added after InjectEdgeContextInterceptorUnary for the user to be
+// available.
+func LoggingInterceptorUnary(args LoggingInterceptorArgs) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (_ interface{}, err error) {
+		if args.Skip != nil && args.Skip(info.FullMethod) {
+			return handler(ctx, req)
+		}
+		start := time.Now()
+		defer func() {
+			args.log(ctx, info.FullMethod, err, time.Since(start))
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// LoggingInterceptorStreaming is a server middleware that logs one structured
+// line per streaming RPC when the stream finishes.
+//
+// See LoggingInterceptorUnary for more details.
+func LoggingInterceptorStreaming(args LoggingInterceptorArgs) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		if args.Skip != nil && args.Skip(info.FullMethod) {
+			return handler(srv, stream)
+		}
+		start := time.Now()
+		defer func() {
+			args.log(stream.Context(), info.FullMethod, err, time.Since(start))
+		}()
+		return handler(srv, stream)
+	}
+}
+
+func (args LoggingInterceptorArgs) log(ctx context.Context, fullMethod string, err error, duration time.Duration) {
+	st, _ := status.FromError(err)
+	if err == nil && args.SampleRate != nil && !randbp.ShouldSampleWithRate(*args.SampleRate) {
+		return
+	}
+
+	kv := []interface{}{
+		LogKeyMethod, fullMethod,
+		LogKeyCode, st.Code().String(),
+		LogKeyDuration, duration,
+	}
+	if args.UserFunc != nil {
+		if user, ok := args.UserFunc(ctx); ok {
+			kv = append(kv, LogKeyUser, user)
+		}
+	}
+	log.C(ctx).Infow("grpc request finished", kv...)
+}
@@ -0,0 +1,69 @@
+package grpcbp
+
+import (
+	"context"
+	"testing"
+
+	pb "github.com/grpc-ecosystem/go-grpc-middleware/testing/testproto"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"google.golang.org/grpc"
+
+	//lint:ignore SA1019 This library is internal only, not actually deprecated
+	"github.com/reddit/baseplate.go/internalv2compat"
+)
+
+func TestLoggingInterceptorUnary(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	original := internalv2compat.GlobalLogger()
+	internalv2compat.SetGlobalLogger(zap.New(core).Sugar())
+	t.Cleanup(func() {
+		internalv2compat.SetGlobalLogger(original)
+	})
+
+	rate := 0.0
+	l, _ := setupServer(t, grpc.UnaryInterceptor(LoggingInterceptorUnary(LoggingInterceptorArgs{
+		SampleRate: &rate,
+		UserFunc: func(context.Context) (string, bool) {
+			return "t2_foo", true
+		},
+	})))
+	client := pb.NewTestServiceClient(setupClient(t, l))
+
+	if _, err := client.Ping(context.Background(), &pb.PingRequest{}); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+	if n := logs.Len(); n != 0 {
+		t.Fatalf("Expected successful RPC to be sampled out, got %d logs", n)
+	}
+
+	if _, err := client.PingError(context.Background(), &pb.PingRequest{}); err == nil {
+		t.Fatal("Expected PingError to return error")
+	}
+	entries := logs.TakeAll()
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 log for failed RPC, got %d", len(entries))
+	}
+	fields := entries[0].ContextMap()
+	for k, v := range map[string]interface{}{
+		LogKeyMethod: "/mwitkow.testproto.TestService/PingError",
+		LogKeyCode:   "Unknown",
+		LogKeyUser:   "t2_foo",
+	} {
+		if fields[k] != v {
+			t.Errorf("Expected %s to be %v, got %v", k, v, fields[k])
+		}
+	}
+	if _, ok := fields[LogKeyDuration]; !ok {
+		t.Errorf("Expected %s to be logged, got %v", LogKeyDuration, fields)
+	}
+}
+
+func TestSkipHealthCheck(t *testing.T) {
+	if !SkipHealthCheck("/grpc.health.v1.Health/Check") {
+		t.Error("Expected health check to be skipped")
+	}
+	if SkipHealthCheck("/mwitkow.testproto.TestService/Ping") {
+		t.Error("Expected Ping not to be skipped")
+	}
+}
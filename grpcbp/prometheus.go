@@ -17,12 +17,16 @@ const (
 	successLabel    = "grpc_success"
 	codeLabel       = "grpc_code"
 	clientNameLabel = "grpc_client_name"
+
+	clientPoolLabel    = "grpc_client_pool"
+	replaceReasonLabel = "grpc_replace_reason"
 )
 
 const (
 	unary        = "unary"
 	clientStream = "client_stream"
 	serverStream = "server_stream"
+	bidiStream   = "bidi_stream"
 )
 
 var (
@@ -106,6 +110,30 @@ var (
 	}, clientActiveRequestsLabels)
 )
 
+var (
+	clientPoolLabels = []string{
+		clientPoolLabel,
+	}
+
+	clientPoolSizeGauge = promauto.With(prometheusbpint.GlobalRegistry).NewGaugeVec(prometheus.GaugeOpts{
+		Name: "grpcbp_client_pool_size",
+		Help: "The number of connections maintained by a grpcbp client pool",
+	}, clientPoolLabels)
+
+	clientPoolReplacedCounter = promauto.With(prometheusbpint.GlobalRegistry).NewCounterVec(prometheus.CounterOpts{
+		Name: "grpcbp_client_pool_replaced_connections_total",
+		Help: "The number of connections replaced in a grpcbp client pool",
+	}, []string{
+		clientPoolLabel,
+		replaceReasonLabel,
+	})
+
+	clientPoolDialErrorsCounter = promauto.With(prometheusbpint.GlobalRegistry).NewCounterVec(prometheus.CounterOpts{
+		Name: "grpcbp_client_pool_dial_errors_total",
+		Help: "The number of failed attempts to dial a new connection in a grpcbp client pool",
+	}, clientPoolLabels)
+)
+
 // serviceAndMethodSlug splits the UnaryServerInfo.FullMethod and returns
 // the package.service part separate from the method part.
 // ref: https://pkg.go.dev/google.golang.org/grpc#UnaryServerInfo
@@ -117,3 +145,15 @@ func serviceAndMethodSlug(fullMethod string) (service, method string) {
 	}
 	return split[0], split[1]
 }
+
+var (
+	panicRecoverLabels = []string{
+		serviceLabel,
+		methodLabel,
+	}
+
+	panicRecoverCounter = promauto.With(prometheusbpint.GlobalRegistry).NewCounterVec(prometheus.CounterOpts{
+		Name: "grpcbp_server_recovered_panics_total",
+		Help: "The number of panics recovered from grpc server handlers",
+	}, panicRecoverLabels)
+)
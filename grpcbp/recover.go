@@ -0,0 +1,127 @@
+package grpcbp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/reddit/baseplate.go/ecinterface"
+	"github.com/reddit/baseplate.go/log"
+)
+
+// recoverPanic converts the recovered value r from a panic in fullMethod into
+// an Internal status error, after logging it and increasing
+// grpcbp_server_recovered_panics_total counter.
+func recoverPanic(ctx context.Context, fullMethod string, r interface{}) error {
+	var rErr error
+	if asErr, ok := r.(error); ok {
+		rErr = asErr
+	} else {
+		rErr = fmt.Errorf("panic in %q: %+v", fullMethod, r)
+	}
+	log.C(ctx).Errorw(
+		"recovered from panic:",
+		"err", rErr,
+		"endpoint", fullMethod,
+	)
+	service, method := serviceAndMethodSlug(fullMethod)
+	panicRecoverCounter.With(prometheus.Labels{
+		serviceLabel: service,
+		methodLabel:  method,
+	}).Inc()
+	return status.Error(codes.Internal, rErr.Error())
+}
+
+// RecoverPanicInterceptorUnary is a server middleware that recovers from the
+// panics in the handlers, logs them, and returns an Internal status error
+// instead, so a panicking handler does not crash the server.
+//
+// It increases grpcbp_server_recovered_panics_total counter with labels:
+//
+//   - grpc_service: the fully qualified name of the gRPC service
+//   - grpc_method: the name of the method called on the gRPC service
+//
+// It should be the last interceptor in the chain,
+// so the other interceptors get the error of the panic.
+func RecoverPanicInterceptorUnary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				resp = nil
+				err = recoverPanic(ctx, info.FullMethod, r)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// RecoverPanicInterceptorStreaming is the streaming version of
+// RecoverPanicInterceptorUnary.
+func RecoverPanicInterceptorStreaming() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recoverPanic(stream.Context(), info.FullMethod, r)
+			}
+		}()
+		return handler(srv, stream)
+	}
+}
+
+// DefaultServerInterceptorsArgs are the args to be passed into
+// BaseplateDefaultUnaryServerInterceptors and
+// BaseplateDefaultStreamServerInterceptors functions.
+type DefaultServerInterceptorsArgs struct {
+	// The edge context implementation. Optional.
+	//
+	// If it's not set, the global one from ecinterface.Get will be used instead.
+	EdgeContextImpl ecinterface.Interface
+}
+
+// BaseplateDefaultUnaryServerInterceptors returns the default unary server
+// interceptors that should be used by a baseplate gRPC service,
+// to be used with grpc.ChainUnaryInterceptor.
+//
+// Currently they are (in order):
+//
+// 1. InjectServerSpanInterceptorUnary
+//
+// 2. InjectEdgeContextInterceptorUnary
+//
+// 3. InjectPrometheusUnaryServerInterceptor
+//
+// 4. RecoverPanicInterceptorUnary
+func BaseplateDefaultUnaryServerInterceptors(args DefaultServerInterceptorsArgs) []grpc.UnaryServerInterceptor {
+	return []grpc.UnaryServerInterceptor{
+		InjectServerSpanInterceptorUnary(),
+		InjectEdgeContextInterceptorUnary(args.EdgeContextImpl),
+		InjectPrometheusUnaryServerInterceptor(),
+		RecoverPanicInterceptorUnary(),
+	}
+}
+
+// BaseplateDefaultStreamServerInterceptors returns the default stream server
+// interceptors that should be used by a baseplate gRPC service,
+// to be used with grpc.ChainStreamInterceptor.
+//
+// Currently they are (in order):
+//
+// 1. InjectServerSpanInterceptorStreaming
+//
+// 2. InjectEdgeContextInterceptorStreaming
+//
+// 3. InjectPrometheusStreamServerInterceptor
+//
+// 4. RecoverPanicInterceptorStreaming
+func BaseplateDefaultStreamServerInterceptors(args DefaultServerInterceptorsArgs) []grpc.StreamServerInterceptor {
+	return []grpc.StreamServerInterceptor{
+		InjectServerSpanInterceptorStreaming(),
+		InjectEdgeContextInterceptorStreaming(args.EdgeContextImpl),
+		InjectPrometheusStreamServerInterceptor(""),
+		RecoverPanicInterceptorStreaming(),
+	}
+}
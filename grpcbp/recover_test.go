@@ -0,0 +1,65 @@
+package grpcbp
+
+import (
+	"context"
+	"testing"
+
+	pb "github.com/grpc-ecosystem/go-grpc-middleware/testing/testproto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRecoverPanicInterceptors(t *testing.T) {
+	const service = "mwitkow.testproto.TestService"
+
+	l, _ := setupServer(
+		t,
+		grpc.UnaryInterceptor(RecoverPanicInterceptorUnary()),
+		grpc.StreamInterceptor(RecoverPanicInterceptorStreaming()),
+	)
+	client := pb.NewTestServiceClient(setupClient(t, l))
+
+	checkPanic := func(t *testing.T, method string, call func() error) {
+		t.Helper()
+		counter := panicRecoverCounter.With(prometheus.Labels{
+			serviceLabel: service,
+			methodLabel:  method,
+		})
+		before := testutil.ToFloat64(counter)
+
+		err := call()
+		if code := status.Code(err); code != codes.Internal {
+			t.Errorf("Expected code %v, got %v: %v", codes.Internal, code, err)
+		}
+		if got := testutil.ToFloat64(counter) - before; got != 1 {
+			t.Errorf("Expected panic counter to increase by 1, got %v", got)
+		}
+	}
+
+	t.Run("unary", func(t *testing.T) {
+		checkPanic(t, "PingEmpty", func() error {
+			_, err := client.PingEmpty(context.Background(), &pb.Empty{})
+			return err
+		})
+	})
+
+	t.Run("streaming", func(t *testing.T) {
+		checkPanic(t, "PingList", func() error {
+			stream, err := client.PingList(context.Background(), &pb.PingRequest{})
+			if err != nil {
+				return err
+			}
+			_, err = stream.Recv()
+			return err
+		})
+	})
+
+	t.Run("server-still-serving", func(t *testing.T) {
+		if _, err := client.Ping(context.Background(), &pb.PingRequest{}); err != nil {
+			t.Errorf("Ping: %v", err)
+		}
+	})
+}
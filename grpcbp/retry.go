@@ -0,0 +1,239 @@
+package grpcbp
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/avast/retry-go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/reddit/baseplate.go/retrybp"
+	"github.com/reddit/baseplate.go/transport"
+)
+
+// RetryPushbackTrailer is the trailer key used by gRPC servers to tell the
+// clients how long to wait before retrying, in milliseconds.
+//
+// A negative value means the client should not retry.
+const RetryPushbackTrailer = "grpc-retry-pushback-ms"
+
+// RetryInterceptorArgs are the args to be passed into RetryInterceptorUnary
+// and RetryInterceptorsUnary functions.
+type RetryInterceptorArgs struct {
+	// ServiceSlug is the short string representing the backend the client is
+	// connecting to, used by the metrics interceptors added by
+	// RetryInterceptorsUnary. It's not used by RetryInterceptorUnary.
+	ServiceSlug string
+
+	// IdempotentMethods are the full names of the methods
+	// (e.g. "/package.Service/Method") that are safe to be retried.
+	//
+	// Calls to the other methods are never retried,
+	// unless RetryNonIdempotent is set to true.
+	IdempotentMethods []string
+
+	// RetryNonIdempotent, when set to true, allows all the methods to be
+	// retried, including the ones not in IdempotentMethods.
+	RetryNonIdempotent bool
+
+	// RetryOptions are the default retry.Options used by the interceptor.
+	// They can be overridden per call via retrybp.WithOptions.
+	//
+	// Before the RetryOptions, the defaults are:
+	//
+	// - retry.Attempts(1), which doesn't retry.
+	//
+	// - retry.LastErrorOnly(true), so the error returned is the gRPC status
+	// error from the last attempt.
+	//
+	// - retrybp.Filters(retrybp.RetryableErrorFilter, StatusCodeFilter)
+	//
+	// - retrybp.CappedExponentialBackoff with the default args,
+	// which honors the RetryPushbackTrailer.
+	// When overriding it, make sure IgnoreRetryAfterError is false to keep
+	// honoring the pushback.
+	RetryOptions []retry.Option
+}
+
+// RetryInterceptorUnary is a client middleware that retries the unary calls to
+// idempotent methods with retrybp.
+//
+// The server-provided retry delay in the RetryPushbackTrailer trailer is
+// honored by the retry delay (as retrybp.RetryAfterError), and a negative
+// pushback stops the retries.
+// The retries also stop as soon as the context of the call is done,
+// so the overall context deadline is always respected.
+func RetryInterceptorUnary(args RetryInterceptorArgs) grpc.UnaryClientInterceptor {
+	idempotent := make(map[string]bool, len(args.IdempotentMethods))
+	for _, method := range args.IdempotentMethods {
+		idempotent[method] = true
+	}
+	options := make([]retry.Option, 0, len(args.RetryOptions)+4)
+	options = append(
+		options,
+		retry.Attempts(1),
+		retry.LastErrorOnly(true),
+		retrybp.Filters(retrybp.RetryableErrorFilter, StatusCodeFilter),
+		retrybp.CappedExponentialBackoff(retrybp.CappedExponentialBackoffArgs{}),
+	)
+	options = append(options, args.RetryOptions...)
+
+	return func(
+		ctx context.Context,
+		method string,
+		req interface{},
+		reply interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		if !args.RetryNonIdempotent && !idempotent[method] {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		err := retrybp.Do(ctx, func() error {
+			var trailer metadata.MD
+			err := invoker(ctx, method, req, reply, cc, append(opts, grpc.Trailer(&trailer))...)
+			if status.Code(err) == codes.DeadlineExceeded && ctx.Err() != nil {
+				// The deadline of the call itself is exceeded, not the server's.
+				return clientDeadlineError{err: err}
+			}
+			return withPushback(err, trailer)
+		}, options...)
+		return unwrapRetryError(err)
+	}
+}
+
+// unwrapRetryError removes the wrappers added by RetryInterceptorUnary from
+// err, including the errors of all the attempts joined by retrybp.Do when
+// retry.LastErrorOnly is false.
+func unwrapRetryError(err error) error {
+	switch e := err.(type) {
+	case pushbackError:
+		return e.err
+	case clientDeadlineError:
+		return e.err
+	case interface{ Unwrap() []error }:
+		errs := e.Unwrap()
+		unwrapped := make([]error, len(errs))
+		for i, err := range errs {
+			unwrapped[i] = unwrapRetryError(err)
+		}
+		return errors.Join(unwrapped...)
+	}
+	return err
+}
+
+// RetryInterceptorsUnary returns RetryInterceptorUnary wrapped by
+// PrometheusUnaryClientInterceptor both before it (with
+// transport.WithRetrySlugSuffix added to args.ServiceSlug) and after it,
+// so the metrics of the individual attempts and the calls with retries are
+// distinguishable.
+//
+// The returned interceptors should be used together in the same order,
+// for example via grpc.WithChainUnaryInterceptor.
+func RetryInterceptorsUnary(args RetryInterceptorArgs) []grpc.UnaryClientInterceptor {
+	return []grpc.UnaryClientInterceptor{
+		PrometheusUnaryClientInterceptor(args.ServiceSlug + transport.WithRetrySlugSuffix),
+		RetryInterceptorUnary(args),
+		PrometheusUnaryClientInterceptor(args.ServiceSlug),
+	}
+}
+
+// StatusCodeFilter is a retrybp.Filter implementation that retries gRPC status
+// errors with Unavailable, ResourceExhausted, and DeadlineExceeded codes.
+//
+// DeadlineExceeded is only retried when it's returned by the server.
+// When it's caused by the deadline of the context of the call exceeded on the
+// client side, as detected by RetryInterceptorUnary, it's not retried.
+func StatusCodeFilter(err error, next retry.RetryIfFunc) bool {
+	if errors.As(err, new(clientDeadlineError)) {
+		return false
+	}
+	if s, ok := status.FromError(err); ok {
+		switch s.Code() {
+		case codes.Unavailable, codes.ResourceExhausted, codes.DeadlineExceeded:
+			return true
+		}
+	}
+	return next(err)
+}
+
+var _ retrybp.Filter = StatusCodeFilter
+
+// pushbackError wraps the error with the server-provided retry delay.
+type pushbackError struct {
+	err   error
+	delay time.Duration
+}
+
+func (e pushbackError) Error() string {
+	return e.err.Error()
+}
+
+func (e pushbackError) Unwrap() error {
+	return e.err
+}
+
+func (e pushbackError) RetryAfterDuration() time.Duration {
+	return e.delay
+}
+
+func (e pushbackError) Retryable() int {
+	if e.delay < 0 {
+		return -1
+	}
+	return 0
+}
+
+func (e pushbackError) GRPCStatus() *status.Status {
+	return status.Convert(e.err)
+}
+
+var (
+	_ retrybp.RetryAfterError = pushbackError{}
+	_ retrybp.RetryableError  = pushbackError{}
+)
+
+// clientDeadlineError wraps the DeadlineExceeded error caused by the deadline
+// of the context of the call on the client side.
+type clientDeadlineError struct {
+	err error
+}
+
+func (e clientDeadlineError) Error() string {
+	return e.err.Error()
+}
+
+func (e clientDeadlineError) Unwrap() error {
+	return e.err
+}
+
+func (e clientDeadlineError) GRPCStatus() *status.Status {
+	return status.Convert(e.err)
+}
+
+// withPushback wraps err with the retry delay from the RetryPushbackTrailer in
+// trailer, if any.
+func withPushback(err error, trailer metadata.MD) error {
+	if err == nil {
+		return nil
+	}
+	value, ok := GetHeader(trailer, RetryPushbackTrailer)
+	if !ok {
+		return err
+	}
+	ms, parseErr := strconv.ParseInt(value, 10, 64)
+	if parseErr != nil {
+		return err
+	}
+	return pushbackError{
+		err:   err,
+		delay: time.Duration(ms) * time.Millisecond,
+	}
+}
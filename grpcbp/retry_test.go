@@ -0,0 +1,226 @@
+package grpcbp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/avast/retry-go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// fakeInvoker returns the errors in order, and sets the trailers of the same
+// index on the call if non-nil.
+type fakeInvoker struct {
+	errs     []error
+	trailers []metadata.MD
+	calls    int
+}
+
+func (f *fakeInvoker) invoke(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+	i := f.calls
+	f.calls++
+	if i < len(f.trailers) && f.trailers[i] != nil {
+		for _, opt := range opts {
+			if t, ok := opt.(grpc.TrailerCallOption); ok {
+				*t.TrailerAddr = f.trailers[i]
+			}
+		}
+	}
+	if i < len(f.errs) {
+		return f.errs[i]
+	}
+	return nil
+}
+
+func TestRetryInterceptorUnary(t *testing.T) {
+	const method = "/test.Service/Get"
+	unavailable := status.Error(codes.Unavailable, "unavailable")
+	invalid := status.Error(codes.InvalidArgument, "invalid")
+
+	for _, c := range []struct {
+		label    string
+		args     RetryInterceptorArgs
+		method   string
+		errs     []error
+		trailers []metadata.MD
+		calls    int
+		code     codes.Code
+	}{
+		{
+			label:  "success-after-retry",
+			args:   RetryInterceptorArgs{IdempotentMethods: []string{method}},
+			method: method,
+			errs:   []error{unavailable, unavailable},
+			calls:  3,
+			code:   codes.OK,
+		},
+		{
+			label:  "all-failed",
+			args:   RetryInterceptorArgs{IdempotentMethods: []string{method}},
+			method: method,
+			errs:   []error{unavailable, unavailable, unavailable},
+			calls:  3,
+			code:   codes.Unavailable,
+		},
+		{
+			label:  "not-retryable-code",
+			args:   RetryInterceptorArgs{IdempotentMethods: []string{method}},
+			method: method,
+			errs:   []error{invalid},
+			calls:  1,
+			code:   codes.InvalidArgument,
+		},
+		{
+			label:  "not-idempotent",
+			args:   RetryInterceptorArgs{IdempotentMethods: []string{method}},
+			method: "/test.Service/Create",
+			errs:   []error{unavailable},
+			calls:  1,
+			code:   codes.Unavailable,
+		},
+		{
+			label:  "not-idempotent-overridden",
+			args:   RetryInterceptorArgs{RetryNonIdempotent: true},
+			method: "/test.Service/Create",
+			errs:   []error{unavailable},
+			calls:  2,
+			code:   codes.OK,
+		},
+		{
+			label:  "server-deadline-exceeded",
+			args:   RetryInterceptorArgs{IdempotentMethods: []string{method}},
+			method: method,
+			errs:   []error{status.Error(codes.DeadlineExceeded, "deadline exceeded")},
+			calls:  2,
+			code:   codes.OK,
+		},
+		{
+			label:    "negative-pushback",
+			args:     RetryInterceptorArgs{IdempotentMethods: []string{method}},
+			method:   method,
+			errs:     []error{unavailable},
+			trailers: []metadata.MD{metadata.Pairs(RetryPushbackTrailer, "-1")},
+			calls:    1,
+			code:     codes.Unavailable,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			c.args.RetryOptions = []retry.Option{retry.Attempts(3), retry.Delay(time.Millisecond)}
+			invoker := &fakeInvoker{errs: c.errs, trailers: c.trailers}
+			err := RetryInterceptorUnary(c.args)(context.Background(), c.method, nil, nil, nil, invoker.invoke)
+			if invoker.calls != c.calls {
+				t.Errorf("Expected %d calls, got %d", c.calls, invoker.calls)
+			}
+			s, ok := status.FromError(err)
+			if !ok {
+				t.Fatalf("Expected a status error, got %#v", err)
+			}
+			if s.Code() != c.code {
+				t.Errorf("Expected code %v, got %v", c.code, s.Code())
+			}
+		})
+	}
+}
+
+func TestRetryInterceptorUnaryPushback(t *testing.T) {
+	const (
+		method = "/test.Service/Get"
+		// Longer than the default delay of retry-go, which is 100ms.
+		pushback = 200 * time.Millisecond
+	)
+	invoker := &fakeInvoker{
+		errs:     []error{status.Error(codes.ResourceExhausted, "slow down")},
+		trailers: []metadata.MD{metadata.Pairs(RetryPushbackTrailer, "200")},
+	}
+	interceptor := RetryInterceptorUnary(RetryInterceptorArgs{
+		IdempotentMethods: []string{method},
+		RetryOptions:      []retry.Option{retry.Attempts(2)},
+	})
+	start := time.Now()
+	if err := interceptor(context.Background(), method, nil, nil, nil, invoker.invoke); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < pushback {
+		t.Errorf("Expected retry to wait for at least %v, got %v", pushback, elapsed)
+	}
+}
+
+func TestRetryInterceptorUnaryContextDeadline(t *testing.T) {
+	const method = "/test.Service/Get"
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	invoker := &fakeInvoker{
+		errs:     []error{status.Error(codes.Unavailable, "unavailable")},
+		trailers: []metadata.MD{metadata.Pairs(RetryPushbackTrailer, "1000")},
+	}
+	interceptor := RetryInterceptorUnary(RetryInterceptorArgs{
+		IdempotentMethods: []string{method},
+		RetryOptions:      []retry.Option{retry.Attempts(2)},
+	})
+	start := time.Now()
+	err := interceptor(ctx, method, nil, nil, nil, invoker.invoke)
+	if err == nil {
+		t.Error("Expected error when the context deadline is exceeded, got nil")
+	}
+	if invoker.calls != 1 {
+		t.Errorf("Expected 1 call, got %d", invoker.calls)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected the retry to stop at the context deadline, took %v", elapsed)
+	}
+}
+
+func TestRetryInterceptorUnaryClientDeadlineExceeded(t *testing.T) {
+	const method = "/test.Service/Get"
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	var calls int
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		calls++
+		<-ctx.Done()
+		return status.FromContextError(ctx.Err()).Err()
+	}
+	interceptor := RetryInterceptorUnary(RetryInterceptorArgs{
+		IdempotentMethods: []string{method},
+		RetryOptions:      []retry.Option{retry.Attempts(3)},
+	})
+	err := interceptor(ctx, method, nil, nil, nil, invoker)
+	if calls != 1 {
+		t.Errorf("Expected 1 call, got %d", calls)
+	}
+	if _, ok := err.(clientDeadlineError); ok {
+		t.Errorf("Expected clientDeadlineError to be unwrapped, got %#v", err)
+	}
+	if code := status.Code(err); code != codes.DeadlineExceeded {
+		t.Errorf("Expected code %v, got %v: %v", codes.DeadlineExceeded, code, err)
+	}
+}
+
+func TestRetryInterceptorUnaryAllErrors(t *testing.T) {
+	const method = "/test.Service/Get"
+	first := status.Error(codes.Unavailable, "first")
+	second := status.Error(codes.ResourceExhausted, "second")
+	invoker := &fakeInvoker{
+		errs:     []error{first, second},
+		trailers: []metadata.MD{metadata.Pairs(RetryPushbackTrailer, "1"), metadata.Pairs(RetryPushbackTrailer, "1")},
+	}
+	interceptor := RetryInterceptorUnary(RetryInterceptorArgs{
+		IdempotentMethods: []string{method},
+		RetryOptions:      []retry.Option{retry.Attempts(2), retry.LastErrorOnly(false)},
+	})
+	err := interceptor(context.Background(), method, nil, nil, nil, invoker.invoke)
+	for _, want := range []error{first, second} {
+		if !errors.Is(err, want) {
+			t.Errorf("Expected error to wrap %v, got %v", want, err)
+		}
+	}
+	var pe pushbackError
+	if errors.As(err, &pe) {
+		t.Errorf("Expected pushbackError to be unwrapped, got %#v", err)
+	}
+}
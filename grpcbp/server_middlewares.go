@@ -2,7 +2,6 @@ package grpcbp
 
 import (
 	"context"
-	"errors"
 	"time"
 
 	"google.golang.org/grpc"
@@ -52,10 +51,25 @@ func InjectServerSpanInterceptorUnary() grpc.UnaryServerInterceptor {
 // server span will also have "peer.service" (tracing.TagKeyPeerService) tag
 // set to its value.
 //
-// This is not implemented yet.
+// The span is finished when the handler returns.
 func InjectServerSpanInterceptorStreaming() grpc.StreamServerInterceptor {
-	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
-		return errors.New("InjectServerSpanInterceptorStreaming: not implemented")
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		m := methodSlug(info.FullMethod)
+		ctx, span := StartSpanFromGRPCContext(stream.Context(), m)
+
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if value, ok := GetHeader(md, transport.HeaderTracingTrace); ok {
+				span.SetTag(tracing.TagKeyPeerService, value)
+			}
+		}
+
+		defer func() {
+			span.FinishWithOptions(tracing.FinishOptions{
+				Ctx: ctx,
+				Err: err,
+			}.Convert())
+		}()
+		return handler(srv, serverStreamWithContext{ServerStream: stream, ctx: ctx})
 	}
 }
 
@@ -73,14 +87,28 @@ func InjectEdgeContextInterceptorUnary(impl ecinterface.Interface) grpc.UnarySer
 
 // InjectEdgeContextInterceptorStreaming is a server middleware that injects an
 // edge request context created from the gRPC headers set on the context.
-//
-// This is not implemented yet.
 func InjectEdgeContextInterceptorStreaming(impl ecinterface.Interface) grpc.StreamServerInterceptor {
+	if impl == nil {
+		impl = ecinterface.Get()
+	}
 	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
-		return errors.New("InjectEdgeContextInterceptorStreaming: not implemented")
+		ctx := InitializeEdgeContext(stream.Context(), impl)
+		return handler(srv, serverStreamWithContext{ServerStream: stream, ctx: ctx})
 	}
 }
 
+// serverStreamWithContext is a grpc.ServerStream with the context object
+// replaced by the server interceptors.
+type serverStreamWithContext struct {
+	grpc.ServerStream
+
+	ctx context.Context
+}
+
+func (s serverStreamWithContext) Context() context.Context {
+	return s.ctx
+}
+
 // InitializeEdgeContext sets an edge request context created from the gRPC
 // headers set on the context onto the context and configures gRPC to forward
 // the edge requent context header on any gRPC calls made by the server.
@@ -167,6 +195,10 @@ func StartSpanFromGRPCContext(ctx context.Context, name string) (context.Context
 // * grpc_server_requests_total counter with labels
 //   - all above labels plus
 //   - grpc_code: the human-readable status code, e.g. OK, Internal, etc
+//
+// When exemplars are enabled via prometheusbp.EnableExemplars,
+// grpc_server_latency_seconds observations carry the trace id of the sampled
+// server span as an exemplar.
 func InjectPrometheusUnaryServerInterceptor() grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (_ interface{}, err error) {
 		start := time.Now()
@@ -190,7 +222,7 @@ func InjectPrometheusUnaryServerInterceptor() grpc.UnaryServerInterceptor {
 				typeLabel:    unary,
 				successLabel: success,
 			}
-			serverLatencyDistribution.With(latencyLabels).Observe(time.Since(start).Seconds())
+			prometheusbp.ObserveWithTrace(ctx, serverLatencyDistribution.With(latencyLabels), time.Since(start).Seconds())
 
 			totalRequestLabels := prometheus.Labels{
 				serviceLabel: serviceName,
@@ -207,12 +239,50 @@ func InjectPrometheusUnaryServerInterceptor() grpc.UnaryServerInterceptor {
 	}
 }
 
-// InjectPrometheusStreamServerInterceptor is a server middleware that tracks
-// Prometheus metrics.
+// InjectPrometheusStreamServerInterceptor is the streaming version of
+// InjectPrometheusUnaryServerInterceptor.
+//
+// The streams are tracked from when the handler is called to when it returns,
+// with grpc_type label of client_stream, server_stream, or bidi_stream.
 //
-// This is not implemented yet.
+// serverSlug is unused and only kept for backward compatibility.
 func InjectPrometheusStreamServerInterceptor(serverSlug string) grpc.StreamServerInterceptor {
 	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
-		return errors.New("InjectPrometheusStreamServerInterceptor: not implemented")
+		start := time.Now()
+
+		serviceName, method := serviceAndMethodSlug(info.FullMethod)
+		streamType := streamTypeOf(info.IsClientStream, info.IsServerStream)
+
+		activeRequestLabels := prometheus.Labels{
+			serviceLabel: serviceName,
+			typeLabel:    streamType,
+			methodLabel:  method,
+		}
+		serverActiveRequests.With(activeRequestLabels).Inc()
+
+		defer func() {
+			success := prometheusbp.BoolString(err == nil)
+			status, _ := status.FromError(err)
+
+			latencyLabels := prometheus.Labels{
+				serviceLabel: serviceName,
+				methodLabel:  method,
+				typeLabel:    streamType,
+				successLabel: success,
+			}
+			prometheusbp.ObserveWithTrace(stream.Context(), serverLatencyDistribution.With(latencyLabels), time.Since(start).Seconds())
+
+			totalRequestLabels := prometheus.Labels{
+				serviceLabel: serviceName,
+				methodLabel:  method,
+				typeLabel:    streamType,
+				successLabel: success,
+				codeLabel:    status.Code().String(),
+			}
+			serverTotalRequests.With(totalRequestLabels).Inc()
+			serverActiveRequests.With(activeRequestLabels).Dec()
+		}()
+
+		return handler(srv, stream)
 	}
 }
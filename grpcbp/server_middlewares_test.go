@@ -4,14 +4,17 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"io"
 	"testing"
 	"time"
 
 	pb "github.com/grpc-ecosystem/go-grpc-middleware/testing/testproto"
+	"github.com/opentracing/opentracing-go"
 	"github.com/prometheus/client_golang/prometheus"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
 
 	"github.com/reddit/baseplate.go/ecinterface"
 	"github.com/reddit/baseplate.go/internal/prometheusbpint/spectest"
@@ -309,3 +312,47 @@ func TestInjectPrometheusUnaryServerClientInterceptor(t *testing.T) {
 		})
 	}
 }
+
+func TestBaseplateDefaultStreamServerInterceptors(t *testing.T) {
+	l := bufconn.Listen(1024 * 1024)
+	s := grpc.NewServer(grpc.ChainStreamInterceptor(
+		BaseplateDefaultStreamServerInterceptors(DefaultServerInterceptorsArgs{
+			EdgeContextImpl: ecinterface.Mock(),
+		})...,
+	))
+	service := &listService{}
+	pb.RegisterTestServiceServer(s, service)
+	go s.Serve(l)
+	t.Cleanup(s.Stop)
+
+	defer promtest.NewPrometheusMetricTest(t, "server rpc count", serverTotalRequests, prometheus.Labels{
+		serviceLabel: "mwitkow.testproto.TestService",
+		methodLabel:  "PingList",
+		typeLabel:    serverStream,
+		successLabel: "true",
+		codeLabel:    codes.OK.String(),
+	}).CheckDelta(1)
+
+	client := pb.NewTestServiceClient(setupClient(t, l))
+	stream, err := client.PingList(context.Background(), &pb.PingRequest{})
+	if err != nil {
+		t.Fatalf("PingList: %v", err)
+	}
+	var count int
+	for {
+		_, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv: %v", err)
+		}
+		count++
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 responses, got %d", count)
+	}
+	if span := opentracing.SpanFromContext(service.ctx); span == nil {
+		t.Error("Expected server span in the context of the stream")
+	}
+}
@@ -1,9 +1,11 @@
 package httpbp
 
 import (
+	"context"
 	"errors"
 	"net/http"
 
+	"github.com/reddit/baseplate.go"
 	"github.com/reddit/baseplate.go/internal/admin"
 	"github.com/reddit/baseplate.go/log"
 )
@@ -13,15 +15,42 @@ import (
 //	health        - serve /health for health checking
 //	metrics       - serve /metrics for prometheus
 //	profiling     - serve /debug/pprof for profiling, ref: https://pkg.go.dev/net/http/pprof
+//	in-flight     - serve /debug/inflight for the requests tracked in DefaultInflightRegistry, see TrackInflight
+//
+// To fail the health check while the server is draining on shutdown,
+// wrap healthCheck with DrainingHealthCheck.
 //
 // Default server address is admin.Addr.
 //
 // This function blocks, so it should be run as its own goroutine.
 func ServeAdmin(healthCheck HandlerFunc) {
 	admin.Mux.Handle("/health", handler{handle: healthCheck})
+	admin.Mux.Handle("/debug/inflight", handler{handle: InflightHandler(DefaultInflightRegistry)})
 	if err := admin.Serve(); errors.Is(err, http.ErrServerClosed) {
 		log.Info("httpbp: admin server closed")
 	} else {
 		log.Panicw("httpbp: admin serving failed", "err", err)
 	}
 }
+
+var errDraining = errors.New("httpbp: server is draining")
+
+// DrainingHealthCheck wraps healthCheck to respond with 503
+// (ServiceUnavailable) without calling healthCheck while server is draining,
+// so the load balancer stops sending traffic to it before it stops accepting
+// new connections.
+//
+// server should be the one returned by NewBaseplateServer
+// (see DrainingServer), otherwise healthCheck is returned as-is.
+func DrainingHealthCheck(server baseplate.Server, healthCheck HandlerFunc) HandlerFunc {
+	ds, ok := server.(DrainingServer)
+	if !ok {
+		return healthCheck
+	}
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		if ds.IsDraining() {
+			return JSONError(ServiceUnavailable(), errDraining)
+		}
+		return healthCheck(ctx, w, r)
+	}
+}
@@ -0,0 +1,51 @@
+package httpbp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestShutdownDrainingHealthCheck(t *testing.T) {
+	s := &server{srv: &http.Server{}}
+	other := &server{srv: &http.Server{}}
+
+	var called bool
+	h := handler{handle: DrainingHealthCheck(s, func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		called = true
+		return nil
+	})}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if !called {
+		t.Error("Expected health check to be called when not draining")
+	}
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	called = false
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	// Shutdown with an already canceled context should still succeed as there
+	// are no connections to drain.
+	if err := s.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown returned error: %v", err)
+	}
+	if !s.IsDraining() {
+		t.Fatal("Expected IsDraining to be true after Shutdown")
+	}
+	if other.IsDraining() {
+		t.Error("Expected other servers not to be draining")
+	}
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if called {
+		t.Error("Expected health check not to be called when draining")
+	}
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+}
@@ -0,0 +1,67 @@
+package httpbp
+
+import (
+	"context"
+	"net/http"
+)
+
+const (
+	// CacheControlHeader is the standard "Cache-Control" header key.
+	CacheControlHeader = "Cache-Control"
+
+	// DefaultSafeCacheControl is the default Cache-Control value used by
+	// CacheControlByMethod for GET and HEAD requests.
+	//
+	// It allows the responses to be cached, but requires them to be
+	// revalidated before being reused.
+	DefaultSafeCacheControl = "no-cache"
+
+	// DefaultUnsafeCacheControl is the default Cache-Control value used by
+	// CacheControlByMethod for all the other requests.
+	//
+	// It prevents the responses from being cached.
+	DefaultUnsafeCacheControl = "no-store"
+)
+
+// CacheControlArgs are the args to be passed into CacheControlByMethod
+// function.
+type CacheControlArgs struct {
+	// Safe is the Cache-Control value to be set for GET and HEAD requests.
+	//
+	// Optional, if it's empty DefaultSafeCacheControl will be used instead.
+	Safe string
+
+	// Unsafe is the Cache-Control value to be set for requests of all the
+	// other methods, e.g. POST, PUT, DELETE, etc.
+	//
+	// Optional, if it's empty DefaultUnsafeCacheControl will be used instead.
+	Unsafe string
+}
+
+// CacheControlByMethod returns a Middleware that sets conservative default
+// Cache-Control header on the responses based on the request method,
+// to prevent accidental caching of the responses of mutations.
+//
+// The header is set before calling the next handler, so the handler can
+// override it by setting (or deleting) the Cache-Control header itself.
+func CacheControlByMethod(args CacheControlArgs) Middleware {
+	safe := args.Safe
+	if safe == "" {
+		safe = DefaultSafeCacheControl
+	}
+	unsafe := args.Unsafe
+	if unsafe == "" {
+		unsafe = DefaultUnsafeCacheControl
+	}
+	return func(name string, next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead:
+				w.Header().Set(CacheControlHeader, safe)
+			default:
+				w.Header().Set(CacheControlHeader, unsafe)
+			}
+			return next(ctx, w, r)
+		}
+	}
+}
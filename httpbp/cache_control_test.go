@@ -0,0 +1,82 @@
+package httpbp_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/reddit/baseplate.go/httpbp"
+)
+
+func TestCacheControlByMethod(t *testing.T) {
+	const override = "public, max-age=60"
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		if r.URL.Query().Get("override") != "" {
+			w.Header().Set(httpbp.CacheControlHeader, override)
+		}
+		return nil
+	}
+
+	for _, c := range []struct {
+		label    string
+		args     httpbp.CacheControlArgs
+		method   string
+		target   string
+		expected string
+	}{
+		{
+			label:    "get",
+			method:   http.MethodGet,
+			target:   "/",
+			expected: httpbp.DefaultSafeCacheControl,
+		},
+		{
+			label:    "head",
+			method:   http.MethodHead,
+			target:   "/",
+			expected: httpbp.DefaultSafeCacheControl,
+		},
+		{
+			label:    "post",
+			method:   http.MethodPost,
+			target:   "/",
+			expected: httpbp.DefaultUnsafeCacheControl,
+		},
+		{
+			label:    "delete",
+			method:   http.MethodDelete,
+			target:   "/",
+			expected: httpbp.DefaultUnsafeCacheControl,
+		},
+		{
+			label: "custom",
+			args: httpbp.CacheControlArgs{
+				Safe:   "private, max-age=10",
+				Unsafe: "private, no-store",
+			},
+			method:   http.MethodPost,
+			target:   "/",
+			expected: "private, no-store",
+		},
+		{
+			label:    "handler-override",
+			method:   http.MethodPost,
+			target:   "/?override=true",
+			expected: override,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			handle := httpbp.Wrap("test", handler, httpbp.CacheControlByMethod(c.args))
+			r := httptest.NewRequest(c.method, c.target, nil)
+			w := httptest.NewRecorder()
+			if err := handle(r.Context(), w, r); err != nil {
+				t.Fatal(err)
+			}
+			if got := w.Header().Get(httpbp.CacheControlHeader); got != c.expected {
+				t.Errorf("Expected %s header %q, got %q", httpbp.CacheControlHeader, c.expected, got)
+			}
+		})
+	}
+}
@@ -13,10 +13,13 @@ import (
 
 	"github.com/avast/retry-go"
 	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
 
 	"github.com/reddit/baseplate.go/breakerbp"
+	"github.com/reddit/baseplate.go/ecinterface"
 	//lint:ignore SA1019 This library is internal only, not actually deprecated
 	"github.com/reddit/baseplate.go/internalv2compat"
+	"github.com/reddit/baseplate.go/log"
 	"github.com/reddit/baseplate.go/retrybp"
 	"github.com/reddit/baseplate.go/transport"
 )
@@ -53,6 +56,14 @@ func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
 //
 // * PrometheusClientMetrics
 //
+// * ForwardEdgeContext - Only if config.ForwardEdgeContext is true.
+//
+// * SetDeadlineBudget - Only if config.SetDeadlineBudget is true.
+//
+// When config.SlowRequestThreshold > 0, LogSlowRequests will also be added
+// both before Retries (with transport.WithRetrySlugSuffix) and after it,
+// to measure both the full retried duration and each attempt.
+//
 // ClientErrorWrapper is included as transitive middleware through Retries.
 func NewClient(config ClientConfig, middleware ...ClientMiddleware) (*http.Client, error) {
 	if err := config.Validate(); err != nil {
@@ -78,9 +89,24 @@ func NewClient(config ClientConfig, middleware ...ClientMiddleware) (*http.Clien
 	defaults := []ClientMiddleware{
 		MonitorClient(config.Slug + transport.WithRetrySlugSuffix),
 		PrometheusClientMetrics(config.Slug + transport.WithRetrySlugSuffix),
+	}
+	if config.SlowRequestThreshold > 0 {
+		defaults = append(defaults, LogSlowRequests(config.Slug+transport.WithRetrySlugSuffix, config.SlowRequestThreshold))
+	}
+	defaults = append(
+		defaults,
 		Retries(config.MaxErrorReadAhead, config.RetryOptions...),
 		MonitorClient(config.Slug),
 		PrometheusClientMetrics(config.Slug),
+	)
+	if config.SlowRequestThreshold > 0 {
+		defaults = append(defaults, LogSlowRequests(config.Slug, config.SlowRequestThreshold))
+	}
+	if config.ForwardEdgeContext {
+		defaults = append(defaults, ForwardEdgeContext(config.EdgeContextImpl))
+	}
+	if config.SetDeadlineBudget {
+		defaults = append(defaults, SetDeadlineBudget)
 	}
 
 	// prepend middleware to ensure Retires with ClientErrorWrapper is still
@@ -262,6 +288,68 @@ func MaxConcurrency(maxConcurrency int64) ClientMiddleware {
 	}
 }
 
+var _ ClientMiddleware = SetDeadlineBudget
+
+// SetDeadlineBudget is the client middleware implementing the HTTP equivalent
+// of Baseplate deadline propagation.
+//
+// If the context object of the request has a deadline, the remaining time,
+// rounded up to the next millisecond, is sent as DeadlineBudgetHeader.
+// If the deadline already passed, the request is not sent and the context
+// error is returned instead.
+func SetDeadlineBudget(next http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		ctx := req.Context()
+		if err := ctx.Err(); err != nil {
+			// Deadline already passed, no need to even try
+			return nil, err
+		}
+
+		if deadline, ok := ctx.Deadline(); ok {
+			timeout := time.Until(deadline) + time.Millisecond - 1
+			ms := timeout.Milliseconds()
+			if ms < 1 {
+				// Make sure we give it at least 1ms.
+				ms = 1
+			}
+			// RoundTripper should not modify the request.
+			req = req.Clone(ctx)
+			req.Header.Set(DeadlineBudgetHeader, strconv.FormatInt(ms, 10))
+		}
+		return next.RoundTrip(req)
+	})
+}
+
+// ForwardEdgeContext returns a ClientMiddleware that forwards the edge request
+// context set on the context object of the request to the HTTP service being
+// called, as EdgeContextHeader, the same way thrift clients forward it.
+//
+// It's no-op when there's no edge request context on the context object,
+// or the request already has EdgeContextHeader set.
+//
+// impl is optional. If it's nil, the global one from ecinterface.Get will be
+// used instead.
+func ForwardEdgeContext(impl ecinterface.Interface) ClientMiddleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if isHeaderSet(req.Header, EdgeContextHeader) {
+				return next.RoundTrip(req)
+			}
+			ecImpl := impl
+			if ecImpl == nil {
+				ecImpl = ecinterface.Get()
+			}
+			ctx := req.Context()
+			if header, ok := ecImpl.ContextToHeader(ctx); ok {
+				// RoundTripper should not modify the request.
+				req = req.Clone(ctx)
+				req.Header.Set(EdgeContextHeader, encodeEdgeContextHeader([]byte(header)))
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
+
 var monitorClientLoggingOnce sync.Once
 
 // MonitorClient is an HTTP client middleware that wraps HTTP requests in a
@@ -349,3 +437,52 @@ func PrometheusClientMetrics(serverSlug string) ClientMiddleware {
 		})
 	}
 }
+
+// slowRequestsLogBurst is the max number of log lines LogSlowRequests writes
+// per second.
+const slowRequestsLogBurst = 10
+
+// LogSlowRequests returns a ClientMiddleware that logs the requests took
+// longer than threshold, at warning level via log.C(ctx),
+// with the client name, method, host, path, and the duration of the request.
+//
+// To avoid flooding the logs, the log lines are rate limited to 10 per second
+// per middleware, while http_client_slow_requests_total counter is always
+// increased for every slow request, with labels:
+//
+//   - http_method: the method of the request
+//   - http_client_name: the clientName arg
+//
+// When put before Retries it measures the full retried duration,
+// and when put after Retries it measures each attempt.
+// NewClient adds both when ClientConfig.SlowRequestThreshold is set.
+func LogSlowRequests(clientName string, threshold time.Duration) ClientMiddleware {
+	limiter := rate.NewLimiter(rate.Every(time.Second/slowRequestsLogBurst), slowRequestsLogBurst)
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			defer func() {
+				duration := time.Since(start)
+				if duration <= threshold {
+					return
+				}
+				clientSlowRequests.With(prometheus.Labels{
+					methodLabel:     req.Method,
+					clientNameLabel: clientName,
+				}).Inc()
+				if limiter.Allow() {
+					log.C(req.Context()).Warnw(
+						"httpbp: slow client request",
+						"client", clientName,
+						"method", req.Method,
+						"host", req.URL.Host,
+						"path", req.URL.Path,
+						"duration", duration,
+						"threshold", threshold,
+					)
+				}
+			}()
+			return next.RoundTrip(req)
+		})
+	}
+}
@@ -2,11 +2,13 @@ package httpbp
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -16,6 +18,7 @@ import (
 	"github.com/sony/gobreaker"
 
 	"github.com/reddit/baseplate.go/breakerbp"
+	"github.com/reddit/baseplate.go/ecinterface"
 )
 
 func TestNewClient(t *testing.T) {
@@ -395,3 +398,150 @@ func TestCircuitBreaker(t *testing.T) {
 		t.Errorf("Expected the third request to return %v, got %v", gobreaker.ErrOpenState, err)
 	}
 }
+
+func TestSetDeadlineBudget(t *testing.T) {
+	var header string
+	transport := SetDeadlineBudget(roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		header = req.Header.Get(DeadlineBudgetHeader)
+		return &http.Response{StatusCode: http.StatusOK}, nil
+	}))
+
+	t.Run("no-deadline", func(t *testing.T) {
+		header = ""
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if _, err := transport.RoundTrip(req); err != nil {
+			t.Fatal(err)
+		}
+		if header != "" {
+			t.Errorf("Expected no %s header, got %q", DeadlineBudgetHeader, header)
+		}
+	})
+
+	t.Run("deadline", func(t *testing.T) {
+		header = ""
+		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		defer cancel()
+		req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+		if _, err := transport.RoundTrip(req); err != nil {
+			t.Fatal(err)
+		}
+		if v, err := strconv.ParseInt(header, 10, 64); err != nil || v <= 0 || v > 500 {
+			t.Errorf("Expected %s header in (0, 500], got %q", DeadlineBudgetHeader, header)
+		}
+		if v := req.Header.Get(DeadlineBudgetHeader); v != "" {
+			t.Errorf("Expected the original request not to be modified, got header %q", v)
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+		if _, err := transport.RoundTrip(req); !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	})
+}
+
+func TestNewClientSetDeadlineBudget(t *testing.T) {
+	var header string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get(DeadlineBudgetHeader)
+	}))
+	defer server.Close()
+
+	for _, c := range []struct {
+		label    string
+		enabled  bool
+		expected bool
+	}{
+		{label: "default", enabled: false, expected: false},
+		{label: "enabled", enabled: true, expected: true},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			header = ""
+			client, err := NewClient(ClientConfig{
+				Slug:              "test",
+				SetDeadlineBudget: c.enabled,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			DrainAndClose(resp.Body)
+			if got := header != ""; got != c.expected {
+				t.Errorf("Expected %s header set to be %v, got %q", DeadlineBudgetHeader, c.expected, header)
+			}
+		})
+	}
+}
+
+func TestForwardEdgeContext(t *testing.T) {
+	const raw = "dummy-edge-context"
+	impl := ecinterface.Mock()
+
+	var header string
+	transport := ForwardEdgeContext(impl)(roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		header = req.Header.Get(EdgeContextHeader)
+		return &http.Response{StatusCode: http.StatusOK}, nil
+	}))
+
+	t.Run("no-edge-context", func(t *testing.T) {
+		header = ""
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if _, err := transport.RoundTrip(req); err != nil {
+			t.Fatal(err)
+		}
+		if header != "" {
+			t.Errorf("Expected no %s header, got %q", EdgeContextHeader, header)
+		}
+	})
+
+	t.Run("edge-context", func(t *testing.T) {
+		header = ""
+		ctx, err := impl.HeaderToContext(context.Background(), raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+		if _, err := transport.RoundTrip(req); err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := decodeEdgeContextHeader(header)
+		if err != nil {
+			t.Fatalf("Failed to decode %s header %q: %v", EdgeContextHeader, header, err)
+		}
+		if string(decoded) != raw {
+			t.Errorf("Expected edge context %q, got %q", raw, decoded)
+		}
+		if v := req.Header.Get(EdgeContextHeader); v != "" {
+			t.Errorf("Expected the original request not to be modified, got header %q", v)
+		}
+	})
+
+	t.Run("already-set", func(t *testing.T) {
+		const existing = "existing"
+		header = ""
+		ctx, err := impl.HeaderToContext(context.Background(), raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+		req.Header.Set(EdgeContextHeader, existing)
+		if _, err := transport.RoundTrip(req); err != nil {
+			t.Fatal(err)
+		}
+		if header != existing {
+			t.Errorf("Expected %s header %q to be kept, got %q", EdgeContextHeader, existing, header)
+		}
+	})
+}
@@ -2,10 +2,12 @@ package httpbp
 
 import (
 	"errors"
+	"time"
 
 	"github.com/avast/retry-go"
 
 	"github.com/reddit/baseplate.go/breakerbp"
+	"github.com/reddit/baseplate.go/ecinterface"
 )
 
 // ClientConfig provides the configuration for a HTTP client including its
@@ -16,6 +18,27 @@ type ClientConfig struct {
 	MaxConnections    int               `yaml:"maxConnections"`
 	CircuitBreaker    *breakerbp.Config `yaml:"circuitBreaker"`
 	RetryOptions      []retry.Option
+
+	// When SlowRequestThreshold > 0, the requests took longer than it will be
+	// logged and counted by LogSlowRequests, both for the full retried duration
+	// and for each attempt. Optional.
+	SlowRequestThreshold time.Duration `yaml:"slowRequestThreshold"`
+
+	// When ForwardEdgeContext is true, the edge request context on the context
+	// object of the requests will be forwarded to the HTTP service being called
+	// by ForwardEdgeContext middleware. Optional.
+	//
+	// EdgeContextImpl is the edge context implementation used by it.
+	// If it's not set, the global one from ecinterface.Get will be used instead.
+	ForwardEdgeContext bool                  `yaml:"forwardEdgeContext"`
+	EdgeContextImpl    ecinterface.Interface `yaml:"-"`
+
+	// When SetDeadlineBudget is true, the remaining time of the deadline of the
+	// context object of the requests will be sent to the HTTP service being
+	// called by SetDeadlineBudget middleware. Optional.
+	//
+	// Only enable it when calling the services trusting the callers' deadlines.
+	SetDeadlineBudget bool `yaml:"setDeadlineBudget"`
 }
 
 // Validate checks ClientConfig for any missing or erroneous values.
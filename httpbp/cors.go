@@ -0,0 +1,140 @@
+package httpbp
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The CORS related headers used by CORS.
+const (
+	OriginHeader                        = "Origin"
+	AccessControlRequestMethodHeader    = "Access-Control-Request-Method"
+	AccessControlRequestHeadersHeader   = "Access-Control-Request-Headers"
+	AccessControlAllowOriginHeader      = "Access-Control-Allow-Origin"
+	AccessControlAllowMethodsHeader     = "Access-Control-Allow-Methods"
+	AccessControlAllowHeadersHeader     = "Access-Control-Allow-Headers"
+	AccessControlAllowCredentialsHeader = "Access-Control-Allow-Credentials"
+	AccessControlMaxAgeHeader           = "Access-Control-Max-Age"
+	VaryHeader                          = "Vary"
+)
+
+// CORSArgs are the args to be passed into CORS function.
+type CORSArgs struct {
+	// AllowedOrigins are the origins allowed to make cross-origin requests,
+	// e.g. "https://www.reddit.com".
+	//
+	// "*" allows all origins, it can't be used with AllowCredentials.
+	AllowedOrigins []string
+
+	// AllowedMethods are the methods allowed in the cross-origin requests,
+	// returned in the preflight responses.
+	//
+	// Optional. Default to GET, HEAD and POST.
+	AllowedMethods []string
+
+	// AllowedHeaders are the request headers allowed in the cross-origin
+	// requests, returned in the preflight responses.
+	//
+	// Optional.
+	AllowedHeaders []string
+
+	// AllowCredentials allows the cross-origin requests to include credentials
+	// like cookies.
+	//
+	// When it's true the allowed origin is echoed back,
+	// and AllowedOrigins must not contain "*",
+	// as that would allow every website to make credentialed requests.
+	AllowCredentials bool
+
+	// MaxAge is how long the browsers can cache the preflight responses.
+	//
+	// Optional. When <= 0 the Access-Control-Max-Age header is not set.
+	MaxAge time.Duration
+}
+
+// CORS returns a Middleware that sets the Access-Control-* response headers for
+// the cross-origin requests from the allowed origins,
+// and handles the preflight requests.
+//
+// The preflight requests (OPTIONS requests with Access-Control-Request-Method
+// header) are responded with 204 by the middleware directly without calling
+// the handler.
+// As the Middlewares of an Endpoint run after the method check,
+// http.MethodOptions must be included in the Methods of the Endpoint for the
+// preflight requests to reach it:
+//
+//	httpbp.Endpoint{
+//		Name:    "foo",
+//		Methods: []string{http.MethodPost, http.MethodOptions},
+//		Handle:  handleFoo,
+//		Middlewares: []httpbp.Middleware{
+//			httpbp.CORS(httpbp.CORSArgs{
+//				AllowedOrigins: []string{"https://www.reddit.com"},
+//				AllowedMethods: []string{http.MethodPost},
+//			}),
+//		},
+//	}
+//
+// The requests from an origin not allowed are passed through without the
+// Access-Control-* headers, so the browsers would block them.
+//
+// It panics when AllowedOrigins contains "*" and AllowCredentials is true.
+func CORS(args CORSArgs) Middleware {
+	origins := make(map[string]bool, len(args.AllowedOrigins))
+	var allowAll bool
+	for _, o := range args.AllowedOrigins {
+		if o == "*" {
+			allowAll = true
+		}
+		origins[o] = true
+	}
+	if allowAll && args.AllowCredentials {
+		panic(`httpbp.CORS: AllowCredentials cannot be used with "*" in AllowedOrigins`)
+	}
+	methods := args.AllowedMethods
+	if len(methods) == 0 {
+		methods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+	}
+	allowedMethods := strings.Join(methods, ", ")
+	allowedHeaders := strings.Join(args.AllowedHeaders, ", ")
+	var maxAge string
+	if args.MaxAge > 0 {
+		maxAge = strconv.FormatInt(int64(args.MaxAge.Seconds()), 10)
+	}
+
+	return func(name string, next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			preflight := r.Method == http.MethodOptions && r.Header.Get(AccessControlRequestMethodHeader) != ""
+			origin := r.Header.Get(OriginHeader)
+			if origin != "" && (allowAll || origins[origin]) {
+				h := w.Header()
+				if allowAll {
+					h.Set(AccessControlAllowOriginHeader, "*")
+				} else {
+					h.Set(AccessControlAllowOriginHeader, origin)
+					h.Add(VaryHeader, OriginHeader)
+				}
+				if args.AllowCredentials {
+					h.Set(AccessControlAllowCredentialsHeader, "true")
+				}
+				if preflight {
+					h.Set(AccessControlAllowMethodsHeader, allowedMethods)
+					if allowedHeaders != "" {
+						h.Set(AccessControlAllowHeadersHeader, allowedHeaders)
+					}
+					if maxAge != "" {
+						h.Set(AccessControlMaxAgeHeader, maxAge)
+					}
+				}
+			}
+			if preflight {
+				w.WriteHeader(http.StatusNoContent)
+				return nil
+			}
+			return next(ctx, w, r)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package httpbp_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/reddit/baseplate.go/httpbp"
+)
+
+func TestCORS(t *testing.T) {
+	const (
+		allowed    = "https://www.reddit.com"
+		notAllowed = "https://example.com"
+	)
+
+	newEndpoint := func(args httpbp.CORSArgs, called *bool) httpbp.Endpoint {
+		return httpbp.Endpoint{
+			Name:    "cors",
+			Methods: []string{http.MethodPost, http.MethodOptions},
+			Handle: func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				*called = true
+				return httpbp.WriteJSON(w, httpbp.Response{Body: "ok"})
+			},
+			Middlewares: []httpbp.Middleware{httpbp.CORS(args)},
+		}
+	}
+
+	newRequest := func(method, origin string, preflight bool) *http.Request {
+		r := httptest.NewRequest(method, "/cors", nil)
+		if origin != "" {
+			r.Header.Set(httpbp.OriginHeader, origin)
+		}
+		if preflight {
+			r.Header.Set(httpbp.AccessControlRequestMethodHeader, http.MethodPost)
+		}
+		return r
+	}
+
+	for _, c := range []struct {
+		label   string
+		args    httpbp.CORSArgs
+		request *http.Request
+
+		expectedCode    int
+		expectedCalled  bool
+		expectedHeaders map[string]string
+	}{
+		{
+			label: "preflight",
+			args: httpbp.CORSArgs{
+				AllowedOrigins: []string{allowed},
+				AllowedMethods: []string{http.MethodPost, http.MethodPut},
+				AllowedHeaders: []string{"Content-Type", "X-Foo"},
+				MaxAge:         time.Hour,
+			},
+			request:        newRequest(http.MethodOptions, allowed, true),
+			expectedCode:   http.StatusNoContent,
+			expectedCalled: false,
+			expectedHeaders: map[string]string{
+				httpbp.AccessControlAllowOriginHeader:      allowed,
+				httpbp.AccessControlAllowMethodsHeader:     "POST, PUT",
+				httpbp.AccessControlAllowHeadersHeader:     "Content-Type, X-Foo",
+				httpbp.AccessControlMaxAgeHeader:           "3600",
+				httpbp.AccessControlAllowCredentialsHeader: "",
+				httpbp.VaryHeader:                          httpbp.OriginHeader,
+			},
+		},
+		{
+			label: "preflight-not-allowed",
+			args: httpbp.CORSArgs{
+				AllowedOrigins: []string{allowed},
+			},
+			request:        newRequest(http.MethodOptions, notAllowed, true),
+			expectedCode:   http.StatusNoContent,
+			expectedCalled: false,
+			expectedHeaders: map[string]string{
+				httpbp.AccessControlAllowOriginHeader:  "",
+				httpbp.AccessControlAllowMethodsHeader: "",
+			},
+		},
+		{
+			label: "actual",
+			args: httpbp.CORSArgs{
+				AllowedOrigins: []string{allowed},
+			},
+			request:        newRequest(http.MethodPost, allowed, false),
+			expectedCode:   http.StatusOK,
+			expectedCalled: true,
+			expectedHeaders: map[string]string{
+				httpbp.AccessControlAllowOriginHeader:  allowed,
+				httpbp.AccessControlAllowMethodsHeader: "",
+			},
+		},
+		{
+			label: "actual-not-allowed",
+			args: httpbp.CORSArgs{
+				AllowedOrigins: []string{allowed},
+			},
+			request:        newRequest(http.MethodPost, notAllowed, false),
+			expectedCode:   http.StatusOK,
+			expectedCalled: true,
+			expectedHeaders: map[string]string{
+				httpbp.AccessControlAllowOriginHeader: "",
+			},
+		},
+		{
+			label: "wildcard",
+			args: httpbp.CORSArgs{
+				AllowedOrigins: []string{"*"},
+			},
+			request:        newRequest(http.MethodPost, notAllowed, false),
+			expectedCode:   http.StatusOK,
+			expectedCalled: true,
+			expectedHeaders: map[string]string{
+				httpbp.AccessControlAllowOriginHeader: "*",
+				httpbp.VaryHeader:                     "",
+			},
+		},
+		{
+			label: "options-without-preflight",
+			args: httpbp.CORSArgs{
+				AllowedOrigins: []string{allowed},
+			},
+			request:        newRequest(http.MethodOptions, allowed, false),
+			expectedCode:   http.StatusOK,
+			expectedCalled: true,
+			expectedHeaders: map[string]string{
+				httpbp.AccessControlAllowOriginHeader: allowed,
+			},
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			var called bool
+			resp, err := httpbp.TestInvoke(newEndpoint(c.args, &called), c.request)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if resp.Code != c.expectedCode {
+				t.Errorf("Expected code %d, got %d", c.expectedCode, resp.Code)
+			}
+			if called != c.expectedCalled {
+				t.Errorf("Expected handler called to be %v, got %v", c.expectedCalled, called)
+			}
+			for k, v := range c.expectedHeaders {
+				if got := resp.Header.Get(k); got != v {
+					t.Errorf("Expected header %q to be %q, got %q", k, v, got)
+				}
+			}
+		})
+	}
+}
+
+func TestCORSWildcardCredentials(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("Expected CORS to panic with wildcard origin and AllowCredentials")
+		}
+	}()
+	httpbp.CORS(httpbp.CORSArgs{
+		AllowedOrigins:   []string{"*"},
+		AllowCredentials: true,
+	})
+}
@@ -0,0 +1,117 @@
+package httpbp
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	// ContentEncodingHeader is the 'Content-Encoding' header key.
+	ContentEncodingHeader = "Content-Encoding"
+
+	// DefaultMaxDecompressedSize is the default MaxDecompressedSize used by
+	// DecompressRequest, 10MiB.
+	DefaultMaxDecompressedSize = 10 << 20
+)
+
+// ErrDecompressedBodyTooLarge is the error returned when reading a request
+// body decompressed by DecompressRequest and the decompressed size exceeds
+// the limit.
+var ErrDecompressedBodyTooLarge = errors.New("httpbp: decompressed request body too large")
+
+// DecompressRequestArgs are the args to be passed into DecompressRequest
+// function.
+type DecompressRequestArgs struct {
+	// MaxDecompressedSize is the max size of the decompressed request body, in
+	// bytes, to prevent decompression bombs.
+	//
+	// Optional, if it's <= 0, DefaultMaxDecompressedSize will be used instead.
+	MaxDecompressedSize int64
+}
+
+// DecompressRequest returns a Middleware that transparently decompresses gzip
+// encoded request bodies.
+//
+// When the request has "Content-Encoding: gzip" header, the request body is
+// replaced by a reader decompressing it, and the Content-Encoding and
+// Content-Length headers are removed.
+// If the body is not valid gzip, the request is rejected with BadRequest
+// response without calling the next handler.
+//
+// Reading the decompressed body beyond args.MaxDecompressedSize fails with
+// ErrDecompressedBodyTooLarge.
+// Requests with other or no Content-Encoding are passed through untouched.
+func DecompressRequest(args DecompressRequestArgs) Middleware {
+	limit := args.MaxDecompressedSize
+	if limit <= 0 {
+		limit = DefaultMaxDecompressedSize
+	}
+	return func(name string, next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if r.Body == nil || r.Body == http.NoBody || !isGzipEncoding(r.Header.Get(ContentEncodingHeader)) {
+				return next(ctx, w, r)
+			}
+
+			gr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				r.Body.Close()
+				return JSONError(BadRequest(), fmt.Errorf("httpbp.DecompressRequest: invalid gzip body: %w", err))
+			}
+			r.Body = &decompressedBody{
+				reader: gr,
+				body:   r.Body,
+				limit:  limit,
+			}
+			r.Header.Del(ContentEncodingHeader)
+			r.Header.Del("Content-Length")
+			r.ContentLength = -1
+			return next(ctx, w, r)
+		}
+	}
+}
+
+func isGzipEncoding(encoding string) bool {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "gzip", "x-gzip":
+		return true
+	}
+	return false
+}
+
+// decompressedBody is the request body replaced by DecompressRequest.
+type decompressedBody struct {
+	reader *gzip.Reader
+	body   io.ReadCloser
+	limit  int64
+	read   int64
+}
+
+func (b *decompressedBody) Read(p []byte) (int, error) {
+	if b.read >= b.limit {
+		// Check whether there's more data beyond the limit.
+		var buf [1]byte
+		n, err := b.reader.Read(buf[:])
+		if n > 0 {
+			return 0, ErrDecompressedBodyTooLarge
+		}
+		if err == nil {
+			err = io.EOF
+		}
+		return 0, err
+	}
+	if remaining := b.limit - b.read; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n, err := b.reader.Read(p)
+	b.read += int64(n)
+	return n, err
+}
+
+func (b *decompressedBody) Close() error {
+	return errors.Join(b.reader.Close(), b.body.Close())
+}
@@ -0,0 +1,125 @@
+package httpbp_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/reddit/baseplate.go/httpbp"
+)
+
+func gzipBytes(t *testing.T, data string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := io.WriteString(w, data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecompressRequest(t *testing.T) {
+	const limit = 10
+
+	var (
+		called   bool
+		body     string
+		readErr  error
+		encoding string
+	)
+	handle := httpbp.Wrap(
+		"test",
+		func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			called = true
+			encoding = r.Header.Get(httpbp.ContentEncodingHeader)
+			data, err := io.ReadAll(r.Body)
+			body = string(data)
+			readErr = err
+			return nil
+		},
+		httpbp.DecompressRequest(httpbp.DecompressRequestArgs{MaxDecompressedSize: limit}),
+	)
+
+	for _, c := range []struct {
+		label    string
+		encoding string
+		payload  []byte
+		body     string
+		readErr  error
+		rejected bool
+	}{
+		{
+			label:    "gzip",
+			encoding: "gzip",
+			payload:  gzipBytes(t, "hello"),
+			body:     "hello",
+		},
+		{
+			label:    "gzip-at-limit",
+			encoding: "GZIP",
+			payload:  gzipBytes(t, strings.Repeat("a", limit)),
+			body:     strings.Repeat("a", limit),
+		},
+		{
+			label:    "gzip-too-large",
+			encoding: "gzip",
+			payload:  gzipBytes(t, strings.Repeat("a", limit+1)),
+			body:     strings.Repeat("a", limit),
+			readErr:  httpbp.ErrDecompressedBodyTooLarge,
+		},
+		{
+			label:    "invalid-gzip",
+			encoding: "gzip",
+			payload:  []byte("hello"),
+			rejected: true,
+		},
+		{
+			label:   "no-encoding",
+			payload: []byte("hello"),
+			body:    "hello",
+		},
+		{
+			label:    "other-encoding",
+			encoding: "br",
+			payload:  []byte("hello"),
+			body:     "hello",
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			called = false
+			r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(c.payload))
+			if c.encoding != "" {
+				r.Header.Set(httpbp.ContentEncodingHeader, c.encoding)
+			}
+			err := handle(r.Context(), httptest.NewRecorder(), r)
+			if c.rejected {
+				checkJSONError(t, err, nil)
+				if called {
+					t.Error("Expected handler not to be called")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if body != c.body {
+				t.Errorf("Expected body %q, got %q", c.body, body)
+			}
+			if !errors.Is(readErr, c.readErr) {
+				t.Errorf("Expected read error %v, got %v", c.readErr, readErr)
+			}
+			if c.encoding == "gzip" && encoding != "" {
+				t.Errorf("Expected Content-Encoding header to be removed, got %q", encoding)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package httpbp
+
+import (
+	"context"
+	"net/http"
+)
+
+type endpointNameKeyType struct{}
+
+var endpointNameKey endpointNameKeyType
+
+// EndpointName returns the Name of the Endpoint handling the request.
+//
+// It's set on the context object by the handlers created by SetupEndpoints
+// (and NewBaseplateServer), before any of the middlewares and the HandlerFunc
+// of the Endpoint are called.
+// It returns empty string for requests not handled by a registered Endpoint.
+func EndpointName(ctx context.Context) string {
+	name, _ := ctx.Value(endpointNameKey).(string)
+	return name
+}
+
+// injectEndpointName is a Middleware that sets the endpoint name on the
+// context object, to be read by EndpointName.
+func injectEndpointName(name string, next HandlerFunc) HandlerFunc {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return next(context.WithValue(ctx, endpointNameKey, name), w, r)
+	}
+}
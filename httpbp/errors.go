@@ -1,6 +1,7 @@
 package httpbp
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"html/template"
@@ -10,7 +11,11 @@ import (
 	"strings"
 	"time"
 
+	"github.com/opentracing/opentracing-go"
+
 	"github.com/reddit/baseplate.go/errorsbp"
+	"github.com/reddit/baseplate.go/randbp"
+	"github.com/reddit/baseplate.go/tracing"
 )
 
 const (
@@ -177,6 +182,13 @@ type ErrorResponse struct {
 	// comfortable presenting to an end-user.
 	Details map[string]string `json:"details,omitempty"`
 
+	// Optional trace id of the request, for the callers to report back for
+	// debugging.
+	//
+	// It's omitted from the response when empty, and can be set using
+	// ErrorResponse.WithTraceID.
+	TraceID string `json:"trace_id,omitempty"`
+
 	code         int
 	raw          string
 	templateName string
@@ -203,6 +215,26 @@ func (r *ErrorResponse) WithDetails(details map[string]string) *ErrorResponse {
 	return r
 }
 
+// WithTraceID sets the TraceID on an ErrorResponse to the trace id of the
+// server span in ctx, so the callers can report it back to help finding the
+// trace of the failed request.
+//
+// It's no-op when there's no span in ctx. Endpoints that don't want to expose
+// their trace ids should just not call it.
+//
+// This returns an ErrorResponse so it can be chained in a call to `JSONError`:
+//
+//	return httpbp.JSONError(
+//		httpbp.InternalServerError().WithTraceID(ctx),
+//		err,
+//	)
+func (r *ErrorResponse) WithTraceID(ctx context.Context) *ErrorResponse {
+	if span, ok := opentracing.SpanFromContext(ctx).(*tracing.Span); ok && span != nil {
+		r.TraceID = span.TraceID()
+	}
+	return r
+}
+
 // WithRawResponse is used to set the respose to return when using a Raw content
 // writer.
 //
@@ -254,6 +286,22 @@ func (r *ErrorResponse) Retryable(w http.ResponseWriter, retryAfter time.Duratio
 	return r
 }
 
+// RetryableWithJitter is the same as Retryable, except that the Retry-After
+// header is set to a random duration in the range of base +/- jitter, with
+// jitter being a fraction of base (see randbp.JitterDuration).
+//
+// Jitter helps to avoid thundering-herd retries, when many clients rejected at
+// the same time (for example, by load shedding) would otherwise all retry at
+// the same time.
+//
+//	return httpbp.JSONError(
+//		httpbp.ServiceUnavailable().RetryableWithJitter(w, time.Second, 0.5),
+//		errors.New("overloaded"),
+//	)
+func (r *ErrorResponse) RetryableWithJitter(w http.ResponseWriter, base time.Duration, jitter float64) *ErrorResponse {
+	return r.Retryable(w, randbp.JitterDuration(base, jitter))
+}
+
 // String implements the fmt.Stringer interface which allows ErrorResponse to be
 // used as a Raw response.
 //
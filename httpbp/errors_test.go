@@ -1,6 +1,7 @@
 package httpbp_test
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -8,12 +9,14 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/reddit/baseplate.go/httpbp"
 	"github.com/reddit/baseplate.go/retrybp"
+	"github.com/reddit/baseplate.go/tracing"
 )
 
 func TestErrorResponse(t *testing.T) {
@@ -335,6 +338,47 @@ func TestWithRawResponse(t *testing.T) {
 	}
 }
 
+func TestWithTraceID(t *testing.T) {
+	t.Parallel()
+
+	decode := func(t *testing.T, resp *httpbp.ErrorResponse) map[string]interface{} {
+		t.Helper()
+
+		err := httpbp.JSONError(resp, nil)
+		w := httptest.NewRecorder()
+		httpbp.WriteResponse(w, err.ContentWriter(), err.Response())
+		var body struct {
+			Error map[string]interface{} `json:"error"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+			t.Fatalf("Failed to decode response body: %v", err)
+		}
+		return body.Error
+	}
+
+	t.Run("span", func(t *testing.T) {
+		ctx, span := tracing.StartTopLevelServerSpan(context.Background(), "test")
+		body := decode(t, httpbp.InternalServerError().WithTraceID(ctx))
+		if got, want := body["trace_id"], span.TraceID(); got != want {
+			t.Errorf("trace_id expected %q, got %v", want, got)
+		}
+	})
+
+	t.Run("no-span", func(t *testing.T) {
+		body := decode(t, httpbp.InternalServerError().WithTraceID(context.Background()))
+		if got, ok := body["trace_id"]; ok {
+			t.Errorf("Expected trace_id to be omitted, got %v", got)
+		}
+	})
+
+	t.Run("not-set", func(t *testing.T) {
+		body := decode(t, httpbp.InternalServerError())
+		if got, ok := body["trace_id"]; ok {
+			t.Errorf("Expected trace_id to be omitted, got %v", got)
+		}
+	})
+}
+
 func TestWithTemplateName(t *testing.T) {
 	t.Parallel()
 
@@ -443,6 +487,39 @@ func TestRetryable(t *testing.T) {
 	}
 }
 
+func TestRetryableWithJitter(t *testing.T) {
+	t.Parallel()
+
+	const (
+		base   = 10 * time.Second
+		jitter = 0.5
+	)
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		w := httptest.NewRecorder()
+		httpbp.ServiceUnavailable().RetryableWithJitter(w, base, jitter)
+
+		header := w.Header().Get(httpbp.RetryAfterHeader)
+		seconds, err := strconv.ParseFloat(header, 64)
+		if err != nil {
+			t.Fatalf("Failed to parse Retry-After header %q: %v", header, err)
+		}
+		if seconds <= 5 || seconds >= 15 {
+			t.Errorf("Expected Retry-After in (5, 15), got %v", seconds)
+		}
+		seen[header] = true
+	}
+	if len(seen) < 2 {
+		t.Errorf("Expected Retry-After to be randomized, got %v", seen)
+	}
+
+	w := httptest.NewRecorder()
+	httpbp.TooManyRequests().RetryableWithJitter(w, time.Second, 0)
+	if got := w.Header().Get(httpbp.RetryAfterHeader); got != "1" {
+		t.Errorf("Expected Retry-After %q without jitter, got %q", "1", got)
+	}
+}
+
 func TestClientError(t *testing.T) {
 	for _, c := range []struct {
 		label              string
@@ -0,0 +1,182 @@
+package httpbp
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+const (
+	// ETagHeader is the standard "ETag" header key.
+	ETagHeader = "ETag"
+
+	// IfNoneMatchHeader is the standard "If-None-Match" header key.
+	IfNoneMatchHeader = "If-None-Match"
+
+	// DefaultETagMaxBodySize is the default MaxBodySize used by ConditionalGET.
+	DefaultETagMaxBodySize = 1 << 20 // 1 MiB
+)
+
+// ConditionalGETArgs are the args to be passed into ConditionalGET function.
+type ConditionalGETArgs struct {
+	// MaxBodySize is the max size in bytes of a response body to be buffered to
+	// compute the ETag.
+	//
+	// Responses larger than it are written as-is without ETag.
+	//
+	// Optional, DefaultETagMaxBodySize will be used when it's <= 0.
+	MaxBodySize int
+}
+
+// ConditionalGET returns a Middleware that adds weak ETags to the successful
+// responses of GET and HEAD requests, and supports conditional requests via
+// If-None-Match header.
+//
+// The response body written by the next handler, e.g. via WriteJSON,
+// is buffered in memory, and the ETag is computed from the hash of the body.
+// When the If-None-Match header of the request matches the ETag,
+// a 304 Not Modified response with the ETag header and no body is written
+// instead.
+// Otherwise the buffered response is written with the ETag header.
+//
+// If the next handler already set ETag header, it will be used as-is.
+// Responses with status codes other than 200, larger than MaxBodySize,
+// flushed by the next handler, or from a next handler returning an error are
+// written as-is without ETag.
+//
+// As the 304 status code is written to the http.ResponseWriter passed into
+// this middleware, it should be put after the middlewares recording the status
+// codes, e.g. RecordStatusCode and PrometheusServerMetrics,
+// so they record 304 for the Not Modified responses.
+//
+// Requests of all other methods are passed to the next handler directly.
+func ConditionalGET(args ConditionalGETArgs) Middleware {
+	maxSize := args.MaxBodySize
+	if maxSize <= 0 {
+		maxSize = DefaultETagMaxBodySize
+	}
+	return func(name string, next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				return next(ctx, w, r)
+			}
+
+			rec := &etagRecorder{
+				ResponseWriter: w,
+				maxSize:        maxSize,
+			}
+			if err := next(ctx, rec, r); err != nil {
+				if rec.code != 0 || rec.body.Len() > 0 {
+					rec.passthrough()
+				}
+				return err
+			}
+			if rec.streaming {
+				return nil
+			}
+
+			code := rec.code
+			if code == 0 {
+				code = http.StatusOK
+			}
+			if code != http.StatusOK {
+				rec.passthrough()
+				return nil
+			}
+
+			etag := w.Header().Get(ETagHeader)
+			if etag == "" {
+				sum := sha256.Sum256(rec.body.Bytes())
+				etag = `W/"` + hex.EncodeToString(sum[:16]) + `"`
+				w.Header().Set(ETagHeader, etag)
+			}
+			if etagMatches(r.Header.Values(IfNoneMatchHeader), etag) {
+				w.Header().Del("Content-Length")
+				w.WriteHeader(http.StatusNotModified)
+				return nil
+			}
+			rec.passthrough()
+			return nil
+		}
+	}
+}
+
+// etagMatches checks the If-None-Match header values against etag using the
+// weak comparison.
+func etagMatches(ifNoneMatch []string, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, v := range ifNoneMatch {
+		for _, tag := range strings.Split(v, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// etagRecorder buffers the status code and the body written by the handler,
+// until it exceeds maxSize or is flushed, in which case it writes the buffered
+// response and streams the rest to the underlying http.ResponseWriter.
+type etagRecorder struct {
+	http.ResponseWriter
+
+	maxSize   int
+	code      int
+	body      bytes.Buffer
+	streaming bool
+}
+
+func (r *etagRecorder) WriteHeader(code int) {
+	if r.streaming {
+		r.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if r.code == 0 {
+		r.code = code
+	}
+}
+
+func (r *etagRecorder) Write(b []byte) (int, error) {
+	if !r.streaming && r.body.Len()+len(b) > r.maxSize {
+		if err := r.passthrough(); err != nil {
+			return 0, err
+		}
+	}
+	if r.streaming {
+		return r.ResponseWriter.Write(b)
+	}
+	return r.body.Write(b)
+}
+
+// Flush implements http.Flusher.
+//
+// Flushing gives up the ETag and writes the buffered response.
+func (r *etagRecorder) Flush() {
+	r.passthrough()
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// passthrough writes the buffered response to the underlying
+// http.ResponseWriter, and switches to streaming mode.
+func (r *etagRecorder) passthrough() error {
+	if r.streaming {
+		return nil
+	}
+	r.streaming = true
+	if r.code != 0 {
+		r.ResponseWriter.WriteHeader(r.code)
+	}
+	if r.body.Len() == 0 {
+		return nil
+	}
+	_, err := r.ResponseWriter.Write(r.body.Bytes())
+	r.body = bytes.Buffer{}
+	return err
+}
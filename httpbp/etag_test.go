@@ -0,0 +1,176 @@
+package httpbp_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/reddit/baseplate.go/httpbp"
+)
+
+func TestConditionalGET(t *testing.T) {
+	t.Parallel()
+
+	body := map[string]string{"foo": "bar"}
+	writeJSON := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return httpbp.WriteJSON(w, httpbp.NewResponse(body))
+	}
+
+	// Get the ETag of the full response first.
+	handle := httpbp.Wrap("test", writeJSON, httpbp.ConditionalGET(httpbp.ConditionalGETArgs{}))
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if err := handle(r.Context(), w, r); err != nil {
+		t.Fatal(err)
+	}
+	etag := w.Header().Get(httpbp.ETagHeader)
+	if !strings.HasPrefix(etag, `W/"`) {
+		t.Fatalf("Expected weak ETag, got %q", etag)
+	}
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected code %d, got %d", http.StatusOK, w.Code)
+	}
+	if got, want := w.Body.String(), "{\"foo\":\"bar\"}\n"; got != want {
+		t.Errorf("Expected body %q, got %q", want, got)
+	}
+	if got := w.Header().Get(httpbp.ContentTypeHeader); got != httpbp.JSONContentType {
+		t.Errorf("Expected Content-Type %q, got %q", httpbp.JSONContentType, got)
+	}
+
+	for _, c := range []struct {
+		label        string
+		method       string
+		ifNoneMatch  string
+		maxBodySize  int
+		handler      httpbp.HandlerFunc
+		expectedCode int
+		expectedErr  bool
+		expectETag   bool
+		expectBody   bool
+	}{
+		{
+			label:        "match",
+			method:       http.MethodGet,
+			ifNoneMatch:  etag,
+			handler:      writeJSON,
+			expectedCode: http.StatusNotModified,
+			expectETag:   true,
+		},
+		{
+			label:        "match-strong",
+			method:       http.MethodGet,
+			ifNoneMatch:  `"foo", ` + strings.TrimPrefix(etag, "W/"),
+			handler:      writeJSON,
+			expectedCode: http.StatusNotModified,
+			expectETag:   true,
+		},
+		{
+			label:        "match-any",
+			method:       http.MethodHead,
+			ifNoneMatch:  "*",
+			handler:      writeJSON,
+			expectedCode: http.StatusNotModified,
+			expectETag:   true,
+		},
+		{
+			label:        "mismatch",
+			method:       http.MethodGet,
+			ifNoneMatch:  `W/"foo"`,
+			handler:      writeJSON,
+			expectedCode: http.StatusOK,
+			expectETag:   true,
+			expectBody:   true,
+		},
+		{
+			label:        "post",
+			method:       http.MethodPost,
+			ifNoneMatch:  etag,
+			handler:      writeJSON,
+			expectedCode: http.StatusOK,
+			expectBody:   true,
+		},
+		{
+			label:        "too-large",
+			method:       http.MethodGet,
+			ifNoneMatch:  etag,
+			maxBodySize:  5,
+			handler:      writeJSON,
+			expectedCode: http.StatusOK,
+			expectBody:   true,
+		},
+		{
+			label:       "non-200",
+			method:      http.MethodGet,
+			ifNoneMatch: etag,
+			handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				return httpbp.WriteJSON(w, httpbp.NewResponse(body).WithCode(http.StatusAccepted))
+			},
+			expectedCode: http.StatusAccepted,
+			expectBody:   true,
+		},
+		{
+			label:       "error",
+			method:      http.MethodGet,
+			ifNoneMatch: etag,
+			handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				return errors.New("foo")
+			},
+			expectedCode: http.StatusOK,
+			expectedErr:  true,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			handle := httpbp.Wrap("test", c.handler, httpbp.ConditionalGET(httpbp.ConditionalGETArgs{
+				MaxBodySize: c.maxBodySize,
+			}))
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(c.method, "/", nil)
+			r.Header.Set(httpbp.IfNoneMatchHeader, c.ifNoneMatch)
+			err := handle(r.Context(), w, r)
+			if (err != nil) != c.expectedErr {
+				t.Errorf("Expected error %v, got %v", c.expectedErr, err)
+			}
+			if w.Code != c.expectedCode {
+				t.Errorf("Expected code %d, got %d", c.expectedCode, w.Code)
+			}
+			if got := w.Header().Get(httpbp.ETagHeader); (got == etag) != c.expectETag {
+				t.Errorf("Expected ETag %v, got %q", c.expectETag, got)
+			}
+			if got := w.Body.Len() > 0; got != c.expectBody {
+				t.Errorf("Expected body %v, got %q", c.expectBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestConditionalGETExistingETag(t *testing.T) {
+	t.Parallel()
+
+	const etag = `"v1"`
+	handle := httpbp.Wrap(
+		"test",
+		func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			w.Header().Set(httpbp.ETagHeader, etag)
+			return httpbp.WriteRawContent(w, httpbp.NewResponse("foo"), httpbp.PlainTextContentType)
+		},
+		httpbp.ConditionalGET(httpbp.ConditionalGETArgs{}),
+	)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(httpbp.IfNoneMatchHeader, etag)
+	if err := handle(r.Context(), w, r); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusNotModified {
+		t.Errorf("Expected code %d, got %d", http.StatusNotModified, w.Code)
+	}
+	if got := w.Header().Get(httpbp.ETagHeader); got != etag {
+		t.Errorf("Expected ETag %q, got %q", etag, got)
+	}
+	if w.Body.Len() > 0 {
+		t.Errorf("Expected no body, got %q", w.Body.String())
+	}
+}
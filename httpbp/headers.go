@@ -44,6 +44,10 @@ const (
 	// TraceIDHeader is the key use to get the trace ID from the HTTP
 	// request headers.
 	TraceIDHeader = "X-Trace"
+
+	// DeadlineBudgetHeader is the key use to get the deadline budget, in
+	// milliseconds, from the HTTP request headers.
+	DeadlineBudgetHeader = "X-Deadline-Budget"
 )
 
 // Headers is an interface to collect all of the HTTP headers for a particular
@@ -205,15 +209,25 @@ func (h AlwaysTrustHeaders) TrustSpan(r *http.Request) bool {
 // internet where you would not trust these headers but is also used internally
 // where you want to accept these headers.
 type TrustHeaderSignature struct {
-	secrets               *secrets.Store
+	secrets               SecretsStore
 	edgeContextSecretPath string
 	spanSecretPath        string
 }
 
+// SecretsStore is the minimal interface of the secrets store needed to sign and
+// verify the headers.
+//
+// *secrets.Store implements this interface.
+type SecretsStore interface {
+	GetVersionedSecret(path string) (secrets.VersionedSecret, error)
+}
+
+var _ SecretsStore = (*secrets.Store)(nil)
+
 // TrustHeaderSignatureArgs is used as input to create a new
 // TrustHeaderSignature.
 type TrustHeaderSignatureArgs struct {
-	SecretsStore          *secrets.Store
+	SecretsStore          SecretsStore
 	EdgeContextSecretPath string
 	SpanSecretPath        string
 }
@@ -36,7 +36,7 @@ func getHeaders() http.Header {
 	return headers
 }
 
-func getTrustHeaderSignature(secretsStore *secrets.Store) httpbp.TrustHeaderSignature {
+func getTrustHeaderSignature(secretsStore httpbp.SecretsStore) httpbp.TrustHeaderSignature {
 	return httpbp.NewTrustHeaderSignature(httpbp.TrustHeaderSignatureArgs{
 		SecretsStore:          secretsStore,
 		EdgeContextSecretPath: "secret/http/edge-context-signature",
@@ -430,3 +430,37 @@ func TestTrustHeaderSignature(t *testing.T) {
 		)
 	}
 }
+
+type fakeSecretsStore map[string]secrets.VersionedSecret
+
+func (s fakeSecretsStore) GetVersionedSecret(path string) (secrets.VersionedSecret, error) {
+	secret, ok := s[path]
+	if !ok {
+		return secrets.VersionedSecret{}, secrets.SecretNotFoundError(path)
+	}
+	return secret, nil
+}
+
+func TestTrustHeaderSignatureSecretsStore(t *testing.T) {
+	truster := getTrustHeaderSignature(fakeSecretsStore{
+		"secret/http/span-signature": {
+			Current: secrets.Secret("hunter2"),
+		},
+	})
+	spanHeaders := httpbp.NewSpanHeaders(getHeaders())
+	signature, err := truster.SignSpanHeaders(spanHeaders, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok, err := truster.VerifySpanHeaders(spanHeaders, signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Error("Expected signature to be verified")
+	}
+
+	if _, err := truster.SignEdgeContextHeader(httpbp.EdgeContextHeaders{EdgeRequest: "foo"}, time.Minute); err == nil {
+		t.Error("Expected error for missing edge context secret")
+	}
+}
@@ -0,0 +1,205 @@
+package httpbp
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultMaxInflightRequests is the default max number of requests tracked by
+// an InflightRegistry.
+const DefaultMaxInflightRequests = 1000
+
+// InflightRequest is the details of an in-flight request tracked by
+// TrackInflight.
+type InflightRequest struct {
+	// The name of the endpoint.
+	Endpoint string `json:"endpoint"`
+
+	Method string `json:"method"`
+
+	// The path of the request, without the query string.
+	//
+	// Only set when TrackInflightArgs.RecordPath is true.
+	Path string `json:"path,omitempty"`
+
+	// The user of the request returned by TrackInflightArgs.User.
+	User string `json:"user,omitempty"`
+
+	Start time.Time `json:"start"`
+
+	// How long the request has been in-flight, in the format of
+	// time.Duration.String.
+	Age string `json:"age"`
+}
+
+// InflightRegistry is the registry of in-flight requests tracked by
+// TrackInflight.
+//
+// It only keeps the requests while they are in-flight,
+// up to a max number of requests to cap the memory usage.
+//
+// Use NewInflightRegistry to create one, or use DefaultInflightRegistry.
+type InflightRegistry struct {
+	maxRequests int
+
+	untracked atomic.Int64
+
+	lock     sync.Mutex
+	nextID   uint64
+	requests map[uint64]*InflightRequest
+}
+
+// DefaultInflightRegistry is the InflightRegistry used by TrackInflight and
+// InflightHandler when no registry is given.
+//
+// ServeAdmin serves it at /debug/inflight.
+var DefaultInflightRegistry = NewInflightRegistry(0)
+
+// NewInflightRegistry creates a new InflightRegistry tracking at most
+// maxRequests requests at the same time.
+//
+// When maxRequests <= 0, DefaultMaxInflightRequests will be used.
+// Requests started when the registry is full are not tracked,
+// they are only reported as a count by InflightHandler.
+func NewInflightRegistry(maxRequests int) *InflightRegistry {
+	if maxRequests <= 0 {
+		maxRequests = DefaultMaxInflightRequests
+	}
+	return &InflightRegistry{
+		maxRequests: maxRequests,
+		requests:    make(map[uint64]*InflightRequest),
+	}
+}
+
+// add adds req into the registry and returns the function to remove it.
+func (r *InflightRegistry) add(req *InflightRequest) (remove func()) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if len(r.requests) >= r.maxRequests {
+		r.untracked.Add(1)
+		return func() {
+			r.untracked.Add(-1)
+		}
+	}
+	r.nextID++
+	id := r.nextID
+	r.requests[id] = req
+	return func() {
+		r.lock.Lock()
+		defer r.lock.Unlock()
+		delete(r.requests, id)
+	}
+}
+
+// Snapshot returns the currently tracked in-flight requests,
+// with the oldest ones first.
+func (r *InflightRegistry) Snapshot() []InflightRequest {
+	now := time.Now()
+	r.lock.Lock()
+	requests := make([]InflightRequest, 0, len(r.requests))
+	for _, req := range r.requests {
+		requests = append(requests, *req)
+	}
+	r.lock.Unlock()
+
+	sort.Slice(requests, func(i, j int) bool {
+		return requests[i].Start.Before(requests[j].Start)
+	})
+	for i := range requests {
+		requests[i].Age = now.Sub(requests[i].Start).String()
+	}
+	return requests
+}
+
+// Untracked returns the number of the current in-flight requests that are not
+// tracked because the registry was full when they started.
+func (r *InflightRegistry) Untracked() int64 {
+	return r.untracked.Load()
+}
+
+// TrackInflightArgs are the args to be passed into TrackInflight function.
+type TrackInflightArgs struct {
+	// The registry to track the requests.
+	//
+	// Optional. Default to DefaultInflightRegistry.
+	Registry *InflightRegistry
+
+	// RecordPath controls whether the path of the request is recorded.
+	//
+	// The paths could contain sensitive information (e.g. user names or
+	// tokens), so by default only the name of the endpoint is recorded.
+	// The query string is never recorded.
+	RecordPath bool
+
+	// User returns the user of the request to be recorded,
+	// e.g. the user id from the edge request context.
+	//
+	// It's called after the middlewares before TrackInflight,
+	// so TrackInflight should be put after the middleware setting up the user.
+	//
+	// Optional. When it's nil no user is recorded.
+	User func(ctx context.Context, r *http.Request) string
+}
+
+// TrackInflight returns a Middleware that registers every request into an
+// InflightRegistry while it's being handled,
+// so the in-flight requests can be inspected via InflightHandler.
+//
+// It's a lightweight way to find stuck handlers,
+// the details of the requests are never logged.
+func TrackInflight(args TrackInflightArgs) Middleware {
+	registry := args.Registry
+	if registry == nil {
+		registry = DefaultInflightRegistry
+	}
+	return func(name string, next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			req := &InflightRequest{
+				Endpoint: name,
+				Method:   r.Method,
+				Start:    time.Now(),
+			}
+			if args.RecordPath && r.URL != nil {
+				req.Path = r.URL.Path
+			}
+			if args.User != nil {
+				req.User = args.User(ctx, r)
+			}
+			defer registry.add(req)()
+			return next(ctx, w, r)
+		}
+	}
+}
+
+// InflightResponse is the response body written by InflightHandler.
+type InflightResponse struct {
+	// The tracked in-flight requests, with the oldest ones first.
+	Requests []InflightRequest `json:"requests"`
+
+	// The number of the in-flight requests not tracked because the registry was
+	// full.
+	Untracked int64 `json:"untracked"`
+}
+
+// InflightHandler returns a HandlerFunc that writes the in-flight requests in
+// registry as InflightResponse in JSON.
+//
+// When registry is nil, DefaultInflightRegistry will be used.
+func InflightHandler(registry *InflightRegistry) HandlerFunc {
+	if registry == nil {
+		registry = DefaultInflightRegistry
+	}
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return WriteJSON(w, Response{
+			Body: InflightResponse{
+				Requests:  registry.Snapshot(),
+				Untracked: registry.Untracked(),
+			},
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package httpbp_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/reddit/baseplate.go/httpbp"
+)
+
+func TestTrackInflight(t *testing.T) {
+	t.Parallel()
+
+	registry := httpbp.NewInflightRegistry(1)
+	block := make(chan struct{})
+	started := make(chan struct{})
+	handle := httpbp.Wrap(
+		"test",
+		func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			started <- struct{}{}
+			<-block
+			return nil
+		},
+		httpbp.TrackInflight(httpbp.TrackInflightArgs{
+			Registry: registry,
+			User: func(ctx context.Context, r *http.Request) string {
+				return "user"
+			},
+		}),
+	)
+
+	getInflight := func(t *testing.T) httpbp.InflightResponse {
+		t.Helper()
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/debug/inflight", nil)
+		if err := httpbp.InflightHandler(registry)(r.Context(), w, r); err != nil {
+			t.Fatal(err)
+		}
+		var resp httpbp.InflightResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Failed to decode response %q: %v", w.Body.String(), err)
+		}
+		return resp
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := httptest.NewRequest(http.MethodPost, "/secret/path?token=foo", nil)
+			handle(r.Context(), httptest.NewRecorder(), r)
+		}()
+		<-started
+	}
+
+	resp := getInflight(t)
+	if len(resp.Requests) != 1 {
+		t.Fatalf("Expected 1 tracked request, got %+v", resp.Requests)
+	}
+	req := resp.Requests[0]
+	if req.Endpoint != "test" || req.Method != http.MethodPost || req.User != "user" {
+		t.Errorf("Unexpected request details: %+v", req)
+	}
+	if req.Path != "" {
+		t.Errorf("Expected path not recorded, got %q", req.Path)
+	}
+	if req.Age == "" {
+		t.Error("Expected age to be set")
+	}
+	if resp.Untracked != 1 {
+		t.Errorf("Expected 1 untracked request, got %d", resp.Untracked)
+	}
+
+	close(block)
+	wg.Wait()
+
+	resp = getInflight(t)
+	if len(resp.Requests) != 0 || resp.Untracked != 0 {
+		t.Errorf("Expected no in-flight requests after completion, got %+v", resp)
+	}
+}
@@ -0,0 +1,45 @@
+package httpbp_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/reddit/baseplate.go/httpbp"
+)
+
+var errUserNotFound = errors.New("user not found")
+
+// This example demonstrates how TestInvoke can be used to unit test an
+// Endpoint, for both the success and the error paths.
+func ExampleTestInvoke() {
+	endpoint := httpbp.Endpoint{
+		Name:    "user",
+		Methods: []string{http.MethodGet},
+		Handle: func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if r.URL.Query().Get("id") != "1" {
+				return httpbp.JSONError(httpbp.NotFound(), errUserNotFound)
+			}
+			return httpbp.WriteJSON(w, httpbp.Response{
+				Body: map[string]string{"name": "foo"},
+			})
+		},
+	}
+
+	// In real tests these should be t.Error/t.Fatal calls instead.
+	resp, err := httpbp.TestInvoke(endpoint, httptest.NewRequest(http.MethodGet, "/user?id=1", nil))
+	fmt.Println(resp.Code, err)
+	fmt.Print(string(resp.Body))
+
+	resp, err = httpbp.TestInvoke(endpoint, httptest.NewRequest(http.MethodGet, "/user?id=2", nil))
+	fmt.Println(resp.Code, errors.Is(err, errUserNotFound))
+	fmt.Print(string(resp.Body))
+
+	// Output:
+	// 200 <nil>
+	// {"name":"foo"}
+	// 404 true
+	// {"success":false,"error":{"reason":"NOT_FOUND","explanation":"The requested resource could not be found."}}
+}
@@ -0,0 +1,259 @@
+package httpbp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// JSON decode guard errors, wrapped in the HTTPError returned by DecodeJSON and
+// LimitJSON when the payload violates the JSONLimits.
+var (
+	ErrJSONTooLarge      = errors.New("httpbp: json payload too large")
+	ErrJSONTooDeep       = errors.New("httpbp: json payload nested too deep")
+	ErrJSONTooManyTokens = errors.New("httpbp: json payload has too many tokens")
+)
+
+// JSONLimits are the limits enforced on the JSON payloads by DecodeJSON and
+// LimitJSON before fully decoding them.
+//
+// For all the limits, <= 0 means no limit.
+type JSONLimits struct {
+	// MaxBytes is the maximum size of the payload, in bytes.
+	MaxBytes int64 `yaml:"maxBytes"`
+
+	// MaxDepth is the maximum nesting depth of objects and arrays.
+	MaxDepth int `yaml:"maxDepth"`
+
+	// MaxTokens is the maximum total number of tokens in the payload,
+	// including delimiters, keys and values.
+	MaxTokens int `yaml:"maxTokens"`
+}
+
+// readAll reads all of r, while enforcing MaxBytes.
+func (l JSONLimits) readAll(r io.Reader) ([]byte, error) {
+	if l.MaxBytes <= 0 {
+		return io.ReadAll(r)
+	}
+	data, err := io.ReadAll(io.LimitReader(r, l.MaxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > l.MaxBytes {
+		return nil, fmt.Errorf("%w: exceeded %d bytes", ErrJSONTooLarge, l.MaxBytes)
+	}
+	return data, nil
+}
+
+// validate walks through the tokens of data without decoding it,
+// and returns an error if it violates MaxDepth or MaxTokens.
+func (l JSONLimits) validate(data []byte) error {
+	if l.MaxDepth <= 0 && l.MaxTokens <= 0 {
+		return nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var depth, tokens int
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		tokens++
+		if l.MaxTokens > 0 && tokens > l.MaxTokens {
+			return fmt.Errorf("%w: exceeded %d tokens", ErrJSONTooManyTokens, l.MaxTokens)
+		}
+		if delim, ok := token.(json.Delim); ok {
+			switch delim {
+			case '{', '[':
+				depth++
+				if l.MaxDepth > 0 && depth > l.MaxDepth {
+					return fmt.Errorf("%w: exceeded depth of %d", ErrJSONTooDeep, l.MaxDepth)
+				}
+			case '}', ']':
+				depth--
+			}
+		}
+	}
+}
+
+// DecodeJSON reads the JSON payload from r and decodes it into v.
+//
+// The payload is checked against limits before being decoded into v,
+// so a payload violating the limits is rejected without allocating the full
+// decoded value.
+//
+// If the payload violates limits or is not valid JSON, the error returned is an
+// HTTPError that can be returned by a HandlerFunc directly,
+// with PayloadTooLarge response when it exceeds limits.MaxBytes,
+// and BadRequest response otherwise.
+func DecodeJSON(r io.Reader, v interface{}, limits JSONLimits) error {
+	return decodeJSON(r, v, limits, false)
+}
+
+// DecodeJSONRequestArgs are the args to be passed into DecodeJSONRequest
+// function.
+type DecodeJSONRequestArgs struct {
+	// Limits are the limits enforced on the request body.
+	//
+	// Setting at least Limits.MaxBytes is strongly recommended for requests
+	// from untrusted clients.
+	Limits JSONLimits
+
+	// DisallowUnknownFields, when set to true, rejects the request bodies
+	// containing object keys that don't match any non-ignored, exported
+	// fields in v.
+	DisallowUnknownFields bool
+}
+
+// DecodeJSONRequest reads the body of r and decodes it as JSON into v.
+//
+// On failure, it returns an HTTPError with BadRequest response
+// (or PayloadTooLarge response when the body exceeds Limits.MaxBytes) that can
+// be returned by a HandlerFunc directly.
+// When possible, the Details of the ErrorResponse points to the offending
+// field, for example:
+//
+//	{"foo": "unknown field"}
+//	{"foo.bar": "expected int, got string"}
+func DecodeJSONRequest(r *http.Request, v interface{}, args DecodeJSONRequestArgs) error {
+	if r.Body == nil {
+		return decodeJSON(http.NoBody, v, args.Limits, args.DisallowUnknownFields)
+	}
+	return decodeJSON(r.Body, v, args.Limits, args.DisallowUnknownFields)
+}
+
+func decodeJSON(r io.Reader, v interface{}, limits JSONLimits, strict bool) error {
+	data, err := limits.readAll(r)
+	if err != nil {
+		return jsonLimitsError(err)
+	}
+	if err := limits.validate(data); err != nil {
+		return jsonLimitsError(err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if strict {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(v); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return JSONError(BadRequest().WithDetails(jsonErrorDetails(err)), err)
+	}
+	if len(bytes.TrimSpace(data[decoder.InputOffset():])) > 0 {
+		return JSONError(
+			BadRequest().WithDetails(map[string]string{
+				jsonBodyDetailsKey: "unexpected data after the json value",
+			}),
+			errors.New("httpbp: unexpected data after the json value"),
+		)
+	}
+	return nil
+}
+
+// jsonBodyDetailsKey is the key used in ErrorResponse.Details for JSON decoding
+// errors that can't be pointed at a specific field.
+const jsonBodyDetailsKey = "body"
+
+// jsonErrorDetails returns the ErrorResponse.Details for the JSON decoding
+// error, pointing at the offending field when possible.
+func jsonErrorDetails(err error) map[string]string {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return map[string]string{
+			typeErr.Field: fmt.Sprintf("expected %v, got %s", typeErr.Type, typeErr.Value),
+		}
+	}
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return map[string]string{
+			jsonBodyDetailsKey: fmt.Sprintf("malformed json at offset %d", syntaxErr.Offset),
+		}
+	}
+	// encoding/json doesn't have a typed error for unknown fields.
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		if unquoted, err := strconv.Unquote(field); err == nil {
+			field = unquoted
+		}
+		return map[string]string{
+			field: "unknown field",
+		}
+	}
+	return map[string]string{
+		jsonBodyDetailsKey: "malformed json",
+	}
+}
+
+// LimitJSON returns a Middleware that checks the bodies of the requests
+// against limits, before calling the next handler.
+//
+// limits.MaxBytes is enforced on all the requests regardless of their
+// Content-Type, as the Content-Type is controlled by the clients.
+// The other limits are only checked for the requests with a JSON Content-Type.
+//
+// Requests exceeding limits.MaxBytes are rejected with PayloadTooLarge
+// response, requests violating the other limits are rejected with BadRequest
+// response.
+// The bodies of the requests passed the check are buffered in memory and
+// can be read again by the next handler.
+func LimitJSON(limits JSONLimits) Middleware {
+	return func(name string, next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if r.Body == nil || r.Body == http.NoBody {
+				return next(ctx, w, r)
+			}
+			isJSON := isJSONContentType(r.Header.Get(ContentTypeHeader))
+			if !isJSON && limits.MaxBytes <= 0 {
+				return next(ctx, w, r)
+			}
+			if limits.MaxBytes > 0 && r.ContentLength > limits.MaxBytes {
+				r.Body.Close()
+				return jsonLimitsError(fmt.Errorf("%w: exceeded %d bytes", ErrJSONTooLarge, limits.MaxBytes))
+			}
+
+			data, err := limits.readAll(r.Body)
+			r.Body.Close()
+			if err != nil {
+				return jsonLimitsError(err)
+			}
+			if isJSON {
+				if err := limits.validate(data); err != nil {
+					return jsonLimitsError(err)
+				}
+			}
+			r.Body = io.NopCloser(bytes.NewReader(data))
+			return next(ctx, w, r)
+		}
+	}
+}
+
+// jsonLimitsError wraps err returned by JSONLimits into an HTTPError,
+// with PayloadTooLarge response for ErrJSONTooLarge and BadRequest response
+// otherwise.
+func jsonLimitsError(err error) error {
+	if errors.Is(err, ErrJSONTooLarge) {
+		return JSONError(PayloadTooLarge(), err)
+	}
+	return JSONError(BadRequest(), err)
+}
+
+// isJSONContentType returns true if contentType is application/json or has the
+// "+json" structured syntax suffix.
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
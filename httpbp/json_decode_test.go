@@ -0,0 +1,247 @@
+package httpbp_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/reddit/baseplate.go/httpbp"
+)
+
+func nestedJSON(depth int) string {
+	return strings.Repeat(`{"a":`, depth) + "1" + strings.Repeat("}", depth)
+}
+
+func checkJSONError(t *testing.T, err error, expected error) {
+	t.Helper()
+	var httpErr httpbp.HTTPError
+	if !errors.As(err, &httpErr) {
+		t.Fatalf("Expected HTTPError, got %v", err)
+	}
+	want := http.StatusBadRequest
+	if errors.Is(expected, httpbp.ErrJSONTooLarge) {
+		want = http.StatusRequestEntityTooLarge
+	}
+	if code := httpErr.Response().Code; code != want {
+		t.Errorf("Expected code %d, got %d", want, code)
+	}
+	if expected != nil && !errors.Is(err, expected) {
+		t.Errorf("Expected error to wrap %v, got %v", expected, err)
+	}
+}
+
+func TestDecodeJSON(t *testing.T) {
+	limits := httpbp.JSONLimits{
+		MaxBytes:  1024,
+		MaxDepth:  5,
+		MaxTokens: 50,
+	}
+
+	t.Run("normal", func(t *testing.T) {
+		var v struct {
+			Foo string `json:"foo"`
+			Bar []int  `json:"bar"`
+		}
+		err := httpbp.DecodeJSON(strings.NewReader(`{"foo":"foo","bar":[1,2,3]}`), &v, limits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v.Foo != "foo" || len(v.Bar) != 3 {
+			t.Errorf("Unexpected decoded value: %+v", v)
+		}
+	})
+
+	for _, c := range []struct {
+		label    string
+		payload  string
+		expected error
+	}{
+		{
+			label:    "too-deep",
+			payload:  nestedJSON(10),
+			expected: httpbp.ErrJSONTooDeep,
+		},
+		{
+			label:    "too-many-tokens",
+			payload:  "[" + strings.TrimSuffix(strings.Repeat("1,", 100), ",") + "]",
+			expected: httpbp.ErrJSONTooManyTokens,
+		},
+		{
+			label:    "too-large",
+			payload:  `"` + strings.Repeat("a", 2048) + `"`,
+			expected: httpbp.ErrJSONTooLarge,
+		},
+		{
+			label:   "malformed",
+			payload: `{"foo":`,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			var v interface{}
+			err := httpbp.DecodeJSON(strings.NewReader(c.payload), &v, limits)
+			checkJSONError(t, err, c.expected)
+			if v != nil {
+				t.Errorf("Expected nothing to be decoded, got %v", v)
+			}
+		})
+	}
+}
+
+func TestLimitJSON(t *testing.T) {
+	const normal = `{"foo":{"bar":[1,2,3]}}`
+
+	var body string
+	handle := httpbp.Wrap(
+		"test",
+		func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			data, err := io.ReadAll(r.Body)
+			body = string(data)
+			return err
+		},
+		httpbp.LimitJSON(httpbp.JSONLimits{MaxBytes: 2048, MaxDepth: 5}),
+	)
+
+	for _, c := range []struct {
+		label       string
+		contentType string
+		payload     string
+		rejected    error
+	}{
+		{
+			label:       "normal",
+			contentType: "application/json",
+			payload:     normal,
+		},
+		{
+			label:       "deeply-nested",
+			contentType: "application/json; charset=utf-8",
+			payload:     nestedJSON(100),
+			rejected:    httpbp.ErrJSONTooDeep,
+		},
+		{
+			label:       "deeply-nested-suffix",
+			contentType: "application/problem+json",
+			payload:     nestedJSON(100),
+			rejected:    httpbp.ErrJSONTooDeep,
+		},
+		{
+			label:       "not-json",
+			contentType: "text/plain",
+			payload:     nestedJSON(100),
+		},
+		{
+			label:       "too-large",
+			contentType: "application/json",
+			payload:     nestedJSON(2000),
+			rejected:    httpbp.ErrJSONTooLarge,
+		},
+		{
+			label:       "not-json-too-large",
+			contentType: "text/plain",
+			payload:     nestedJSON(2000),
+			rejected:    httpbp.ErrJSONTooLarge,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			body = ""
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(c.payload))
+			r.Header.Set(httpbp.ContentTypeHeader, c.contentType)
+			err := handle(r.Context(), httptest.NewRecorder(), r)
+			if c.rejected != nil {
+				checkJSONError(t, err, c.rejected)
+				if body != "" {
+					t.Errorf("Expected handler not to be called, got body %q", body)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if body != c.payload {
+				t.Errorf("Expected handler to read body %q, got %q", c.payload, body)
+			}
+		})
+	}
+}
+
+func TestDecodeJSONRequest(t *testing.T) {
+	type payload struct {
+		Foo string `json:"foo"`
+		Bar struct {
+			Baz int `json:"baz"`
+		} `json:"bar"`
+	}
+
+	for _, c := range []struct {
+		label   string
+		body    string
+		strict  bool
+		details map[string]string
+	}{
+		{
+			label: "normal",
+			body:  `{"foo":"foo","bar":{"baz":1}}`,
+		},
+		{
+			label: "unknown-field-lenient",
+			body:  `{"foo":"foo","qux":1}`,
+		},
+		{
+			label:   "unknown-field-strict",
+			body:    `{"foo":"foo","qux":1}`,
+			strict:  true,
+			details: map[string]string{"qux": "unknown field"},
+		},
+		{
+			label:   "wrong-type",
+			body:    `{"bar":{"baz":"1"}}`,
+			details: map[string]string{"bar.baz": "expected int, got string"},
+		},
+		{
+			label:   "malformed",
+			body:    `{"foo":}`,
+			details: map[string]string{"body": "malformed json at offset 8"},
+		},
+		{
+			label:   "empty",
+			body:    ``,
+			details: map[string]string{"body": "malformed json"},
+		},
+		{
+			label:   "trailing-data",
+			body:    `{"foo":"foo"} {}`,
+			details: map[string]string{"body": "unexpected data after the json value"},
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(c.body))
+			var v payload
+			err := httpbp.DecodeJSONRequest(r, &v, httpbp.DecodeJSONRequestArgs{
+				Limits:                httpbp.JSONLimits{MaxBytes: 1024},
+				DisallowUnknownFields: c.strict,
+			})
+			if c.details == nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if v.Foo != "foo" {
+					t.Errorf("Unexpected decoded value: %+v", v)
+				}
+				return
+			}
+			checkJSONError(t, err, nil)
+			var httpErr httpbp.HTTPError
+			errors.As(err, &httpErr)
+			details := httpErr.Response().Body.(httpbp.ErrorResponseJSONWrapper).Error.Details
+			for k, v := range c.details {
+				if details[k] != v {
+					t.Errorf("Expected details[%q] to be %q, got %v", k, v, details)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package httpbp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// PriorityHeader is the default header used by LoadShed to read the
+	// priority of a request.
+	PriorityHeader = "X-Request-Priority"
+
+	// DefaultHighPriority is the default LoadShedConfig.HighPriority.
+	DefaultHighPriority = 1
+)
+
+// LoadShedConfig is the configuration for LoadShed middleware.
+type LoadShedConfig struct {
+	// MaxInFlight is the number of in-flight requests, across all the endpoints
+	// wrapped by the same LoadShed middleware, above which low priority requests
+	// will be shed.
+	//
+	// Required. When MaxInFlight <= 0 no requests will be shed.
+	MaxInFlight int64 `yaml:"maxInFlight"`
+
+	// HardMaxInFlight is the number of in-flight requests above which all
+	// requests, including the high priority ones, will be shed.
+	//
+	// Optional. When HardMaxInFlight <= 0 high priority requests are never shed.
+	HardMaxInFlight int64 `yaml:"hardMaxInFlight"`
+
+	// HighPriority is the minimal priority for a request to be considered as
+	// high priority.
+	//
+	// Optional. Default to DefaultHighPriority.
+	HighPriority *int `yaml:"highPriority"`
+
+	// Header is the request header to read the priority from.
+	//
+	// The header value should be an integer, with higher values meaning higher
+	// priorities. Requests without the header, or with a malformed value,
+	// are treated as priority 0.
+	//
+	// Optional. Default to PriorityHeader.
+	Header string `yaml:"header"`
+
+	// When RetryAfter > 0, the shed requests will have the Retry-After header
+	// set via ErrorResponse.RetryableWithJitter, with RetryAfter as the base and
+	// RetryAfterJitter as the jitter.
+	//
+	// Optional. When RetryAfter <= 0 no Retry-After header will be set.
+	RetryAfter       time.Duration `yaml:"retryAfter"`
+	RetryAfterJitter float64       `yaml:"retryAfterJitter"`
+}
+
+// LoadShed returns a Middleware that sheds low priority requests when the
+// server is overloaded.
+//
+// The load is measured by the number of in-flight requests handled by the
+// endpoints wrapped by the returned Middleware.
+// When the number of in-flight requests is above MaxInFlight,
+// requests with a priority lower than HighPriority are rejected with
+// ServiceUnavailable;
+// When it's above HardMaxInFlight, all requests are rejected.
+//
+// It also emits the httpbp_server_load_shed_requests_total counter with
+// http_endpoint and http_high_priority labels.
+func LoadShed(cfg LoadShedConfig) Middleware {
+	header := cfg.Header
+	if header == "" {
+		header = PriorityHeader
+	}
+	highPriority := DefaultHighPriority
+	if cfg.HighPriority != nil {
+		highPriority = *cfg.HighPriority
+	}
+
+	var inFlight atomic.Int64
+	return func(name string, next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			current := inFlight.Add(1)
+			defer inFlight.Add(-1)
+
+			if cfg.MaxInFlight > 0 && current > cfg.MaxInFlight {
+				priority, _ := strconv.Atoi(r.Header.Get(header))
+				isHighPriority := priority >= highPriority
+				if !isHighPriority || (cfg.HardMaxInFlight > 0 && current > cfg.HardMaxInFlight) {
+					loadShedCounter.With(prometheus.Labels{
+						endpointLabel:     name,
+						highPriorityLabel: strconv.FormatBool(isHighPriority),
+					}).Inc()
+					resp := ServiceUnavailable()
+					if cfg.RetryAfter > 0 {
+						resp = resp.RetryableWithJitter(w, cfg.RetryAfter, cfg.RetryAfterJitter)
+					}
+					return JSONError(
+						resp,
+						fmt.Errorf("httpbp: request to %q with priority %d shed with %d in-flight requests", name, priority, current),
+					)
+				}
+			}
+			return next(ctx, w, r)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package httpbp_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/reddit/baseplate.go/httpbp"
+)
+
+func TestLoadShed(t *testing.T) {
+	t.Parallel()
+
+	const maxInFlight = 2
+
+	block := make(chan struct{})
+	started := make(chan struct{})
+	handle := httpbp.Wrap(
+		"test",
+		func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if r.Header.Get("block") != "" {
+				started <- struct{}{}
+				<-block
+			}
+			return nil
+		},
+		httpbp.LoadShed(httpbp.LoadShedConfig{
+			MaxInFlight:     maxInFlight,
+			HardMaxInFlight: maxInFlight + 1,
+		}),
+	)
+
+	request := func(priority int, blocking bool) error {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set(httpbp.PriorityHeader, strconv.Itoa(priority))
+		if blocking {
+			r.Header.Set("block", "true")
+		}
+		return handle(r.Context(), httptest.NewRecorder(), r)
+	}
+	checkShed := func(t *testing.T, err error, shed bool) {
+		t.Helper()
+		if !shed {
+			if err != nil {
+				t.Errorf("Expected request to pass, got %v", err)
+			}
+			return
+		}
+		var httpErr httpbp.HTTPError
+		if !errors.As(err, &httpErr) {
+			t.Fatalf("Expected HTTPError, got %v", err)
+		}
+		if code := httpErr.Response().Code; code != http.StatusServiceUnavailable {
+			t.Errorf("Expected code %d, got %d", http.StatusServiceUnavailable, code)
+		}
+	}
+
+	t.Run("not-loaded", func(t *testing.T) {
+		checkShed(t, request(0, false), false)
+	})
+
+	// Saturate the server with low priority requests.
+	var wg sync.WaitGroup
+	for i := 0; i < maxInFlight; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := request(0, true); err != nil {
+				t.Errorf("Blocking request failed: %v", err)
+			}
+		}()
+		<-started
+	}
+
+	t.Run("loaded/low-priority", func(t *testing.T) {
+		checkShed(t, request(0, false), true)
+	})
+
+	t.Run("loaded/high-priority", func(t *testing.T) {
+		checkShed(t, request(httpbp.DefaultHighPriority, false), false)
+	})
+
+	// Push the server over the hard limit with a high priority request.
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := request(httpbp.DefaultHighPriority, true); err != nil {
+			t.Errorf("Blocking request failed: %v", err)
+		}
+	}()
+	<-started
+
+	t.Run("overloaded/high-priority", func(t *testing.T) {
+		checkShed(t, request(httpbp.DefaultHighPriority, false), true)
+	})
+
+	close(block)
+	wg.Wait()
+
+	t.Run("recovered", func(t *testing.T) {
+		checkShed(t, request(0, false), false)
+	})
+}
@@ -65,19 +65,32 @@ type DefaultMiddlewareArgs struct {
 	// The edgecontext implementation to use. Optional.
 	// If not set, the global one from ecinterface.Get will be used instead.
 	EdgeContextImpl ecinterface.Interface
+
+	// The overhead to subtract from the deadline budget.
+	// See ExtractDeadlineBudgetArgs.Overhead for more details.
+	DeadlineBudgetOverhead time.Duration
 }
 
 // DefaultMiddleware returns a slice of all the default Middleware for a
 // Baseplate HTTP server. The default middleware are (in order):
 //
-//  1. InjectEdgeRequestContext
-//  2. PrometheusServerMetrics
+//  1. ExtractDeadlineBudgetWithArgs
+//  2. InjectEdgeRequestContext
+//  3. PrometheusServerMetrics
 func DefaultMiddleware(args DefaultMiddlewareArgs) []Middleware {
 	if args.TrustHandler == nil {
 		args.TrustHandler = NeverTrustHeaders{}
 	}
 	return []Middleware{
-		InjectEdgeRequestContext(InjectEdgeRequestContextArgs(args)),
+		ExtractDeadlineBudgetWithArgs(ExtractDeadlineBudgetArgs{
+			TrustHandler: args.TrustHandler,
+			Overhead:     args.DeadlineBudgetOverhead,
+		}),
+		InjectEdgeRequestContext(InjectEdgeRequestContextArgs{
+			TrustHandler:    args.TrustHandler,
+			Logger:          args.Logger,
+			EdgeContextImpl: args.EdgeContextImpl,
+		}),
 		PrometheusServerMetrics(""),
 	}
 }
@@ -89,7 +102,7 @@ func isHeaderSet(h http.Header, key string) bool {
 // StartSpanFromTrustedRequest starts a server span using the Span headers from
 // the given request if the provided HeaderTrustHandler confirms that they can
 // be trusted and the Span headers are provided, otherwise it starts a new
-// server span.
+// server span via tracing.StartSpanFromUntrustedHeaders.
 //
 // StartSpanFromTrustedRequest is used by InjectServerSpan and should not
 // generally be used directly but is provided for testing purposes or use cases
@@ -103,20 +116,25 @@ func StartSpanFromTrustedRequest(
 	var spanHeaders tracing.Headers
 	var sampled bool
 
-	if truster.TrustSpan(r) {
-		if isHeaderSet(r.Header, TraceIDHeader) {
-			spanHeaders.TraceID = r.Header.Get(TraceIDHeader)
-		}
-		if isHeaderSet(r.Header, SpanIDHeader) {
-			spanHeaders.SpanID = r.Header.Get(SpanIDHeader)
-		}
-		if isHeaderSet(r.Header, SpanFlagsHeader) {
-			spanHeaders.Flags = r.Header.Get(SpanFlagsHeader)
-		}
-		if isHeaderSet(r.Header, SpanSampledHeader) {
-			sampled = r.Header.Get(SpanSampledHeader) == spanSampledTrue
-			spanHeaders.Sampled = &sampled
-		}
+	if isHeaderSet(r.Header, SpanFlagsHeader) {
+		spanHeaders.Flags = r.Header.Get(SpanFlagsHeader)
+	}
+	if isHeaderSet(r.Header, SpanSampledHeader) {
+		sampled = r.Header.Get(SpanSampledHeader) == spanSampledTrue
+		spanHeaders.Sampled = &sampled
+	}
+
+	if !truster.TrustSpan(r) {
+		// Only the debug and sampled flags are used from untrusted headers,
+		// with capped sampling.
+		return tracing.StartSpanFromUntrustedHeaders(ctx, name, spanHeaders)
+	}
+
+	if isHeaderSet(r.Header, TraceIDHeader) {
+		spanHeaders.TraceID = r.Header.Get(TraceIDHeader)
+	}
+	if isHeaderSet(r.Header, SpanIDHeader) {
+		spanHeaders.SpanID = r.Header.Get(SpanIDHeader)
 	}
 
 	return tracing.StartSpanFromHeaders(ctx, name, spanHeaders)
@@ -170,6 +188,81 @@ func InjectServerSpan(truster HeaderTrustHandler) Middleware {
 	}
 }
 
+// DefaultDeadlineBudgetOverhead is the default
+// ExtractDeadlineBudgetArgs.Overhead.
+const DefaultDeadlineBudgetOverhead = 5 * time.Millisecond
+
+// ExtractDeadlineBudgetArgs are the args to be passed into
+// ExtractDeadlineBudgetWithArgs.
+type ExtractDeadlineBudgetArgs struct {
+	// The HeaderTrustHandler to use.
+	// If empty, NeverTrustHeaders{} will be used instead.
+	TrustHandler HeaderTrustHandler
+
+	// Overhead is subtracted from the deadline budget read from the request
+	// before it's applied to the context,
+	// to leave headroom for the response to travel back to the caller.
+	//
+	// Without it, the downstream calls made by the handler could use the full
+	// budget, and the response could arrive after the caller has already timed
+	// out.
+	//
+	// The timeout is never reduced below 1ms by the overhead.
+	//
+	// Optional. DefaultDeadlineBudgetOverhead will be used when it's 0,
+	// use a negative value to disable it.
+	Overhead time.Duration
+}
+
+// ExtractDeadlineBudget returns a Middleware that implements the HTTP
+// equivalent of Baseplate deadline propagation.
+//
+// It's a shortcut for ExtractDeadlineBudgetWithArgs with the given truster and
+// DefaultDeadlineBudgetOverhead.
+func ExtractDeadlineBudget(truster HeaderTrustHandler) Middleware {
+	return ExtractDeadlineBudgetWithArgs(ExtractDeadlineBudgetArgs{
+		TrustHandler: truster,
+	})
+}
+
+// ExtractDeadlineBudgetWithArgs returns a Middleware that implements the HTTP
+// equivalent of Baseplate deadline propagation.
+//
+// If the provided HeaderTrustHandler trusts the span headers of the request,
+// the deadline budget in milliseconds is read from DeadlineBudgetHeader,
+// reduced by the overhead,
+// and set as the timeout of the context object passed into the next
+// HandlerFunc.
+// Same as thriftbp.ExtractDeadlineBudget, it only sets the timeout if the
+// budget is at least 1ms.
+func ExtractDeadlineBudgetWithArgs(args ExtractDeadlineBudgetArgs) Middleware {
+	truster := args.TrustHandler
+	if truster == nil {
+		truster = NeverTrustHeaders{}
+	}
+	overhead := args.Overhead
+	if overhead == 0 {
+		overhead = DefaultDeadlineBudgetOverhead
+	}
+	return func(name string, next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if !isHeaderSet(r.Header, DeadlineBudgetHeader) || !truster.TrustSpan(r) {
+				return next(ctx, w, r)
+			}
+			if v, err := strconv.ParseInt(r.Header.Get(DeadlineBudgetHeader), 10, 64); err == nil && v >= 1 {
+				timeout := time.Duration(v) * time.Millisecond
+				if overhead > 0 {
+					timeout = max(timeout-overhead, time.Millisecond)
+				}
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, timeout)
+				defer cancel()
+			}
+			return next(ctx, w, r)
+		}
+	}
+}
+
 // InitializeEdgeContextFromTrustedRequest initializen an EdgeRequestContext on
 // the context object if the provided HeaderTrustHandler confirms that the
 // headers can be trusted and the header is set on the request.  If the header
@@ -446,6 +539,10 @@ func RecordStatusCode() Middleware {
 //
 //   - http_response_code: numeric status code as a string, e.g. 200
 //
+// When exemplars are enabled via prometheusbp.EnableExemplars,
+// http_server_latency_seconds observations carry the trace id of the sampled
+// server span as an exemplar.
+//
 // TODO: the arg of this function is unused and will be removed in a future version.
 func PrometheusServerMetrics(_ string) Middleware {
 	return func(name string, next HandlerFunc) HandlerFunc {
@@ -470,7 +567,7 @@ func PrometheusServerMetrics(_ string) Middleware {
 					successLabel:  success,
 					endpointLabel: name,
 				}
-				serverLatency.With(labels).Observe(time.Since(start).Seconds())
+				prometheusbp.ObserveWithTrace(ctx, serverLatency.With(labels), time.Since(start).Seconds())
 				serverRequestSize.With(labels).Observe(float64(r.ContentLength))
 				serverResponseSize.With(labels).Observe(float64(rec.bytesWritten))
 
@@ -135,6 +135,22 @@ func TestRecordStatusCode(t *testing.T) {
 			},
 			status: "4xx",
 		},
+		{
+			name: "not-modified/conditional-get",
+			handler: func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				r = r.Clone(ctx)
+				r.Method = http.MethodGet
+				r.Header.Set(IfNoneMatchHeader, "*")
+				return Wrap(
+					"etag",
+					func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+						return WriteJSON(w, NewResponse("foo"))
+					},
+					ConditionalGET(ConditionalGETArgs{}),
+				)(ctx, w, r)
+			},
+			status: "3xx",
+		},
 		{
 			name:    "continue/set",
 			handler: newTestHandler(testHandlerPlan{code: http.StatusContinue}),
@@ -10,6 +10,7 @@ import (
 	"sort"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/reddit/baseplate.go"
 	"github.com/reddit/baseplate.go/ecinterface"
@@ -357,3 +358,87 @@ func (p *pushableResponseRecorder) Push(target string, opts *http.PushOptions) e
 	p.Pushed = true
 	return nil
 }
+
+func TestExtractDeadlineBudget(t *testing.T) {
+	for _, c := range []struct {
+		label    string
+		truster  httpbp.HeaderTrustHandler
+		overhead time.Duration
+		budget   string
+		expected time.Duration // 0 means no deadline
+	}{
+		{
+			label:    "trusted",
+			truster:  httpbp.AlwaysTrustHeaders{},
+			budget:   "100",
+			expected: 100*time.Millisecond - httpbp.DefaultDeadlineBudgetOverhead,
+		},
+		{
+			label:    "custom-overhead",
+			truster:  httpbp.AlwaysTrustHeaders{},
+			overhead: 20 * time.Millisecond,
+			budget:   "100",
+			expected: 80 * time.Millisecond,
+		},
+		{
+			label:    "no-overhead",
+			truster:  httpbp.AlwaysTrustHeaders{},
+			overhead: -1,
+			budget:   "100",
+			expected: 100 * time.Millisecond,
+		},
+		{
+			label:    "overhead-exceeds-budget",
+			truster:  httpbp.AlwaysTrustHeaders{},
+			overhead: time.Second,
+			budget:   "100",
+			expected: time.Millisecond,
+		},
+		{
+			label:   "untrusted",
+			truster: httpbp.NeverTrustHeaders{},
+			budget:  "100",
+		},
+		{
+			label:   "sub-millisecond",
+			truster: httpbp.AlwaysTrustHeaders{},
+			budget:  "0",
+		},
+		{
+			label:   "malformed",
+			truster: httpbp.AlwaysTrustHeaders{},
+			budget:  "foo",
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			var timeout time.Duration
+			handle := httpbp.Wrap(
+				"test",
+				func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+					if deadline, ok := ctx.Deadline(); ok {
+						timeout = time.Until(deadline)
+					}
+					return nil
+				},
+				httpbp.ExtractDeadlineBudgetWithArgs(httpbp.ExtractDeadlineBudgetArgs{
+					TrustHandler: c.truster,
+					Overhead:     c.overhead,
+				}),
+			)
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set(httpbp.DeadlineBudgetHeader, c.budget)
+			if err := handle(r.Context(), httptest.NewRecorder(), r); err != nil {
+				t.Fatal(err)
+			}
+			if c.expected == 0 {
+				if timeout != 0 {
+					t.Errorf("Expected no deadline, got %v", timeout)
+				}
+				return
+			}
+			if timeout <= 0 || timeout > c.expected || c.expected-timeout > 10*time.Millisecond {
+				t.Errorf("Expected timeout about %v, got %v", c.expected, timeout)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package httpbp
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// AcceptHeader is the 'Accept' header key.
+	AcceptHeader = "Accept"
+
+	// ProtobufContentType is the Content-Type header for protobuf responses.
+	ProtobufContentType = "application/x-protobuf"
+)
+
+// protobufMediaTypes are the media types in Accept header that are considered
+// to be asking for protobuf responses.
+var protobufMediaTypes = map[string]bool{
+	"application/x-protobuf":          true,
+	"application/protobuf":            true,
+	"application/vnd.google.protobuf": true,
+}
+
+// ProtobufContentWriter returns a ContentWriter for writing protobuf in binary
+// wire format.
+//
+// When using a protobuf ContentWriter, your Response.Body should be a
+// proto.Message, otherwise an error will be returned.
+func ProtobufContentWriter() ContentWriter {
+	return contentWriter{
+		contentType: ProtobufContentType,
+		write: func(w io.Writer, body interface{}) error {
+			msg, ok := body.(proto.Message)
+			if !ok {
+				return errors.New("httpbp: wrong response type for protobuf response")
+			}
+			data, err := proto.Marshal(msg)
+			if err != nil {
+				return err
+			}
+			_, err = w.Write(data)
+			return err
+		},
+	}
+}
+
+// protoJSONContentWriter returns a ContentWriter for writing proto.Message
+// bodies as JSON, using the canonical protobuf JSON mapping.
+func protoJSONContentWriter() ContentWriter {
+	return contentWriter{
+		contentType: JSONContentType,
+		write: func(w io.Writer, body interface{}) error {
+			data, err := protojson.Marshal(body.(proto.Message))
+			if err != nil {
+				return err
+			}
+			_, err = w.Write(data)
+			return err
+		},
+	}
+}
+
+// NegotiateContentWriter returns the ContentWriter to write body as the
+// response to r, based on the Accept header of r.
+//
+// When body is a proto.Message and the Accept header prefers protobuf over
+// JSON, ProtobufContentWriter is returned.
+// Otherwise it defaults to JSON, using the canonical protobuf JSON mapping when
+// body is a proto.Message, and JSONContentWriter when it's not.
+//
+// As errors are always rendered as JSON by JSONError,
+// handlers using NegotiateContentWriter should keep using JSONError to return
+// errors.
+func NegotiateContentWriter(r *http.Request, body interface{}) ContentWriter {
+	if _, ok := body.(proto.Message); !ok {
+		return JSONContentWriter()
+	}
+	if prefersProtobuf(r.Header.Values(AcceptHeader)) {
+		return ProtobufContentWriter()
+	}
+	return protoJSONContentWriter()
+}
+
+// WriteNegotiated calls WriteResponse with the ContentWriter returned by
+// NegotiateContentWriter.
+func WriteNegotiated(w http.ResponseWriter, r *http.Request, resp Response) error {
+	return WriteResponse(w, NegotiateContentWriter(r, resp.Body), resp)
+}
+
+// prefersProtobuf returns true if the Accept header values give protobuf a
+// higher quality value than JSON.
+//
+// Wildcards are considered to be matching JSON, and ties are resolved in favor
+// of JSON.
+func prefersProtobuf(accept []string) bool {
+	var protobufQ, jsonQ float64
+	for _, value := range accept {
+		for _, part := range strings.Split(value, ",") {
+			mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+			if err != nil {
+				continue
+			}
+			q := 1.0
+			if v, ok := params["q"]; ok {
+				if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+					q = parsed
+				}
+			}
+			switch {
+			case protobufMediaTypes[mediaType]:
+				protobufQ = max(protobufQ, q)
+			case mediaType == "application/json", mediaType == "application/*", mediaType == "*/*":
+				jsonQ = max(jsonQ, q)
+			}
+		}
+	}
+	return protobufQ > jsonQ
+}
@@ -0,0 +1,101 @@
+package httpbp_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/reddit/baseplate.go/httpbp"
+)
+
+func TestWriteNegotiated(t *testing.T) {
+	msg := wrapperspb.String("hello")
+
+	for _, c := range []struct {
+		label       string
+		accept      string
+		body        interface{}
+		contentType string
+	}{
+		{
+			label:       "no-accept",
+			body:        msg,
+			contentType: httpbp.JSONContentType,
+		},
+		{
+			label:       "protobuf",
+			accept:      "application/x-protobuf",
+			body:        msg,
+			contentType: httpbp.ProtobufContentType,
+		},
+		{
+			label:       "protobuf-preferred",
+			accept:      "application/json;q=0.5, application/protobuf",
+			body:        msg,
+			contentType: httpbp.ProtobufContentType,
+		},
+		{
+			label:       "json-preferred",
+			accept:      "application/x-protobuf;q=0.5, application/json",
+			body:        msg,
+			contentType: httpbp.JSONContentType,
+		},
+		{
+			label:       "wildcard",
+			accept:      "*/*",
+			body:        msg,
+			contentType: httpbp.JSONContentType,
+		},
+		{
+			label:       "not-proto",
+			accept:      "application/x-protobuf",
+			body:        map[string]string{"foo": "bar"},
+			contentType: httpbp.JSONContentType,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if c.accept != "" {
+				r.Header.Set(httpbp.AcceptHeader, c.accept)
+			}
+			w := httptest.NewRecorder()
+			if err := httpbp.WriteNegotiated(w, r, httpbp.NewResponse(c.body)); err != nil {
+				t.Fatal(err)
+			}
+			if got := w.Header().Get(httpbp.ContentTypeHeader); got != c.contentType {
+				t.Errorf("Expected Content-Type %q, got %q", c.contentType, got)
+			}
+
+			switch {
+			case c.contentType == httpbp.ProtobufContentType:
+				got := new(wrapperspb.StringValue)
+				if err := proto.Unmarshal(w.Body.Bytes(), got); err != nil {
+					t.Fatal(err)
+				}
+				if !proto.Equal(got, msg) {
+					t.Errorf("Expected protobuf body %v, got %v", msg, got)
+				}
+			case c.body == msg:
+				if body := strings.TrimSpace(w.Body.String()); body != `"hello"` {
+					t.Errorf("Expected protobuf JSON body %q, got %q", `"hello"`, body)
+				}
+			default:
+				if body := strings.TrimSpace(w.Body.String()); body != `{"foo":"bar"}` {
+					t.Errorf("Expected JSON body %q, got %q", `{"foo":"bar"}`, body)
+				}
+			}
+		})
+	}
+}
+
+func TestProtobufContentWriterWrongType(t *testing.T) {
+	w := httptest.NewRecorder()
+	err := httpbp.WriteResponse(w, httpbp.ProtobufContentWriter(), httpbp.NewResponse("foo"))
+	if err == nil {
+		t.Error("Expected error writing non-proto body, got nil")
+	}
+}
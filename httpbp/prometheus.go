@@ -142,6 +142,45 @@ var (
 	}, panicRecoverLabels)
 )
 
+const (
+	highPriorityLabel = "http_high_priority"
+)
+
+var (
+	loadShedLabels = []string{
+		endpointLabel,
+		highPriorityLabel,
+	}
+
+	loadShedCounter = promauto.With(prometheusbpint.GlobalRegistry).NewCounterVec(prometheus.CounterOpts{
+		Name: "httpbp_server_load_shed_requests_total",
+		Help: "The number of requests shed by the LoadShed middleware",
+	}, loadShedLabels)
+)
+
+var (
+	rateLimitLabels = []string{
+		endpointLabel,
+	}
+
+	rateLimitCounter = promauto.With(prometheusbpint.GlobalRegistry).NewCounterVec(prometheus.CounterOpts{
+		Name: "httpbp_server_rate_limited_requests_total",
+		Help: "The number of requests rejected by the RateLimit middleware",
+	}, rateLimitLabels)
+)
+
+var (
+	clientSlowRequestsLabels = []string{
+		methodLabel,
+		clientNameLabel,
+	}
+
+	clientSlowRequests = promauto.With(prometheusbpint.GlobalRegistry).NewCounterVec(prometheus.CounterOpts{
+		Name: "http_client_slow_requests_total",
+		Help: "The number of client requests took longer than the configured slow request threshold",
+	}, clientSlowRequestsLabels)
+)
+
 // PerformanceMonitoringMiddleware returns optional Prometheus historgram metrics for monitoring the following:
 //  1. http server time to write header in seconds
 //  2. http server time to write header in seconds
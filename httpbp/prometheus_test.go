@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 
@@ -192,3 +193,59 @@ func TestPrometheusClientServerMetrics(t *testing.T) {
 		})
 	}
 }
+
+func TestLogSlowRequests(t *testing.T) {
+	const (
+		clientName = "slow"
+		threshold  = 10 * time.Millisecond
+	)
+
+	clientSlowRequests.Reset()
+	defer clientSlowRequests.Reset()
+
+	for _, c := range []struct {
+		method   string
+		duration time.Duration
+		slow     bool
+	}{
+		{
+			method:   http.MethodGet,
+			duration: 0,
+			slow:     false,
+		},
+		{
+			method:   http.MethodPost,
+			duration: threshold * 2,
+			slow:     true,
+		},
+	} {
+		t.Run(c.method, func(t *testing.T) {
+			metric := promtest.NewPrometheusMetricTest(t, "slow requests", clientSlowRequests, prometheus.Labels{
+				methodLabel:     c.method,
+				clientNameLabel: clientName,
+			})
+			transport := WrapTransport(
+				roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+					time.Sleep(c.duration)
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body:       http.NoBody,
+					}, nil
+				}),
+				LogSlowRequests(clientName, threshold),
+			)
+			req, err := http.NewRequest(c.method, "http://example.com/foo", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := transport.RoundTrip(req); err != nil {
+				t.Fatal(err)
+			}
+			if c.slow {
+				metric.CheckDelta(1)
+			} else {
+				metric.CheckDelta(0)
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package httpbp
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
+
+	"github.com/reddit/baseplate.go/log"
+)
+
+// RateLimitKeyFunc extracts the key to rate limit a request by.
+//
+// Requests with the same key share the same token bucket.
+// Returning an empty key exempts the request from rate limiting.
+//
+// Keys based on edge context, for example the loid or the session id, can be
+// implemented by reading them from ctx with the edgecontext implementation
+// used by the service.
+type RateLimitKeyFunc func(ctx context.Context, r *http.Request) string
+
+// RateLimitByRemoteIP is a RateLimitKeyFunc that uses the IP of
+// http.Request.RemoteAddr as the key.
+func RateLimitByRemoteIP(_ context.Context, r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// RateLimitByHeader returns a RateLimitKeyFunc that uses the value of the given
+// request header as the key.
+//
+// Requests without the header are not rate limited.
+func RateLimitByHeader(header string) RateLimitKeyFunc {
+	return func(_ context.Context, r *http.Request) string {
+		return r.Header.Get(header)
+	}
+}
+
+// RateLimitStore is the storage of the token buckets used by RateLimit.
+//
+// The default implementation is NewInMemoryRateLimitStore, which only limits
+// the requests handled by the same process.
+// Implementations backed by shared storage, for example redis, can be used to
+// enforce the limit across all the instances of a service.
+type RateLimitStore interface {
+	// Take takes a token from the bucket of key.
+	//
+	// If the bucket is empty, it returns allowed as false, and retryAfter as the
+	// duration until the next token will be available.
+	Take(ctx context.Context, key string) (allowed bool, retryAfter time.Duration, err error)
+}
+
+// InMemoryRateLimitArgs are the args to be passed into
+// NewInMemoryRateLimitStore function.
+type InMemoryRateLimitArgs struct {
+	// Rate is the number of tokens refilled into each bucket per second.
+	//
+	// Required. When Rate <= 0 all requests with a key are rejected once their
+	// bucket is empty.
+	Rate float64
+
+	// Burst is the size of each bucket.
+	//
+	// Optional. Default to 1.
+	Burst int
+}
+
+// minRateLimitSweepInterval is the minimum interval between the sweeps of the
+// idle buckets of the in-memory RateLimitStore, so a high rate doesn't make
+// every Take scan all the buckets.
+const minRateLimitSweepInterval = time.Second
+
+// NewInMemoryRateLimitStore creates a RateLimitStore that keeps the token
+// buckets in memory.
+//
+// Buckets that have been idle long enough to be fully refilled are evicted
+// periodically, at most once per second.
+func NewInMemoryRateLimitStore(args InMemoryRateLimitArgs) RateLimitStore {
+	burst := args.Burst
+	if burst <= 0 {
+		burst = 1
+	}
+	idle := time.Minute
+	if args.Rate > 0 {
+		idle = time.Duration(float64(burst) / args.Rate * float64(time.Second))
+	}
+	return &inMemoryRateLimitStore{
+		limit:         rate.Limit(args.Rate),
+		burst:         burst,
+		idle:          idle,
+		sweepInterval: max(idle, minRateLimitSweepInterval),
+		buckets:       make(map[string]*inMemoryBucket),
+		now:           time.Now,
+	}
+}
+
+type inMemoryBucket struct {
+	limiter  *rate.Limiter
+	lastUsed time.Time
+}
+
+type inMemoryRateLimitStore struct {
+	limit rate.Limit
+	burst int
+	// idle is the duration after which an unused bucket is fully refilled,
+	// and thus can be evicted without changing the behavior.
+	idle time.Duration
+	// sweepInterval is the interval between the sweeps of the idle buckets.
+	sweepInterval time.Duration
+	now           func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*inMemoryBucket
+	lastSweep time.Time
+}
+
+func (s *inMemoryRateLimitStore) Take(_ context.Context, key string) (bool, time.Duration, error) {
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) >= s.sweepInterval {
+		for k, b := range s.buckets {
+			if now.Sub(b.lastUsed) >= s.idle {
+				delete(s.buckets, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &inMemoryBucket{
+			limiter: rate.NewLimiter(s.limit, s.burst),
+		}
+		s.buckets[key] = b
+	}
+	b.lastUsed = now
+
+	reservation := b.limiter.ReserveN(now, 1)
+	if !reservation.OK() {
+		return false, 0, nil
+	}
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return false, delay, nil
+	}
+	return true, 0, nil
+}
+
+// RateLimitArgs are the args to be passed into RateLimit function.
+type RateLimitArgs struct {
+	// KeyFunc extracts the key to rate limit the requests by.
+	//
+	// Optional. Default to RateLimitByRemoteIP.
+	KeyFunc RateLimitKeyFunc
+
+	// Store is the storage of the token buckets.
+	//
+	// Required. Use NewInMemoryRateLimitStore for per-process rate limiting.
+	Store RateLimitStore
+
+	// Logger is an optional arg to be called when Store returned an error.
+	//
+	// Requests are allowed when Store returned an error.
+	Logger log.Wrapper
+}
+
+// RateLimit returns a Middleware that rate limits the requests with a token
+// bucket per key.
+//
+// Requests exceeding the limit are rejected with TooManyRequests,
+// with Retry-After header set to the number of seconds until the next token
+// will be available, when known.
+//
+// It also emits the httpbp_server_rate_limited_requests_total counter with
+// http_endpoint label.
+func RateLimit(args RateLimitArgs) Middleware {
+	keyFunc := args.KeyFunc
+	if keyFunc == nil {
+		keyFunc = RateLimitByRemoteIP
+	}
+	return func(name string, next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			key := keyFunc(ctx, r)
+			if key == "" || args.Store == nil {
+				return next(ctx, w, r)
+			}
+
+			allowed, retryAfter, err := args.Store.Take(ctx, key)
+			if err != nil {
+				args.Logger.Log(ctx, "httpbp.RateLimit: store returned error: "+err.Error())
+				return next(ctx, w, r)
+			}
+			if allowed {
+				return next(ctx, w, r)
+			}
+
+			rateLimitCounter.With(prometheus.Labels{
+				endpointLabel: name,
+			}).Inc()
+			if retryAfter > 0 {
+				w.Header().Set(
+					RetryAfterHeader,
+					fmt.Sprint(int64(math.Ceil(retryAfter.Seconds()))),
+				)
+			}
+			return JSONError(
+				TooManyRequests(),
+				fmt.Errorf("httpbp: request to %q rate limited", name),
+			)
+		}
+	}
+}
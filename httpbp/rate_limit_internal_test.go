@@ -0,0 +1,40 @@
+package httpbp
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestInMemoryRateLimitStoreSweep(t *testing.T) {
+	// With 1000 tokens per second the buckets are idle after 1ms,
+	// but the sweeps should still happen at most once per second.
+	store := NewInMemoryRateLimitStore(InMemoryRateLimitArgs{
+		Rate: 1000,
+	}).(*inMemoryRateLimitStore)
+	now := time.Now()
+	store.now = func() time.Time {
+		return now
+	}
+
+	take := func(key string) {
+		t.Helper()
+		if _, _, err := store.Take(context.Background(), key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 10; i++ {
+		take(fmt.Sprintf("key-%d", i))
+		now = now.Add(10 * time.Millisecond)
+	}
+	if got := len(store.buckets); got != 10 {
+		t.Errorf("Expected no buckets swept within a second, got %d buckets", got)
+	}
+
+	now = now.Add(time.Second)
+	take("key-new")
+	if got := len(store.buckets); got != 1 {
+		t.Errorf("Expected the idle buckets to be swept, got %d buckets", got)
+	}
+}
@@ -0,0 +1,118 @@
+package httpbp_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/reddit/baseplate.go/httpbp"
+)
+
+type fakeRateLimitStore struct {
+	allowed    bool
+	retryAfter time.Duration
+	err        error
+	keys       []string
+}
+
+func (s *fakeRateLimitStore) Take(_ context.Context, key string) (bool, time.Duration, error) {
+	s.keys = append(s.keys, key)
+	return s.allowed, s.retryAfter, s.err
+}
+
+func TestRateLimit(t *testing.T) {
+	noop := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return nil
+	}
+
+	t.Run("rejected", func(t *testing.T) {
+		store := &fakeRateLimitStore{retryAfter: 1500 * time.Millisecond}
+		handle := httpbp.Wrap("test", noop, httpbp.RateLimit(httpbp.RateLimitArgs{
+			Store: store,
+		}))
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = "10.0.0.1:1234"
+		w := httptest.NewRecorder()
+		err := handle(r.Context(), w, r)
+
+		var httpErr httpbp.HTTPError
+		if !errors.As(err, &httpErr) {
+			t.Fatalf("Expected HTTPError, got %v", err)
+		}
+		if code := httpErr.Response().Code; code != http.StatusTooManyRequests {
+			t.Errorf("Expected code %d, got %d", http.StatusTooManyRequests, code)
+		}
+		if got := w.Header().Get(httpbp.RetryAfterHeader); got != "2" {
+			t.Errorf("Expected Retry-After %q, got %q", "2", got)
+		}
+		if len(store.keys) != 1 || store.keys[0] != "10.0.0.1" {
+			t.Errorf("Expected key %q, got %v", "10.0.0.1", store.keys)
+		}
+	})
+
+	t.Run("no-key", func(t *testing.T) {
+		store := &fakeRateLimitStore{}
+		handle := httpbp.Wrap("test", noop, httpbp.RateLimit(httpbp.RateLimitArgs{
+			KeyFunc: httpbp.RateLimitByHeader("X-Loid"),
+			Store:   store,
+		}))
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if err := handle(r.Context(), httptest.NewRecorder(), r); err != nil {
+			t.Fatal(err)
+		}
+		if len(store.keys) != 0 {
+			t.Errorf("Expected store not to be called, got keys %v", store.keys)
+		}
+	})
+
+	t.Run("store-error", func(t *testing.T) {
+		store := &fakeRateLimitStore{err: errors.New("store down")}
+		handle := httpbp.Wrap("test", noop, httpbp.RateLimit(httpbp.RateLimitArgs{
+			Store:  store,
+			Logger: func(context.Context, string) {},
+		}))
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if err := handle(r.Context(), httptest.NewRecorder(), r); err != nil {
+			t.Errorf("Expected request to be allowed on store error, got %v", err)
+		}
+	})
+}
+
+func TestInMemoryRateLimitStore(t *testing.T) {
+	ctx := context.Background()
+	store := httpbp.NewInMemoryRateLimitStore(httpbp.InMemoryRateLimitArgs{
+		Rate:  1,
+		Burst: 2,
+	})
+
+	for i := 0; i < 2; i++ {
+		allowed, _, err := store.Take(ctx, "foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !allowed {
+			t.Fatalf("Expected request #%d to be allowed", i)
+		}
+	}
+	allowed, retryAfter, err := store.Take(ctx, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if allowed {
+		t.Error("Expected request to be rejected after burst")
+	}
+	if retryAfter <= 0 || retryAfter > time.Second {
+		t.Errorf("Expected retryAfter in (0, 1s], got %v", retryAfter)
+	}
+
+	allowed, _, err = store.Take(ctx, "bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !allowed {
+		t.Error("Expected a different key to have its own bucket")
+	}
+}
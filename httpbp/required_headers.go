@@ -0,0 +1,107 @@
+package httpbp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	missingHeaderDetail   = "required header is missing"
+	untrustedHeaderDetail = "required header is not trusted"
+)
+
+// RequireHeaders returns a Middleware that rejects the requests missing any of
+// the given headers with a 400 BadRequest JSON error,
+// before calling the next handler.
+//
+// The Details of the error response enumerate all the missing headers,
+// keyed by their canonical names.
+//
+// It's meant to be attached to the endpoints that require the headers,
+// replacing the header presence checks inside the handlers.
+// For Baseplate headers that are gated by a HeaderTrustHandler,
+// use RequireTrustedHeaders instead.
+func RequireHeaders(names ...string) Middleware {
+	return RequireTrustedHeaders(AlwaysTrustHeaders{}, names...)
+}
+
+// RequireTrustedHeaders is the same as RequireHeaders,
+// except that the Baseplate headers gated by truster are only considered
+// present when truster trusts them:
+//
+//   - EdgeContextHeader is gated by TrustEdgeContext.
+//   - The span headers (TraceIDHeader, ParentIDHeader, SpanIDHeader,
+//     SpanFlagsHeader, SpanSampledHeader) and DeadlineBudgetHeader are gated
+//     by TrustSpan.
+//
+// This matches the behavior of the middlewares consuming those headers,
+// e.g. InjectEdgeRequestContext and InjectServerSpan,
+// which ignore them when they are not trusted.
+// All other headers are checked for presence only.
+//
+// If truster is nil, NeverTrustHeaders will be used.
+func RequireTrustedHeaders(truster HeaderTrustHandler, names ...string) Middleware {
+	if truster == nil {
+		truster = NeverTrustHeaders{}
+	}
+	required := make([]string, len(names))
+	for i, name := range names {
+		required[i] = http.CanonicalHeaderKey(name)
+	}
+	return func(name string, next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			var details map[string]string
+			for _, header := range required {
+				var detail string
+				if !isHeaderSet(r.Header, header) {
+					detail = missingHeaderDetail
+				} else if !headerTrusted(truster, r, header) {
+					detail = untrustedHeaderDetail
+				} else {
+					continue
+				}
+				if details == nil {
+					details = make(map[string]string)
+				}
+				details[header] = detail
+			}
+			if len(details) > 0 {
+				missing := make([]string, 0, len(details))
+				for _, header := range required {
+					if _, ok := details[header]; ok {
+						missing = append(missing, header)
+					}
+				}
+				return JSONError(
+					BadRequest().WithDetails(details),
+					fmt.Errorf(
+						"httpbp: required headers [%s] missing or untrusted for %q",
+						strings.Join(missing, ", "),
+						name,
+					),
+				)
+			}
+			return next(ctx, w, r)
+		}
+	}
+}
+
+// headerTrusted returns whether the given canonical header can be trusted by
+// truster.
+func headerTrusted(truster HeaderTrustHandler, r *http.Request, header string) bool {
+	switch header {
+	case EdgeContextHeader:
+		return truster.TrustEdgeContext(r)
+	case TraceIDHeader,
+		ParentIDHeader,
+		SpanIDHeader,
+		SpanFlagsHeader,
+		SpanSampledHeader,
+		DeadlineBudgetHeader:
+		return truster.TrustSpan(r)
+	default:
+		return true
+	}
+}
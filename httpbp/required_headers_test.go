@@ -0,0 +1,98 @@
+package httpbp_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/reddit/baseplate.go/httpbp"
+)
+
+func TestRequireTrustedHeaders(t *testing.T) {
+	const versionHeader = "X-Api-Version"
+
+	for _, c := range []struct {
+		label           string
+		truster         httpbp.HeaderTrustHandler
+		headers         map[string]string
+		expectedDetails map[string]string
+	}{
+		{
+			label:   "all-present",
+			truster: httpbp.AlwaysTrustHeaders{},
+			headers: map[string]string{
+				versionHeader:        "1",
+				httpbp.TraceIDHeader: "12345",
+			},
+		},
+		{
+			label:   "missing",
+			truster: httpbp.AlwaysTrustHeaders{},
+			expectedDetails: map[string]string{
+				versionHeader:        "required header is missing",
+				httpbp.TraceIDHeader: "required header is missing",
+			},
+		},
+		{
+			label:   "untrusted",
+			truster: httpbp.NeverTrustHeaders{},
+			headers: map[string]string{
+				versionHeader:        "1",
+				httpbp.TraceIDHeader: "12345",
+			},
+			expectedDetails: map[string]string{
+				httpbp.TraceIDHeader: "required header is not trusted",
+			},
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			var called bool
+			handle := httpbp.Wrap(
+				"test",
+				func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+					called = true
+					return nil
+				},
+				// Use non-canonical names to make sure they are canonicalized.
+				httpbp.RequireTrustedHeaders(c.truster, "x-api-version", "x-trace"),
+			)
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			for k, v := range c.headers {
+				r.Header.Set(k, v)
+			}
+			err := handle(r.Context(), httptest.NewRecorder(), r)
+			if c.expectedDetails == nil {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if !called {
+					t.Error("Expected the handler to be called")
+				}
+				return
+			}
+
+			if called {
+				t.Error("Expected the handler not to be called")
+			}
+			var httpErr httpbp.HTTPError
+			if !errors.As(err, &httpErr) {
+				t.Fatalf("Expected httpbp.HTTPError, got %#v", err)
+			}
+			resp := httpErr.Response()
+			if resp.Code != http.StatusBadRequest {
+				t.Errorf("Expected code %d, got %d", http.StatusBadRequest, resp.Code)
+			}
+			wrapper, ok := resp.Body.(httpbp.ErrorResponseJSONWrapper)
+			if !ok {
+				t.Fatalf("Expected httpbp.ErrorResponseJSONWrapper body, got %#v", resp.Body)
+			}
+			body := wrapper.Error
+			if !reflect.DeepEqual(body.Details, c.expectedDetails) {
+				t.Errorf("Expected details %v, got %v", c.expectedDetails, body.Details)
+			}
+		})
+	}
+}
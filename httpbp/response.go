@@ -23,6 +23,10 @@ const (
 
 	// PlainTextContentType is the Content-Type header for plain text responses.
 	PlainTextContentType = "text/plain; charset=utf-8"
+
+	// NDJSONContentType is the Content-Type header for newline-delimited JSON
+	// responses.
+	NDJSONContentType = "application/x-ndjson"
 )
 
 // ContentWriter is responsible writing the response body and communicating the
@@ -0,0 +1,100 @@
+package httpbp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// RolesFunc extracts the roles of the user from the edge request context
+// injected into ctx.
+//
+// It returns ok as false when there's no edge request context in ctx.
+//
+// As the edge request context is implementation specific, it should be
+// provided by the ecinterface.Interface implementation used by the service.
+type RolesFunc func(ctx context.Context) (roles []string, ok bool)
+
+// RequireRolesArgs are the args to be passed into RequireRoles function.
+type RequireRolesArgs struct {
+	// Roles are the roles to check against the roles of the user.
+	//
+	// Required. When empty all requests with an edge request context are allowed.
+	Roles []string
+
+	// RequireAll, when true, requires the user to have all of the Roles.
+	// Otherwise the user only need to have one of them.
+	//
+	// Optional. Default to false.
+	RequireAll bool
+
+	// RolesFunc extracts the roles of the user.
+	//
+	// Required. When nil all requests are rejected with Unauthorized.
+	RolesFunc RolesFunc
+}
+
+// RequireRoles returns a Middleware that gates the requests by the roles of the
+// user in the edge request context, before the handler runs.
+//
+// Requests without an edge request context are rejected with Unauthorized,
+// and requests with a user without the required roles are rejected with
+// Forbidden.
+//
+// It should be added after InjectEdgeRequestContext, for example:
+//
+//	httpbp.Endpoint{
+//		Name:    "admin",
+//		Methods: []string{http.MethodPost},
+//		Handle:  handleAdmin,
+//		Middlewares: []httpbp.Middleware{
+//			httpbp.RequireRoles(httpbp.RequireRolesArgs{
+//				Roles:     []string{"admin"},
+//				RolesFunc: rolesFromEdgeContext,
+//			}),
+//		},
+//	}
+func RequireRoles(args RequireRolesArgs) Middleware {
+	required := make(map[string]struct{}, len(args.Roles))
+	for _, role := range args.Roles {
+		required[role] = struct{}{}
+	}
+	return func(name string, next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			var roles []string
+			ok := false
+			if args.RolesFunc != nil {
+				roles, ok = args.RolesFunc(ctx)
+			}
+			if !ok {
+				return JSONError(
+					Unauthorized(),
+					fmt.Errorf("httpbp: no edge request context for %q", name),
+				)
+			}
+			if !hasRoles(required, roles, args.RequireAll) {
+				return JSONError(
+					Forbidden(),
+					fmt.Errorf("httpbp: user does not have the required roles %q for %q", args.Roles, name),
+				)
+			}
+			return next(ctx, w, r)
+		}
+	}
+}
+
+func hasRoles(required map[string]struct{}, roles []string, all bool) bool {
+	if len(required) == 0 {
+		return true
+	}
+	matched := make(map[string]struct{}, len(required))
+	for _, role := range roles {
+		if _, ok := required[role]; ok {
+			if !all {
+				return true
+			}
+			matched[role] = struct{}{}
+		}
+	}
+	return all && len(matched) == len(required)
+}
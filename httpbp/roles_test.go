@@ -0,0 +1,82 @@
+package httpbp_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/reddit/baseplate.go/httpbp"
+)
+
+func TestRequireRoles(t *testing.T) {
+	noop := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return nil
+	}
+	rolesFunc := func(roles ...string) httpbp.RolesFunc {
+		return func(context.Context) ([]string, bool) {
+			return roles, roles != nil
+		}
+	}
+
+	for _, c := range []struct {
+		label      string
+		roles      httpbp.RolesFunc
+		requireAll bool
+		expected   int
+	}{
+		{
+			label:    "no-edge-context",
+			roles:    rolesFunc(),
+			expected: http.StatusUnauthorized,
+		},
+		{
+			label:    "nil-roles-func",
+			expected: http.StatusUnauthorized,
+		},
+		{
+			label:    "no-match",
+			roles:    rolesFunc("user"),
+			expected: http.StatusForbidden,
+		},
+		{
+			label: "any-match",
+			roles: rolesFunc("user", "moderator"),
+		},
+		{
+			label:      "all-partial-match",
+			roles:      rolesFunc("user", "moderator"),
+			requireAll: true,
+			expected:   http.StatusForbidden,
+		},
+		{
+			label:      "all-match",
+			roles:      rolesFunc("admin", "moderator"),
+			requireAll: true,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			handle := httpbp.Wrap("test", noop, httpbp.RequireRoles(httpbp.RequireRolesArgs{
+				Roles:      []string{"admin", "moderator"},
+				RequireAll: c.requireAll,
+				RolesFunc:  c.roles,
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			err := handle(r.Context(), httptest.NewRecorder(), r)
+			if c.expected == 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			var httpErr httpbp.HTTPError
+			if !errors.As(err, &httpErr) {
+				t.Fatalf("Expected HTTPError, got %v", err)
+			}
+			if code := httpErr.Response().Code; code != c.expected {
+				t.Errorf("Expected code %d, got %d", c.expected, code)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package httpbp
+
+import (
+	"context"
+	"net/http"
+)
+
+// The response headers set by SecurityHeaders.
+const (
+	ContentTypeOptionsHeader      = "X-Content-Type-Options"
+	FrameOptionsHeader            = "X-Frame-Options"
+	ContentSecurityPolicyHeader   = "Content-Security-Policy"
+	StrictTransportSecurityHeader = "Strict-Transport-Security"
+)
+
+// DefaultSecurityHeaders returns the default response headers set by
+// SecurityHeaders.
+//
+// Content-Security-Policy is not set by default as it's specific to the
+// content served, use SecurityHeadersArgs.Overrides to set it.
+func DefaultSecurityHeaders() map[string]string {
+	return map[string]string{
+		ContentTypeOptionsHeader:      "nosniff",
+		FrameOptionsHeader:            "DENY",
+		StrictTransportSecurityHeader: "max-age=31536000; includeSubDomains",
+	}
+}
+
+// SecurityHeadersArgs are the args to be passed into SecurityHeaders function.
+type SecurityHeadersArgs struct {
+	// Overrides are merged into DefaultSecurityHeaders.
+	//
+	// The keys are case-insensitive.
+	// A non-empty value overrides the default value of the header,
+	// and an empty value disables the header.
+	// For example:
+	//
+	//	httpbp.SecurityHeadersArgs{
+	//		Overrides: map[string]string{
+	//			httpbp.ContentSecurityPolicyHeader:   "default-src 'self'",
+	//			httpbp.FrameOptionsHeader:            "SAMEORIGIN",
+	//			httpbp.StrictTransportSecurityHeader: "",
+	//		},
+	//	}
+	//
+	// Optional.
+	Overrides map[string]string
+}
+
+// SecurityHeaders returns a Middleware that sets the security related response
+// headers consistently, see DefaultSecurityHeaders for the default ones.
+//
+// The headers are set before calling the handler, so handlers can still
+// override them by setting them in the response.
+// Headers already set on the response before the middleware, for example by
+// another middleware, are not changed.
+func SecurityHeaders(args SecurityHeadersArgs) Middleware {
+	headers := DefaultSecurityHeaders()
+	for k, v := range args.Overrides {
+		k = http.CanonicalHeaderKey(k)
+		if v == "" {
+			delete(headers, k)
+		} else {
+			headers[k] = v
+		}
+	}
+	return func(name string, next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			h := w.Header()
+			for k, v := range headers {
+				if !isHeaderSet(h, k) {
+					h.Set(k, v)
+				}
+			}
+			return next(ctx, w, r)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package httpbp_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/reddit/baseplate.go/httpbp"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	handle := httpbp.Wrap(
+		"test",
+		func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			w.Header().Set(httpbp.FrameOptionsHeader, "SAMEORIGIN")
+			return nil
+		},
+		httpbp.SecurityHeaders(httpbp.SecurityHeadersArgs{
+			Overrides: map[string]string{
+				httpbp.ContentSecurityPolicyHeader: "default-src 'self'",
+				// The keys are case-insensitive.
+				"strict-transport-security": "",
+			},
+		}),
+	)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	w.Header().Set(httpbp.ContentTypeOptionsHeader, "preset")
+	if err := handle(r.Context(), w, r); err != nil {
+		t.Fatal(err)
+	}
+
+	for header, expected := range map[string]string{
+		httpbp.ContentTypeOptionsHeader:      "preset",
+		httpbp.FrameOptionsHeader:            "SAMEORIGIN",
+		httpbp.ContentSecurityPolicyHeader:   "default-src 'self'",
+		httpbp.StrictTransportSecurityHeader: "",
+	} {
+		if got := w.Header().Get(header); got != expected {
+			t.Errorf("Expected %s to be %q, got %q", header, expected, got)
+		}
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/reddit/baseplate.go"
 
@@ -47,22 +49,77 @@ type httpHandlerFactory struct {
 }
 
 func (f httpHandlerFactory) NewHandler(endpoint Endpoint) http.Handler {
-	// +2 because we always add SupportedMethods and recoverPanik
-	wrappers := make([]Middleware, 0, len(f.middlewares)+len(endpoint.Middlewares)+2)
+	return handler{handle: f.wrap(endpoint)}
+}
+
+func (f httpHandlerFactory) wrap(endpoint Endpoint) HandlerFunc {
+	// +3 because we always add injectEndpointName, SupportedMethods and
+	// recoverPanik
+	wrappers := make([]Middleware, 0, len(f.middlewares)+len(endpoint.Middlewares)+3)
+	// Always inject injectEndpointName as the first middleware in the chain,
+	// so that EndpointName works in all the other middlewares.
+	wrappers = append(wrappers, injectEndpointName)
 	wrappers = append(wrappers, f.middlewares...)
-	wrappers = append(wrappers, SupportedMethods(endpoint.Methods[0], endpoint.Methods[1:]...))
+	methods := endpoint.Methods
+	if endpoint.HandleHead != nil {
+		methods = append(methods[:len(methods):len(methods)], http.MethodHead)
+	}
+	wrappers = append(wrappers, SupportedMethods(methods[0], methods[1:]...))
 	wrappers = append(wrappers, endpoint.Middlewares...)
 	// Always inject recoverPanik as the final middleware in the chain. This
 	// allows it to capture any panics before other middlewares return and bubble
 	// up the panic as an error to those middlewares.
 	wrappers = append(wrappers, recoverPanik)
-	return NewHandler(endpoint.Name, endpoint.Handle, wrappers...)
+	return Wrap(endpoint.Name, endpoint.handler(), wrappers...)
+}
+
+// handler returns the base HandlerFunc of the endpoint,
+// dispatching HEAD requests to HandleHead when it's set.
+func (e Endpoint) handler() HandlerFunc {
+	if e.HandleHead == nil {
+		return e.Handle
+	}
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		if r.Method == http.MethodHead {
+			return e.HandleHead(ctx, w, r)
+		}
+		return e.Handle(ctx, w, r)
+	}
 }
 
 // Pattern is the pattern passed to a EndpointRegistry when registering an
 // Endpoint.
+//
+// When using the default *http.ServeMux EndpointRegistry, patterns can include
+// wildcards, for example "/user/{id}" or "/files/{path...}", and the handlers
+// can read the matched values via PathValue.
+// Endpoints are always reported to the metrics by their Name,
+// not by the resolved request path.
 type Pattern string
 
+// Validate checks that p is a valid pattern for *http.ServeMux,
+// including its wildcards.
+func (p Pattern) Validate() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("httpbp: invalid Pattern %q: %v", p, r)
+		}
+	}()
+	// ServeMux.Handle panics on malformed patterns. Use a new ServeMux so it
+	// won't conflict with any other patterns.
+	http.NewServeMux().Handle(string(p), http.NotFoundHandler())
+	return nil
+}
+
+// PathValue returns the value of the named path wildcard of the Pattern that
+// matched the request, or empty string if there's no such wildcard.
+//
+// It's a shorthand of r.PathValue, and only works with the default
+// *http.ServeMux EndpointRegistry.
+func PathValue(r *http.Request, name string) string {
+	return r.PathValue(name)
+}
+
 // Endpoint holds the values needed to create a new HandlerFunc.
 type Endpoint struct {
 	// Name is required, it is the "name" of the endpoint that will be passed
@@ -83,6 +140,20 @@ type Endpoint struct {
 	// by any Middleware.
 	Handle HandlerFunc
 
+	// HandleHead is an optional HandlerFunc used instead of Handle for HEAD
+	// requests, wrapped by the same Middlewares.
+	//
+	// By default HEAD requests are handled by Handle,
+	// with the body it writes discarded by the server.
+	// Set HandleHead for the endpoints with expensive GET handlers to skip
+	// generating the body for HEAD requests (e.g. monitoring probes).
+	// It should set the same headers as Handle,
+	// including Content-Length if it can be known cheaply.
+	//
+	// When HandleHead is set, http.MethodHead is supported even if
+	// http.MethodGet is not in Methods.
+	HandleHead HandlerFunc
+
 	// Middlewares is an optional list of additional Middleware to wrap the
 	// given HandlerFunc.
 	Middlewares []Middleware
@@ -172,6 +243,9 @@ type ServerArgs struct {
 // ValidateAndSetDefaults checks the ServerArgs for any errors and sets any
 // default values.
 //
+// When EndpointRegistry is the default *http.ServeMux, the patterns of the
+// Endpoints are also validated via Pattern.Validate.
+//
 // ValidateAndSetDefaults does not generally need to be called manually but can
 // be used for testing purposes.  It is called as a part of setting up a new
 // Baseplate server.
@@ -186,6 +260,13 @@ func (args ServerArgs) ValidateAndSetDefaults() (ServerArgs, error) {
 	if args.EndpointRegistry == nil {
 		args.EndpointRegistry = http.NewServeMux()
 	}
+	if _, ok := args.EndpointRegistry.(*http.ServeMux); ok {
+		// Custom EndpointRegistry implementations could have their own pattern
+		// syntax, so only validate the patterns for ServeMux.
+		for pattern := range args.Endpoints {
+			errs = append(errs, pattern.Validate())
+		}
+	}
 	if args.TrustHandler == nil {
 		args.TrustHandler = NeverTrustHeaders{}
 	}
@@ -257,18 +338,34 @@ func NewBaseplateServer(args ServerArgs) (baseplate.Server, error) {
 	return &server{bp: args.Baseplate, srv: srv}, nil
 }
 
+var (
+	_ baseplate.Shutdowner   = (*server)(nil)
+	_ baseplate.DrainStarter = (*server)(nil)
+	_ DrainingServer         = (*server)(nil)
+)
+
+// shutdownHooksTimeout is the timeout of the context passed into the shutdown
+// hooks, which are run after the server is shut down.
+const shutdownHooksTimeout = time.Second * 10
+
 type server struct {
 	bp  baseplate.Baseplate
 	srv *http.Server
 
+	draining atomic.Bool
+
 	internalv2compat.IsHTTP
 }
 
-func (s server) Baseplate() baseplate.Baseplate {
+func (s *server) Baseplate() baseplate.Baseplate {
 	return s.bp
 }
 
-func (s server) Serve() error {
+func (s *server) Serve() error {
+	if err := baseplate.RunStartHooks(context.Background(), s.bp); err != nil {
+		return fmt.Errorf("httpbp: start hooks failed: %w", err)
+	}
+
 	// ListenAndServe always returns a non-nil error, http.ErrServerClosed is the
 	// "expected" error for it to return after being shutdown.
 	//
@@ -281,8 +378,107 @@ func (s server) Serve() error {
 	return err
 }
 
-func (s server) Close() error {
-	return s.srv.Shutdown(context.TODO())
+func (s *server) Close() error {
+	return s.Shutdown(context.TODO())
+}
+
+// StartDraining implements baseplate.DrainStarter.
+//
+// It marks the server as draining (see DrainingServer),
+// it's called by baseplate.Serve before waiting for Config.StopDelay.
+func (s *server) StartDraining() {
+	s.draining.Store(true)
+}
+
+// IsDraining implements DrainingServer.
+func (s *server) IsDraining() bool {
+	return s.draining.Load()
+}
+
+// Shutdown implements baseplate.Shutdowner.
+//
+// It marks the server as draining (if not already), stops accepting new
+// connections, and waits for the in-flight requests to finish until ctx is
+// done, after which the remaining connections are closed forcibly.
+// The shutdown hooks are run afterwards with their own context.
+//
+// When run via baseplate.Serve, the server is marked as draining before
+// waiting for Config.StopDelay, and ctx is canceled after Config.StopTimeout.
+// The load balancer needs time to deregister the instance after the health
+// check starts failing, so StopDelay should be at least the load balancer's
+// deregistration delay, so that no new requests are routed to the server after
+// it stops accepting connections, and StopTimeout should be longer than
+// StopDelay plus the longest expected request duration.
+func (s *server) Shutdown(ctx context.Context) error {
+	s.StartDraining()
+	err := s.srv.Shutdown(ctx)
+	if err != nil {
+		err = errors.Join(err, s.srv.Close())
+	}
+	// ctx could be already done when the draining timed out.
+	hooksCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownHooksTimeout)
+	defer cancel()
+	return errors.Join(
+		err,
+		baseplate.RunShutdownHooks(hooksCtx, s.bp),
+	)
+}
+
+// DrainingServer is implemented by the servers created by NewBaseplateServer.
+type DrainingServer interface {
+	baseplate.Server
+
+	// IsDraining returns true after the server started shutting down.
+	IsDraining() bool
+}
+
+// RecordedResponse is the response recorded by TestInvoke.
+type RecordedResponse struct {
+	// Code is the HTTP status code written.
+	Code int
+
+	// Header is the headers written.
+	Header http.Header
+
+	// Body is the response body written.
+	Body []byte
+}
+
+// TestInvoke runs the given Endpoint with its middleware chain against an
+// httptest.ResponseRecorder, and returns the recorded response along with the
+// error returned by the wrapped HandlerFunc, if any.
+//
+// The recorded response is the one written by the baseplate http.Handler,
+// so when the returned error is an HTTPError it contains the output of the
+// error's ContentWriter.
+//
+// Only the Middlewares of the endpoint (and the ones always applied to an
+// endpoint, like SupportedMethods) are used, the server level middlewares,
+// including the ones from DefaultMiddleware, are not.
+// Use NewTestBaseplateServer if you need to test with those.
+//
+// If the endpoint is invalid the error from Endpoint.Validate is returned.
+//
+// It's provided for testing purposes.
+func TestInvoke(endpoint Endpoint, req *http.Request) (RecordedResponse, error) {
+	if err := endpoint.Validate(); err != nil {
+		return RecordedResponse{}, err
+	}
+	wrapped := httpHandlerFactory{}.wrap(endpoint)
+	var handleErr error
+	h := handler{
+		handle: func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			handleErr = wrapped(ctx, w, r)
+			return handleErr
+		},
+	}
+	recorder := httptest.NewRecorder()
+	h.ServeHTTP(recorder, req)
+	return RecordedResponse{
+		Code:   recorder.Code,
+		Header: recorder.Header(),
+		Body:   recorder.Body.Bytes(),
+	}, handleErr
 }
 
 // NewTestBaseplateServer returns a new HTTP implementation of a Baseplate
@@ -334,7 +530,7 @@ func (s *testServer) Close() error {
 		cb()
 	}
 	s.wg.Done()
-	return nil
+	return baseplate.RunShutdownHooks(context.TODO(), s.bp)
 }
 
 var (
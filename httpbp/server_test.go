@@ -223,6 +223,81 @@ func TestServerArgsValidateAndSetDefaults(t *testing.T) {
 	}
 }
 
+func TestPatternValidate(t *testing.T) {
+	for pattern, valid := range map[httpbp.Pattern]bool{
+		"/user/{id}":           true,
+		"GET /files/{path...}": true,
+		"/exact/{$}":           true,
+		"/user/{id":            false,
+		"/user/{id}/{id}":      false,
+		"/user/{1d}":           false,
+		"/files/{path...}/foo": false,
+	} {
+		err := pattern.Validate()
+		if valid && err != nil {
+			t.Errorf("Expected %q to be valid, got %v", pattern, err)
+		}
+		if !valid && err == nil {
+			t.Errorf("Expected %q to be invalid", pattern)
+		}
+	}
+}
+
+func TestServerArgsSetupEndpointsPathValue(t *testing.T) {
+	store := newSecretsStore(t)
+	defer store.Close()
+
+	bp := baseplate.NewTestBaseplate(baseplate.NewTestBaseplateArgs{
+		Config:          baseplate.Config{Addr: ":8080"},
+		Store:           store,
+		EdgeContextImpl: ecinterface.Mock(),
+	})
+
+	var id string
+	args, err := httpbp.ServerArgs{
+		Baseplate: bp,
+		Endpoints: map[httpbp.Pattern]httpbp.Endpoint{
+			"/user/{id}": {
+				Name:    "user",
+				Methods: []string{http.MethodGet},
+				Handle: func(_ context.Context, _ http.ResponseWriter, r *http.Request) error {
+					id = httpbp.PathValue(r, "id")
+					return nil
+				},
+			},
+		},
+		Logger: log.TestWrapper(t),
+	}.SetupEndpoints()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	args.EndpointRegistry.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/user/t2_foo", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if id != "t2_foo" {
+		t.Errorf("Expected path value %q, got %q", "t2_foo", id)
+	}
+
+	_, err = httpbp.ServerArgs{
+		Baseplate: bp,
+		Endpoints: map[httpbp.Pattern]httpbp.Endpoint{
+			"/user/{id": {
+				Name:    "user",
+				Methods: []string{http.MethodGet},
+				Handle: func(context.Context, http.ResponseWriter, *http.Request) error {
+					return nil
+				},
+			},
+		},
+	}.SetupEndpoints()
+	if err == nil {
+		t.Error("Expected error for malformed pattern")
+	}
+}
+
 func TestServerArgsSetupEndpoints(t *testing.T) {
 	store := newSecretsStore(t)
 	defer store.Close()
@@ -249,13 +324,15 @@ func TestServerArgsSetupEndpoints(t *testing.T) {
 			var pattern httpbp.Pattern = "/test"
 			name := "test"
 			recorder := edgecontextRecorder{}
+			var handlerEndpointName, middlewareEndpointName string
 			args := httpbp.ServerArgs{
 				Baseplate: bp,
 				Endpoints: map[httpbp.Pattern]httpbp.Endpoint{
 					pattern: {
 						Name:    name,
 						Methods: []string{http.MethodGet},
-						Handle: func(context.Context, http.ResponseWriter, *http.Request) error {
+						Handle: func(ctx context.Context, _ http.ResponseWriter, _ *http.Request) error {
+							handlerEndpointName = httpbp.EndpointName(ctx)
 							return nil
 						},
 					},
@@ -263,6 +340,12 @@ func TestServerArgsSetupEndpoints(t *testing.T) {
 				EndpointRegistry: &mockEndpointRegistry{},
 				Middlewares: []httpbp.Middleware{
 					edgecontextRecorderMiddleware(ecinterface.Mock(), &recorder),
+					func(_ string, next httpbp.HandlerFunc) httpbp.HandlerFunc {
+						return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+							middlewareEndpointName = httpbp.EndpointName(ctx)
+							return next(ctx, w, r)
+						}
+					},
 				},
 				TrustHandler: httpbp.AlwaysTrustHeaders{},
 				Logger:       log.TestWrapper(t),
@@ -295,6 +378,16 @@ func TestServerArgsSetupEndpoints(t *testing.T) {
 			if recorder.header == "" {
 				t.Error("edge request context not set")
 			}
+
+			if handlerEndpointName != name {
+				t.Errorf("Expected EndpointName %q in handler, got %q", name, handlerEndpointName)
+			}
+			if middlewareEndpointName != name {
+				t.Errorf("Expected EndpointName %q in middleware, got %q", name, middlewareEndpointName)
+			}
+			if got := httpbp.EndpointName(context.Background()); got != "" {
+				t.Errorf("Expected empty EndpointName outside of endpoints, got %q", got)
+			}
 		},
 	)
 }
@@ -359,6 +452,54 @@ func TestNewTestBaseplateServer(t *testing.T) {
 	}
 }
 
+func TestTestInvoke(t *testing.T) {
+	c := counter{}
+	endpoint := httpbp.Endpoint{
+		Name:    "test",
+		Methods: []string{http.MethodPost},
+		Handle: func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			return httpbp.WriteJSON(w, httpbp.Response{Body: "ok"})
+		},
+		Middlewares: []httpbp.Middleware{testMiddleware(&c)},
+	}
+
+	t.Run("success", func(t *testing.T) {
+		resp, err := httpbp.TestInvoke(endpoint, httptest.NewRequest(http.MethodPost, "/test", nil))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if resp.Code != http.StatusOK {
+			t.Errorf("Expected code %d, got %d", http.StatusOK, resp.Code)
+		}
+		if got, want := string(resp.Body), "\"ok\"\n"; got != want {
+			t.Errorf("Expected body %q, got %q", want, got)
+		}
+		if c.count != 1 {
+			t.Errorf("Expected endpoint middleware to be called once, got %d", c.count)
+		}
+	})
+
+	t.Run("unsupported-method", func(t *testing.T) {
+		resp, err := httpbp.TestInvoke(endpoint, httptest.NewRequest(http.MethodGet, "/test", nil))
+		var httpErr httpbp.HTTPError
+		if !errors.As(err, &httpErr) {
+			t.Fatalf("Expected HTTPError, got %v", err)
+		}
+		if resp.Code != http.StatusMethodNotAllowed {
+			t.Errorf("Expected code %d, got %d", http.StatusMethodNotAllowed, resp.Code)
+		}
+		if got := resp.Header.Get("Allow"); got != http.MethodPost {
+			t.Errorf("Expected Allow header %q, got %q", http.MethodPost, got)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		if _, err := httpbp.TestInvoke(httpbp.Endpoint{}, httptest.NewRequest(http.MethodGet, "/test", nil)); err == nil {
+			t.Error("Expected error for invalid endpoint, got nil")
+		}
+	})
+}
+
 func TestPanicRecovery(t *testing.T) {
 	var pattern httpbp.Pattern = "/test"
 	path := string(pattern)
@@ -427,3 +568,43 @@ func TestPanicRecovery(t *testing.T) {
 		t.Fatalf("unexpected service code")
 	}
 }
+
+func TestEndpointHandleHead(t *testing.T) {
+	const contentLength = "2"
+	var getCalled, headCalled int
+	endpoint := httpbp.Endpoint{
+		Name:    "test",
+		Methods: []string{http.MethodPost},
+		Handle: func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			getCalled++
+			return httpbp.WriteRawContent(w, httpbp.Response{Body: []byte("ok")}, httpbp.PlainTextContentType)
+		},
+		HandleHead: func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			headCalled++
+			w.Header().Set(httpbp.ContentTypeHeader, httpbp.PlainTextContentType)
+			w.Header().Set("Content-Length", contentLength)
+			return nil
+		},
+	}
+
+	resp, err := httpbp.TestInvoke(endpoint, httptest.NewRequest(http.MethodHead, "/test", nil))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if headCalled != 1 || getCalled != 0 {
+		t.Errorf("Expected only HandleHead to be called, got HandleHead %d, Handle %d", headCalled, getCalled)
+	}
+	if len(resp.Body) != 0 {
+		t.Errorf("Expected empty body, got %q", resp.Body)
+	}
+	if got := resp.Header.Get("Content-Length"); got != contentLength {
+		t.Errorf("Expected Content-Length %q, got %q", contentLength, got)
+	}
+
+	if _, err := httpbp.TestInvoke(endpoint, httptest.NewRequest(http.MethodPost, "/test", nil)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if headCalled != 1 || getCalled != 1 {
+		t.Errorf("Expected only Handle to be called, got HandleHead %d, Handle %d", headCalled, getCalled)
+	}
+}
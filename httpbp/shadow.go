@@ -0,0 +1,154 @@
+package httpbp
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/reddit/baseplate.go/detach"
+	"github.com/reddit/baseplate.go/log"
+	"github.com/reddit/baseplate.go/randbp"
+)
+
+// ShadowTrafficArgs are the args to be passed into ShadowTraffic function.
+type ShadowTrafficArgs struct {
+	// Rate is the fraction of requests to be mirrored to the shadow backend,
+	// in the range of [0, 1].
+	//
+	// When Rate <= 0 no requests will be mirrored;
+	// When Rate >= 1 all requests will be mirrored.
+	Rate float64
+
+	// Target is the URL of the shadow backend, required.
+	//
+	// Only its scheme and host are used, the path and query of the mirrored
+	// requests are taken from the original requests.
+	Target *url.URL
+
+	// Client is the http client used to send the mirrored requests, required.
+	//
+	// It must have a Timeout set, so a slow shadow backend can't pile up the
+	// mirrored requests.
+	Client *http.Client
+
+	// MaxConcurrency is the max number of the mirrored requests in flight.
+	//
+	// When it's reached, the sampled requests are not mirrored until some of the
+	// in-flight ones finished.
+	//
+	// Optional, DefaultShadowTrafficMaxConcurrency will be used when it's <= 0.
+	MaxConcurrency int
+
+	// By default the credentials of the original requests,
+	// namely the Authorization, Proxy-Authorization, Cookie and
+	// EdgeContextHeader headers, are removed from the mirrored requests.
+	//
+	// Set ForwardCredentials to true to keep them, only when the shadow backend
+	// is trusted as much as the handler.
+	ForwardCredentials bool
+
+	// Logger is an optional arg to be called when sending the mirrored request
+	// failed.
+	//
+	// If it's nil, log.DefaultWrapper will be used instead.
+	Logger log.Wrapper
+}
+
+// DefaultShadowTrafficMaxConcurrency is the default MaxConcurrency used by
+// ShadowTraffic.
+const DefaultShadowTrafficMaxConcurrency = 100
+
+// shadowCredentialHeaders are the headers removed from the mirrored requests
+// unless ShadowTrafficArgs.ForwardCredentials is true.
+var shadowCredentialHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	EdgeContextHeader,
+}
+
+// ShadowTraffic returns a Middleware that mirrors a sampled fraction of the
+// requests to a shadow backend.
+//
+// The mirrored requests are sent asynchronously with a context detached from
+// the original request, and their responses are discarded.
+// They never affect the response to the original request.
+//
+// In order to send the same body to both the handler and the shadow backend,
+// the body of a sampled request is fully read into memory before calling the
+// next handler.
+//
+// It panics when args.Client is nil or has no Timeout.
+func ShadowTraffic(args ShadowTrafficArgs) Middleware {
+	client := args.Client
+	if client == nil || client.Timeout <= 0 {
+		panic("httpbp.ShadowTraffic: Client with a Timeout is required")
+	}
+	maxConcurrency := args.MaxConcurrency
+	if maxConcurrency <= 0 {
+		maxConcurrency = DefaultShadowTrafficMaxConcurrency
+	}
+	sem := make(chan struct{}, maxConcurrency)
+	return func(name string, next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if args.Target == nil || !randbp.ShouldSampleWithRate(args.Rate) {
+				return next(ctx, w, r)
+			}
+			select {
+			case sem <- struct{}{}:
+			default:
+				// Too many mirrored requests in flight, drop this one.
+				return next(ctx, w, r)
+			}
+
+			var body []byte
+			if r.Body != nil && r.Body != http.NoBody {
+				var err error
+				body, err = io.ReadAll(r.Body)
+				r.Body.Close()
+				if err != nil {
+					<-sem
+					return RawError(BadRequest(), err, PlainTextContentType)
+				}
+				r.Body = io.NopCloser(bytes.NewReader(body))
+			}
+
+			shadow := cloneShadowRequest(r, args.Target, body)
+			if !args.ForwardCredentials {
+				for _, h := range shadowCredentialHeaders {
+					shadow.Header.Del(h)
+				}
+			}
+			go detach.Async(ctx, func(ctx context.Context) {
+				defer func() { <-sem }()
+				resp, err := client.Do(shadow.WithContext(ctx))
+				if err != nil {
+					args.Logger.Log(ctx, "httpbp.ShadowTraffic: failed to send shadow request: "+err.Error())
+					return
+				}
+				DrainAndClose(resp.Body)
+			})
+
+			return next(ctx, w, r)
+		}
+	}
+}
+
+// cloneShadowRequest clones r into a client request to be sent to target.
+func cloneShadowRequest(r *http.Request, target *url.URL, body []byte) *http.Request {
+	shadow := r.Clone(context.Background())
+	shadow.RequestURI = ""
+	shadow.URL.Scheme = target.Scheme
+	shadow.URL.Host = target.Host
+	shadow.Host = target.Host
+	if body != nil {
+		shadow.Body = io.NopCloser(bytes.NewReader(body))
+		shadow.ContentLength = int64(len(body))
+	} else {
+		shadow.Body = http.NoBody
+		shadow.ContentLength = 0
+	}
+	return shadow
+}
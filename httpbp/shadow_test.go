@@ -0,0 +1,221 @@
+package httpbp_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/reddit/baseplate.go/httpbp"
+)
+
+func TestShadowTraffic(t *testing.T) {
+	t.Parallel()
+
+	const (
+		body     = "hello, world"
+		requests = 200
+	)
+
+	for _, c := range []struct {
+		name     string
+		rate     float64
+		min, max int64
+	}{
+		{name: "none", rate: 0, min: 0, max: 0},
+		{name: "all", rate: 1, min: requests, max: requests},
+		{name: "half", rate: 0.5, min: requests / 4, max: requests * 3 / 4},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			var shadowCount atomic.Int64
+			shadow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				shadowCount.Add(1)
+				b, _ := io.ReadAll(r.Body)
+				if string(b) != body {
+					t.Errorf("Shadow request body expected %q, got %q", body, b)
+				}
+				if r.URL.Path != "/foo" {
+					t.Errorf("Shadow request path expected %q, got %q", "/foo", r.URL.Path)
+				}
+				w.WriteHeader(http.StatusInternalServerError)
+				io.WriteString(w, "shadow response")
+			}))
+			defer shadow.Close()
+			target, err := url.Parse(shadow.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			handle := httpbp.Wrap(
+				"test",
+				func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+					b, err := io.ReadAll(r.Body)
+					if err != nil {
+						return err
+					}
+					if string(b) != body {
+						t.Errorf("Request body expected %q, got %q", body, b)
+					}
+					_, err = io.WriteString(w, "primary response")
+					return err
+				},
+				httpbp.ShadowTraffic(httpbp.ShadowTrafficArgs{
+					Rate:           c.rate,
+					Target:         target,
+					Client:         &http.Client{Timeout: time.Second},
+					MaxConcurrency: requests,
+				}),
+			)
+
+			for i := 0; i < requests; i++ {
+				w := httptest.NewRecorder()
+				r := httptest.NewRequest(http.MethodPost, "/foo", strings.NewReader(body))
+				if err := handle(r.Context(), w, r); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if w.Code != http.StatusOK {
+					t.Errorf("Response code expected %d, got %d", http.StatusOK, w.Code)
+				}
+				if got := w.Body.String(); got != "primary response" {
+					t.Errorf("Response body expected %q, got %q", "primary response", got)
+				}
+			}
+
+			// The shadow requests are sent asynchronously, wait for them to settle.
+			deadline := time.Now().Add(time.Second)
+			for shadowCount.Load() < c.min && time.Now().Before(deadline) {
+				time.Sleep(time.Millisecond)
+			}
+			time.Sleep(50 * time.Millisecond)
+			if got := shadowCount.Load(); got < c.min || got > c.max {
+				t.Errorf("Expected shadow requests in [%d, %d], got %d", c.min, c.max, got)
+			}
+		})
+	}
+}
+
+func TestShadowTrafficCredentials(t *testing.T) {
+	t.Parallel()
+
+	for _, c := range []struct {
+		name    string
+		forward bool
+	}{
+		{name: "stripped", forward: false},
+		{name: "forwarded", forward: true},
+	} {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+
+			headers := make(chan http.Header, 1)
+			shadow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				headers <- r.Header.Clone()
+			}))
+			defer shadow.Close()
+			target, err := url.Parse(shadow.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			handle := httpbp.Wrap(
+				"test",
+				func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+					if r.Header.Get("Authorization") == "" {
+						t.Error("Expected the original request to keep Authorization header")
+					}
+					return nil
+				},
+				httpbp.ShadowTraffic(httpbp.ShadowTrafficArgs{
+					Rate:               1,
+					Target:             target,
+					Client:             &http.Client{Timeout: time.Second},
+					ForwardCredentials: c.forward,
+				}),
+			)
+			r := httptest.NewRequest(http.MethodGet, "/foo", nil)
+			r.Header.Set("Authorization", "Bearer foo")
+			r.Header.Set("Cookie", "foo=bar")
+			r.Header.Set(httpbp.EdgeContextHeader, "edge")
+			r.Header.Set("X-Foo", "bar")
+			if err := handle(r.Context(), httptest.NewRecorder(), r); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			select {
+			case h := <-headers:
+				for _, name := range []string{"Authorization", "Cookie", httpbp.EdgeContextHeader} {
+					if got := h.Get(name) != ""; got != c.forward {
+						t.Errorf("Expected header %q forwarded to be %v, got %v", name, c.forward, got)
+					}
+				}
+				if got := h.Get("X-Foo"); got != "bar" {
+					t.Errorf("Expected header X-Foo to be %q, got %q", "bar", got)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("Shadow request not received")
+			}
+		})
+	}
+}
+
+func TestShadowTrafficMaxConcurrency(t *testing.T) {
+	t.Parallel()
+
+	var shadowCount atomic.Int64
+	release := make(chan struct{})
+	shadow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		shadowCount.Add(1)
+		<-release
+	}))
+	defer shadow.Close()
+	defer close(release)
+	target, err := url.Parse(shadow.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handle := httpbp.Wrap(
+		"test",
+		func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			return nil
+		},
+		httpbp.ShadowTraffic(httpbp.ShadowTrafficArgs{
+			Rate:           1,
+			Target:         target,
+			Client:         &http.Client{Timeout: time.Second},
+			MaxConcurrency: 1,
+		}),
+	)
+	for i := 0; i < 5; i++ {
+		r := httptest.NewRequest(http.MethodGet, "/foo", nil)
+		if err := handle(r.Context(), httptest.NewRecorder(), r); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if got := shadowCount.Load(); got != 1 {
+		t.Errorf("Expected 1 shadow request, got %d", got)
+	}
+}
+
+func TestShadowTrafficRequiresClientTimeout(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("Expected ShadowTraffic to panic without a Client with Timeout")
+		}
+	}()
+	httpbp.ShadowTraffic(httpbp.ShadowTrafficArgs{
+		Rate:   1,
+		Client: &http.Client{},
+	})
+}
@@ -0,0 +1,126 @@
+package httpbp_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/reddit/baseplate.go/httpbp"
+	"github.com/reddit/baseplate.go/secrets"
+	"github.com/reddit/baseplate.go/signing"
+)
+
+func TestSignedURL(t *testing.T) {
+	secret := secrets.VersionedSecret{
+		Current:  secrets.Secret("current"),
+		Previous: secrets.Secret("previous"),
+	}
+	rotated := secrets.VersionedSecret{
+		Current: secrets.Secret("previous"),
+	}
+	base, err := url.Parse("https://www.reddit.com/download/file?id=1&name=foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	escapedBase, err := url.Parse("https://www.reddit.com/download/a%2Fb")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sign := func(t *testing.T, secret secrets.VersionedSecret, base *url.URL) *url.URL {
+		t.Helper()
+		signed, err := httpbp.SignURL(base, secret, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+
+	for _, c := range []struct {
+		label            string
+		url              func(t *testing.T) string
+		expectedMismatch bool
+		expectedErr      error
+	}{
+		{
+			label: "valid",
+			url: func(t *testing.T) string {
+				return sign(t, secret, base).String()
+			},
+		},
+		{
+			label: "signed-with-previous-secret",
+			url: func(t *testing.T) string {
+				return sign(t, rotated, base).String()
+			},
+		},
+		{
+			label: "tampered-query",
+			url: func(t *testing.T) string {
+				u := sign(t, secret, base)
+				query := u.Query()
+				query.Set("id", "2")
+				u.RawQuery = query.Encode()
+				return u.String()
+			},
+			expectedMismatch: true,
+		},
+		{
+			label: "tampered-path",
+			url: func(t *testing.T) string {
+				u := sign(t, secret, base)
+				u.Path = "/download/other"
+				return u.String()
+			},
+			expectedMismatch: true,
+		},
+		{
+			label: "valid-escaped-path",
+			url: func(t *testing.T) string {
+				return sign(t, secret, escapedBase).String()
+			},
+		},
+		{
+			label: "tampered-escaped-path",
+			url: func(t *testing.T) string {
+				u := sign(t, secret, escapedBase)
+				u.RawPath = ""
+				u.Path = "/download/a/b"
+				return u.String()
+			},
+			expectedMismatch: true,
+		},
+		{
+			label: "missing-signature",
+			url: func(t *testing.T) string {
+				return base.String()
+			},
+			expectedErr: httpbp.ErrMissingURLSignature,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, c.url(t), nil)
+			ok, err := httpbp.ValidateSignedURL(r, secret)
+			expectedOK := !c.expectedMismatch && c.expectedErr == nil
+			if ok != expectedOK {
+				t.Errorf("Expected ok to be %v, got %v: %v", expectedOK, ok, err)
+			}
+			if c.expectedErr != nil && !errors.Is(err, c.expectedErr) {
+				t.Errorf("Expected error %v, got %v", c.expectedErr, err)
+			}
+			if c.expectedMismatch {
+				var verifyErr signing.VerifyError
+				if !errors.As(err, &verifyErr) {
+					t.Fatalf("Expected signing.VerifyError, got %v", err)
+				}
+				if verifyErr.Reason != signing.VerifyErrorReasonMismatch {
+					t.Errorf("Expected reason %v, got %v", signing.VerifyErrorReasonMismatch, verifyErr.Reason)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,303 @@
+package httpbp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// DefaultSingleFlightMaxResponseSize is the default MaxResponseSize used by
+// SingleFlight.
+const DefaultSingleFlightMaxResponseSize = 1 << 20 // 1 MiB
+
+// SingleFlightArgs are the args to be passed into SingleFlightWithArgs
+// function.
+type SingleFlightArgs struct {
+	// KeyFunc returns the key of the request.
+	// Concurrent GET requests with the same key are coalesced.
+	//
+	// The key must cover everything in the request that could change the
+	// response, e.g. the path, the query and headers like Accept-Language.
+	// When it returns an empty string the request is not coalesced.
+	//
+	// Required. If it's nil, no requests are coalesced.
+	KeyFunc func(*http.Request) string
+
+	// MaxResponseSize is the max size in bytes of a response body to be buffered
+	// and replayed to the coalesced requests.
+	//
+	// If a response exceeds it, the coalesced requests will call the handler
+	// themselves instead.
+	//
+	// Optional, DefaultSingleFlightMaxResponseSize will be used when it's <= 0.
+	MaxResponseSize int
+}
+
+// SingleFlight is SingleFlightWithArgs with only KeyFunc set.
+func SingleFlight(keyFunc func(*http.Request) string) Middleware {
+	return SingleFlightWithArgs(SingleFlightArgs{
+		KeyFunc: keyFunc,
+	})
+}
+
+// SingleFlightWithArgs returns a Middleware that coalesces concurrent
+// identical GET requests via golang.org/x/sync/singleflight,
+// so the expensive handler only runs once per key and its response is shared.
+//
+// The first request of a key calls the next handler as usual,
+// while its response is also buffered in memory.
+// The concurrent requests with the same key arriving before it finishes wait
+// for it, then write the buffered status code, body and the headers set by the
+// next handler as their own responses, without calling the next handler.
+// The waiting requests stop waiting when their own contexts are done.
+//
+// This trades memory for upstream load: for each key in flight,
+// up to MaxResponseSize bytes of the response body are held in memory until
+// all the waiting requests replayed it, so MaxResponseSize times the number of
+// distinct keys in flight is the extra memory it could use.
+//
+// Per-request differences are handled conservatively,
+// the waiting requests fall back to calling the next handler themselves when
+// the shared response might not be right for them:
+//
+//   - The requests with Authorization, Cookie or EdgeContextHeader headers,
+//     which usually carry the identity of the user, are never coalesced.
+//   - The responses with Set-Cookie header or "Vary: *" are not shared.
+//   - The responses with Vary header are only shared with the requests having
+//     the same values of the listed headers as the first request.
+//   - The responses larger than MaxResponseSize are not shared.
+//   - When the next handler returned an error or panicked,
+//     its response is not shared.
+//
+// Requests of all other methods are passed to the next handler directly.
+func SingleFlightWithArgs(args SingleFlightArgs) Middleware {
+	maxSize := args.MaxResponseSize
+	if maxSize <= 0 {
+		maxSize = DefaultSingleFlightMaxResponseSize
+	}
+	return func(name string, next HandlerFunc) HandlerFunc {
+		var group singleflight.Group
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if args.KeyFunc == nil ||
+				r.Method != http.MethodGet ||
+				isHeaderSet(r.Header, "Authorization") ||
+				isHeaderSet(r.Header, "Cookie") ||
+				isHeaderSet(r.Header, EdgeContextHeader) {
+				return next(ctx, w, r)
+			}
+			key := args.KeyFunc(r)
+			if key == "" {
+				return next(ctx, w, r)
+			}
+
+			call := &singleFlightCall{
+				ctx:     ctx,
+				w:       w,
+				r:       r,
+				next:    next,
+				maxSize: maxSize,
+			}
+			ch := group.DoChan(key, call.lead)
+			var res singleflight.Result
+			select {
+			case res = <-ch:
+			case <-ctx.Done():
+				if call.state.CompareAndSwap(singleFlightPending, singleFlightAbandoned) {
+					return ctx.Err()
+				}
+				// This request is leading the call, it must wait for the next handler
+				// to return before returning, as the next handler is writing to w.
+				res = <-ch
+			}
+			if call.state.Load() == singleFlightLeading {
+				if call.panicked != nil {
+					// Re-panic in the goroutine of the request,
+					// so it's handled by the panic recovering middleware.
+					panic(call.panicked)
+				}
+				return call.err
+			}
+
+			result := res.Val.(*singleFlightResult)
+			if !result.shareableWith(r) {
+				return next(ctx, w, r)
+			}
+			result.replay(w)
+			return nil
+		}
+	}
+}
+
+const (
+	singleFlightPending int32 = iota
+	singleFlightLeading
+	singleFlightAbandoned
+)
+
+// errSingleFlightAbandoned is returned by singleFlightCall.lead when the
+// request started the call had already given up waiting.
+var errSingleFlightAbandoned = errors.New("httpbp: single flight request abandoned")
+
+// singleFlightCall is a request joining a single flight call.
+//
+// When it's the one starting the call,
+// lead is called in a separate goroutine by singleflight.Group.DoChan.
+type singleFlightCall struct {
+	ctx     context.Context
+	w       http.ResponseWriter
+	r       *http.Request
+	next    HandlerFunc
+	maxSize int
+
+	// state is CAS-ed from singleFlightPending to either singleFlightLeading by
+	// lead, or singleFlightAbandoned when the request stops waiting,
+	// so that the next handler never writes to w after the request returned.
+	state atomic.Int32
+
+	// The result of the next handler when leading,
+	// only read after the result of lead is received.
+	err      error
+	panicked any
+}
+
+// lead calls next and records its response.
+//
+// The returned value is always a *singleFlightResult.
+func (c *singleFlightCall) lead() (val any, err error) {
+	if !c.state.CompareAndSwap(singleFlightPending, singleFlightLeading) {
+		return &singleFlightResult{}, errSingleFlightAbandoned
+	}
+
+	recorder := &singleFlightRecorder{
+		ResponseWriter: c.w,
+		maxSize:        c.maxSize,
+		before:         c.w.Header().Clone(),
+	}
+	defer func() {
+		// Panics in the singleflight goroutine would crash the process,
+		// pass it to the request goroutine instead.
+		if p := recover(); p != nil {
+			c.panicked = p
+			val = &singleFlightResult{}
+		}
+	}()
+	c.err = c.next(c.ctx, wrapResponseWriter(c.w, recorder), c.r)
+	if c.err != nil || recorder.discarded {
+		return &singleFlightResult{}, nil
+	}
+	if recorder.code == 0 {
+		recorder.record(http.StatusOK)
+	}
+	return &singleFlightResult{
+		shareable:     true,
+		requestHeader: c.r.Header,
+		code:          recorder.code,
+		header:        recorder.header,
+		body:          recorder.body.Bytes(),
+	}, nil
+}
+
+// singleFlightResult is the recorded response shared by a single flight call.
+//
+// It's read-only after being returned by singleFlightCall.lead.
+type singleFlightResult struct {
+	shareable     bool
+	requestHeader http.Header
+	code          int
+	// header only contains the headers set by the next handler.
+	header http.Header
+	body   []byte
+}
+
+// shareableWith returns whether the recorded response can be shared with r.
+func (res *singleFlightResult) shareableWith(r *http.Request) bool {
+	if !res.shareable || isHeaderSet(res.header, "Set-Cookie") {
+		return false
+	}
+	for _, v := range res.header.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			if name == "*" {
+				return false
+			}
+			if !slices.Equal(res.requestHeader.Values(name), r.Header.Values(name)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// replay writes the recorded response to w.
+//
+// Only the headers set by the next handler are copied,
+// the headers already set on w (e.g. by the outer middlewares) are kept.
+func (res *singleFlightResult) replay(w http.ResponseWriter) {
+	h := w.Header()
+	for k, v := range res.header {
+		h[k] = slices.Clone(v)
+	}
+	w.WriteHeader(res.code)
+	w.Write(res.body)
+}
+
+// singleFlightRecorder writes the response to the underlying
+// http.ResponseWriter, while recording up to maxSize bytes of it.
+type singleFlightRecorder struct {
+	http.ResponseWriter
+
+	maxSize int
+	// before is the snapshot of the headers before calling the next handler.
+	before http.Header
+	code   int
+	// header is the headers set by the next handler.
+	header http.Header
+	body   bytes.Buffer
+	// discarded is set when the response exceeded maxSize or failed to be
+	// written, so it can't be replayed.
+	discarded bool
+}
+
+func (r *singleFlightRecorder) record(code int) {
+	if r.code != 0 {
+		return
+	}
+	r.code = code
+	r.header = make(http.Header)
+	for k, v := range r.ResponseWriter.Header() {
+		if !slices.Equal(r.before[k], v) {
+			r.header[k] = slices.Clone(v)
+		}
+	}
+}
+
+func (r *singleFlightRecorder) WriteHeader(code int) {
+	r.record(code)
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *singleFlightRecorder) Write(b []byte) (int, error) {
+	r.record(http.StatusOK)
+	n, err := r.ResponseWriter.Write(b)
+	if err != nil {
+		r.discarded = true
+	}
+	if !r.discarded {
+		if r.body.Len()+n > r.maxSize {
+			r.discarded = true
+			r.body = bytes.Buffer{}
+		} else {
+			r.body.Write(b[:n])
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,278 @@
+package httpbp_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/reddit/baseplate.go/httpbp"
+)
+
+func TestSingleFlight(t *testing.T) {
+	t.Parallel()
+
+	const waiters = 3
+
+	pathKey := func(r *http.Request) string {
+		return r.URL.Path
+	}
+	respond := func(w http.ResponseWriter, r *http.Request) error {
+		w.Header().Set("X-Foo", "bar")
+		w.WriteHeader(http.StatusCreated)
+		_, err := io.WriteString(w, "hello")
+		return err
+	}
+	get := func(header ...string) func() *http.Request {
+		return func() *http.Request {
+			r := httptest.NewRequest(http.MethodGet, "/foo", nil)
+			for i := 0; i+1 < len(header); i += 2 {
+				r.Header.Set(header[i], header[i+1])
+			}
+			return r
+		}
+	}
+
+	for _, c := range []struct {
+		label      string
+		args       httpbp.SingleFlightArgs
+		respond    func(w http.ResponseWriter, r *http.Request) error
+		leader     func() *http.Request
+		waiter     func() *http.Request
+		expected   int64
+		checkResps bool
+	}{
+		{
+			label:      "coalesced",
+			args:       httpbp.SingleFlightArgs{KeyFunc: pathKey},
+			respond:    respond,
+			leader:     get(),
+			waiter:     get(),
+			expected:   1,
+			checkResps: true,
+		},
+		{
+			label:   "post",
+			args:    httpbp.SingleFlightArgs{KeyFunc: pathKey},
+			respond: respond,
+			leader: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/foo", nil)
+			},
+			waiter: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/foo", nil)
+			},
+			expected: 1 + waiters,
+		},
+		{
+			label:    "authorization",
+			args:     httpbp.SingleFlightArgs{KeyFunc: pathKey},
+			respond:  respond,
+			leader:   get("Authorization", "Bearer foo"),
+			waiter:   get("Authorization", "Bearer foo"),
+			expected: 1 + waiters,
+		},
+		{
+			label:    "edge-context",
+			args:     httpbp.SingleFlightArgs{KeyFunc: pathKey},
+			respond:  respond,
+			leader:   get(httpbp.EdgeContextHeader, "user-a"),
+			waiter:   get(httpbp.EdgeContextHeader, "user-b"),
+			expected: 1 + waiters,
+		},
+		{
+			label: "empty-key",
+			args: httpbp.SingleFlightArgs{KeyFunc: func(*http.Request) string {
+				return ""
+			}},
+			respond:  respond,
+			leader:   get(),
+			waiter:   get(),
+			expected: 1 + waiters,
+		},
+		{
+			label: "vary-same",
+			args:  httpbp.SingleFlightArgs{KeyFunc: pathKey},
+			respond: func(w http.ResponseWriter, r *http.Request) error {
+				w.Header().Set("Vary", "Accept-Language")
+				return respond(w, r)
+			},
+			leader:   get("Accept-Language", "en"),
+			waiter:   get("Accept-Language", "en"),
+			expected: 1,
+		},
+		{
+			label: "vary-different",
+			args:  httpbp.SingleFlightArgs{KeyFunc: pathKey},
+			respond: func(w http.ResponseWriter, r *http.Request) error {
+				w.Header().Set("Vary", "Accept-Language")
+				return respond(w, r)
+			},
+			leader:   get("Accept-Language", "en"),
+			waiter:   get("Accept-Language", "fr"),
+			expected: 1 + waiters,
+		},
+		{
+			label: "set-cookie",
+			args:  httpbp.SingleFlightArgs{KeyFunc: pathKey},
+			respond: func(w http.ResponseWriter, r *http.Request) error {
+				w.Header().Set("Set-Cookie", "foo=bar")
+				return respond(w, r)
+			},
+			leader:   get(),
+			waiter:   get(),
+			expected: 1 + waiters,
+		},
+		{
+			label: "too-large",
+			args: httpbp.SingleFlightArgs{
+				KeyFunc:         pathKey,
+				MaxResponseSize: 3,
+			},
+			respond:  respond,
+			leader:   get(),
+			waiter:   get(),
+			expected: 1 + waiters,
+		},
+		{
+			label: "error",
+			args:  httpbp.SingleFlightArgs{KeyFunc: pathKey},
+			respond: func(w http.ResponseWriter, r *http.Request) error {
+				return errors.New("foo")
+			},
+			leader:   get(),
+			waiter:   get(),
+			expected: 1 + waiters,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			t.Parallel()
+
+			var calls atomic.Int64
+			started := make(chan struct{})
+			release := make(chan struct{})
+			var requests atomic.Int64
+			handle := httpbp.Wrap(
+				"test",
+				func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+					if calls.Add(1) == 1 {
+						close(started)
+						<-release
+					}
+					return c.respond(w, r)
+				},
+				func(name string, next httpbp.HandlerFunc) httpbp.HandlerFunc {
+					// An outer middleware setting a per-request header.
+					return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+						w.Header().Set("X-Request", strconv.FormatInt(requests.Add(1), 10))
+						return next(ctx, w, r)
+					}
+				},
+				httpbp.SingleFlightWithArgs(c.args),
+			)
+
+			recorders := make([]*httptest.ResponseRecorder, 1+waiters)
+			var wg sync.WaitGroup
+			serve := func(i int, r *http.Request) {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					recorders[i] = httptest.NewRecorder()
+					handle(r.Context(), recorders[i], r)
+				}()
+			}
+			serve(0, c.leader())
+			<-started
+			for i := 1; i <= waiters; i++ {
+				serve(i, c.waiter())
+			}
+			// Give the waiters time to join the in-flight call.
+			time.Sleep(100 * time.Millisecond)
+			close(release)
+			wg.Wait()
+
+			if got := calls.Load(); got != c.expected {
+				t.Errorf("Expected handler to be called %d times, got %d", c.expected, got)
+			}
+			if !c.checkResps {
+				return
+			}
+			seen := make(map[string]bool)
+			for i, w := range recorders {
+				id := w.Header().Get("X-Request")
+				if id == "" || seen[id] {
+					t.Errorf("#%d: Expected unique X-Request header from the outer middleware, got %q", i, id)
+				}
+				seen[id] = true
+				if w.Code != http.StatusCreated {
+					t.Errorf("#%d: Expected code %d, got %d", i, http.StatusCreated, w.Code)
+				}
+				if got := w.Header().Get("X-Foo"); got != "bar" {
+					t.Errorf("#%d: Expected X-Foo header %q, got %q", i, "bar", got)
+				}
+				if got := w.Body.String(); got != "hello" {
+					t.Errorf("#%d: Expected body %q, got %q", i, "hello", got)
+				}
+			}
+		})
+	}
+}
+
+func TestSingleFlightWaiterContext(t *testing.T) {
+	t.Parallel()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	handle := httpbp.Wrap(
+		"test",
+		func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			close(started)
+			<-release
+			return nil
+		},
+		httpbp.SingleFlight(func(r *http.Request) string {
+			return r.URL.Path
+		}),
+	)
+
+	go func() {
+		r := httptest.NewRequest(http.MethodGet, "/foo", nil)
+		handle(r.Context(), httptest.NewRecorder(), r)
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	r := httptest.NewRequest(http.MethodGet, "/foo", nil).WithContext(ctx)
+	if err := handle(ctx, httptest.NewRecorder(), r); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestSingleFlightPanic(t *testing.T) {
+	t.Parallel()
+
+	handle := httpbp.Wrap(
+		"test",
+		func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			panic("oops")
+		},
+		httpbp.SingleFlight(func(r *http.Request) string {
+			return r.URL.Path
+		}),
+	)
+
+	defer func() {
+		if r := recover(); r != "oops" {
+			t.Errorf("Expected the panic to be re-raised in the request goroutine, got %v", r)
+		}
+	}()
+	r := httptest.NewRequest(http.MethodGet, "/foo", nil)
+	handle(r.Context(), httptest.NewRecorder(), r)
+}
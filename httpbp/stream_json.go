@@ -0,0 +1,210 @@
+package httpbp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// streamJSONFlushInterval is the number of items StreamJSONArray writes
+// between flushes.
+const streamJSONFlushInterval = 100
+
+// StreamJSONArray writes the items received from the channel as a JSON array,
+// encoding each item as it arrives instead of marshaling the whole array into
+// memory first.
+//
+// It sets the Content-Type header to JSONContentType, and returns after items
+// is closed and the closing bracket is written.
+// The response is flushed to the client periodically if w implements
+// http.Flusher.
+//
+// As the status code and headers are already sent when the first item is
+// written, errors happened mid-stream (for example an item failed to be
+// marshaled, or ctx is canceled before items is closed) can't be communicated
+// to the client via the status code. In those cases the error is returned,
+// and the client will receive an incomplete, thus invalid, JSON array.
+// Callers should not try to write an error response after StreamJSONArray
+// returned an error.
+//
+// It's the caller's responsibility to close items, and to stop sending into it
+// after StreamJSONArray returned.
+func StreamJSONArray(ctx context.Context, w http.ResponseWriter, items <-chan any) error {
+	w.Header().Set(ContentTypeHeader, JSONContentType)
+	flusher, _ := w.(http.Flusher)
+	bw := bufio.NewWriter(w)
+	flush := func() error {
+		if err := bw.Flush(); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	}
+
+	if err := bw.WriteByte('['); err != nil {
+		return err
+	}
+	for n := 0; ; n++ {
+		var item any
+		var ok bool
+		select {
+		case <-ctx.Done():
+			return errors.Join(ctx.Err(), bw.Flush())
+		case item, ok = <-items:
+		}
+		if !ok {
+			break
+		}
+
+		data, err := json.Marshal(item)
+		if err != nil {
+			return errors.Join(
+				fmt.Errorf("httpbp: failed to marshal json array item #%d: %w", n, err),
+				bw.Flush(),
+			)
+		}
+		if n > 0 {
+			if err := bw.WriteByte(','); err != nil {
+				return err
+			}
+		}
+		if _, err := bw.Write(data); err != nil {
+			return err
+		}
+		if (n+1)%streamJSONFlushInterval == 0 {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if _, err := bw.WriteString("]\n"); err != nil {
+		return err
+	}
+	return flush()
+}
+
+// NDJSONWriter writes records as newline-delimited JSON (NDJSON),
+// one JSON object per line,
+// so the clients can process the records incrementally.
+//
+// It's useful when the records come from an iterator:
+// call Write for each record and Close when done.
+// For records coming from a channel, StreamNDJSON can be used instead.
+//
+// As the status code and headers are already sent when the first record is
+// written, errors happened mid-stream can't be communicated to the client via
+// the status code.
+// In those cases the error is returned and the client will receive a truncated
+// stream, which might end with a partial line.
+// Callers should not try to write an error response after a Write call
+// returned an error.
+//
+// NDJSONWriter is not thread-safe.
+type NDJSONWriter struct {
+	bw      *bufio.Writer
+	flusher http.Flusher
+	n       int
+	err     error
+}
+
+// NewNDJSONWriter creates an NDJSONWriter writing into w,
+// and sets the Content-Type header to NDJSONContentType.
+//
+// The response is flushed to the client periodically if w implements
+// http.Flusher.
+func NewNDJSONWriter(w http.ResponseWriter) *NDJSONWriter {
+	w.Header().Set(ContentTypeHeader, NDJSONContentType)
+	flusher, _ := w.(http.Flusher)
+	return &NDJSONWriter{
+		bw:      bufio.NewWriter(w),
+		flusher: flusher,
+	}
+}
+
+// Write writes record as a single line of JSON.
+//
+// Once it returned an error, the writer is broken and all the subsequent calls
+// return the same error.
+func (nw *NDJSONWriter) Write(record any) error {
+	if nw.err != nil {
+		return nw.err
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		// Make sure the records written before are sent to the client.
+		nw.err = errors.Join(
+			fmt.Errorf("httpbp: failed to marshal ndjson record #%d: %w", nw.n, err),
+			nw.Flush(),
+		)
+		return nw.err
+	}
+	if _, err := nw.bw.Write(data); err != nil {
+		nw.err = err
+		return err
+	}
+	if err := nw.bw.WriteByte('\n'); err != nil {
+		nw.err = err
+		return err
+	}
+	nw.n++
+	if nw.n%streamJSONFlushInterval == 0 {
+		return nw.Flush()
+	}
+	return nil
+}
+
+// Flush sends the buffered records to the client.
+func (nw *NDJSONWriter) Flush() error {
+	if err := nw.bw.Flush(); err != nil {
+		if nw.err == nil {
+			nw.err = err
+		}
+		return err
+	}
+	if nw.flusher != nil {
+		nw.flusher.Flush()
+	}
+	return nil
+}
+
+// Close flushes the remaining buffered records.
+//
+// It does not close the underlying http.ResponseWriter.
+func (nw *NDJSONWriter) Close() error {
+	if nw.err != nil {
+		return nw.err
+	}
+	return nw.Flush()
+}
+
+// StreamNDJSON writes the records received from the channel as
+// newline-delimited JSON via NDJSONWriter,
+// and returns after records is closed and all the records are flushed.
+//
+// If ctx is canceled before records is closed,
+// the records written so far are flushed and the ctx error is returned.
+// See NDJSONWriter for the caveats about the errors happened mid-stream.
+//
+// It's the caller's responsibility to close records, and to stop sending into
+// it after StreamNDJSON returned.
+func StreamNDJSON(ctx context.Context, w http.ResponseWriter, records <-chan any) error {
+	nw := NewNDJSONWriter(w)
+	for {
+		select {
+		case <-ctx.Done():
+			return errors.Join(ctx.Err(), nw.Close())
+		case record, ok := <-records:
+			if !ok {
+				return nw.Close()
+			}
+			if err := nw.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package httpbp_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/reddit/baseplate.go/httpbp"
+)
+
+func TestStreamJSONArray(t *testing.T) {
+	stream := func(ctx context.Context, values ...any) (*httptest.ResponseRecorder, error) {
+		items := make(chan any, len(values))
+		for _, v := range values {
+			items <- v
+		}
+		close(items)
+		w := httptest.NewRecorder()
+		return w, httpbp.StreamJSONArray(ctx, w, items)
+	}
+
+	t.Run("items", func(t *testing.T) {
+		values := make([]any, 250)
+		for i := range values {
+			values[i] = map[string]int{"id": i}
+		}
+		w, err := stream(context.Background(), values...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ct := w.Header().Get(httpbp.ContentTypeHeader); ct != httpbp.JSONContentType {
+			t.Errorf("Expected content type %q, got %q", httpbp.JSONContentType, ct)
+		}
+		var got []map[string]int
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Fatalf("Invalid json array %q: %v", w.Body.String(), err)
+		}
+		if len(got) != len(values) || got[249]["id"] != 249 {
+			t.Errorf("Unexpected decoded array: %v", got)
+		}
+		if !w.Flushed {
+			t.Error("Expected response to be flushed")
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		w, err := stream(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.TrimSpace(w.Body.String()); got != "[]" {
+			t.Errorf("Expected %q, got %q", "[]", got)
+		}
+	})
+
+	t.Run("marshal-error", func(t *testing.T) {
+		w, err := stream(context.Background(), 1, make(chan int))
+		if err == nil {
+			t.Fatal("Expected error for unmarshalable item")
+		}
+		if got := w.Body.String(); got != "[1" {
+			t.Errorf("Expected incomplete body %q, got %q", "[1", got)
+		}
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		w := httptest.NewRecorder()
+		if err := httpbp.StreamJSONArray(ctx, w, make(chan any)); err == nil {
+			t.Fatal("Expected error for canceled context")
+		}
+	})
+}
+
+func TestStreamNDJSON(t *testing.T) {
+	stream := func(ctx context.Context, values ...any) (*httptest.ResponseRecorder, error) {
+		records := make(chan any, len(values))
+		for _, v := range values {
+			records <- v
+		}
+		close(records)
+		w := httptest.NewRecorder()
+		return w, httpbp.StreamNDJSON(ctx, w, records)
+	}
+
+	t.Run("records", func(t *testing.T) {
+		values := make([]any, 250)
+		for i := range values {
+			values[i] = map[string]int{"id": i}
+		}
+		w, err := stream(context.Background(), values...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ct := w.Header().Get(httpbp.ContentTypeHeader); ct != httpbp.NDJSONContentType {
+			t.Errorf("Expected content type %q, got %q", httpbp.NDJSONContentType, ct)
+		}
+		lines := strings.Split(strings.TrimSuffix(w.Body.String(), "\n"), "\n")
+		if len(lines) != len(values) {
+			t.Fatalf("Expected %d lines, got %d", len(values), len(lines))
+		}
+		for i, line := range lines {
+			var got map[string]int
+			if err := json.Unmarshal([]byte(line), &got); err != nil {
+				t.Fatalf("Invalid json line #%d %q: %v", i, line, err)
+			}
+			if got["id"] != i {
+				t.Errorf("Expected line #%d to have id %d, got %v", i, i, got)
+			}
+		}
+		if !w.Flushed {
+			t.Error("Expected response to be flushed")
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		w, err := stream(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := w.Body.String(); got != "" {
+			t.Errorf("Expected empty body, got %q", got)
+		}
+	})
+
+	t.Run("marshal-error", func(t *testing.T) {
+		w, err := stream(context.Background(), 1, make(chan int), 2)
+		if err == nil {
+			t.Fatal("Expected error for unmarshalable record")
+		}
+		if got := w.Body.String(); got != "1\n" {
+			t.Errorf("Expected truncated body %q, got %q", "1\n", got)
+		}
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		w := httptest.NewRecorder()
+		if err := httpbp.StreamNDJSON(ctx, w, make(chan any)); !errors.Is(err, context.Canceled) {
+			t.Fatalf("Expected context.Canceled, got %v", err)
+		}
+	})
+}
+
+func TestNDJSONWriterBroken(t *testing.T) {
+	w := httptest.NewRecorder()
+	nw := httpbp.NewNDJSONWriter(w)
+	if err := nw.Write(1); err != nil {
+		t.Fatal(err)
+	}
+	err := nw.Write(make(chan int))
+	if err == nil {
+		t.Fatal("Expected error for unmarshalable record")
+	}
+	if got := nw.Write(2); got != err {
+		t.Errorf("Expected subsequent Write to return %v, got %v", err, got)
+	}
+	if got := nw.Close(); got != err {
+		t.Errorf("Expected Close to return %v, got %v", err, got)
+	}
+	if got := w.Body.String(); got != "1\n" {
+		t.Errorf("Expected truncated body %q, got %q", "1\n", got)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"github.com/reddit/baseplate.go/log"
+	"github.com/reddit/baseplate.go/prometheusbp"
 )
 
 // Constants regarding the admin port
@@ -38,7 +39,7 @@ func init() {
 	Mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
 	Mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
 
-	Mux.Handle("/metrics", promhttp.Handler())
+	Mux.Handle("/metrics", metricsHandler())
 
 	// Unregister the default GoCollector, and reregister with baseplate defaults
 	if prometheus.Unregister(collectors.NewGoCollector()) {
@@ -47,6 +48,28 @@ func init() {
 	}
 }
 
+// metricsHandler returns the handler serving /metrics.
+//
+// When exemplars are enabled via prometheusbp.EnableExemplars,
+// the metrics are also served in the OpenMetrics format when requested by the
+// scraper, as exemplars are only exposed in that format.
+func metricsHandler() http.Handler {
+	plain := promhttp.Handler()
+	openMetrics := promhttp.InstrumentMetricHandler(
+		prometheus.DefaultRegisterer,
+		promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{
+			EnableOpenMetrics: true,
+		}),
+	)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if prometheusbp.ExemplarsEnabled() {
+			openMetrics.ServeHTTP(w, r)
+			return
+		}
+		plain.ServeHTTP(w, r)
+	})
+}
+
 // Serve the admin http server.
 func Serve() error {
 	addr := DefaultPort
@@ -0,0 +1,76 @@
+package iobp
+
+import (
+	"bytes"
+	"sync"
+)
+
+// Pool is a typed, sync.Pool backed pool of reusable values,
+// e.g. buffers or the encoders writing into them.
+//
+// A Pool must not be copied after first use.
+type Pool[T any] struct {
+	// New creates a new value when the pool is empty.
+	//
+	// Optional. When it's nil Get returns the zero value of T from an empty
+	// pool.
+	New func() T
+
+	pool sync.Pool
+}
+
+// Get returns a value from the pool, or a new one if the pool is empty.
+func (p *Pool[T]) Get() T {
+	if v, ok := p.pool.Get().(T); ok {
+		return v
+	}
+	if p.New != nil {
+		return p.New()
+	}
+	var zero T
+	return zero
+}
+
+// Put puts v back into the pool.
+//
+// The caller must not use v after calling Put.
+func (p *Pool[T]) Put(v T) {
+	p.pool.Put(v)
+}
+
+// BufferPool is a sync.Pool backed pool of *bytes.Buffer.
+//
+// Buffers grown larger than MaxCap are not put back into the pool,
+// so a few large payloads won't keep large buffers alive in the pool forever.
+//
+// The zero value is ready to use, with no limit on the buffer sizes.
+// A BufferPool must not be copied after first use.
+type BufferPool struct {
+	// MaxCap is the maximal capacity of the buffers to be put back into the
+	// pool. Buffers with larger capacity are dropped by Put.
+	//
+	// Optional. <=0 means no limit.
+	MaxCap int
+
+	pool Pool[*bytes.Buffer]
+}
+
+// Get returns an empty buffer from the pool, or a new one if the pool is empty.
+func (p *BufferPool) Get() *bytes.Buffer {
+	if buf := p.pool.Get(); buf != nil {
+		buf.Reset()
+		return buf
+	}
+	return new(bytes.Buffer)
+}
+
+// Put puts buf back into the pool.
+//
+// The caller must not use buf after calling Put.
+// Put on nil buffer is no-op.
+func (p *BufferPool) Put(buf *bytes.Buffer) {
+	if buf == nil || (p.MaxCap > 0 && buf.Cap() > p.MaxCap) {
+		return
+	}
+	p.pool.Put(buf)
+}
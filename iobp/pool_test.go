@@ -0,0 +1,69 @@
+package iobp_test
+
+import (
+	"testing"
+
+	"github.com/reddit/baseplate.go/iobp"
+)
+
+func TestBufferPool(t *testing.T) {
+	t.Run("reset", func(t *testing.T) {
+		var pool iobp.BufferPool
+		for i := 0; i < 10; i++ {
+			buf := pool.Get()
+			if buf.Len() != 0 {
+				t.Fatalf("Expected empty buffer from Get, got %q", buf.String())
+			}
+			buf.WriteString("foo")
+			pool.Put(buf)
+		}
+	})
+
+	t.Run("max-cap", func(t *testing.T) {
+		const maxCap = 64
+		pool := iobp.BufferPool{MaxCap: maxCap}
+		buf := pool.Get()
+		buf.Grow(maxCap * 4)
+		pool.Put(buf)
+		for i := 0; i < 10; i++ {
+			if buf := pool.Get(); buf.Cap() > maxCap {
+				t.Errorf("Expected buffers larger than %d to be dropped, got cap %d", maxCap, buf.Cap())
+			}
+		}
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		var pool iobp.BufferPool
+		pool.Put(nil)
+		if buf := pool.Get(); buf == nil {
+			t.Error("Expected non-nil buffer from Get")
+		}
+	})
+}
+
+func TestPool(t *testing.T) {
+	t.Run("new", func(t *testing.T) {
+		var created int
+		pool := iobp.Pool[*int]{
+			New: func() *int {
+				created++
+				return new(int)
+			},
+		}
+		v := pool.Get()
+		if v == nil || created != 1 {
+			t.Fatalf("Expected a new value from New, got %v with %d created", v, created)
+		}
+		pool.Put(v)
+		if v := pool.Get(); v == nil {
+			t.Error("Expected non-nil value from Get")
+		}
+	})
+
+	t.Run("no-new", func(t *testing.T) {
+		var pool iobp.Pool[*int]
+		if v := pool.Get(); v != nil {
+			t.Errorf("Expected nil from empty pool without New, got %v", v)
+		}
+	})
+}
@@ -0,0 +1,115 @@
+package baseplate
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// LifecycleHook is a function that can be registered to a Baseplate via
+// OnStart or OnShutdown.
+type LifecycleHook func(ctx context.Context) error
+
+// lifecycle holds the registered start and shutdown hooks of a Baseplate.
+//
+// It's safe for concurrent use.
+type lifecycle struct {
+	lock     sync.Mutex
+	start    []LifecycleHook
+	shutdown []LifecycleHook
+
+	shutdownDone bool
+}
+
+func (l *lifecycle) onStart(hook LifecycleHook) {
+	if hook == nil {
+		return
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.start = append(l.start, hook)
+}
+
+func (l *lifecycle) onShutdown(hook LifecycleHook) {
+	if hook == nil {
+		return
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.shutdown = append(l.shutdown, hook)
+}
+
+// runStart runs all the start hooks in the order they were registered.
+func (l *lifecycle) runStart(ctx context.Context) error {
+	l.lock.Lock()
+	hooks := make([]LifecycleHook, len(l.start))
+	copy(hooks, l.start)
+	l.lock.Unlock()
+
+	errs := make([]error, 0, len(hooks))
+	for _, hook := range hooks {
+		errs = append(errs, hook(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+// runShutdown runs all the shutdown hooks in the reverse order they were
+// registered.
+//
+// The hooks are only run on the first call, subsequent calls are no-ops.
+func (l *lifecycle) runShutdown(ctx context.Context) error {
+	l.lock.Lock()
+	if l.shutdownDone {
+		l.lock.Unlock()
+		return nil
+	}
+	l.shutdownDone = true
+	hooks := make([]LifecycleHook, len(l.shutdown))
+	copy(hooks, l.shutdown)
+	l.lock.Unlock()
+
+	errs := make([]error, 0, len(hooks))
+	for i := len(hooks) - 1; i >= 0; i-- {
+		errs = append(errs, hooks[i](ctx))
+	}
+	return errors.Join(errs...)
+}
+
+type lifecycleRunner interface {
+	runStartHooks(ctx context.Context) error
+	runShutdownHooks(ctx context.Context) error
+}
+
+// RunStartHooks runs all the hooks registered via bp.OnStart,
+// in the order they were registered.
+//
+// All hooks are run even if some of them failed,
+// and the errors are joined together.
+//
+// You generally don't need to call this directly,
+// Serve of the servers returned by httpbp.NewBaseplateServer and
+// thriftbp.NewBaseplateServer calls it before starting to serve.
+func RunStartHooks(ctx context.Context, bp Baseplate) error {
+	if runner, ok := bp.(lifecycleRunner); ok {
+		return runner.runStartHooks(ctx)
+	}
+	return nil
+}
+
+// RunShutdownHooks runs all the hooks registered via bp.OnShutdown,
+// in the reverse order they were registered.
+// The hooks are only run once, subsequent calls return nil without running
+// them again.
+//
+// All hooks are run even if some of them failed,
+// and the errors are joined together.
+//
+// You generally don't need to call this directly,
+// Close of the servers returned by httpbp.NewBaseplateServer and
+// thriftbp.NewBaseplateServer calls it after the server is stopped.
+func RunShutdownHooks(ctx context.Context, bp Baseplate) error {
+	if runner, ok := bp.(lifecycleRunner); ok {
+		return runner.runShutdownHooks(ctx)
+	}
+	return nil
+}
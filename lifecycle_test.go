@@ -0,0 +1,78 @@
+package baseplate_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/reddit/baseplate.go"
+	"github.com/reddit/baseplate.go/ecinterface"
+)
+
+func TestLifecycleHooks(t *testing.T) {
+	t.Parallel()
+
+	store := newSecretsStore(t)
+	defer store.Close()
+
+	bp := baseplate.NewTestBaseplate(baseplate.NewTestBaseplateArgs{
+		Store:           store,
+		EdgeContextImpl: ecinterface.Mock(),
+	})
+
+	var calls []string
+	hook := func(name string, err error) baseplate.LifecycleHook {
+		return func(context.Context) error {
+			calls = append(calls, name)
+			return err
+		}
+	}
+	errStart := errors.New("start error")
+	errShutdown1 := errors.New("shutdown error 1")
+	errShutdown2 := errors.New("shutdown error 2")
+
+	bp.OnStart(hook("start1", nil))
+	bp.OnStart(hook("start2", errStart))
+	bp.OnStart(hook("start3", nil))
+	bp.OnShutdown(hook("shutdown1", errShutdown1))
+	bp.OnShutdown(hook("shutdown2", nil))
+	bp.OnShutdown(hook("shutdown3", errShutdown2))
+
+	t.Run("start", func(t *testing.T) {
+		calls = nil
+		err := baseplate.RunStartHooks(context.Background(), bp)
+		if !errors.Is(err, errStart) {
+			t.Errorf("Expected error to be %v, got %v", errStart, err)
+		}
+		want := []string{"start1", "start2", "start3"}
+		if !reflect.DeepEqual(calls, want) {
+			t.Errorf("Expected calls %v, got %v", want, calls)
+		}
+	})
+
+	t.Run("shutdown", func(t *testing.T) {
+		calls = nil
+		err := baseplate.RunShutdownHooks(context.Background(), bp)
+		if !errors.Is(err, errShutdown1) {
+			t.Errorf("Expected error to contain %v, got %v", errShutdown1, err)
+		}
+		if !errors.Is(err, errShutdown2) {
+			t.Errorf("Expected error to contain %v, got %v", errShutdown2, err)
+		}
+		want := []string{"shutdown3", "shutdown2", "shutdown1"}
+		if !reflect.DeepEqual(calls, want) {
+			t.Errorf("Expected calls %v, got %v", want, calls)
+		}
+	})
+
+	t.Run("shutdown-again", func(t *testing.T) {
+		calls = nil
+		if err := baseplate.RunShutdownHooks(context.Background(), bp); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+		if len(calls) != 0 {
+			t.Errorf("Expected no calls, got %v", calls)
+		}
+	})
+}
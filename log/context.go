@@ -5,7 +5,9 @@ import (
 	"fmt"
 
 	"github.com/getsentry/sentry-go"
+	"github.com/opentracing/opentracing-go"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 
 	"github.com/reddit/baseplate.go/detach"
 	//lint:ignore SA1019 This library is internal only, not actually deprecated
@@ -108,3 +110,55 @@ func C(ctx context.Context) *zap.SugaredLogger {
 	}
 	return internalv2compat.GlobalLogger()
 }
+
+// sampledSpan is the interface implemented by *tracing.Span,
+// defined here to avoid import cycles.
+type sampledSpan interface {
+	ShouldSample() bool
+}
+
+// ShouldDebug returns true if the span attached to the context object is
+// sampled, or has the debug flag set.
+//
+// It returns false when there's no span attached,
+// or the span is not a *tracing.Span.
+//
+// It can be used to only do verbose logging for the sampled requests,
+// so they are both traced and verbosely logged.
+// See DebugwIfSampled for the common case.
+func ShouldDebug(ctx context.Context) bool {
+	span, ok := opentracing.SpanFromContext(ctx).(sampledSpan)
+	return ok && span.ShouldSample()
+}
+
+// DebugwIfSampled logs a message with some additional context at DebugLevel
+// via C(ctx), only when ShouldDebug(ctx) returns true.
+//
+// The log is written regardless of the configured log level of the logger,
+// so the sampled requests can be debugged without enabling debug logs for all
+// the requests.
+func DebugwIfSampled(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	if ShouldDebug(ctx) {
+		C(ctx).WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return debugEnabledCore{Core: core}
+		})).Debugw(msg, keysAndValues...)
+	}
+}
+
+// debugEnabledCore is a zapcore.Core with all the levels enabled,
+// regardless of the level of the wrapped core.
+type debugEnabledCore struct {
+	zapcore.Core
+}
+
+func (c debugEnabledCore) Enabled(zapcore.Level) bool {
+	return true
+}
+
+func (c debugEnabledCore) With(fields []zapcore.Field) zapcore.Core {
+	return debugEnabledCore{Core: c.Core.With(fields)}
+}
+
+func (c debugEnabledCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return ce.AddCore(ent, c)
+}
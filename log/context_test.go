@@ -0,0 +1,82 @@
+package log_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	//lint:ignore SA1019 This library is internal only, not actually deprecated
+	"github.com/reddit/baseplate.go/internalv2compat"
+	"github.com/reddit/baseplate.go/log"
+	"github.com/reddit/baseplate.go/tracing"
+)
+
+func TestShouldDebug(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	original := internalv2compat.GlobalLogger()
+	internalv2compat.SetGlobalLogger(zap.New(core).Sugar())
+	t.Cleanup(func() {
+		internalv2compat.SetGlobalLogger(original)
+	})
+
+	sampled := true
+	notSampled := false
+	for _, c := range []struct {
+		label    string
+		headers  *tracing.Headers
+		expected bool
+	}{
+		{
+			label:    "no-span",
+			expected: false,
+		},
+		{
+			label: "not-sampled",
+			headers: &tracing.Headers{
+				TraceID: "1",
+				Sampled: &notSampled,
+			},
+			expected: false,
+		},
+		{
+			label: "sampled",
+			headers: &tracing.Headers{
+				TraceID: "1",
+				Sampled: &sampled,
+			},
+			expected: true,
+		},
+		{
+			label: "debug",
+			headers: &tracing.Headers{
+				TraceID: "1",
+				Sampled: &notSampled,
+				Flags:   strconv.FormatInt(tracing.FlagMaskDebug, 10),
+			},
+			expected: true,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			ctx := context.Background()
+			if c.headers != nil {
+				ctx, _ = tracing.StartSpanFromHeaders(ctx, "foo", *c.headers)
+			}
+			if got := log.ShouldDebug(ctx); got != c.expected {
+				t.Errorf("Expected ShouldDebug to return %v, got %v", c.expected, got)
+			}
+			// The sampled requests should be logged even at InfoLevel.
+			log.DebugwIfSampled(ctx, "test", "key", "value")
+			if got := logs.TakeAll(); (len(got) == 1) != c.expected {
+				t.Errorf("Expected debug log written to be %v, got %d logs", c.expected, len(got))
+			}
+			// The level of the logger should not be changed.
+			log.C(ctx).Debugw("test", "key", "value")
+			if n := logs.Len(); n != 0 {
+				t.Errorf("Expected no debug logs from the original logger, got %d", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package log
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	//lint:ignore SA1019 This library is internal only, not actually deprecated
+	"github.com/reddit/baseplate.go/internalv2compat"
+)
+
+// FailOnErrorWrapper is a wrapper can be used in test codes to enforce that
+// the code under test does not log any errors.
+//
+// It replaces the global logger with one writing all the logs to tb.Log,
+// and fails the test via tb.Errorf when anything is logged at error level or
+// above, via either the global logger, C(ctx), or the returned Wrapper
+// (which logs at error level).
+// The original global logger is restored when the test finishes.
+//
+// It's safe to be used from the goroutines spawned during the test,
+// logs emitted after the test finished are dropped.
+// As it replaces the global logger,
+// it should not be used in parallel tests.
+func FailOnErrorWrapper(tb testing.TB) Wrapper {
+	tb.Helper()
+
+	w := &failOnErrorWriter{tb: tb}
+	encoderConfig := zap.NewDevelopmentEncoderConfig()
+	encoderConfig.TimeKey = ""
+	core := zapcore.NewCore(
+		zapcore.NewConsoleEncoder(encoderConfig),
+		zapcore.AddSync(w),
+		zapcore.DebugLevel,
+	)
+	logger := zap.New(
+		wrappedCore{Core: core},
+		zap.Hooks(func(entry zapcore.Entry) error {
+			if entry.Level >= zapcore.ErrorLevel {
+				w.fail(entry.Message)
+			}
+			return nil
+		}),
+	).Sugar()
+
+	prev := internalv2compat.GlobalLogger()
+	internalv2compat.SetGlobalLogger(logger)
+	tb.Cleanup(func() {
+		w.finish()
+		internalv2compat.SetGlobalLogger(prev)
+	})
+
+	return func(ctx context.Context, msg string) {
+		C(ctx).Error(msg)
+	}
+}
+
+// failOnErrorWriter is the zapcore.WriteSyncer used by FailOnErrorWrapper.
+type failOnErrorWriter struct {
+	lock sync.Mutex
+	tb   testing.TB
+	done bool
+}
+
+func (w *failOnErrorWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if !w.done {
+		w.tb.Log(strings.TrimSuffix(string(p), "\n"))
+	}
+	return len(p), nil
+}
+
+func (w *failOnErrorWriter) fail(msg string) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if !w.done {
+		w.tb.Errorf("error-level log emitted: %q", msg)
+	}
+}
+
+func (w *failOnErrorWriter) finish() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.done = true
+}
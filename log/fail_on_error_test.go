@@ -0,0 +1,83 @@
+package log_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/reddit/baseplate.go/log"
+)
+
+// recordingTB is a testing.TB records the calls to Log and Errorf instead of
+// actually failing the test.
+type recordingTB struct {
+	testing.TB
+
+	lock     sync.Mutex
+	logs     []string
+	errors   []string
+	cleanups []func()
+}
+
+func (tb *recordingTB) Helper() {}
+
+func (tb *recordingTB) Log(args ...interface{}) {
+	tb.lock.Lock()
+	defer tb.lock.Unlock()
+	tb.logs = append(tb.logs, args[0].(string))
+}
+
+func (tb *recordingTB) Errorf(format string, args ...interface{}) {
+	tb.lock.Lock()
+	defer tb.lock.Unlock()
+	tb.errors = append(tb.errors, format)
+}
+
+func (tb *recordingTB) Cleanup(f func()) {
+	tb.cleanups = append(tb.cleanups, f)
+}
+
+func (tb *recordingTB) runCleanups() {
+	for i := len(tb.cleanups) - 1; i >= 0; i-- {
+		tb.cleanups[i]()
+	}
+}
+
+func TestFailOnErrorWrapper(t *testing.T) {
+	tb := &recordingTB{TB: t}
+	wrapper := log.FailOnErrorWrapper(tb)
+
+	log.Debugw("debug")
+	log.C(context.Background()).Infow("info", "key", "value")
+	log.Warn("warn")
+	if len(tb.logs) != 3 {
+		t.Errorf("Expected 3 logs printed, got %q", tb.logs)
+	}
+	if len(tb.errors) != 0 {
+		t.Errorf("Expected no errors for lower levels, got %q", tb.errors)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			log.Errorw("error")
+		}()
+	}
+	wg.Wait()
+	if len(tb.errors) != 5 {
+		t.Errorf("Expected 5 errors, got %q", tb.errors)
+	}
+
+	wrapper.Log(context.Background(), "wrapper")
+	if len(tb.errors) != 6 {
+		t.Errorf("Expected 6 errors after calling the wrapper, got %q", tb.errors)
+	}
+
+	tb.runCleanups()
+	log.Errorw("after cleanup")
+	if len(tb.errors) != 6 {
+		t.Errorf("Expected no errors after cleanup, got %q", tb.errors)
+	}
+}
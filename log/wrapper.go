@@ -73,6 +73,8 @@ var DefaultWrapper Wrapper = ErrorWithSentryWrapper()
 //
 // For unit tests of library code using Wrapper,
 // TestWrapper is provided that would fail the test when Wrapper is called.
+// For tests that should fail on any error-level logs,
+// FailOnErrorWrapper is provided.
 //
 // Not all Wrapper implementations take advantage of context object passed in,
 // but the caller should always pass it into Wrapper if they already have one,
@@ -0,0 +1,99 @@
+package metricsbp
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/reddit/baseplate.go/internal/prometheusbpint"
+)
+
+// ErrOddLabelValues is the error returned by GaugeFunc when labelValues is not
+// in key-value pairs.
+var ErrOddLabelValues = errors.New("metricsbp: odd number of label values")
+
+type gaugeFunc struct {
+	gauge metrics.Gauge
+	f     func() float64
+}
+
+type gaugeFuncs struct {
+	lock  sync.Mutex
+	funcs []gaugeFunc
+}
+
+func (gf *gaugeFuncs) add(gauge metrics.Gauge, f func() float64) {
+	gf.lock.Lock()
+	defer gf.lock.Unlock()
+	gf.funcs = append(gf.funcs, gaugeFunc{gauge: gauge, f: f})
+}
+
+// update sets all the statsd gauges to the current values of their callbacks.
+func (gf *gaugeFuncs) update() {
+	gf.lock.Lock()
+	defer gf.lock.Unlock()
+	for _, g := range gf.funcs {
+		g.gauge.Set(g.f())
+	}
+}
+
+// GaugeFunc registers a gauge with its value pulled lazily from f.
+//
+// labelValues are in key-value pairs, the same as the ones passed into the
+// With function of the gauges returned by Gauge.
+//
+// f is called when prometheus scrapes the metrics, and right before the
+// buffered statsd metrics are reported, so there's no need to run a
+// goroutine just to keep the gauge current.
+// f must be safe to be called concurrently.
+//
+// For prometheus, the characters not allowed in prometheus metric and label
+// names (for example "." and "-") are replaced with "_",
+// and the gauge is registered into the same registry used by other baseplate
+// metrics.
+// It returns an error if the gauge with the same name and labels is already
+// registered.
+func (st *Statsd) GaugeFunc(name string, f func() float64, labelValues ...string) error {
+	st = st.fallback()
+	if len(labelValues)%2 != 0 {
+		return fmt.Errorf("%w: %d", ErrOddLabelValues, len(labelValues))
+	}
+
+	labels := make(prometheus.Labels, len(labelValues)/2)
+	for i := 0; i < len(labelValues); i += 2 {
+		labels[sanitizePrometheusName(labelValues[i])] = labelValues[i+1]
+	}
+	collector := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name:        sanitizePrometheusName(name),
+		Help:        "Gauge registered via metricsbp.Statsd.GaugeFunc: " + name,
+		ConstLabels: labels,
+	}, f)
+	if err := prometheusbpint.GlobalRegistry.Register(collector); err != nil {
+		return fmt.Errorf("metricsbp: failed to register gauge func %q: %w", name, err)
+	}
+
+	st.gaugeFuncs.add(st.Gauge(name).With(labelValues...), f)
+	return nil
+}
+
+// sanitizePrometheusName replaces all the characters not allowed in prometheus
+// metric and label names with "_".
+func sanitizePrometheusName(name string) string {
+	var sb strings.Builder
+	sb.Grow(len(name))
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_':
+			sb.WriteRune(r)
+		case r >= '0' && r <= '9' && i > 0:
+			sb.WriteRune(r)
+		default:
+			sb.WriteByte('_')
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,61 @@
+package metricsbp_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/reddit/baseplate.go/metricsbp"
+)
+
+func TestGaugeFunc(t *testing.T) {
+	st := metricsbp.NewStatsd(
+		context.Background(),
+		metricsbp.Config{
+			BufferInMemoryForTesting: true,
+		},
+	)
+
+	var value atomic.Int64
+	value.Store(42)
+	if err := st.GaugeFunc("test.gauge-func", func() float64 {
+		return float64(value.Load())
+	}, "pool", "foo"); err != nil {
+		t.Fatal(err)
+	}
+
+	const expected = `
+# HELP test_gauge_func Gauge registered via metricsbp.Statsd.GaugeFunc: test.gauge-func
+# TYPE test_gauge_func gauge
+test_gauge_func{baseplate_go="v0",pool="foo"} 42
+`
+	if err := testutil.GatherAndCompare(
+		prometheus.DefaultGatherer,
+		strings.NewReader(expected),
+		"test_gauge_func",
+	); err != nil {
+		t.Error(err)
+	}
+
+	value.Store(7)
+	var buf bytes.Buffer
+	if _, err := st.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if str, want := buf.String(), "test.gauge-func,pool=foo:7.000000|g"; !strings.Contains(str, want) {
+		t.Errorf("Expected %q in statsd output, got %q", want, str)
+	}
+
+	if err := st.GaugeFunc("test.gauge-func", func() float64 { return 0 }, "pool", "foo"); err == nil {
+		t.Error("Expected error registering the same gauge func twice, got nil")
+	}
+	if err := st.GaugeFunc("test.gauge-func-odd", func() float64 { return 0 }, "pool"); !errors.Is(err, metricsbp.ErrOddLabelValues) {
+		t.Errorf("Expected ErrOddLabelValues, got %v", err)
+	}
+}
@@ -64,6 +64,7 @@ type Statsd struct {
 	wg                  sync.WaitGroup
 
 	activeRequests atomic.Int64
+	gaugeFuncs     gaugeFuncs
 }
 
 func convertSampleRate(rate *float64) float64 {
@@ -118,10 +119,10 @@ func NewStatsd(ctx context.Context, cfg Config) *Statsd {
 			for {
 				select {
 				case <-ticker.C:
-					st.writer.doWrite(st.statsd, kitlogger)
+					st.writer.doWrite(st, kitlogger)
 				case <-st.ctx.Done():
 					// Flush one more time before returning.
-					st.writer.doWrite(st.statsd, kitlogger)
+					st.writer.doWrite(st, kitlogger)
 					return
 				}
 			}
@@ -256,6 +257,30 @@ func (st *Statsd) TimingWithRate(args RateArgs) metrics.Histogram {
 	}
 }
 
+// Time starts timing and returns a function that, when called, records the
+// elapsed time into the timing histogram to the name (see Timing),
+// with the optional labelValues applied via With.
+//
+// It's a shortcut for timing a function:
+//
+//	func query(ctx context.Context) error {
+//		defer metricsbp.M.Time("db.query")()
+//		// ...
+//	}
+//
+// The returned function should only be called once.
+func (st *Statsd) Time(name string, labelValues ...string) func() {
+	st = st.fallback()
+	histogram := st.Timing(name)
+	if len(labelValues) > 0 {
+		histogram = histogram.With(labelValues...)
+	}
+	start := time.Now()
+	return func() {
+		recordDuration(histogram, time.Since(start))
+	}
+}
+
 // Gauge returns a gauge metrics to the name.
 //
 // Please note that gauges are considered "low level".
@@ -277,6 +302,7 @@ func (st *Statsd) fallback() *Statsd {
 // It's useful when you need to implement your own goroutine to report some
 // metrics (usually gauges) periodically,
 // and be able to stop that goroutine gracefully.
+// For gauges, GaugeFunc is usually the simpler alternative.
 // For example:
 //
 //	func reportGauges() {
@@ -335,7 +361,9 @@ func (st *Statsd) Close() error {
 // When you use this in test code you also want to set BufferInMemoryForTesting
 // to true in the statsd Config, otherwise your test could become flaky.
 func (st *Statsd) WriteTo(w io.Writer) (n int64, err error) {
-	return st.fallback().statsd.WriteTo(w)
+	st = st.fallback()
+	st.gaugeFuncs.update()
+	return st.statsd.WriteTo(w)
 }
 
 func (st *Statsd) incActiveRequests() {
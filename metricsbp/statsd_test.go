@@ -21,6 +21,7 @@ func TestGlobalStatsd(t *testing.T) {
 	metricsbp.M.HistogramWithRate(metricsbp.RateArgs{}).Observe(1)
 	metricsbp.M.Timing("timing").Observe(1)
 	metricsbp.M.TimingWithRate(metricsbp.RateArgs{}).Observe(1)
+	metricsbp.M.Time("time")()
 	metricsbp.M.Gauge("gauge").Set(1)
 	metricsbp.M.RuntimeGauge("gauge").Set(1)
 	metricsbp.M.WriteTo(io.Discard)
@@ -37,6 +38,7 @@ func TestNilStatsd(t *testing.T) {
 	st.HistogramWithRate(metricsbp.RateArgs{}).Observe(1)
 	st.Timing("timing").Observe(1)
 	st.TimingWithRate(metricsbp.RateArgs{}).Observe(1)
+	st.Time("time")()
 	st.Gauge("gauge").Set(1)
 	st.RuntimeGauge("gauge").Set(1)
 	st.WriteTo(io.Discard)
@@ -110,6 +112,28 @@ func TestNoFallback(t *testing.T) {
 	}
 }
 
+func TestTime(t *testing.T) {
+	st := metricsbp.NewStatsd(
+		context.Background(),
+		metricsbp.Config{
+			Namespace:                "test",
+			BufferInMemoryForTesting: true,
+		},
+	)
+	st.Time("foo", "key", "value")()
+
+	var buf bytes.Buffer
+	st.WriteTo(&buf)
+	str := buf.String()
+	const prefix = "test.foo,key=value:"
+	if !strings.HasPrefix(str, prefix) {
+		t.Errorf("Expected prefix %q, got %q", prefix, str)
+	}
+	if !strings.HasSuffix(strings.TrimSpace(str), "|ms") {
+		t.Errorf("Expected timing in milliseconds, got %q", str)
+	}
+}
+
 func BenchmarkStatsd(b *testing.B) {
 	const (
 		tag        = "tag"
@@ -182,6 +206,18 @@ func BenchmarkStatsd(b *testing.B) {
 		},
 	)
 
+	// Registered outside of b.Run, as the same gauge func can only be registered
+	// once.
+	st.GaugeFunc("benchmark.gauge-func", func() float64 { return 1 }, tags...)
+	b.Run(
+		"gauge-func",
+		func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				st.WriteTo(io.Discard)
+			}
+		},
+	)
+
 	b.Run(
 		"on-the-fly",
 		func(b *testing.B) {
@@ -203,6 +239,15 @@ func BenchmarkStatsd(b *testing.B) {
 				},
 			)
 
+			b.Run(
+				"time",
+				func(b *testing.B) {
+					for i := 0; i < b.N; i++ {
+						st.Time(tag)()
+					}
+				},
+			)
+
 			b.Run(
 				"counter",
 				func(b *testing.B) {
@@ -244,6 +289,15 @@ func BenchmarkStatsd(b *testing.B) {
 				},
 			)
 
+			b.Run(
+				"time",
+				func(b *testing.B) {
+					for i := 0; i < b.N; i++ {
+						st.Time(tag, tags...)()
+					}
+				},
+			)
+
 			b.Run(
 				"counter",
 				func(b *testing.B) {
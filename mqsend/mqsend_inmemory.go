@@ -0,0 +1,96 @@
+package mqsend
+
+import (
+	"context"
+	"sync"
+)
+
+// InMemoryMessageQueue is an in-memory implementation of MessageQueue that
+// records all the sent messages, for assertions in tests.
+//
+// Unlike MockMessageQueue, the messages are never consumed,
+// and it can be switched to simulate a full queue via SetFull.
+// It's available on all platforms.
+type InMemoryMessageQueue struct {
+	lock     sync.Mutex
+	msgs     [][]byte
+	full     bool
+	maxSize  int
+	maxQueue int
+}
+
+// Compile time check that InMemoryMessageQueue implements MessageQueue.
+var _ MessageQueue = (*InMemoryMessageQueue)(nil)
+
+// NewInMemoryMessageQueue creates an InMemoryMessageQueue.
+//
+// The name from the cfg will be ignored.
+// If cfg.MaxMessageSize > 0, messages larger than that will be rejected with
+// MessageTooLargeError.
+// If cfg.MaxQueueSize > 0, the queue is considered full after recording that
+// many messages.
+func NewInMemoryMessageQueue(cfg MessageQueueConfig) *InMemoryMessageQueue {
+	return &InMemoryMessageQueue{
+		maxSize:  int(cfg.MaxMessageSize),
+		maxQueue: int(cfg.MaxQueueSize),
+	}
+}
+
+// Close is a no-op. The recorded messages are still accessible after Close.
+func (q *InMemoryMessageQueue) Close() error {
+	return nil
+}
+
+// Send records a copy of data as a sent message.
+//
+// When the queue is full, either because of SetFull or cfg.MaxQueueSize,
+// it returns TimedOutError without recording the message,
+// wrapping ctx.Err() if ctx is done or context.DeadlineExceeded otherwise.
+// Send never blocks.
+func (q *InMemoryMessageQueue) Send(ctx context.Context, data []byte) error {
+	if q.maxSize > 0 && len(data) > q.maxSize {
+		return MessageTooLargeError{
+			MessageSize: len(data),
+			MaxSize:     q.maxSize,
+		}
+	}
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if q.full || (q.maxQueue > 0 && len(q.msgs) >= q.maxQueue) {
+		cause := ctx.Err()
+		if cause == nil {
+			cause = context.DeadlineExceeded
+		}
+		return TimedOutError{
+			Cause: cause,
+		}
+	}
+	q.msgs = append(q.msgs, append([]byte(nil), data...))
+	return nil
+}
+
+// Messages returns all the messages sent successfully so far, in order.
+func (q *InMemoryMessageQueue) Messages() [][]byte {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	msgs := make([][]byte, len(q.msgs))
+	copy(msgs, q.msgs)
+	return msgs
+}
+
+// SetFull sets whether the queue should be simulated as full.
+//
+// While it's set to true, all Send calls fail with TimedOutError.
+func (q *InMemoryMessageQueue) SetFull(full bool) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.full = full
+}
+
+// Reset clears all the recorded messages.
+func (q *InMemoryMessageQueue) Reset() {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.msgs = nil
+}
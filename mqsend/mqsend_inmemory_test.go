@@ -0,0 +1,58 @@
+package mqsend_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/reddit/baseplate.go/mqsend"
+)
+
+func TestInMemoryMessageQueue(t *testing.T) {
+	const msg = "hello, world!"
+	const max = len(msg)
+	const timeout = time.Millisecond
+
+	mq := mqsend.NewInMemoryMessageQueue(mqsend.MessageQueueConfig{
+		MaxMessageSize: int64(max),
+		MaxQueueSize:   4,
+	})
+	defer mq.Close()
+
+	sharedTest(t, mq, msg, max, timeout)
+
+	msgs := mq.Messages()
+	if len(msgs) != 4 {
+		t.Fatalf("Expected 4 recorded messages, got %d", len(msgs))
+	}
+	for i, data := range msgs {
+		if string(data) != msg {
+			t.Errorf("Expected message #%d to be %q, got %q", i, msg, data)
+		}
+	}
+
+	t.Run("set-full", func(t *testing.T) {
+		mq.Reset()
+		if len(mq.Messages()) != 0 {
+			t.Fatalf("Expected no messages after Reset, got %q", mq.Messages())
+		}
+
+		mq.SetFull(true)
+		err := mq.Send(context.Background(), []byte("foo"))
+		if !errors.As(err, new(mqsend.TimedOutError)) {
+			t.Errorf("Expected TimedOutError when the queue is full, got %v", err)
+		}
+
+		mq.SetFull(false)
+		data := []byte("bar")
+		if err := mq.Send(context.Background(), data); err != nil {
+			t.Errorf("Send returned error: %v", err)
+		}
+		data[0] = 'c'
+		msgs := mq.Messages()
+		if len(msgs) != 1 || string(msgs[0]) != "bar" {
+			t.Errorf("Expected recorded messages to be [bar], got %q", msgs)
+		}
+	})
+}
@@ -0,0 +1,61 @@
+package prometheusbp
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// TraceIDExemplarLabel is the exemplar label carrying the trace id added by
+// ObserveWithTrace.
+const TraceIDExemplarLabel = "trace_id"
+
+var exemplarsEnabled atomic.Bool
+
+// EnableExemplars sets whether ObserveWithTrace attaches exemplars.
+//
+// Exemplars are disabled by default.
+// When enabled, the metrics are also served in the OpenMetrics format by the
+// admin server (when requested by the scraper),
+// as exemplars are only exposed in that format.
+func EnableExemplars(enabled bool) {
+	exemplarsEnabled.Store(enabled)
+}
+
+// ExemplarsEnabled returns whether exemplars are enabled via EnableExemplars.
+func ExemplarsEnabled() bool {
+	return exemplarsEnabled.Load()
+}
+
+// tracedSpan is the subset of *tracing.Span used by ObserveWithTrace,
+// to avoid depending on the tracing package.
+type tracedSpan interface {
+	TraceID() string
+	ShouldSample() bool
+}
+
+// ObserveWithTrace records value into observer,
+// with the trace id of the span in ctx attached as an exemplar under
+// TraceIDExemplarLabel.
+//
+// The exemplar is only attached when exemplars are enabled via
+// EnableExemplars, observer supports exemplars (e.g. histograms),
+// and there's a sampled span in ctx, so the trace can actually be found.
+// Otherwise it's the same as observer.Observe(value).
+func ObserveWithTrace(ctx context.Context, observer prometheus.Observer, value float64) {
+	if ExemplarsEnabled() {
+		if eo, ok := observer.(prometheus.ExemplarObserver); ok {
+			if span, ok := opentracing.SpanFromContext(ctx).(tracedSpan); ok && span.ShouldSample() {
+				if traceID := span.TraceID(); traceID != "" {
+					eo.ObserveWithExemplar(value, prometheus.Labels{
+						TraceIDExemplarLabel: traceID,
+					})
+					return
+				}
+			}
+		}
+	}
+	observer.Observe(value)
+}
@@ -0,0 +1,86 @@
+package prometheusbp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/reddit/baseplate.go/tracing"
+)
+
+func TestObserveWithTrace(t *testing.T) {
+	const traceID = "12345"
+	sampled := true
+	notSampled := false
+
+	defer EnableExemplars(false)
+	for _, c := range []struct {
+		label    string
+		enabled  bool
+		headers  *tracing.Headers
+		expected string
+	}{
+		{
+			label: "disabled",
+			headers: &tracing.Headers{
+				TraceID: traceID,
+				Sampled: &sampled,
+			},
+		},
+		{
+			label:   "no-span",
+			enabled: true,
+		},
+		{
+			label:   "not-sampled",
+			enabled: true,
+			headers: &tracing.Headers{
+				TraceID: traceID,
+				Sampled: &notSampled,
+			},
+		},
+		{
+			label:   "sampled",
+			enabled: true,
+			headers: &tracing.Headers{
+				TraceID: traceID,
+				Sampled: &sampled,
+			},
+			expected: traceID,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			EnableExemplars(c.enabled)
+			ctx := context.Background()
+			if c.headers != nil {
+				ctx, _ = tracing.StartSpanFromHeaders(ctx, "foo", *c.headers)
+			}
+			histogram := prometheus.NewHistogram(prometheus.HistogramOpts{
+				Name:    "test_histogram",
+				Buckets: []float64{1},
+			})
+			ObserveWithTrace(ctx, histogram, 0.5)
+
+			var m dto.Metric
+			if err := histogram.Write(&m); err != nil {
+				t.Fatal(err)
+			}
+			if got := m.GetHistogram().GetSampleCount(); got != 1 {
+				t.Errorf("Expected 1 observation, got %d", got)
+			}
+			var got string
+			if exemplar := m.GetHistogram().GetBucket()[0].GetExemplar(); exemplar != nil {
+				for _, label := range exemplar.GetLabel() {
+					if label.GetName() == TraceIDExemplarLabel {
+						got = label.GetValue()
+					}
+				}
+			}
+			if got != c.expected {
+				t.Errorf("Expected exemplar trace id %q, got %q", c.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package prometheusbp
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/reddit/baseplate.go/internal/prometheusbpint"
+)
+
+// ServiceLabel is the constant label added to the metrics created by
+// NewServiceCounterVec and NewServiceHistogramVec,
+// with the service name passed to them as the value.
+//
+// This label is only added by the helpers in this package,
+// the metrics defined by thriftbp and httpbp do not have it.
+const ServiceLabel = "baseplate_service"
+
+var (
+	// ErrInvalidMetricName is the error returned by NewServiceCounterVec and
+	// NewServiceHistogramVec when the metric name doesn't follow the baseplate
+	// naming convention.
+	ErrInvalidMetricName = errors.New("prometheusbp: invalid metric name")
+
+	// ErrEmptyServiceName is the error returned by NewServiceCounterVec and
+	// NewServiceHistogramVec when the service name is empty.
+	ErrEmptyServiceName = errors.New("prometheusbp: empty service name")
+)
+
+// metricNameRegexp matches lower snake case names with at least 2 parts.
+var metricNameRegexp = regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)+$`)
+
+// reservedHistogramSuffixes are the suffixes prometheus adds to the series of
+// a histogram, they should not be used as the suffix of the histogram itself.
+var reservedHistogramSuffixes = []string{
+	"_total",
+	"_count",
+	"_sum",
+	"_bucket",
+}
+
+func serviceConstLabels(service string, labels prometheus.Labels) prometheus.Labels {
+	merged := make(prometheus.Labels, len(labels)+1)
+	for k, v := range labels {
+		merged[k] = v
+	}
+	merged[ServiceLabel] = service
+	return merged
+}
+
+func validateCounterName(name string) error {
+	if !metricNameRegexp.MatchString(name) {
+		return fmt.Errorf("%w: %q is not in lower snake case with at least 2 parts", ErrInvalidMetricName, name)
+	}
+	if !strings.HasSuffix(name, "_total") {
+		return fmt.Errorf("%w: counter name %q does not end with \"_total\"", ErrInvalidMetricName, name)
+	}
+	return nil
+}
+
+func validateHistogramName(name string) error {
+	if !metricNameRegexp.MatchString(name) {
+		return fmt.Errorf("%w: %q is not in lower snake case with at least 2 parts", ErrInvalidMetricName, name)
+	}
+	for _, suffix := range reservedHistogramSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return fmt.Errorf("%w: histogram name %q ends with reserved suffix %q", ErrInvalidMetricName, name, suffix)
+		}
+	}
+	return nil
+}
+
+// NewServiceCounterVec creates a new CounterVec with the standard baseplate
+// constant labels and registers it to the same registry used by other
+// baseplate metrics.
+//
+// service is used as the value of ServiceLabel and must not be empty,
+// otherwise ErrEmptyServiceName will be returned.
+//
+// The full name of the counter must be in lower snake case and end with
+// "_total", otherwise an error wrapping ErrInvalidMetricName will be returned.
+func NewServiceCounterVec(service string, opts prometheus.CounterOpts, labelNames []string) (*prometheus.CounterVec, error) {
+	if service == "" {
+		return nil, ErrEmptyServiceName
+	}
+	if err := validateCounterName(prometheus.BuildFQName(opts.Namespace, opts.Subsystem, opts.Name)); err != nil {
+		return nil, err
+	}
+	opts.ConstLabels = serviceConstLabels(service, opts.ConstLabels)
+	cv := prometheus.NewCounterVec(opts, labelNames)
+	if err := prometheusbpint.GlobalRegistry.Register(cv); err != nil {
+		return nil, fmt.Errorf("prometheusbp: failed to register counter %q: %w", opts.Name, err)
+	}
+	return cv, nil
+}
+
+// MustNewServiceCounterVec is the same as NewServiceCounterVec,
+// except that it panics on errors.
+//
+// It's intended to be used in package level var blocks.
+func MustNewServiceCounterVec(service string, opts prometheus.CounterOpts, labelNames []string) *prometheus.CounterVec {
+	cv, err := NewServiceCounterVec(service, opts, labelNames)
+	if err != nil {
+		panic(err)
+	}
+	return cv
+}
+
+// NewServiceHistogramVec creates a new HistogramVec with the standard
+// baseplate constant labels and registers it to the same registry used by
+// other baseplate metrics.
+//
+// service is used as the value of ServiceLabel and must not be empty,
+// otherwise ErrEmptyServiceName will be returned.
+//
+// If opts.Buckets is empty, DefaultLatencyBuckets will be used.
+//
+// The full name of the histogram must be in lower snake case and must not end
+// with the suffixes reserved by prometheus ("_total", "_count", "_sum",
+// "_bucket"), otherwise an error wrapping ErrInvalidMetricName will be
+// returned.
+func NewServiceHistogramVec(service string, opts prometheus.HistogramOpts, labelNames []string) (*prometheus.HistogramVec, error) {
+	if service == "" {
+		return nil, ErrEmptyServiceName
+	}
+	if err := validateHistogramName(prometheus.BuildFQName(opts.Namespace, opts.Subsystem, opts.Name)); err != nil {
+		return nil, err
+	}
+	if len(opts.Buckets) == 0 {
+		opts.Buckets = DefaultLatencyBuckets
+	}
+	opts.ConstLabels = serviceConstLabels(service, opts.ConstLabels)
+	hv := prometheus.NewHistogramVec(opts, labelNames)
+	if err := prometheusbpint.GlobalRegistry.Register(hv); err != nil {
+		return nil, fmt.Errorf("prometheusbp: failed to register histogram %q: %w", opts.Name, err)
+	}
+	return hv, nil
+}
+
+// MustNewServiceHistogramVec is the same as NewServiceHistogramVec,
+// except that it panics on errors.
+//
+// It's intended to be used in package level var blocks.
+func MustNewServiceHistogramVec(service string, opts prometheus.HistogramOpts, labelNames []string) *prometheus.HistogramVec {
+	hv, err := NewServiceHistogramVec(service, opts, labelNames)
+	if err != nil {
+		panic(err)
+	}
+	return hv
+}
@@ -0,0 +1,127 @@
+package prometheusbp
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestValidateMetricNames(t *testing.T) {
+	for _, c := range []struct {
+		name      string
+		counter   bool
+		histogram bool
+	}{
+		{name: "foo_requests_total", counter: true},
+		{name: "foo_latency_seconds", histogram: true},
+		{name: "foo"},
+		{name: "Foo_requests_total"},
+		{name: "foo-requests_total"},
+		{name: "foo_requests", histogram: true},
+		{name: "foo__total"},
+		{name: "_foo_total"},
+		{name: "foo_latency_bucket"},
+		{name: "foo_latency_count"},
+		{name: "foo_latency_sum"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			check := func(err error, valid bool) {
+				t.Helper()
+				if valid {
+					if err != nil {
+						t.Errorf("Expected no error, got %v", err)
+					}
+					return
+				}
+				if !errors.Is(err, ErrInvalidMetricName) {
+					t.Errorf("Expected ErrInvalidMetricName, got %v", err)
+				}
+			}
+			check(validateCounterName(c.name), c.counter)
+			check(validateHistogramName(c.name), c.histogram)
+		})
+	}
+}
+
+func TestNewServiceMetrics(t *testing.T) {
+	const service = "test-service"
+
+	cv, err := NewServiceCounterVec(service, prometheus.CounterOpts{
+		Name: "prometheusbp_test_service_requests_total",
+		Help: "Test counter",
+	}, []string{"endpoint"})
+	if err != nil {
+		t.Fatalf("NewServiceCounterVec failed: %v", err)
+	}
+	cv.With(prometheus.Labels{"endpoint": "foo"}).Inc()
+
+	hv := MustNewServiceHistogramVec(service, prometheus.HistogramOpts{
+		Name: "prometheusbp_test_service_latency_seconds",
+		Help: "Test histogram",
+	}, []string{"endpoint"})
+	hv.With(prometheus.Labels{"endpoint": "foo"}).Observe(0.1)
+
+	const expected = `
+# HELP prometheusbp_test_service_requests_total Test counter
+# TYPE prometheusbp_test_service_requests_total counter
+prometheusbp_test_service_requests_total{baseplate_go="v0",baseplate_service="test-service",endpoint="foo"} 1
+`
+	if err := testutil.GatherAndCompare(
+		prometheus.DefaultGatherer,
+		strings.NewReader(expected),
+		"prometheusbp_test_service_requests_total",
+	); err != nil {
+		t.Error(err)
+	}
+
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found bool
+	for _, mf := range families {
+		if mf.GetName() != "prometheusbp_test_service_latency_seconds" {
+			continue
+		}
+		found = true
+		if got, want := len(mf.GetMetric()[0].GetHistogram().GetBucket()), len(DefaultLatencyBuckets); got != want {
+			t.Errorf("Expected %d buckets, got %d", want, got)
+		}
+	}
+	if !found {
+		t.Error("Histogram prometheusbp_test_service_latency_seconds not registered")
+	}
+
+	if _, err := NewServiceCounterVec(service, prometheus.CounterOpts{
+		Name: "prometheusbp_test_service_requests_total",
+		Help: "Test counter",
+	}, []string{"endpoint"}); err == nil {
+		t.Error("Expected error registering the same counter twice, got nil")
+	}
+
+	if _, err := NewServiceCounterVec(service, prometheus.CounterOpts{
+		Name: "invalid",
+	}, nil); !errors.Is(err, ErrInvalidMetricName) {
+		t.Errorf("Expected ErrInvalidMetricName, got %v", err)
+	}
+
+	if _, err := NewServiceCounterVec("", prometheus.CounterOpts{
+		Name: "prometheusbp_test_service_empty_total",
+	}, nil); !errors.Is(err, ErrEmptyServiceName) {
+		t.Errorf("Expected ErrEmptyServiceName, got %v", err)
+	}
+
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Error("Expected MustNewServiceHistogramVec to panic on invalid name")
+			}
+		}()
+		MustNewServiceHistogramVec(service, prometheus.HistogramOpts{
+			Name: "prometheusbp_test_service_latency_count",
+		}, nil)
+	}()
+}
@@ -0,0 +1,120 @@
+package redisx
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/joomcode/redispipe/redis"
+)
+
+// DefaultDeleteBatchSize is the default BatchSize used by
+// Syncx.DeleteByPattern.
+const DefaultDeleteBatchSize = 100
+
+// DeleteByPatternOpts are the options used by Syncx.DeleteByPattern.
+type DeleteByPatternOpts struct {
+	// BatchSize is the max number of keys deleted by a single batch of DEL/UNLINK
+	// commands sent in a pipeline. It's also used as the COUNT hint of the SCAN
+	// commands.
+	//
+	// Optional, DefaultDeleteBatchSize will be used when it's <= 0.
+	BatchSize int
+
+	// Pause is the time to sleep between the batches,
+	// to avoid keeping Redis busy with the deletions.
+	//
+	// Optional, there's no pause when it's <= 0.
+	Pause time.Duration
+
+	// Unlink, when set to true, uses UNLINK instead of DEL to delete the keys,
+	// so the memory is reclaimed by Redis in the background.
+	// It requires Redis 4.0+.
+	Unlink bool
+}
+
+// DeleteByPattern deletes all the keys matching pattern and returns the number
+// of keys deleted.
+//
+// Instead of KEYS, which blocks Redis until all the keys are checked,
+// it iterates the keys via SCAN (see Syncx.Scanner),
+// and deletes the matched keys in batches, optionally pausing between the
+// batches.
+// Each key is deleted by its own DEL/UNLINK command, pipelined within a batch,
+// so that it also works with Redis Cluster, where a multi-key command fails
+// with CROSSSLOT error when the keys are in different hash slots.
+//
+// The deletion is best-effort under concurrent writes:
+// per SCAN guarantees, the keys created (or renamed to match pattern) after the
+// iteration started might not be deleted,
+// and the keys deleted by other clients concurrently are not counted.
+//
+// When ctx is canceled or an error happened, it stops and returns the number
+// of keys deleted so far with the error.
+func (s Syncx) DeleteByPattern(ctx context.Context, pattern string, opts DeleteByPatternOpts) (int, error) {
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultDeleteBatchSize
+	}
+	cmd := "DEL"
+	if opts.Unlink {
+		cmd = "UNLINK"
+	}
+
+	var deleted int
+	var batches int
+	del := func(keys []string) error {
+		if batches > 0 && opts.Pause > 0 {
+			timer := time.NewTimer(opts.Pause)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+		}
+		batches++
+
+		counts := make([]int64, len(keys))
+		reqs := make([]Request, len(keys))
+		for i, key := range keys {
+			reqs[i] = Req(&counts[i], cmd, key)
+		}
+		errs := s.SendMany(ctx, reqs...)
+		for _, n := range counts {
+			deleted += int(n)
+		}
+		return errors.Join(errs...)
+	}
+
+	scanner := s.Scanner(ctx, redis.ScanOpts{
+		Match: pattern,
+		Count: batchSize,
+	})
+	var pending []string
+	for {
+		if err := ctx.Err(); err != nil {
+			return deleted, err
+		}
+		keys, err := scanner.Next()
+		if errors.Is(err, redis.ScanEOF) {
+			break
+		}
+		if err != nil {
+			return deleted, err
+		}
+		pending = append(pending, keys...)
+		for len(pending) >= batchSize {
+			if err := del(pending[:batchSize]); err != nil {
+				return deleted, err
+			}
+			pending = pending[batchSize:]
+		}
+	}
+	if len(pending) > 0 {
+		if err := del(pending); err != nil {
+			return deleted, err
+		}
+	}
+	return deleted, nil
+}
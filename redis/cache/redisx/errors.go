@@ -1,6 +1,7 @@
 package redisx
 
 import (
+	"fmt"
 	"reflect"
 
 	"github.com/reddit/baseplate.go/retrybp"
@@ -73,8 +74,29 @@ func (e *UnexpectedResponseError) Retryable() int {
 	return -1
 }
 
+// TransactionAbortedError is returned by Syncx.WatchTransaction when the
+// transaction was not executed because at least one of the watched keys was
+// modified after WATCH.
+//
+// The caller can retry the whole WatchTransaction call.
+type TransactionAbortedError struct {
+	Keys []string
+}
+
+// Error implements the error interface.
+func (e *TransactionAbortedError) Error() string {
+	return fmt.Sprintf("redisx: transaction aborted as watched keys %q were modified", e.Keys)
+}
+
+// Retryable implements retrybp.RetryableError. TransactionAbortedError is always
+// retryable.
+func (e *TransactionAbortedError) Retryable() int {
+	return 1
+}
+
 var (
 	_ retrybp.RetryableError = (*InvalidInputError)(nil)
 	_ retrybp.RetryableError = (*ResponseInputTypeError)(nil)
 	_ retrybp.RetryableError = (*UnexpectedResponseError)(nil)
+	_ retrybp.RetryableError = (*TransactionAbortedError)(nil)
 )
@@ -13,7 +13,8 @@ import (
 )
 
 var (
-	client redisx.Syncx
+	client    redisx.Syncx
+	redisAddr string
 )
 
 func TestMain(m *testing.M) {
@@ -22,6 +23,7 @@ func TestMain(m *testing.M) {
 		panic(err)
 	}
 	defer s.Close()
+	redisAddr = s.Addr()
 
 	sender, err := redisconn.Connect(context.TODO(), s.Addr(), redisconn.Opts{})
 	if err != nil {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 
+	"github.com/joomcode/errorx"
 	"github.com/joomcode/redispipe/redis"
 )
 
@@ -133,6 +134,55 @@ func (s Syncx) SendTransaction(ctx context.Context, reqs ...Request) error {
 	return errors.Join(errs...)
 }
 
+// WatchTransaction implements optimistic locking with WATCH and a MULTI/EXEC
+// transaction.
+//
+// It sends WATCH on keys, then calls fn, which can read the current values via
+// the Syncx passed in, and returns the write requests to be queued in the
+// transaction.
+// The transaction is only executed if none of the watched keys were modified
+// since WATCH, otherwise a *TransactionAbortedError is returned and the caller
+// can retry the whole WatchTransaction call.
+// The results of the write requests are set the same way as SendTransaction.
+//
+// If fn returns an error or no requests, the keys will be unwatched and the
+// error from fn will be returned.
+//
+// As WATCH is scoped to the connection, the underlying Sync must be backed by a
+// dedicated redisconn.Connection not shared with other concurrent callers,
+// otherwise the commands from other callers could be executed between WATCH and
+// EXEC, and clear or invalidate the watch.
+// redispipe also rejects WATCH unless the connection is created with
+// redisconn.Opts.ScriptMode set to true.
+func (s Syncx) WatchTransaction(
+	ctx context.Context,
+	keys []string,
+	fn func(ctx context.Context, read Syncx) ([]Request, error),
+) error {
+	args := make([]interface{}, len(keys))
+	for i, key := range keys {
+		args[i] = key
+	}
+	if err := s.Do(ctx, nil, "WATCH", args...); err != nil {
+		return err
+	}
+
+	reqs, err := fn(ctx, s)
+	if err != nil || len(reqs) == 0 {
+		return errors.Join(err, s.Do(ctx, nil, "UNWATCH"))
+	}
+
+	err = s.SendTransaction(ctx, reqs...)
+	if errorx.IsOfType(err, redis.ErrExecEmpty) {
+		return &TransactionAbortedError{Keys: keys}
+	}
+	if err != nil {
+		// EXEC might not be executed, make sure the keys are unwatched.
+		return errors.Join(err, s.Do(ctx, nil, "UNWATCH"))
+	}
+	return nil
+}
+
 // Scanner returns a ScanIterator from the underlying Sync.
 func (s Syncx) Scanner(ctx context.Context, opts redis.ScanOpts) ScanIterator {
 	return s.Sync.Scanner(ctx, opts)
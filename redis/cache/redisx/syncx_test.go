@@ -6,9 +6,11 @@ import (
 	"reflect"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/joomcode/errorx"
 	"github.com/joomcode/redispipe/redis"
+	"github.com/joomcode/redispipe/redisconn"
 
 	"github.com/reddit/baseplate.go/redis/cache/redisx"
 )
@@ -197,6 +199,80 @@ func TestMonitoredSync_SendTransaction(t *testing.T) {
 	})
 }
 
+func TestSyncx_WatchTransaction(t *testing.T) {
+	defer flushRedis()
+	ctx := context.Background()
+
+	// WATCH requires a dedicated connection in script mode.
+	sender, err := redisconn.Connect(ctx, redisAddr, redisconn.Opts{ScriptMode: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sender.Close()
+	watcher := redisx.Syncx{Sync: redisx.BaseSync{
+		SyncCtx: redis.SyncCtx{S: sender},
+	}}
+
+	if err := client.Do(ctx, nil, "SET", "counter", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	incr := func(ctx context.Context, read redisx.Syncx) ([]redisx.Request, error) {
+		var v int64
+		if err := read.Do(ctx, &v, "GET", "counter"); err != nil {
+			return nil, err
+		}
+		return []redisx.Request{redisx.Req(nil, "SET", "counter", v+1)}, nil
+	}
+
+	t.Run("success", func(t *testing.T) {
+		if err := watcher.WatchTransaction(ctx, []string{"counter"}, incr); err != nil {
+			t.Fatal(err)
+		}
+		var v int64
+		if err := client.Do(ctx, &v, "GET", "counter"); err != nil {
+			t.Fatal(err)
+		}
+		if v != 2 {
+			t.Errorf("expected counter to be 2, got %d", v)
+		}
+	})
+
+	t.Run("aborted", func(t *testing.T) {
+		err := watcher.WatchTransaction(ctx, []string{"counter"}, func(ctx context.Context, read redisx.Syncx) ([]redisx.Request, error) {
+			reqs, err := incr(ctx, read)
+			if err != nil {
+				return nil, err
+			}
+			if err := client.Do(ctx, nil, "SET", "counter", 10); err != nil {
+				return nil, err
+			}
+			return reqs, nil
+		})
+		var aborted *redisx.TransactionAbortedError
+		if !errors.As(err, &aborted) {
+			t.Fatalf("expected *redisx.TransactionAbortedError, got %v", err)
+		}
+		var v int64
+		if err := client.Do(ctx, &v, "GET", "counter"); err != nil {
+			t.Fatal(err)
+		}
+		if v != 10 {
+			t.Errorf("expected counter to be 10, got %d", v)
+		}
+	})
+
+	t.Run("error/fn", func(t *testing.T) {
+		fnErr := errors.New("fn error")
+		err := watcher.WatchTransaction(ctx, []string{"counter"}, func(context.Context, redisx.Syncx) ([]redisx.Request, error) {
+			return nil, fnErr
+		})
+		if !errors.Is(err, fnErr) {
+			t.Errorf("expected error to wrap %v, got %v", fnErr, err)
+		}
+	})
+}
+
 func TestSyncx_Scanner(t *testing.T) {
 	defer flushRedis()
 
@@ -222,6 +298,86 @@ func TestSyncx_Scanner(t *testing.T) {
 	}
 }
 
+func TestSyncx_DeleteByPattern(t *testing.T) {
+	defer flushRedis()
+
+	ctx := context.Background()
+
+	setKeys := func(t *testing.T) {
+		t.Helper()
+		reqs := make([]redisx.Request, 0, 12)
+		for i := 0; i < 10; i++ {
+			reqs = append(reqs, redisx.Req(nil, "SET", "foo:"+strconv.Itoa(i), "x"))
+		}
+		reqs = append(reqs,
+			redisx.Req(nil, "SET", "bar:x", "a"),
+			redisx.Req(nil, "SET", "bar:y", "b"),
+		)
+		for _, err := range client.SendMany(ctx, reqs...) {
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	for _, c := range []struct {
+		label string
+		opts  redisx.DeleteByPatternOpts
+	}{
+		{
+			label: "default",
+		},
+		{
+			label: "batches",
+			opts: redisx.DeleteByPatternOpts{
+				BatchSize: 3,
+				Pause:     time.Millisecond,
+			},
+		},
+		{
+			label: "unlink",
+			opts: redisx.DeleteByPatternOpts{
+				Unlink: true,
+			},
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			defer flushRedis()
+			setKeys(t)
+
+			deleted, err := client.DeleteByPattern(ctx, "foo:*", c.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if deleted != 10 {
+				t.Errorf("Expected 10 keys deleted, got %d", deleted)
+			}
+			var keys [][]byte
+			if err := client.Do(ctx, &keys, "KEYS", "*"); err != nil {
+				t.Fatal(err)
+			}
+			if len(keys) != 2 {
+				t.Errorf("Expected the 2 bar keys to be kept, got %q", keys)
+			}
+		})
+	}
+
+	t.Run("canceled", func(t *testing.T) {
+		defer flushRedis()
+		setKeys(t)
+
+		cancelCtx, cancel := context.WithCancel(ctx)
+		cancel()
+		deleted, err := client.DeleteByPattern(cancelCtx, "foo:*", redisx.DeleteByPatternOpts{})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+		if deleted != 0 {
+			t.Errorf("Expected 0 keys deleted, got %d", deleted)
+		}
+	})
+}
+
 func TestResponseTypes(t *testing.T) {
 	defer flushRedis()
 
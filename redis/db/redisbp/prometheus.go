@@ -79,6 +79,19 @@ var (
 	}, latencyLabels)
 )
 
+var (
+	slowCommandsLabels = []string{
+		nameLabel,
+		commandLabel,
+	}
+
+	slowCommandsCounter = promauto.With(prometheusbpint.GlobalRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: promNamespace,
+		Name:      "slow_commands_total",
+		Help:      "The number of redis commands took longer than the configured slow command threshold",
+	}, slowCommandsLabels)
+)
+
 // exporter provides an interface for Prometheus metrics.
 type exporter struct {
 	client PoolStatser
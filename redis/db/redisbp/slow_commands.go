@@ -0,0 +1,130 @@
+package redisbp
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
+
+	"github.com/reddit/baseplate.go/log"
+)
+
+// slowCommandsLogBurst is the max number of log lines SlowCommandHook writes
+// per second.
+const slowCommandsLogBurst = 10
+
+type slowCommandCtxKeyType struct{}
+
+var slowCommandCtxKey slowCommandCtxKeyType
+
+// SlowCommandHook is a redis.Hook that logs the Redis commands and pipelines
+// took longer than a threshold, at warning level via log.C(ctx).
+//
+// Only the command name and the number of keys are logged,
+// the values of the arguments are never logged.
+// Pipelines are logged with "pipeline" as the command name,
+// with the number of commands and the total number of keys in the pipeline.
+//
+// To avoid flooding the logs, the log lines are rate limited to 10 per second
+// per hook, while redisbp_slow_commands_total counter is always increased for
+// every slow command, with labels:
+//
+//   - redis_pool: the name arg
+//   - redis_command: the command name, or "pipeline"
+//
+// It should be created via NewSlowCommandHook,
+// and can be added to any go-redis client in addition to SpanHook:
+//
+//	client := redisbp.NewMonitoredClient(name, opt)
+//	client.AddHook(redisbp.NewSlowCommandHook(name, 100*time.Millisecond))
+type SlowCommandHook struct {
+	name      string
+	threshold time.Duration
+	limiter   *rate.Limiter
+}
+
+var _ redis.Hook = (*SlowCommandHook)(nil)
+
+// NewSlowCommandHook creates a new SlowCommandHook with the given client name
+// and threshold.
+func NewSlowCommandHook(name string, threshold time.Duration) *SlowCommandHook {
+	return &SlowCommandHook{
+		name:      name,
+		threshold: threshold,
+		limiter:   rate.NewLimiter(rate.Every(time.Second/slowCommandsLogBurst), slowCommandsLogBurst),
+	}
+}
+
+// BeforeProcess implements redis.Hook.
+func (h *SlowCommandHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, slowCommandCtxKey, time.Now()), nil
+}
+
+// AfterProcess implements redis.Hook.
+func (h *SlowCommandHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	h.check(ctx, cmd.Name(), 1, countKeys(cmd))
+	return nil
+}
+
+// BeforeProcessPipeline implements redis.Hook.
+func (h *SlowCommandHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, slowCommandCtxKey, time.Now()), nil
+}
+
+// AfterProcessPipeline implements redis.Hook.
+func (h *SlowCommandHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	var keys int
+	for _, cmd := range cmds {
+		keys += countKeys(cmd)
+	}
+	h.check(ctx, "pipeline", len(cmds), keys)
+	return nil
+}
+
+func (h *SlowCommandHook) check(ctx context.Context, command string, commands, keys int) {
+	start, ok := ctx.Value(slowCommandCtxKey).(time.Time)
+	if !ok {
+		return
+	}
+	duration := time.Since(start)
+	if duration <= h.threshold {
+		return
+	}
+	slowCommandsCounter.With(prometheus.Labels{
+		nameLabel:    h.name,
+		commandLabel: command,
+	}).Inc()
+	if h.limiter.Allow() {
+		log.C(ctx).Warnw(
+			"redisbp: slow command",
+			"command", command,
+			"commands", commands,
+			"keys", keys,
+			"pool", h.name,
+			"duration", duration,
+			"threshold", h.threshold,
+		)
+	}
+}
+
+// countKeys returns the number of keys in the command.
+//
+// It's best effort: the commands taking multiple keys are handled explicitly,
+// other commands are counted as having a single key when they have arguments.
+func countKeys(cmd redis.Cmder) int {
+	args := len(cmd.Args()) - 1
+	if args <= 0 {
+		return 0
+	}
+	switch strings.ToLower(cmd.Name()) {
+	case "mget", "del", "unlink", "exists", "touch", "watch", "sinter", "sunion", "sdiff", "pfcount":
+		return args
+	case "mset", "msetnx":
+		return args / 2
+	default:
+		return 1
+	}
+}
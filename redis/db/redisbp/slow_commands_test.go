@@ -0,0 +1,118 @@
+package redisbp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/reddit/baseplate.go/prometheusbp/promtest"
+)
+
+func TestSlowCommandHook(t *testing.T) {
+	const (
+		name      = "slow"
+		threshold = 10 * time.Millisecond
+	)
+
+	slowCommandsCounter.Reset()
+	defer slowCommandsCounter.Reset()
+
+	hook := NewSlowCommandHook(name, threshold)
+	for _, c := range []struct {
+		label    string
+		duration time.Duration
+		slow     bool
+	}{
+		{
+			label:    "fast",
+			duration: 0,
+			slow:     false,
+		},
+		{
+			label:    "slow",
+			duration: threshold * 2,
+			slow:     true,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			t.Run("command", func(t *testing.T) {
+				metric := promtest.NewPrometheusMetricTest(t, "slow commands", slowCommandsCounter, prometheus.Labels{
+					nameLabel:    name,
+					commandLabel: "get",
+				})
+				cmd := redis.NewStringCmd(context.Background(), "get", "key")
+				ctx, err := hook.BeforeProcess(context.Background(), cmd)
+				if err != nil {
+					t.Fatal(err)
+				}
+				time.Sleep(c.duration)
+				if err := hook.AfterProcess(ctx, cmd); err != nil {
+					t.Fatal(err)
+				}
+				if c.slow {
+					metric.CheckDelta(1)
+				} else {
+					metric.CheckDelta(0)
+				}
+			})
+
+			t.Run("pipeline", func(t *testing.T) {
+				metric := promtest.NewPrometheusMetricTest(t, "slow commands", slowCommandsCounter, prometheus.Labels{
+					nameLabel:    name,
+					commandLabel: "pipeline",
+				})
+				cmds := []redis.Cmder{
+					redis.NewStringCmd(context.Background(), "get", "key"),
+					redis.NewStatusCmd(context.Background(), "ping"),
+				}
+				ctx, err := hook.BeforeProcessPipeline(context.Background(), cmds)
+				if err != nil {
+					t.Fatal(err)
+				}
+				time.Sleep(c.duration)
+				if err := hook.AfterProcessPipeline(ctx, cmds); err != nil {
+					t.Fatal(err)
+				}
+				if c.slow {
+					metric.CheckDelta(1)
+				} else {
+					metric.CheckDelta(0)
+				}
+			})
+		})
+	}
+}
+
+func TestCountKeys(t *testing.T) {
+	ctx := context.Background()
+	for _, c := range []struct {
+		cmd      redis.Cmder
+		expected int
+	}{
+		{
+			cmd:      redis.NewStatusCmd(ctx, "ping"),
+			expected: 0,
+		},
+		{
+			cmd:      redis.NewStatusCmd(ctx, "set", "key", "value"),
+			expected: 1,
+		},
+		{
+			cmd:      redis.NewSliceCmd(ctx, "mget", "a", "b", "c"),
+			expected: 3,
+		},
+		{
+			cmd:      redis.NewStatusCmd(ctx, "mset", "a", "1", "b", "2"),
+			expected: 2,
+		},
+	} {
+		t.Run(c.cmd.Name(), func(t *testing.T) {
+			if got := countKeys(c.cmd); got != c.expected {
+				t.Errorf("Expected %d keys, got %d", c.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package retrybp
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/avast/retry-go"
+)
+
+// Decision is the retry decision made by a Classifier.
+type Decision int
+
+// Decision values.
+const (
+	// NoDecision means the Classifier doesn't know about the error.
+	NoDecision Decision = iota
+
+	// Retry means the error is retryable.
+	Retry
+
+	// DoNotRetry means the error is not retryable.
+	DoNotRetry
+)
+
+// Classifier classifies an error into a retry Decision.
+//
+// Classifiers should return NoDecision for errors they don't know about.
+type Classifier func(err error) Decision
+
+var (
+	classifiersLock sync.RWMutex
+	classifiers     []Classifier
+)
+
+// RegisterClassifier registers a Classifier to the global registry,
+// to centrally declare which errors are retryable for the whole service.
+//
+// It's usually called during service initialization.
+//
+// The registered classifiers are consulted by Classify,
+// and used by Do and ClassifierFilter.
+func RegisterClassifier(c Classifier) {
+	classifiersLock.Lock()
+	defer classifiersLock.Unlock()
+	classifiers = append(classifiers, c)
+}
+
+// Classify returns the combined Decision of all the registered classifiers on
+// err.
+//
+// When multiple classifiers return conflicting decisions,
+// DoNotRetry takes precedence over Retry,
+// as it's always safer to not retry.
+// NoDecision is only returned when none of the classifiers made a decision.
+func Classify(err error) Decision {
+	if err == nil {
+		return NoDecision
+	}
+	classifiersLock.RLock()
+	defer classifiersLock.RUnlock()
+	decision := NoDecision
+	for _, c := range classifiers {
+		switch c(err) {
+		case DoNotRetry:
+			return DoNotRetry
+		case Retry:
+			decision = Retry
+		}
+	}
+	return decision
+}
+
+// ClassifierFilter is a Filter implementation that uses the Decision from
+// Classify, and defers to the next filter on NoDecision.
+func ClassifierFilter(err error, next retry.RetryIfFunc) bool {
+	switch Classify(err) {
+	case Retry:
+		return true
+	case DoNotRetry:
+		return false
+	}
+	return next(err)
+}
+
+var _ Filter = ClassifierFilter
+
+// classifiedError is the error wrapped by Do with the Decision from Classify,
+// so that it's honored by RetryableErrorFilter.
+type classifiedError struct {
+	retryableWrapper
+}
+
+// classify wraps err into classifiedError when the registered classifiers
+// made a decision on it,
+// unless err itself already made a decision via RetryableError.
+func classify(err error) error {
+	if err == nil {
+		return nil
+	}
+	var re RetryableError
+	if errors.As(err, &re) && re.Retryable() != 0 {
+		return err
+	}
+	var retryable int
+	switch Classify(err) {
+	default:
+		return err
+	case Retry:
+		retryable = 1
+	case DoNotRetry:
+		retryable = -1
+	}
+	return classifiedError{
+		retryableWrapper: retryableWrapper{
+			err:       err,
+			retryable: retryable,
+		},
+	}
+}
+
+// unclassify reverts classify.
+func unclassify(err error) error {
+	if ce, ok := err.(classifiedError); ok {
+		return ce.err
+	}
+	return err
+}
@@ -0,0 +1,129 @@
+package retrybp
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/avast/retry-go"
+)
+
+func registerTestClassifiers(t *testing.T, cs ...Classifier) {
+	t.Helper()
+	classifiersLock.Lock()
+	prev := classifiers
+	classifiers = nil
+	classifiersLock.Unlock()
+	t.Cleanup(func() {
+		classifiersLock.Lock()
+		defer classifiersLock.Unlock()
+		classifiers = prev
+	})
+	for _, c := range cs {
+		RegisterClassifier(c)
+	}
+}
+
+var (
+	errRetryable    = errors.New("retryable")
+	errNotRetryable = errors.New("not retryable")
+	errConflicting  = errors.New("conflicting")
+	errUnknown      = errors.New("unknown")
+)
+
+func TestClassify(t *testing.T) {
+	registerTestClassifiers(
+		t,
+		func(err error) Decision {
+			if errors.Is(err, errRetryable) || errors.Is(err, errConflicting) {
+				return Retry
+			}
+			return NoDecision
+		},
+		func(err error) Decision {
+			if errors.Is(err, errNotRetryable) || errors.Is(err, errConflicting) {
+				return DoNotRetry
+			}
+			return NoDecision
+		},
+	)
+
+	for _, c := range []struct {
+		err      error
+		expected Decision
+	}{
+		{err: nil, expected: NoDecision},
+		{err: errUnknown, expected: NoDecision},
+		{err: errRetryable, expected: Retry},
+		{err: errNotRetryable, expected: DoNotRetry},
+		{err: errConflicting, expected: DoNotRetry},
+	} {
+		if got := Classify(c.err); got != c.expected {
+			t.Errorf("Classify(%v) expected %v, got %v", c.err, c.expected, got)
+		}
+	}
+}
+
+func TestDoClassifiers(t *testing.T) {
+	registerTestClassifiers(t, func(err error) Decision {
+		switch {
+		case errors.Is(err, errRetryable):
+			return Retry
+		case errors.Is(err, errNotRetryable):
+			return DoNotRetry
+		}
+		return NoDecision
+	})
+
+	for _, c := range []struct {
+		label    string
+		err      error
+		filters  []Filter
+		expected int
+	}{
+		{
+			label:    "retry",
+			err:      errRetryable,
+			filters:  []Filter{RetryableErrorFilter},
+			expected: 3,
+		},
+		{
+			label:    "do-not-retry",
+			err:      errNotRetryable,
+			filters:  []Filter{RetryableErrorFilter, NetworkErrorFilter, func(error, retry.RetryIfFunc) bool { return true }},
+			expected: 1,
+		},
+		{
+			label:    "unrecoverable-overrides",
+			err:      Unrecoverable(errRetryable),
+			filters:  []Filter{RetryableErrorFilter},
+			expected: 1,
+		},
+		{
+			label:    "no-decision",
+			err:      errUnknown,
+			filters:  []Filter{RetryableErrorFilter},
+			expected: 1,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			var attempts int
+			err := Do(
+				context.Background(),
+				func() error {
+					attempts++
+					return c.err
+				},
+				retry.Attempts(3),
+				retry.LastErrorOnly(true),
+				Filters(c.filters...),
+			)
+			if attempts != c.expected {
+				t.Errorf("Expected %d attempts, got %d", c.expected, attempts)
+			}
+			if err != c.err {
+				t.Errorf("Expected the original error %v to be returned, got %#v", c.err, err)
+			}
+		})
+	}
+}
@@ -50,6 +50,18 @@ func GetOptions(ctx context.Context) (options []retry.Option, ok bool) {
 // It also auto applies retry.Context with the ctx given,
 // so that the retries will be stopped as soon as ctx is canceled.
 // You can override this behavior by injecting a retry.Context option into ctx.
+//
+// The errors returned by fn are also checked against the classifiers
+// registered via RegisterClassifier, and the decisions are honored by
+// RetryableErrorFilter (used by the retry.RetryIf option via Filters).
+// The precedence of the decisions is:
+//
+// 1. The decision made by the error itself via RetryableError
+// (e.g. Unrecoverable).
+//
+// 2. The decision made by the registered classifiers, see Classify.
+//
+// 3. The decisions made by the other filters in the chain.
 func Do(ctx context.Context, fn func() error, defaults ...retry.Option) error {
 	options, _ := GetOptions(ctx)
 	mergedOptions := make([]retry.Option, 1, 1+len(defaults)+len(options))
@@ -58,12 +70,18 @@ func Do(ctx context.Context, fn func() error, defaults ...retry.Option) error {
 	mergedOptions[0] = retry.Context(ctx)
 	mergedOptions = append(mergedOptions, defaults...)
 	mergedOptions = append(mergedOptions, options...)
-	err := retry.Do(fn, mergedOptions...)
+	err := retry.Do(func() error {
+		return classify(fn())
+	}, mergedOptions...)
 
 	var retryErr retry.Error
 	if errors.As(err, &retryErr) {
-		return errors.Join(retryErr.WrappedErrors()...)
+		errs := retryErr.WrappedErrors()
+		for i, e := range errs {
+			errs[i] = unclassify(e)
+		}
+		return errors.Join(errs...)
 	}
 
-	return err
+	return unclassify(err)
 }
@@ -0,0 +1,91 @@
+package secrets
+
+import (
+	"crypto/tls"
+	"fmt"
+	"sync"
+)
+
+// GetCertificate fetches the PEM encoded certificate and private key from the
+// simple secrets at certPath and keyPath, and parses them into a
+// tls.Certificate.
+//
+// It returns an error if either of the secrets is missing, the PEM is
+// malformed, or the private key doesn't match the certificate.
+func (s *Secrets) GetCertificate(certPath, keyPath string) (tls.Certificate, error) {
+	certSecret, err := s.GetSimpleSecret(certPath)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	keySecret, err := s.GetSimpleSecret(keyPath)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	cert, err := tls.X509KeyPair(certSecret.Value, keySecret.Value)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf(
+			"secrets: failed to parse certificate %q with key %q: %w",
+			certPath,
+			keyPath,
+			err,
+		)
+	}
+	return cert, nil
+}
+
+// certificateCache caches the parsed certificates of a Store,
+// until the secrets are refreshed.
+type certificateCache struct {
+	mu    sync.Mutex
+	certs map[[2]string]cachedCertificate
+}
+
+type cachedCertificate struct {
+	secrets *Secrets
+	cert    tls.Certificate
+}
+
+func (c *certificateCache) get(secrets *Secrets, certPath, keyPath string) (tls.Certificate, error) {
+	key := [2]string{certPath, keyPath}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cached, ok := c.certs[key]; ok && cached.secrets == secrets {
+		return cached.cert, nil
+	}
+	cert, err := secrets.GetCertificate(certPath, keyPath)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	if c.certs == nil {
+		c.certs = make(map[[2]string]cachedCertificate)
+	}
+	c.certs[key] = cachedCertificate{
+		secrets: secrets,
+		cert:    cert,
+	}
+	return cert, nil
+}
+
+// GetCertificate loads secrets from watcher, and parses the certificate and
+// private key from the simple secrets at certPath and keyPath into a
+// tls.Certificate.
+//
+// The parsed certificate is cached until the secrets are refreshed,
+// so it's cheap to call GetCertificate every time the certificate is needed,
+// and the rotated certificates will be picked up without restart.
+// For example:
+//
+//	tlsConfig := &tls.Config{
+//		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+//			cert, err := store.GetCertificate(certPath, keyPath)
+//			if err != nil {
+//				return nil, err
+//			}
+//			return &cert, nil
+//		},
+//	}
+//
+// See Secrets.GetCertificate for the errors it could return.
+func (s *Store) GetCertificate(certPath, keyPath string) (tls.Certificate, error) {
+	return s.certs.get(s.getSecrets(), certPath, keyPath)
+}
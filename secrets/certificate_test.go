@@ -0,0 +1,128 @@
+package secrets_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/reddit/baseplate.go/secrets"
+)
+
+// generateCertificate generates a self-signed certificate and returns the PEM
+// encoded certificate and private key.
+func generateCertificate(t *testing.T, cn string) (certPEM, keyPEM string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	keyPEM = string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+	return certPEM, keyPEM
+}
+
+func certSecrets(cert, key string) map[string]secrets.GenericSecret {
+	return map[string]secrets.GenericSecret{
+		"tls/cert": {
+			Type:  secrets.SimpleType,
+			Value: cert,
+		},
+		"tls/key": {
+			Type:  secrets.SimpleType,
+			Value: key,
+		},
+	}
+}
+
+func TestStoreGetCertificate(t *testing.T) {
+	cert1, key1 := generateCertificate(t, "cert1")
+	cert2, key2 := generateCertificate(t, "cert2")
+
+	store, fw, err := secrets.NewTestSecrets(context.Background(), certSecrets(cert1, key1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkCN := func(t *testing.T, expected string) {
+		t.Helper()
+		cert, err := store.GetCertificate("tls/cert", "tls/key")
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parsed.Subject.CommonName != expected {
+			t.Errorf("Expected certificate CN %q, got %q", expected, parsed.Subject.CommonName)
+		}
+	}
+
+	checkCN(t, "cert1")
+	checkCN(t, "cert1")
+
+	if err := secrets.UpdateTestSecrets(fw, certSecrets(cert2, key2)); err != nil {
+		t.Fatal(err)
+	}
+	checkCN(t, "cert2")
+
+	t.Run("errors", func(t *testing.T) {
+		for _, c := range []struct {
+			label     string
+			cert, key string
+		}{
+			{
+				label: "malformed-pem",
+				cert:  "not a pem",
+				key:   key1,
+			},
+			{
+				label: "mismatched-key",
+				cert:  cert1,
+				key:   key2,
+			},
+		} {
+			t.Run(c.label, func(t *testing.T) {
+				if err := secrets.UpdateTestSecrets(fw, certSecrets(c.cert, c.key)); err != nil {
+					t.Fatal(err)
+				}
+				_, err := store.GetCertificate("tls/cert", "tls/key")
+				if err == nil {
+					t.Fatal("Expected error, got nil")
+				}
+				if !strings.Contains(err.Error(), `"tls/cert"`) {
+					t.Errorf("Expected error to mention the secret path, got %v", err)
+				}
+			})
+		}
+
+		_, err := store.GetCertificate("tls/missing", "tls/key")
+		var notFound secrets.SecretNotFoundError
+		if !errors.As(err, &notFound) {
+			t.Errorf("Expected SecretNotFoundError, got %v", err)
+		}
+	})
+}
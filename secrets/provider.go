@@ -0,0 +1,29 @@
+package secrets
+
+// Provider is the interface to access secrets.
+//
+// Store, which reads the secrets from a file or directory and keeps them
+// updated, is the implementation used by baseplate services.
+// *Secrets, as returned by NewSecrets, is an in-memory implementation that
+// can be used in tests without touching the filesystem.
+//
+// Code only reading secrets should accept a Provider instead of *Store,
+// so other sources of secrets can be plugged in.
+type Provider interface {
+	// GetSimpleSecret fetches a simple secret.
+	GetSimpleSecret(path string) (SimpleSecret, error)
+
+	// GetVersionedSecret fetches a versioned secret.
+	GetVersionedSecret(path string) (VersionedSecret, error)
+
+	// GetCredentialSecret fetches a credential secret.
+	GetCredentialSecret(path string) (CredentialSecret, error)
+
+	// GetVault returns the credentials to access Vault directly.
+	GetVault() (Vault, error)
+}
+
+var (
+	_ Provider = (*Store)(nil)
+	_ Provider = (*Secrets)(nil)
+)
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"io/fs"
+	"os"
 	"path/filepath"
 )
 
@@ -71,6 +72,28 @@ func (s *Secrets) GetSimpleSecret(path string) (SimpleSecret, error) {
 	return secret, nil
 }
 
+// GetMany fetches the values of multiple simple secrets.
+//
+// The returned map is keyed by the paths.
+// If any of the paths failed to be fetched,
+// it returns nil map and the errors of all the failed paths joined.
+func (s *Secrets) GetMany(paths ...string) (map[string]Secret, error) {
+	values := make(map[string]Secret, len(paths))
+	var errs []error
+	for _, path := range paths {
+		secret, err := s.GetSimpleSecret(path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		values[path] = secret.Value
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return values, nil
+}
+
 // GetVersionedSecret fetches a versioned secret or error if the key is not present.
 func (s *Secrets) GetVersionedSecret(path string) (VersionedSecret, error) {
 	if path == "" {
@@ -100,6 +123,13 @@ func (s *Secrets) GetVersionedSecret(path string) (VersionedSecret, error) {
 	return secret, nil
 }
 
+// GetVault returns the credentials to access Vault directly.
+//
+// This function always returns nil error.
+func (s *Secrets) GetVault() (Vault, error) {
+	return s.vault, nil
+}
+
 // GetCredentialSecret fetches a credential secret or error if the key is not
 // present.
 func (s *Secrets) GetCredentialSecret(path string) (CredentialSecret, error) {
@@ -325,6 +355,35 @@ func FromDir(dir fs.FS) (*Secrets, error) {
 
 }
 
+// ValidateFile parses the secrets file (or the Vault CSI directory) at path
+// with the same logic used by Store, and returns an error if any of the
+// secrets is malformed, e.g. invalid base64 encoding or unknown type.
+//
+// It doesn't install a file watcher, so it can be used by CI and deploy
+// tooling to validate a secrets file before rolling it out.
+func ValidateFile(path string) error {
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("secrets.ValidateFile: %w", err)
+	}
+	if fileInfo.IsDir() {
+		if _, err := FromDir(os.DirFS(path)); err != nil {
+			return fmt.Errorf("secrets.ValidateFile: %q: %w", path, err)
+		}
+		return nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("secrets.ValidateFile: %w", err)
+	}
+	defer file.Close()
+	if _, err := NewSecrets(file); err != nil {
+		return fmt.Errorf("secrets.ValidateFile: %q: %w", path, err)
+	}
+	return nil
+}
+
 func secretsValidate(secretsDocument Document) (*Secrets, error) {
 	secrets := &Secrets{
 		simpleSecrets:     make(map[string]SimpleSecret),
@@ -340,13 +399,13 @@ func secretsValidate(secretsDocument Document) (*Secrets, error) {
 		case "simple":
 			simple, err := newSimpleSecret(&secret)
 			if err != nil {
-				return nil, err
+				return nil, fmt.Errorf("secrets.NewSecrets: failed to decode simple secret %q: %w", key, err)
 			}
 			secrets.simpleSecrets[key] = simple
 		case "versioned":
 			versioned, err := newVersionedSecret(&secret)
 			if err != nil {
-				return nil, err
+				return nil, fmt.Errorf("secrets.NewSecrets: failed to decode versioned secret %q: %w", key, err)
 			}
 			secrets.versionedSecrets[key] = versioned
 		case "credential":
@@ -3,7 +3,11 @@ package secrets
 import (
 	"bytes"
 	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -241,3 +245,133 @@ func TestSecretsWrongType(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateFile(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name          string
+		input         string
+		expectedError string
+	}{
+		{
+			name: "valid",
+			input: `{
+				"secrets": {
+					"secret/myservice/some-api-key": {
+						"type": "simple",
+						"value": "Y2RvVXhNMVdsTXJma3BDaHRGZ0dPYkVGSg==",
+						"encoding": "base64"
+					}
+				}
+			}`,
+		},
+		{
+			name: "bad base64",
+			input: `{
+				"secrets": {
+					"secret/myservice/some-api-key": {
+						"type": "simple",
+						"value": "not base64!",
+						"encoding": "base64"
+					}
+				}
+			}`,
+			expectedError: `secret/myservice/some-api-key`,
+		},
+		{
+			name: "unknown type",
+			input: `{
+				"secrets": {
+					"secret/myservice/some-api-key": {
+						"type": "foo",
+						"value": "bar"
+					}
+				}
+			}`,
+			expectedError: `unknown secret type "foo"`,
+		},
+		{
+			name: "invalid encoding",
+			input: `{
+				"secrets": {
+					"secret/myservice/some-api-key": {
+						"type": "simple",
+						"value": "bar",
+						"encoding": "foo"
+					}
+				}
+			}`,
+			expectedError: ErrInvalidEncoding.Error(),
+		},
+		{
+			name:          "invalid json",
+			input:         `{"secrets": `,
+			expectedError: "unexpected EOF",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt // capture range variable for parallel testing
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			path := filepath.Join(dir, strings.ReplaceAll(tt.name, " ", "-")+".json")
+			if err := os.WriteFile(path, []byte(tt.input), 0644); err != nil {
+				t.Fatal(err)
+			}
+			err := ValidateFile(path)
+			if tt.expectedError == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected error containing %q, got nil", tt.expectedError)
+			}
+			if !strings.Contains(err.Error(), tt.expectedError) {
+				t.Errorf("expected error containing %q, actual: %v", tt.expectedError, err)
+			}
+		})
+	}
+
+	t.Run("not exist", func(t *testing.T) {
+		err := ValidateFile(filepath.Join(dir, "not-exist.json"))
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("expected fs.ErrNotExist, actual: %v", err)
+		}
+	})
+}
+
+func TestSecretsProvider(t *testing.T) {
+	s, err := NewSecrets(strings.NewReader(`{
+		"secrets": {
+			"secret/myservice/some-api-key": {
+				"type": "simple",
+				"value": "hunter2"
+			}
+		},
+		"vault": {
+			"url": "vault.reddit.ue1.snooguts.net",
+			"token": "17213328-36d4-11e7-8459-525400f56d04"
+		}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var provider Provider = s
+	secret, err := provider.GetSimpleSecret("secret/myservice/some-api-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(secret.Value) != "hunter2" {
+		t.Errorf("Expected secret %q, got %q", "hunter2", secret.Value)
+	}
+	vault, err := provider.GetVault()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vault.URL != "vault.reddit.ue1.snooguts.net" {
+		t.Errorf("Unexpected vault: %+v", vault)
+	}
+}
@@ -38,6 +38,8 @@ type Store struct {
 	// calling unsafeSecretHandlerFunc directly
 	mu                      sync.Mutex
 	unsafeSecretHandlerFunc SecretHandlerFunc
+
+	certs certificateCache
 }
 
 // NewStore returns a new instance of Store by configuring it
@@ -175,11 +177,34 @@ func (s *Store) GetCredentialSecret(path string) (CredentialSecret, error) {
 	return s.getSecrets().GetCredentialSecret(path)
 }
 
+// GetMany loads secrets from watcher, and fetches the values of multiple
+// simple secrets from them.
+//
+// All the paths are read from the same version of the secrets,
+// so the caller never observes a half-updated set of related secrets (e.g. a
+// username and a password stored as separate secrets) even if the secrets are
+// reloaded concurrently.
+// To read multiple secrets of other types consistently, use Snapshot instead.
+func (s *Store) GetMany(paths ...string) (map[string]Secret, error) {
+	return s.getSecrets().GetMany(paths...)
+}
+
+// Snapshot returns the current version of all the secrets.
+//
+// The returned Secrets never changes, so all the secrets read from it are
+// consistent with each other even if the secrets are reloaded concurrently.
+// Same as the values returned by other methods, it should only be used for
+// a short period of time (e.g. while reading several related secrets) instead
+// of being cached.
+func (s *Store) Snapshot() *Secrets {
+	return s.getSecrets()
+}
+
 // GetVault returns a struct with a URL and token to access Vault directly. The
 // token will have policies attached based on the current EC2 server's Vault
 // role. This is only necessary if talking directly to Vault.
 //
 // This function always returns nil error.
 func (s *Store) GetVault() (Vault, error) {
-	return s.getSecrets().vault, nil
+	return s.getSecrets().GetVault()
 }
@@ -3,9 +3,12 @@ package secrets
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io/fs"
 	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -193,3 +196,79 @@ func TestDirectoryError(t *testing.T) {
 		t.Error("Error is not of type notCSIError")
 	}
 }
+
+func TestGetManyConsistentDuringReload(t *testing.T) {
+	const (
+		delay       = 5 * time.Millisecond
+		generations = 30
+
+		userPath = "secret/db/username"
+		passPath = "secret/db/password"
+	)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "secrets.json")
+	write := func(t *testing.T, gen int) {
+		t.Helper()
+		content := fmt.Sprintf(`{
+	"secrets": {
+		%q: {"type": "simple", "value": "user-%d"},
+		%q: {"type": "simple", "value": "pass-%d"}
+	}
+}`, userPath, gen, passPath, gen)
+		tmp := filepath.Join(dir, fmt.Sprintf("tmp-%d.json", gen))
+		if err := os.WriteFile(tmp, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(t, 0)
+
+	store, err := newStore(context.Background(), delay, path, log.TestWrapper(t))
+	if err != nil {
+		t.Fatalf("Failed to create secrets store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	done := make(chan struct{})
+	seen := make(chan map[string]bool, 1)
+	go func() {
+		generations := make(map[string]bool)
+		defer func() {
+			seen <- generations
+		}()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			values, err := store.GetMany(userPath, passPath)
+			if err != nil {
+				t.Errorf("GetMany failed: %v", err)
+				return
+			}
+			user, _ := strings.CutPrefix(string(values[userPath]), "user-")
+			pass, _ := strings.CutPrefix(string(values[passPath]), "pass-")
+			if user != pass {
+				t.Errorf("Got mismatched secrets %q and %q", values[userPath], values[passPath])
+				return
+			}
+			generations[user] = true
+		}
+	}()
+
+	for gen := 1; gen <= generations; gen++ {
+		write(t, gen)
+		time.Sleep(delay * 2)
+	}
+	close(done)
+	if got := <-seen; len(got) < 2 {
+		t.Errorf("Expected the secrets to be reloaded during the test, only saw generations %v", got)
+	}
+
+	if _, err := store.GetMany(userPath, "secret/db/missing"); !errors.As(err, new(SecretNotFoundError)) {
+		t.Errorf("Expected SecretNotFoundError, got %v", err)
+	}
+}
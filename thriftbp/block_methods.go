@@ -0,0 +1,143 @@
+package thriftbp
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/yaml.v2"
+
+	"github.com/reddit/baseplate.go/filewatcher/v2"
+)
+
+// BlockMethods returns a ProcessorMiddleware that rejects the requests to the
+// given thrift methods, before the handler runs.
+//
+// See BlockMethodsFunc for more details.
+func BlockMethods(names ...string) thrift.ProcessorMiddleware {
+	blocked := make(map[string]bool, len(names))
+	for _, name := range names {
+		blocked[name] = true
+	}
+	return BlockMethodsFunc(func(method string) bool {
+		return blocked[method]
+	})
+}
+
+// BlockMethodsFunc returns a ProcessorMiddleware that rejects the requests to
+// the thrift methods isBlocked returns true for, before the handler runs.
+//
+// isBlocked is called on every request, so it can be backed by a config that
+// changes at runtime, for example BlockedMethodsFile.IsBlocked.
+//
+// The blocked requests are responded with a TApplicationException of type
+// UNKNOWN_METHOD, the same as the requests to a method the server doesn't
+// implement.
+// A baseplate.Error can't be used here as it's only a valid response for the
+// methods declaring it in the thrift IDL.
+//
+// It also emits the thriftbp_server_blocked_requests_total counter with
+// thrift_method label.
+func BlockMethodsFunc(isBlocked func(method string) bool) thrift.ProcessorMiddleware {
+	return func(name string, next thrift.TProcessorFunction) thrift.TProcessorFunction {
+		return thrift.WrappedTProcessorFunction{
+			Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+				if !isBlocked(name) {
+					return next.Process(ctx, seqID, in, out)
+				}
+				serverBlockedRequests.With(prometheus.Labels{
+					methodLabel: name,
+				}).Inc()
+				return false, rejectRequest(ctx, name, seqID, in, out, thrift.NewTApplicationException(
+					thrift.UNKNOWN_METHOD,
+					fmt.Sprintf("thriftbp: method %q is blocked", name),
+				))
+			},
+		}
+	}
+}
+
+// rejectRequest discards the request args and writes exc as the response,
+// the same way the thrift compiler generated processors do for unknown
+// methods.
+func rejectRequest(ctx context.Context, name string, seqID int32, in, out thrift.TProtocol, exc thrift.TApplicationException) thrift.TException {
+	if err := in.Skip(ctx, thrift.STRUCT); err != nil {
+		return thrift.WrapTException(err)
+	}
+	if err := in.ReadMessageEnd(ctx); err != nil {
+		return thrift.WrapTException(err)
+	}
+	if err := out.WriteMessageBegin(ctx, name, thrift.EXCEPTION, seqID); err != nil {
+		return thrift.WrapTException(err)
+	}
+	if err := exc.Write(ctx, out); err != nil {
+		return thrift.WrapTException(err)
+	}
+	if err := out.WriteMessageEnd(ctx); err != nil {
+		return thrift.WrapTException(err)
+	}
+	if err := out.Flush(ctx); err != nil {
+		return thrift.WrapTException(err)
+	}
+	return exc
+}
+
+// BlockedMethodsFile is the list of blocked thrift methods loaded from a yaml
+// file, kept updated with the changes to the file.
+//
+// The file should contain a yaml list of the method names, for example:
+//
+//	# methods to reject
+//	- expensiveAdminMethod
+//	- anotherMethod
+//
+// An empty file means no methods are blocked.
+type BlockedMethodsFile struct {
+	watcher filewatcher.FileWatcher[map[string]bool]
+}
+
+// WatchBlockedMethods loads the blocked methods from the yaml file at path,
+// and watches the file for changes.
+//
+// Context should come with a timeout otherwise this might block forever, i.e.
+// if the path never becomes available.
+//
+// Use IsBlocked with BlockMethodsFunc, for example:
+//
+//	blocked, err := thriftbp.WatchBlockedMethods(ctx, path)
+//	if err != nil {
+//		// handle err
+//	}
+//	defer blocked.Close()
+//	middlewares = append(middlewares, thriftbp.BlockMethodsFunc(blocked.IsBlocked))
+func WatchBlockedMethods(ctx context.Context, path string, opts ...filewatcher.Option) (*BlockedMethodsFile, error) {
+	watcher, err := filewatcher.New(ctx, path, parseBlockedMethods, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("thriftbp: failed to load blocked methods from %q: %w", path, err)
+	}
+	return &BlockedMethodsFile{watcher: watcher}, nil
+}
+
+func parseBlockedMethods(r io.Reader) (map[string]bool, error) {
+	var names []string
+	if err := yaml.NewDecoder(r).Decode(&names); err != nil && err != io.EOF {
+		return nil, err
+	}
+	blocked := make(map[string]bool, len(names))
+	for _, name := range names {
+		blocked[name] = true
+	}
+	return blocked, nil
+}
+
+// IsBlocked returns true if method is in the latest list of blocked methods.
+func (f *BlockedMethodsFile) IsBlocked(method string) bool {
+	return f.watcher.Get()[method]
+}
+
+// Close stops watching the file.
+func (f *BlockedMethodsFile) Close() error {
+	return f.watcher.Close()
+}
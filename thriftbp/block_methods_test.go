@@ -0,0 +1,88 @@
+package thriftbp_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/apache/thrift/lib/go/thrift"
+
+	"github.com/reddit/baseplate.go/thriftbp"
+)
+
+func TestBlockMethods(t *testing.T) {
+	ctx := context.Background()
+	process := func(t *testing.T, middleware thrift.ProcessorMiddleware, method string) (called bool, out *thrift.TMemoryBuffer) {
+		t.Helper()
+		in := thrift.NewTMemoryBuffer()
+		inProto := thrift.NewTBinaryProtocolConf(in, nil)
+		// An empty args struct.
+		inProto.WriteStructBegin(ctx, "args")
+		inProto.WriteFieldStop(ctx)
+		inProto.WriteStructEnd(ctx)
+
+		out = thrift.NewTMemoryBuffer()
+		processor := middleware(method, thrift.WrappedTProcessorFunction{
+			Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+				called = true
+				return true, nil
+			},
+		})
+		processor.Process(ctx, 1, inProto, thrift.NewTBinaryProtocolConf(out, nil))
+		return called, out
+	}
+
+	middleware := thriftbp.BlockMethods("blocked")
+
+	t.Run("blocked", func(t *testing.T) {
+		called, out := process(t, middleware, "blocked")
+		if called {
+			t.Error("Expected handler not to be called")
+		}
+		outProto := thrift.NewTBinaryProtocolConf(out, nil)
+		name, typeID, seqID, err := outProto.ReadMessageBegin(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name != "blocked" || typeID != thrift.EXCEPTION || seqID != 1 {
+			t.Errorf("Unexpected message begin: %q, %v, %d", name, typeID, seqID)
+		}
+		exc := thrift.NewTApplicationException(0, "")
+		if err := exc.Read(ctx, outProto); err != nil {
+			t.Fatal(err)
+		}
+		if exc.TypeId() != thrift.UNKNOWN_METHOD {
+			t.Errorf("Expected exception type %d, got %d", thrift.UNKNOWN_METHOD, exc.TypeId())
+		}
+	})
+
+	t.Run("allowed", func(t *testing.T) {
+		called, _ := process(t, middleware, "allowed")
+		if !called {
+			t.Error("Expected handler to be called")
+		}
+	})
+}
+
+func TestWatchBlockedMethods(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocked.yaml")
+	if err := os.WriteFile(path, []byte("- foo\n- bar\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	blocked, err := thriftbp.WatchBlockedMethods(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer blocked.Close()
+
+	for method, expected := range map[string]bool{
+		"foo": true,
+		"bar": true,
+		"baz": false,
+	} {
+		if got := blocked.IsBlocked(method); got != expected {
+			t.Errorf("IsBlocked(%q) expected %v, got %v", method, expected, got)
+		}
+	}
+}
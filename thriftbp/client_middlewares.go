@@ -4,19 +4,23 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"sort"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/apache/thrift/lib/go/thrift"
 	"github.com/avast/retry-go"
 	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
 
 	"github.com/reddit/baseplate.go/breakerbp"
 	"github.com/reddit/baseplate.go/ecinterface"
 	"github.com/reddit/baseplate.go/errorsbp"
 	"github.com/reddit/baseplate.go/internal/gen-go/reddit/baseplate"
 	"github.com/reddit/baseplate.go/internal/thriftint"
+	"github.com/reddit/baseplate.go/log"
 	//lint:ignore SA1019 This library is internal only, not actually deprecated
 	"github.com/reddit/baseplate.go/internalv2compat"
 	"github.com/reddit/baseplate.go/prometheusbp"
@@ -98,6 +102,28 @@ type DefaultClientMiddlewareArgs struct {
 	//
 	// Optional. If this is empty, no "User-Agent" header will be sent.
 	ClientName string
+
+	// ContextHeaders are the headers to be injected into every client call,
+	// keyed by the header names, with the values derived from the context
+	// object via the ContextHeaderFunc.
+	//
+	// See InjectContextHeader for more details. Optional.
+	ContextHeaders map[string]ContextHeaderFunc
+
+	// When SlowCallThreshold > 0, LogSlowCalls will be added to log the calls
+	// took longer than it. Optional.
+	SlowCallThreshold time.Duration
+
+	// When OTelSpanAttributes is true, OTelClientSpanAttributes will be added
+	// right after the MonitorClient of the raw client calls. Optional.
+	OTelSpanAttributes bool
+
+	// MaxAttempts is the ceiling of the attempts of a single call enforced by
+	// CapAttempts, regardless of the RetryOptions and the per-call options set
+	// via retrybp.WithOptions.
+	//
+	// Optional. When MaxAttempts <= 0, CapAttempts will not be added.
+	MaxAttempts int
 }
 
 // BaseplateDefaultClientMiddlewares returns the default client middlewares that
@@ -109,29 +135,38 @@ type DefaultClientMiddlewareArgs struct {
 //
 // 2. SetClientName(clientName)
 //
-// 3. MonitorClient with MonitorClientWrappedSlugSuffix - This creates the spans
+// 3. InjectContextHeader - One for each of ContextHeaders, sorted by the header
+// names.
+//
+// 4. MonitorClient with MonitorClientWrappedSlugSuffix - This creates the spans
 // from the view of the client that group all retries into a single,
 // wrapped span.
 //
-// 4. PrometheusClientMiddleware with MonitorClientWrappedSlugSuffix - This
+// 5. PrometheusClientMiddleware with MonitorClientWrappedSlugSuffix - This
 // creates the prometheus client metrics from the view of the client that group
 // all retries into a single operation.
 //
-// 5. Retry(retryOptions) - If retryOptions is empty/nil, default to only
+// 6. CapAttempts(maxAttempts) - Only if MaxAttempts > 0.
+//
+// 7. Retry(retryOptions) - If retryOptions is empty/nil, default to only
 // retry.Attempts(1), this will not actually retry any calls but your client is
 // configured to set retry logic per-call using retrybp.WithOptions.
 //
-// 6. FailureRatioBreaker - Only if BreakerConfig is non-nil.
+// 8. FailureRatioBreaker - Only if BreakerConfig is non-nil.
+//
+// 9. MonitorClient - This creates the spans of the raw client calls.
 //
-// 7. MonitorClient - This creates the spans of the raw client calls.
+// 10. OTelClientSpanAttributes - Only if OTelSpanAttributes is true.
 //
-// 8. PrometheusClientMiddleware
+// 11. PrometheusClientMiddleware
 //
-// 9. BaseplateErrorWrapper
+// 12. LogSlowCalls - Only if SlowCallThreshold > 0.
 //
-// 10. thrift.ExtractIDLExceptionClientMiddleware
+// 13. BaseplateErrorWrapper
 //
-// 11. SetDeadlineBudget
+// 14. thrift.ExtractIDLExceptionClientMiddleware
+//
+// 15. SetDeadlineBudget
 func BaseplateDefaultClientMiddlewares(args DefaultClientMiddlewareArgs) []thrift.ClientMiddleware {
 	if len(args.RetryOptions) == 0 {
 		args.RetryOptions = []retry.Option{retry.Attempts(1)}
@@ -139,13 +174,27 @@ func BaseplateDefaultClientMiddlewares(args DefaultClientMiddlewareArgs) []thrif
 	middlewares := []thrift.ClientMiddleware{
 		ForwardEdgeRequestContext(args.EdgeContextImpl),
 		SetClientName(args.ClientName),
+	}
+	headerNames := make([]string, 0, len(args.ContextHeaders))
+	for name := range args.ContextHeaders {
+		headerNames = append(headerNames, name)
+	}
+	sort.Strings(headerNames)
+	for _, name := range headerNames {
+		middlewares = append(middlewares, InjectContextHeader(name, args.ContextHeaders[name]))
+	}
+	middlewares = append(
+		middlewares,
 		MonitorClient(MonitorClientArgs{
 			ServiceSlug:         args.ServiceSlug + MonitorClientWrappedSlugSuffix,
 			ErrorSpanSuppressor: args.ErrorSpanSuppressor,
 		}),
-		PrometheusClientMiddleware(args.ServiceSlug + MonitorClientWrappedSlugSuffix),
-		Retry(args.RetryOptions...),
+		PrometheusClientMiddleware(args.ServiceSlug+MonitorClientWrappedSlugSuffix),
+	)
+	if args.MaxAttempts > 0 {
+		middlewares = append(middlewares, CapAttempts(args.ServiceSlug, args.MaxAttempts))
 	}
+	middlewares = append(middlewares, Retry(args.RetryOptions...))
 	if args.BreakerConfig != nil {
 		middlewares = append(
 			middlewares,
@@ -158,7 +207,22 @@ func BaseplateDefaultClientMiddlewares(args DefaultClientMiddlewareArgs) []thrif
 			ServiceSlug:         args.ServiceSlug,
 			ErrorSpanSuppressor: args.ErrorSpanSuppressor,
 		}),
+	)
+	if args.OTelSpanAttributes {
+		middlewares = append(middlewares, OTelClientSpanAttributes(args.ServiceSlug))
+	}
+	middlewares = append(
+		middlewares,
 		PrometheusClientMiddleware(args.ServiceSlug),
+	)
+	if args.SlowCallThreshold > 0 {
+		middlewares = append(
+			middlewares,
+			LogSlowCalls(args.ServiceSlug, args.SlowCallThreshold),
+		)
+	}
+	middlewares = append(
+		middlewares,
 		BaseplateErrorWrapper,
 		thrift.ExtractIDLExceptionClientMiddleware,
 		SetDeadlineBudget,
@@ -230,6 +294,15 @@ func ForwardEdgeRequestContext(ecImpl ecinterface.Interface) thrift.ClientMiddle
 
 // SetDeadlineBudget is the client middleware implementing Phase 1 of Baseplate
 // deadline propogation.
+//
+// If the context object has a deadline, the remaining time, rounded up to the
+// next millisecond, is sent as transport.HeaderDeadlineBudget header.
+// Nothing is sent when there's no deadline.
+// If the deadline already passed, the call is not made and the context error is
+// returned instead.
+//
+// It's the last one of BaseplateDefaultClientMiddlewares, after Retry,
+// so the budget is recomputed for every attempt and shrinks with the retries.
 func SetDeadlineBudget(next thrift.TClient) thrift.TClient {
 	return thrift.WrappedTClient{
 		Wrapped: func(ctx context.Context, method string, args, result thrift.TStruct) (thrift.ResponseMeta, error) {
@@ -261,25 +334,108 @@ func SetDeadlineBudget(next thrift.TClient) thrift.TClient {
 
 // Retry returns a thrift.ClientMiddleware that can be used to automatically
 // retry thrift requests.
+//
+// When there's a CapAttempts before it, the attempts are also capped by that.
 func Retry(defaults ...retry.Option) thrift.ClientMiddleware {
 	return func(next thrift.TClient) thrift.TClient {
 		return thrift.WrappedTClient{
 			Wrapped: func(ctx context.Context, method string, args, result thrift.TStruct) (thrift.ResponseMeta, error) {
 				var lastMeta thrift.ResponseMeta
-				return lastMeta, retrybp.Do(
+				// capErr is the error of the last attempt allowed by CapAttempts.
+				var capErr error
+				err := retrybp.Do(
 					ctx,
 					func() error {
+						if capErr != nil {
+							// The retry filters ignored the unrecoverable error.
+							return retry.Unrecoverable(capErr)
+						}
 						var err error
 						lastMeta, err = next.Call(ctx, method, args, result)
-						return getClientError(result, err)
+						err = getClientError(result, err)
+						if err != nil && attemptsCapReached(ctx, method) {
+							capErr = err
+							// Use retry.Unrecoverable instead of retrybp.Unrecoverable, so
+							// it's honored by the default retry.RetryIf as well as
+							// RetryableErrorFilter, and retry stops without another backoff.
+							return retry.Unrecoverable(err)
+						}
+						return err
 					},
 					defaults...,
 				)
+				if capErr != nil {
+					return lastMeta, capErr
+				}
+				return lastMeta, err
 			},
 		}
 	}
 }
 
+type attemptsCapCtxKeyType struct{}
+
+var attemptsCapCtxKey attemptsCapCtxKeyType
+
+type attemptsCap struct {
+	slug        string
+	maxAttempts int32
+	attempts    atomic.Int32
+}
+
+// CapAttempts returns a ClientMiddleware that enforces an absolute ceiling of
+// maxAttempts on the attempts made by all the Retry middlewares after it for a
+// single call, regardless of their retry.Options, including the ones set per
+// call via retrybp.WithOptions.
+//
+// It protects against the misconfigurations where a high retry count amplifies
+// the load on the upstream.
+// When the last allowed attempt fails, Retry stops right away with the error
+// of that attempt, without waiting for another backoff,
+// and thriftbp_client_max_attempts_reached_total counter is increased with
+// labels:
+//
+//   - thrift_method: the method of the endpoint called
+//   - thrift_client_name: the slug arg
+//
+// When maxAttempts <= 0, it's no-op.
+//
+// If you are using a thrift ClientPool created by NewBaseplateClientPool,
+// set ClientPoolConfig.MaxAttempts to add it.
+func CapAttempts(slug string, maxAttempts int) thrift.ClientMiddleware {
+	return func(next thrift.TClient) thrift.TClient {
+		if maxAttempts <= 0 {
+			return next
+		}
+		return thrift.WrappedTClient{
+			Wrapped: func(ctx context.Context, method string, args, result thrift.TStruct) (thrift.ResponseMeta, error) {
+				ctx = context.WithValue(ctx, attemptsCapCtxKey, &attemptsCap{
+					slug:        slug,
+					maxAttempts: int32(maxAttempts),
+				})
+				return next.Call(ctx, method, args, result)
+			},
+		}
+	}
+}
+
+// attemptsCapReached records a failed attempt in the attemptsCap from ctx,
+// and returns true when no more attempts are allowed.
+func attemptsCapReached(ctx context.Context, method string) bool {
+	c, ok := ctx.Value(attemptsCapCtxKey).(*attemptsCap)
+	if !ok {
+		return false
+	}
+	if c.attempts.Add(1) < c.maxAttempts {
+		return false
+	}
+	clientMaxAttemptsReachedCounter.With(prometheus.Labels{
+		methodLabel:     method,
+		clientNameLabel: c.slug,
+	}).Inc()
+	return true
+}
+
 // BaseplateErrorWrapper is a client middleware that calls WrapBaseplateError to
 // wrap the error returned by the next client call.
 func BaseplateErrorWrapper(next thrift.TClient) thrift.TClient {
@@ -311,6 +467,33 @@ func SetClientName(clientName string) thrift.ClientMiddleware {
 	}
 }
 
+// ContextHeaderFunc derives the value of a header from the context object.
+//
+// It should return false when the header should not be sent.
+type ContextHeaderFunc func(ctx context.Context) (value string, ok bool)
+
+// InjectContextHeader returns a ClientMiddleware that sets the thrift THeader
+// headerName on the requests, with the value returned by valueFn.
+//
+// When valueFn returns false, the header will be left untouched.
+//
+// This can be used to carry information derived from the context object
+// (for example the current tenant) to every outbound call.
+// If you are using a thrift ClientPool created by NewBaseplateClientPool,
+// use ClientPoolConfig.ContextHeaders instead.
+func InjectContextHeader(headerName string, valueFn ContextHeaderFunc) thrift.ClientMiddleware {
+	return func(next thrift.TClient) thrift.TClient {
+		return thrift.WrappedTClient{
+			Wrapped: func(ctx context.Context, method string, args, result thrift.TStruct) (thrift.ResponseMeta, error) {
+				if value, ok := valueFn(ctx); ok {
+					ctx = AddClientHeader(ctx, headerName, value)
+				}
+				return next.Call(ctx, method, args, result)
+			},
+		}
+	}
+}
+
 var (
 	_ thrift.ClientMiddleware = SetDeadlineBudget
 	_ thrift.ClientMiddleware = BaseplateErrorWrapper
@@ -390,6 +573,55 @@ func PrometheusClientMiddleware(remoteServerSlug string) thrift.ClientMiddleware
 	}
 }
 
+// slowCallsLogBurst is the max number of log lines LogSlowCalls writes per
+// second.
+const slowCallsLogBurst = 10
+
+// LogSlowCalls returns a ClientMiddleware that logs the calls took longer than
+// threshold, at warning level via log.C(ctx),
+// with the method, the pool slug, and the duration of the call.
+//
+// To avoid flooding the logs, the log lines are rate limited to 10 per second
+// per middleware, while
+// thriftbp_client_slow_calls_total counter is always increased for every slow
+// call, with labels:
+//
+//   - thrift_method: the method of the endpoint called
+//   - thrift_client_name: the slug arg
+//
+// If you are using a thrift ClientPool created by NewBaseplateClientPool,
+// set ClientPoolConfig.SlowCallThreshold instead.
+func LogSlowCalls(slug string, threshold time.Duration) thrift.ClientMiddleware {
+	limiter := rate.NewLimiter(rate.Every(time.Second/slowCallsLogBurst), slowCallsLogBurst)
+	return func(next thrift.TClient) thrift.TClient {
+		return thrift.WrappedTClient{
+			Wrapped: func(ctx context.Context, method string, args, result thrift.TStruct) (thrift.ResponseMeta, error) {
+				start := time.Now()
+				defer func() {
+					duration := time.Since(start)
+					if duration <= threshold {
+						return
+					}
+					clientSlowCallsCounter.With(prometheus.Labels{
+						methodLabel:     method,
+						clientNameLabel: slug,
+					}).Inc()
+					if limiter.Allow() {
+						log.C(ctx).Warnw(
+							"thriftbp: slow client call",
+							"method", method,
+							"pool", slug,
+							"duration", duration,
+							"threshold", threshold,
+						)
+					}
+				}()
+				return next.Call(ctx, method, args, result)
+			},
+		}
+	}
+}
+
 func getClientError(result thrift.TStruct, err error) error {
 	if err != nil {
 		return err
@@ -3,6 +3,8 @@ package thriftbp_test
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strconv"
 	"testing"
 	"time"
 
@@ -159,6 +161,128 @@ func TestSetDeadlineBudget(t *testing.T) {
 			headerInWriteHeaderList(ctx, t, transport.HeaderDeadlineBudget)
 		},
 	)
+
+	t.Run(
+		"no-deadline",
+		func(t *testing.T) {
+			mock, recorder, client := initClients(nil)
+			mock.AddMockCall(
+				method,
+				func(ctx context.Context, args, result thrift.TStruct) (meta thrift.ResponseMeta, err error) {
+					return
+				},
+			)
+
+			if _, err := client.Call(context.Background(), method, nil, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(recorder.Calls()) != 1 {
+				t.Fatalf("Wrong number of calls: %d", len(recorder.Calls()))
+			}
+			if v, ok := thrift.GetHeader(recorder.Calls()[0].Ctx, transport.HeaderDeadlineBudget); ok {
+				t.Errorf("Expected %s header not set, got %q", transport.HeaderDeadlineBudget, v)
+			}
+		},
+	)
+
+	t.Run(
+		"remaining",
+		func(t *testing.T) {
+			const (
+				timeout   = 500 * time.Millisecond
+				tolerance = 100 * time.Millisecond
+			)
+
+			mock, recorder, client := initClients(nil)
+			mock.AddMockCall(
+				method,
+				func(ctx context.Context, args, result thrift.TStruct) (meta thrift.ResponseMeta, err error) {
+					return
+				},
+			)
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			if _, err := client.Call(ctx, method, nil, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(recorder.Calls()) != 1 {
+				t.Fatalf("Wrong number of calls: %d", len(recorder.Calls()))
+			}
+			budget := deadlineBudget(t, recorder.Calls()[0].Ctx)
+			if budget > timeout || budget < timeout-tolerance {
+				t.Errorf("Expected budget within %v of %v, got %v", tolerance, timeout, budget)
+			}
+		},
+	)
+
+	t.Run(
+		"retries",
+		func(t *testing.T) {
+			const (
+				timeout = 500 * time.Millisecond
+				delay   = 50 * time.Millisecond
+			)
+
+			mock := &thrifttest.MockClient{FailUnregisteredMethods: true}
+			mock.AddMockCall(
+				method,
+				func(ctx context.Context, args, result thrift.TStruct) (meta thrift.ResponseMeta, err error) {
+					time.Sleep(delay)
+					return meta, errors.New("retry me")
+				},
+			)
+			recorder := thrifttest.NewRecordedClient(mock)
+			client := thrift.WrapClient(
+				recorder,
+				thriftbp.BaseplateDefaultClientMiddlewares(
+					thriftbp.DefaultClientMiddlewareArgs{
+						EdgeContextImpl: ecinterface.Mock(),
+						ServiceSlug:     service,
+						RetryOptions: []retry.Option{
+							retry.Attempts(2),
+							retry.Delay(0),
+							retrybp.Filters(func(err error, next retry.RetryIfFunc) bool {
+								return true
+							}),
+						},
+					},
+				)...,
+			)
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			if _, err := client.Call(ctx, method, nil, nil); err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+
+			calls := recorder.Calls()
+			if len(calls) != 2 {
+				t.Fatalf("Wrong number of calls: %d", len(calls))
+			}
+			first := deadlineBudget(t, calls[0].Ctx)
+			second := deadlineBudget(t, calls[1].Ctx)
+			if first-second < delay {
+				t.Errorf("Expected budget to shrink by at least %v between attempts, got %v and %v", delay, first, second)
+			}
+		},
+	)
+}
+
+func deadlineBudget(t *testing.T, ctx context.Context) time.Duration {
+	t.Helper()
+
+	v, ok := thrift.GetHeader(ctx, transport.HeaderDeadlineBudget)
+	if !ok {
+		t.Fatalf("%s header not set", transport.HeaderDeadlineBudget)
+	}
+	ms, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		t.Fatalf("Failed to parse %s header %q: %v", transport.HeaderDeadlineBudget, v, err)
+	}
+	return time.Duration(ms) * time.Millisecond
 }
 
 const retryTestTimeout = 10 * time.Millisecond
@@ -232,6 +356,95 @@ func TestRetry(t *testing.T) {
 	}
 }
 
+func TestCapAttempts(t *testing.T) {
+	const maxAttempts = 3
+	errCall := errors.New("call failed")
+	for _, c := range []struct {
+		label    string
+		options  []retry.Option
+		expected int
+		capped   bool
+		// delays is the expected number of backoffs.
+		delays int
+	}{
+		{
+			label:    "capped",
+			options:  []retry.Option{retry.Attempts(10)},
+			expected: maxAttempts,
+			capped:   true,
+			delays:   maxAttempts - 1,
+		},
+		{
+			label: "capped-with-filters",
+			options: []retry.Option{
+				retry.Attempts(10),
+				retrybp.Filters(
+					retrybp.RetryableErrorFilter,
+					func(err error, next retry.RetryIfFunc) bool { return true },
+				),
+			},
+			expected: maxAttempts,
+			capped:   true,
+			delays:   maxAttempts - 1,
+		},
+		{
+			label: "capped-ignoring-unrecoverable",
+			options: []retry.Option{
+				retry.Attempts(10),
+				retry.RetryIf(func(error) bool { return true }),
+			},
+			expected: maxAttempts,
+			capped:   true,
+			// The filter ignoring unrecoverable errors makes retry-go keep backing
+			// off, but no more calls are made.
+			delays: 9,
+		},
+		{
+			label:    "under-cap",
+			options:  []retry.Option{retry.Attempts(2)},
+			expected: 2,
+			delays:   1,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			var calls, delays int
+			options := append([]retry.Option{
+				retry.DelayType(func(uint, error, *retry.Config) time.Duration {
+					delays++
+					return 0
+				}),
+			}, c.options...)
+			client := thrift.WrapClient(
+				thrift.WrappedTClient{
+					Wrapped: func(ctx context.Context, method string, args, result thrift.TStruct) (thrift.ResponseMeta, error) {
+						calls++
+						return thrift.ResponseMeta{}, fmt.Errorf("attempt #%d: %w", calls, errCall)
+					},
+				},
+				thriftbp.CapAttempts(service, maxAttempts),
+				thriftbp.Retry(options...),
+			)
+			_, err := client.Call(context.Background(), method, nil, nil)
+			if calls != c.expected {
+				t.Errorf("Expected %d calls, got %d", c.expected, calls)
+			}
+			if !errors.Is(err, errCall) {
+				t.Errorf("Expected error to wrap %v, got %v", errCall, err)
+			}
+			if delays != c.delays {
+				t.Errorf("Expected %d delays, got %d", c.delays, delays)
+			}
+			if !c.capped {
+				return
+			}
+			// Only the error of the last attempt should be returned.
+			if want := fmt.Sprintf("attempt #%d: %v", maxAttempts, errCall); err.Error() != want {
+				t.Errorf("Expected error %q, got %q", want, err)
+			}
+		})
+	}
+}
+
 func TestSetClientName(t *testing.T) {
 	const header = transport.HeaderUserAgent
 
@@ -312,6 +525,88 @@ func TestSetClientName(t *testing.T) {
 	)
 }
 
+func TestInjectContextHeader(t *testing.T) {
+	const header = "tenant"
+
+	type tenantKey struct{}
+	valueFn := func(ctx context.Context) (string, bool) {
+		tenant, ok := ctx.Value(tenantKey{}).(string)
+		return tenant, ok
+	}
+
+	initClientsForHeader := func() (*thrifttest.RecordedClient, thrift.TClient) {
+		mock := &thrifttest.MockClient{FailUnregisteredMethods: true}
+		mock.AddMockCall(
+			method,
+			func(ctx context.Context, args, result thrift.TStruct) (meta thrift.ResponseMeta, err error) {
+				return
+			},
+		)
+		recorder := thrifttest.NewRecordedClient(mock)
+		client := thrift.WrapClient(
+			recorder,
+			thriftbp.BaseplateDefaultClientMiddlewares(
+				thriftbp.DefaultClientMiddlewareArgs{
+					EdgeContextImpl: ecinterface.Mock(),
+					ServiceSlug:     service,
+					ContextHeaders: map[string]thriftbp.ContextHeaderFunc{
+						header: valueFn,
+					},
+				},
+			)...,
+		)
+		return recorder, client
+	}
+
+	t.Run(
+		"omitted",
+		func(t *testing.T) {
+			recorder, client := initClientsForHeader()
+			_, err := client.Call(context.Background(), method, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(recorder.Calls()) != 1 {
+				t.Fatalf("Wrong number of calls: %d", len(recorder.Calls()))
+			}
+
+			ctx := recorder.Calls()[0].Ctx
+			for _, h := range thrift.GetWriteHeaderList(ctx) {
+				if h == header {
+					t.Errorf("Did not expect header %q in write header list", header)
+				}
+			}
+			if v, ok := thrift.GetHeader(ctx, header); ok {
+				t.Errorf("Did not expect header %q, got %q", header, v)
+			}
+		},
+	)
+
+	t.Run(
+		"set",
+		func(t *testing.T) {
+			const tenant = "foo"
+			recorder, client := initClientsForHeader()
+			ctx := context.WithValue(context.Background(), tenantKey{}, tenant)
+			_, err := client.Call(ctx, method, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(recorder.Calls()) != 1 {
+				t.Fatalf("Wrong number of calls: %d", len(recorder.Calls()))
+			}
+
+			ctx = recorder.Calls()[0].Ctx
+			headerInWriteHeaderList(ctx, t, header)
+			if v, ok := thrift.GetHeader(ctx, header); v != tenant {
+				t.Errorf("Expected header %q to be %q, got %q, %v", header, tenant, v, ok)
+			}
+		},
+	)
+}
+
 const (
 	methodIsHealthy = "is_healthy"
 )
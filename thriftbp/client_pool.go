@@ -44,6 +44,27 @@ func (err PoolError) Unwrap() error {
 	return err.Cause
 }
 
+// ConnectError is returned when a new connection to Addr failed to be opened.
+//
+// It allows the failures to be attributed to specific addresses when an
+// AddressGenerator returning multiple addresses is used.
+type ConnectError struct {
+	// Addr is the address the connection attempted to connect to.
+	Addr string
+
+	// Cause is the inner error wrapped by ConnectError,
+	// usually wrapping the underlying net error.
+	Cause error
+}
+
+func (err ConnectError) Error() string {
+	return fmt.Sprintf("thriftbp: error opening TSocket to %q for new Thrift client: %v", err.Addr, err.Cause)
+}
+
+func (err ConnectError) Unwrap() error {
+	return err.Cause
+}
+
 var (
 	_ error = PoolError{}
 	_ error = (*PoolError)(nil)
@@ -84,6 +105,13 @@ type ClientPoolConfig struct {
 	// the pool, we log the errors on warning level,
 	// then return the pool with the <N connections we already established.
 	//
+	// The number of the connections failed to be established is also counted
+	// by thriftbp_client_pool_initial_connection_shortfall counter
+	// (including the case of RequiredInitialConnections not being met),
+	// and InitialConnections is reported via
+	// thriftbp_client_pool_initial_connections gauge for context,
+	// both with thrift_pool label.
+	//
 	// If that's unacceptable, then RequiredInitialConnections can be used to set
 	// the hard requirement of minimal initial connections to be established.
 	// Note that enabling this can cause cascading failures during an outage,
@@ -99,6 +127,11 @@ type ClientPoolConfig struct {
 
 	// MaxConnections is the maximum number of thrift connections the client
 	// pool can maintain.
+	//
+	// Calls are not multiplexed over the pooled connections: each connection is
+	// checked out of the pool by a single call and only returned to the pool
+	// after that call finishes, so MaxConnections is also the maximum number of
+	// concurrent in-flight calls through the pool.
 	MaxConnections int `yaml:"maxConnections"`
 
 	// MaxConnectionAge is the maximum duration that a pooled connection will be
@@ -158,6 +191,97 @@ type ClientPoolConfig struct {
 	ConnectTimeout time.Duration `yaml:"connectTimeout"`
 	SocketTimeout  time.Duration `yaml:"socketTimeout"`
 
+	// PoolWaitTimeout is the max duration a call would wait for a client to be
+	// released back to the pool when the pool is exhausted.
+	//
+	// When it's > 0, instead of failing immediately with
+	// clientpool.ErrExhausted, the call waits until a client is released,
+	// PoolWaitTimeout passed, or the context of the call is done,
+	// whichever comes first.
+	//
+	// Optional. When <= 0 the calls fail immediately when the pool is
+	// exhausted.
+	PoolWaitTimeout time.Duration `yaml:"poolWaitTimeout"`
+
+	// KeepConnectionOnApplicationException, when set to true, keeps the
+	// connection to be reused after a call failed with a
+	// thrift.TApplicationException (application-level errors not defined in the
+	// thrift IDL).
+	//
+	// By default the connection is closed after any error other than the
+	// exceptions defined in the thrift IDL, to be safe.
+	// This could cause a lot of connection churn for services calling an
+	// upstream that frequently returns TApplicationExceptions.
+	//
+	// The risk of enabling it is that a TApplicationException doesn't always
+	// guarantee the response was read fully from the connection, for example
+	// when it's from a misbehaving server,
+	// and reusing such connection could cause the following calls to read
+	// wrong responses.
+	// The connections are still closed on TApplicationExceptions indicating the
+	// connection is out of sync (BAD_SEQUENCE_ID, WRONG_METHOD_NAME, and
+	// INVALID_MESSAGE_TYPE_EXCEPTION), as well as transport and protocol errors.
+	//
+	// Optional. Default to false.
+	KeepConnectionOnApplicationException bool `yaml:"keepConnectionOnApplicationException"`
+
+	// MaxResponseSize is the maximum size in bytes of a response from the
+	// upstream server that the clients in this pool would accept.
+	//
+	// When it's set to a positive value, it's used as both MaxFrameSize and
+	// MaxMessageSize of the thrift.TConfiguration returned by ToTConfiguration,
+	// and the calls receiving a response larger than it will fail with
+	// ResponseTooLargeError.
+	//
+	// Optional. When <= 0 the thrift library defaults are used.
+	MaxResponseSize int32 `yaml:"maxResponseSize"`
+
+	// ReportExhaustedMethods, when set to true, additionally reports pool
+	// exhaustions with the name of the thrift method that was waiting for the
+	// client, via thriftbp_client_pool_method_exhaustions_total counter with
+	// thrift_pool and thrift_method labels.
+	//
+	// The method is always logged with the pool exhaustion errors.
+	// The method counter is opt-in to control the cardinality,
+	// thriftbp_client_pool_exhaustions_total (without the method label) is
+	// always reported.
+	//
+	// Optional. Default to false.
+	ReportExhaustedMethods bool `yaml:"reportExhaustedMethods"`
+
+	// ReportConnectionAges, when set to true, additionally reports the ages of
+	// the connections currently held by the pool (both idle and in-use),
+	// sampled at scrape time, via thrift_client_pool_connection_age_seconds
+	// histogram with thrift_pool label.
+	//
+	// It can be used to verify that MaxConnectionAge and MaxConnectionAgeJitter
+	// are spreading the reconnects as expected,
+	// and that the connections are not dying long before their max age.
+	//
+	// Optional. Default to false.
+	ReportConnectionAges bool `yaml:"reportConnectionAges"`
+
+	// When DNSRefreshInterval > 0, the hostname of the address returned by the
+	// AddressGenerator is resolved in a background goroutine every
+	// DNSRefreshInterval, and new connections are opened against the cached
+	// IPs (in a round-robin manner) instead of doing a DNS lookup on every
+	// connection, which is on the hot path when the pool needs to be refilled.
+	//
+	// The cached IPs are also refreshed in the background when a connection to
+	// one of them fails to open, to pick up endpoint changes sooner.
+	// When a refresh fails, the previously resolved IPs are kept.
+	// Until the first successful refresh, the address returned by the
+	// AddressGenerator is used as-is.
+	//
+	// Each refresh is reported via thriftbp_client_pool_dns_refreshes_total
+	// counter with thrift_pool and thrift_success labels.
+	//
+	// It has no effect on unix domain socket addresses or IP addresses.
+	// The background goroutine is stopped when the pool is closed.
+	//
+	// Optional. Default to 0 (resolve on every new connection).
+	DNSRefreshInterval time.Duration `yaml:"dnsRefreshInterval"`
+
 	// Any tags that should be applied to metrics logged by the ClientPool.
 	// This includes the optional pool stats.
 	//
@@ -224,6 +348,31 @@ type ClientPoolConfig struct {
 	// Optional. If this is empty, no "User-Agent" header will be sent.
 	ClientName string `yaml:"clientName"`
 
+	// The headers to be injected into every client call, with the values
+	// derived from the context object.
+	//
+	// See DefaultClientMiddlewareArgs.ContextHeaders for more details.
+	// Optional.
+	ContextHeaders map[string]ContextHeaderFunc `yaml:"-"`
+
+	// When SlowCallThreshold > 0, the calls took longer than it will be logged
+	// and counted.
+	//
+	// See LogSlowCalls for more details. Optional.
+	SlowCallThreshold time.Duration `yaml:"slowCallThreshold"`
+
+	// The ceiling of the attempts of a single call, regardless of the retry
+	// options.
+	//
+	// See DefaultClientMiddlewareArgs.MaxAttempts for more details. Optional.
+	MaxAttempts int `yaml:"maxAttempts"`
+
+	// When OTelSpanAttributes is true, OpenTelemetry semantic convention
+	// attributes will be set on the client spans.
+	//
+	// See OTelClientSpanAttributes for more details. Optional.
+	OTelSpanAttributes bool `yaml:"otelSpanAttributes"`
+
 	// The hostname to add as a "thrift-hostname" header.
 	//
 	// Optional. If empty, no "thrift-hostname" header will be sent.
@@ -234,6 +383,16 @@ type ClientPoolConfig struct {
 	//
 	// Optional. Default is false.
 	UseZlib bool `yaml:"useZlib"`
+
+	// The protocol used inside THeader connections between client and server,
+	// either thrift.THeaderProtocolBinary (0) or thrift.THeaderProtocolCompact
+	// (2).
+	//
+	// It's useful when talking to legacy upstreams only speaking the binary
+	// protocol.
+	//
+	// Optional. Default is thrift.THeaderProtocolCompact.
+	ProtocolID *thrift.THeaderProtocolID `yaml:"protocolID"`
 }
 
 // Validate checks ClientPoolConfig for any missing or erroneous values.
@@ -241,30 +400,46 @@ type ClientPoolConfig struct {
 // If this is the configuration for a baseplate service
 // BaseplateClientPoolConfig(c).Validate should be used instead.
 func (c ClientPoolConfig) Validate() error {
+	var errs []error
 	if c.InitialConnections > c.MaxConnections {
-		return ErrConfigInvalidConnections
+		errs = append(errs, ErrConfigInvalidConnections)
+	}
+	if !c.validProtocolID() {
+		errs = append(errs, ErrConfigInvalidProtocolID)
 	}
-	return nil
+	return errors.Join(errs...)
+}
+
+func (c ClientPoolConfig) validProtocolID() bool {
+	return c.ProtocolID == nil || c.ProtocolID.Validate() == nil
 }
 
 var tHeaderProtocolCompact = thrift.THeaderProtocolIDPtrMust(thrift.THeaderProtocolCompact)
 
 // ToTConfiguration generates *thrift.TConfiguration from this config.
 //
-// Note that it always set THeaderProtocolID to thrift.THeaderProtocolCompact,
-// even though that's not part of the ClientPoolConfig.
-// To override this behavior, change the value from the returned TConfiguration.
+// THeaderProtocolID is set to ProtocolID,
+// or thrift.THeaderProtocolCompact when ProtocolID is not set.
 func (c ClientPoolConfig) ToTConfiguration() *thrift.TConfiguration {
 	var transforms []thrift.THeaderTransformID
 	if c.UseZlib {
 		transforms = []thrift.THeaderTransformID{thrift.TransformZlib}
 	}
-	return &thrift.TConfiguration{
+	protoID := *tHeaderProtocolCompact
+	if c.ProtocolID != nil {
+		protoID = *c.ProtocolID
+	}
+	cfg := &thrift.TConfiguration{
 		ConnectTimeout:    c.ConnectTimeout,
 		SocketTimeout:     c.SocketTimeout,
-		THeaderProtocolID: thrift.THeaderProtocolIDPtrMust(*tHeaderProtocolCompact),
+		THeaderProtocolID: &protoID,
 		THeaderTransforms: transforms,
 	}
+	if c.MaxResponseSize > 0 {
+		cfg.MaxFrameSize = c.MaxResponseSize
+		cfg.MaxMessageSize = c.MaxResponseSize
+	}
+	return cfg
 }
 
 // BaseplateClientPoolConfig provides a more concrete Validate method tailored
@@ -286,6 +461,9 @@ func (c BaseplateClientPoolConfig) Validate() error {
 	if c.InitialConnections > c.MaxConnections {
 		errs = append(errs, ErrConfigInvalidConnections)
 	}
+	if !ClientPoolConfig(c).validProtocolID() {
+		errs = append(errs, ErrConfigInvalidProtocolID)
+	}
 	return errors.Join(errs...)
 }
 
@@ -388,7 +566,8 @@ func NewBaseplateClientPool(cfg ClientPoolConfig, middlewares ...thrift.ClientMi
 // passed into this function.
 //
 // It always uses SingleAddressGenerator with the server address configured in
-// cfg, and THeader+TCompact as the protocol factory.
+// cfg, and THeader as the protocol factory, with TCompact (or the ProtocolID
+// configured in cfg) as the protocol inside THeader.
 //
 // If you have RequiredInitialConnections > 0, ctx passed in controls the
 // timeout of retries to hit required initial connections. Having a ctx without
@@ -407,6 +586,10 @@ func NewBaseplateClientPoolWithContext(ctx context.Context, cfg ClientPoolConfig
 			ErrorSpanSuppressor: cfg.ErrorSpanSuppressor,
 			BreakerConfig:       cfg.BreakerConfig,
 			ClientName:          cfg.ClientName,
+			ContextHeaders:      cfg.ContextHeaders,
+			SlowCallThreshold:   cfg.SlowCallThreshold,
+			MaxAttempts:         cfg.MaxAttempts,
+			OTelSpanAttributes:  cfg.OTelSpanAttributes,
 		},
 	)
 	middlewares = append(middlewares, defaults...)
@@ -482,14 +665,36 @@ func newClientPool(
 	proto thrift.TProtocolFactory,
 	middlewares ...thrift.ClientMiddleware,
 ) (*clientPool, error) {
-	clientPoolMaxSizeGauge.With(prometheus.Labels{
+	labels := prometheus.Labels{
 		"thrift_pool": cfg.ServiceSlug,
-	}).Set(float64(cfg.MaxConnections))
+	}
+	clientPoolMaxSizeGauge.With(labels).Set(float64(cfg.MaxConnections))
+	clientPoolInitialConnectionsGauge.With(labels).Set(float64(cfg.InitialConnections))
+	shortfall := clientPoolInitialConnectionShortfallCounter.With(labels)
+	recordClientPoolConfigInfo(cfg)
 	tConfig := cfg.ToTConfiguration()
 	jitter := DefaultMaxConnectionAgeJitter
 	if cfg.MaxConnectionAgeJitter != nil {
 		jitter = *cfg.MaxConnectionAgeJitter
 	}
+	var ages *connectionAgeTracker
+	if cfg.ReportConnectionAges {
+		ages = newConnectionAgeTracker()
+	}
+	var dns *dnsCache
+	if cfg.DNSRefreshInterval > 0 {
+		timeout := cfg.ConnectTimeout
+		if timeout <= 0 {
+			timeout = defaultDNSLookupTimeout
+		}
+		dns = newDNSCache(
+			cfg.ServiceSlug,
+			genAddr,
+			net.DefaultResolver.LookupHost,
+			cfg.DNSRefreshInterval,
+			timeout,
+		)
+	}
 	opener := func() (clientpool.Client, error) {
 		// opener is only called in 2 scenarios:
 		//
@@ -511,6 +716,8 @@ func newClientPool(
 			jitter,
 			genAddr,
 			proto,
+			ages,
+			dns,
 		)
 	}
 	pool, err := clientpool.NewChannelPool(
@@ -521,6 +728,11 @@ func newClientPool(
 		opener,
 	)
 	if err != nil {
+		dns.close()
+		// None of the connections established are kept.
+		if cfg.InitialConnections > 0 {
+			shortfall.Add(float64(cfg.InitialConnections))
+		}
 		return nil, fmt.Errorf(
 			"thriftbp: error initializing the required number of connections in the thrift clientpool for %q: %w",
 			cfg.ServiceSlug,
@@ -528,13 +740,21 @@ func newClientPool(
 		)
 	}
 
+	// The best-effort initial connections failed to be established are only
+	// logged by the pool, so count them here for alerting.
+	if n := cfg.InitialConnections - int(pool.NumAllocated()); n > 0 {
+		shortfall.Add(float64(n))
+	}
+
 	if err := prometheusbpint.GlobalRegistry.Register(&clientPoolGaugeExporter{
 		slug: cfg.ServiceSlug,
 		pool: pool,
+		ages: ages,
 	}); err != nil {
 		// Register should never fail because clientPoolGaugeExporter.Describe is
 		// a no-op, but just in case.
 
+		dns.close()
 		return nil, fmt.Errorf(
 			"thriftbp: error registering prometheus exporter for client pool %q: %w",
 			cfg.ServiceSlug,
@@ -548,10 +768,17 @@ func newClientPool(
 	// create the base clientPool, this is not ready for use.
 	pooledClient := &clientPool{
 		Pool: pool,
+		dns:  dns,
 
-		slug: cfg.ServiceSlug,
+		slug:               cfg.ServiceSlug,
+		waitTimeout:        cfg.PoolWaitTimeout,
+		keepOnAppException: cfg.KeepConnectionOnApplicationException,
+		reportMethods:      cfg.ReportExhaustedMethods,
 	}
 	middlewares = append(middlewares, thriftHostnameHeaderMiddleware(cfg.ThriftHostnameHeader))
+	if cfg.MaxResponseSize > 0 {
+		middlewares = append(middlewares, LimitResponseSize(cfg.MaxResponseSize))
+	}
 
 	// finish setting up the clientPool by wrapping the inner "Call" with the
 	// given middleware.
@@ -560,9 +787,6 @@ func newClientPool(
 	pooledClient.wrapCalls(middlewares...)
 
 	// Register the error prometheus counters so they can be monitored
-	labels := prometheus.Labels{
-		"thrift_pool": cfg.ServiceSlug,
-	}
 	clientPoolExhaustedCounter.With(labels)
 	clientPoolClosedConnectionsCounter.With(labels)
 	clientPoolReleaseErrorCounter.With(labels)
@@ -577,7 +801,12 @@ func newClient(
 	maxConnectionAgeJitter float64,
 	genAddr AddressGenerator,
 	protoFactory thrift.TProtocolFactory,
+	ages *connectionAgeTracker,
+	dns *dnsCache,
 ) (*ttlClient, error) {
+	if dns != nil {
+		genAddr = dns.generate
+	}
 	return newTTLClient(func() (thrift.TClient, *countingDelegateTransport, error) {
 		addr, err := genAddr()
 		if err != nil {
@@ -597,24 +826,40 @@ func newClient(
 			TTransport: raw,
 		}
 		if err := transport.Open(); err != nil {
-			return nil, nil, fmt.Errorf("thriftbp: error opening TSocket for new Thrift client: %w", err)
+			dns.requestRefresh()
+			return nil, nil, ConnectError{
+				Addr:  addr,
+				Cause: err,
+			}
 		}
 
 		return thrift.NewTStandardClient(
 			protoFactory.GetProtocol(transport),
 			protoFactory.GetProtocol(transport),
 		), transport, nil
-	}, maxConnectionAge, maxConnectionAgeJitter, slug)
+	}, maxConnectionAge, maxConnectionAgeJitter, slug, ages)
 }
 
 type clientPool struct {
 	clientpool.Pool
 
-	slug string
+	slug               string
+	waitTimeout        time.Duration
+	keepOnAppException bool
+	reportMethods      bool
+
+	dns *dnsCache
 
 	wrappedClient thrift.TClient
 }
 
+// Close closes the underlying clientpool.Pool,
+// and stops the background DNS refreshes if enabled.
+func (p *clientPool) Close() error {
+	p.dns.close()
+	return p.Pool.Close()
+}
+
 func (p *clientPool) TClient() thrift.TClient {
 	// A clientPool needs to be set up properly before it can be used,
 	// specifically use p.wrapCalls to set up p.wrappedClient before using it.
@@ -643,12 +888,12 @@ func (p *clientPool) wrapCalls(middlewares ...thrift.ClientMiddleware) {
 // wrapCalls, so it runs after all of the middleware.
 func (p *clientPool) pooledCall(ctx context.Context, method string, args, result thrift.TStruct) (_ thrift.ResponseMeta, err error) {
 	var client Client
-	client, err = p.getClient()
+	client, err = p.getClient(ctx, method)
 	if err != nil {
 		return thrift.ResponseMeta{}, PoolError{Cause: err}
 	}
 	defer func() {
-		if shouldCloseConnection(err) {
+		if shouldCloseConnection(err, p.keepOnAppException) {
 			clientPoolClosedConnectionsCounter.With(prometheus.Labels{
 				"thrift_pool": p.slug,
 			}).Inc()
@@ -667,23 +912,45 @@ func (p *clientPool) pooledCall(ctx context.Context, method string, args, result
 	return client.Call(ctx, method, args, result)
 }
 
-func (p *clientPool) getClient() (_ Client, err error) {
+// get gets a client from the inner clientpool.Pool,
+// waiting up to p.waitTimeout when the pool is exhausted.
+func (p *clientPool) get(ctx context.Context) (clientpool.Client, error) {
+	pool, ok := p.Pool.(clientpool.BlockingPool)
+	if p.waitTimeout <= 0 || !ok {
+		return p.Pool.Get()
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.waitTimeout)
+	defer cancel()
+	return pool.GetWithTimeout(ctx)
+}
+
+func (p *clientPool) getClient(ctx context.Context, method string) (_ Client, err error) {
+	start := time.Now()
 	defer func() {
-		clientPoolGetsCounter.With(prometheus.Labels{
+		labels := prometheus.Labels{
 			"thrift_pool":    p.slug,
 			"thrift_success": strconv.FormatBool(err == nil),
-		}).Inc()
+		}
+		clientPoolGetsCounter.With(labels).Inc()
+		clientPoolGetWaitHisto.With(labels).Observe(time.Since(start).Seconds())
 	}()
-	c, err := p.Pool.Get()
+	c, err := p.get(ctx)
 	if err != nil {
 		if errors.Is(err, clientpool.ErrExhausted) {
 			clientPoolExhaustedCounter.With(prometheus.Labels{
 				"thrift_pool": p.slug,
 			}).Inc()
+			if p.reportMethods {
+				clientPoolMethodExhaustedCounter.With(prometheus.Labels{
+					"thrift_pool": p.slug,
+					methodLabel:   method,
+				}).Inc()
+			}
 		}
-		log.Errorw(
+		log.C(ctx).Errorw(
 			"Failed to get client from pool",
 			"pool", p.slug,
+			"method", method,
 			"err", err,
 		)
 		return nil, err
@@ -704,7 +971,7 @@ func (p *clientPool) releaseClient(c Client) {
 	}
 }
 
-func shouldCloseConnection(err error) bool {
+func shouldCloseConnection(err error, keepOnAppException bool) bool {
 	if err == nil {
 		return false
 	}
@@ -716,6 +983,21 @@ func shouldCloseConnection(err error) bool {
 			// Getting those means the roundtrip is done and response is read fully,
 			// so safe to reuse.
 			// This is the only non-nil error that the connection is safe for reuse
+			// by default.
+			return false
+		case thrift.TExceptionTypeApplication:
+			if !keepOnAppException {
+				return true
+			}
+			var ae thrift.TApplicationException
+			if !errors.As(err, &ae) {
+				return true
+			}
+			switch ae.TypeId() {
+			case thrift.BAD_SEQUENCE_ID, thrift.WRONG_METHOD_NAME, thrift.INVALID_MESSAGE_TYPE_EXCEPTION:
+				// The connection is out of sync.
+				return true
+			}
 			return false
 		}
 	}
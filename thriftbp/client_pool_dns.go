@@ -0,0 +1,168 @@
+package thriftbp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// defaultDNSLookupTimeout is the timeout of a single background DNS lookup
+// when ClientPoolConfig.ConnectTimeout is not set.
+const defaultDNSLookupTimeout = time.Second * 5
+
+// dnsCache resolves the hostname returned by an AddressGenerator in the
+// background, so the clients can be opened against the cached IPs without
+// doing DNS lookups on the hot path.
+//
+// All methods are safe to be called on a nil *dnsCache.
+type dnsCache struct {
+	slug     string
+	genAddr  AddressGenerator
+	lookup   func(ctx context.Context, host string) ([]string, error)
+	interval time.Duration
+	timeout  time.Duration
+
+	// addrs holds the resolved "ip:port" addresses.
+	addrs atomic.Pointer[[]string]
+	next  atomic.Uint64
+
+	refreshCh chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// newDNSCache creates a dnsCache, resolves the address once synchronously,
+// then starts the background goroutine to refresh it every interval.
+//
+// If the initial resolution fails, generate falls back to genAddr until the
+// next successful refresh.
+func newDNSCache(
+	slug string,
+	genAddr AddressGenerator,
+	lookup func(ctx context.Context, host string) ([]string, error),
+	interval time.Duration,
+	timeout time.Duration,
+) *dnsCache {
+	c := &dnsCache{
+		slug:      slug,
+		genAddr:   genAddr,
+		lookup:    lookup,
+		interval:  interval,
+		timeout:   timeout,
+		refreshCh: make(chan struct{}, 1),
+		done:      make(chan struct{}),
+	}
+	c.refresh()
+	c.wg.Add(1)
+	go c.run()
+	return c
+}
+
+func (c *dnsCache) run() {
+	defer c.wg.Done()
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+		case <-c.refreshCh:
+		}
+		c.refresh()
+	}
+}
+
+// refresh resolves the address returned by genAddr and replaces the cached
+// addresses on success.
+//
+// On failures the previously cached addresses are kept.
+func (c *dnsCache) refresh() {
+	addrs, err := c.resolve()
+	dnsRefreshCounter.With(prometheus.Labels{
+		"thrift_pool": c.slug,
+		successLabel:  strconv.FormatBool(err == nil),
+	}).Inc()
+	if err != nil {
+		return
+	}
+	c.addrs.Store(&addrs)
+}
+
+func (c *dnsCache) resolve() ([]string, error) {
+	addr, err := c.genAddr()
+	if err != nil {
+		return nil, fmt.Errorf("thriftbp: error getting address to resolve: %w", err)
+	}
+	if strings.HasPrefix(addr, "unix://") {
+		return []string{addr}, nil
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("thriftbp: error parsing address %q to resolve: %w", addr, err)
+	}
+	if net.ParseIP(host) != nil {
+		return []string{addr}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	ips, err := c.lookup(ctx, host)
+	if err != nil {
+		return nil, fmt.Errorf("thriftbp: error resolving %q: %w", host, err)
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("thriftbp: no addresses resolved for %q", host)
+	}
+	addrs := make([]string, len(ips))
+	for i, ip := range ips {
+		addrs[i] = net.JoinHostPort(ip, port)
+	}
+	return addrs, nil
+}
+
+// generate is an AddressGenerator returning the cached addresses in a
+// round-robin manner.
+func (c *dnsCache) generate() (string, error) {
+	addrs := c.addrs.Load()
+	if addrs == nil {
+		// Not resolved yet, fallback to the original generator.
+		return c.genAddr()
+	}
+	i := c.next.Add(1) - 1
+	return (*addrs)[i%uint64(len(*addrs))], nil
+}
+
+// requestRefresh triggers a background refresh without blocking.
+//
+// It's called when a connection to a cached address failed to open,
+// so endpoint changes are picked up before the next interval.
+func (c *dnsCache) requestRefresh() {
+	if c == nil {
+		return
+	}
+	select {
+	case c.refreshCh <- struct{}{}:
+	default:
+		// A refresh is already pending.
+	}
+}
+
+// close stops the background goroutine and waits for it to exit.
+func (c *dnsCache) close() {
+	if c == nil {
+		return
+	}
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
+	c.wg.Wait()
+}
@@ -0,0 +1,416 @@
+package thriftbp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/reddit/baseplate.go/clientpool"
+	"github.com/reddit/baseplate.go/prometheusbp/promtest"
+)
+
+type fakeBlockingPool struct {
+	clientpool.Pool
+
+	getCalled         bool
+	getWithTimeoutCtx context.Context
+}
+
+func (p *fakeBlockingPool) Get() (clientpool.Client, error) {
+	p.getCalled = true
+	return nil, clientpool.ErrExhausted
+}
+
+func (p *fakeBlockingPool) GetWithTimeout(ctx context.Context) (clientpool.Client, error) {
+	p.getWithTimeoutCtx = ctx
+	return nil, clientpool.ErrExhausted
+}
+
+func TestClientPoolWaitTimeout(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		pool := &fakeBlockingPool{}
+		p := &clientPool{Pool: pool}
+		if _, err := p.get(context.Background()); !errors.Is(err, clientpool.ErrExhausted) {
+			t.Errorf("Expected ErrExhausted, got %v", err)
+		}
+		if !pool.getCalled {
+			t.Error("Expected Get to be called")
+		}
+		if pool.getWithTimeoutCtx != nil {
+			t.Error("Did not expect GetWithTimeout to be called")
+		}
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		const timeout = time.Minute
+		pool := &fakeBlockingPool{}
+		p := &clientPool{Pool: pool, waitTimeout: timeout}
+		before := time.Now()
+		if _, err := p.get(context.Background()); !errors.Is(err, clientpool.ErrExhausted) {
+			t.Errorf("Expected ErrExhausted, got %v", err)
+		}
+		if pool.getCalled {
+			t.Error("Did not expect Get to be called")
+		}
+		if pool.getWithTimeoutCtx == nil {
+			t.Fatal("Expected GetWithTimeout to be called")
+		}
+		deadline, ok := pool.getWithTimeoutCtx.Deadline()
+		if !ok {
+			t.Fatal("Expected GetWithTimeout to be called with a deadline")
+		}
+		if deadline.Before(before.Add(timeout)) || deadline.After(time.Now().Add(timeout)) {
+			t.Errorf("Expected deadline around %v later, got %v", timeout, deadline.Sub(before))
+		}
+	})
+}
+
+func TestClientPoolExhaustedMethod(t *testing.T) {
+	const method = "myMethod"
+	for _, c := range []struct {
+		label         string
+		reportMethods bool
+		expected      float64
+	}{
+		{
+			label:         "disabled",
+			reportMethods: false,
+			expected:      0,
+		},
+		{
+			label:         "enabled",
+			reportMethods: true,
+			expected:      1,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			slug := "test-exhausted-method-" + c.label
+			p := &clientPool{
+				Pool:          &fakeBlockingPool{},
+				slug:          slug,
+				reportMethods: c.reportMethods,
+			}
+			defer promtest.NewPrometheusMetricTest(t, "exhausted", clientPoolExhaustedCounter, prometheus.Labels{
+				"thrift_pool": slug,
+			}).CheckDelta(1)
+			defer promtest.NewPrometheusMetricTest(t, "method exhausted", clientPoolMethodExhaustedCounter, prometheus.Labels{
+				"thrift_pool": slug,
+				methodLabel:   method,
+			}).CheckDelta(c.expected)
+
+			if _, err := p.getClient(context.Background(), method); !errors.Is(err, clientpool.ErrExhausted) {
+				t.Errorf("Expected ErrExhausted, got %v", err)
+			}
+		})
+	}
+}
+
+func TestShouldCloseConnection(t *testing.T) {
+	for _, c := range []struct {
+		label    string
+		err      error
+		keep     bool
+		expected bool
+	}{
+		{
+			label:    "nil",
+			err:      nil,
+			expected: false,
+		},
+		{
+			label:    "transport",
+			err:      thrift.NewTTransportException(thrift.TIMED_OUT, "timeout"),
+			keep:     true,
+			expected: true,
+		},
+		{
+			label:    "protocol",
+			err:      thrift.NewTProtocolException(errors.New("foo")),
+			keep:     true,
+			expected: true,
+		},
+		{
+			label:    "application-default",
+			err:      thrift.NewTApplicationException(thrift.INTERNAL_ERROR, "foo"),
+			expected: true,
+		},
+		{
+			label:    "application-keep",
+			err:      fmt.Errorf("wrapped: %w", thrift.NewTApplicationException(thrift.INTERNAL_ERROR, "foo")),
+			keep:     true,
+			expected: false,
+		},
+		{
+			label:    "application-keep-out-of-sync",
+			err:      thrift.NewTApplicationException(thrift.BAD_SEQUENCE_ID, "foo"),
+			keep:     true,
+			expected: true,
+		},
+		{
+			label:    "other",
+			err:      errors.New("foo"),
+			keep:     true,
+			expected: true,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			if got := shouldCloseConnection(c.err, c.keep); got != c.expected {
+				t.Errorf("shouldCloseConnection(%v, %v) expected %v, got %v", c.err, c.keep, c.expected, got)
+			}
+		})
+	}
+}
+
+func TestNewClientConnectError(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	// Close the listener so the connection will be refused.
+	ln.Close()
+
+	_, err = newClient(
+		&thrift.TConfiguration{ConnectTimeout: time.Second},
+		"test",
+		0,
+		0,
+		SingleAddressGenerator(addr),
+		thrift.NewTHeaderProtocolFactoryConf(nil),
+		nil,
+		nil,
+	)
+	var ce ConnectError
+	if !errors.As(err, &ce) {
+		t.Fatalf("Expected ConnectError, got %#v", err)
+	}
+	if ce.Addr != addr {
+		t.Errorf("Expected Addr %q, got %q", addr, ce.Addr)
+	}
+	if !errors.Is(err, syscall.ECONNREFUSED) {
+		t.Errorf("Expected error to unwrap to ECONNREFUSED, got %v", err)
+	}
+}
+
+type fakeDNSLookup struct {
+	lock  sync.Mutex
+	ips   []string
+	err   error
+	calls int
+}
+
+func (l *fakeDNSLookup) set(ips []string, err error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.ips = ips
+	l.err = err
+}
+
+func (l *fakeDNSLookup) getCalls() int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.calls
+}
+
+func (l *fakeDNSLookup) lookup(_ context.Context, host string) ([]string, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.calls++
+	return l.ips, l.err
+}
+
+func waitFor(t *testing.T, desc string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", desc)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestDNSCache(t *testing.T) {
+	const slug = "dns-cache"
+	defer promtest.NewPrometheusMetricTest(t, "success", dnsRefreshCounter, prometheus.Labels{
+		"thrift_pool": slug,
+		successLabel:  "true",
+	}).CheckDelta(2)
+	defer promtest.NewPrometheusMetricTest(t, "failure", dnsRefreshCounter, prometheus.Labels{
+		"thrift_pool": slug,
+		successLabel:  "false",
+	}).CheckDelta(1)
+
+	l := &fakeDNSLookup{ips: []string{"10.0.0.1", "10.0.0.2"}}
+	c := newDNSCache(slug, SingleAddressGenerator("upstream:9090"), l.lookup, time.Hour, time.Second)
+	defer c.close()
+
+	checkAddrs := func(t *testing.T, expected ...string) {
+		t.Helper()
+		for i := 0; i < len(expected)*2; i++ {
+			addr, err := c.generate()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := expected[i%len(expected)]; addr != want {
+				t.Errorf("#%d: Expected address %q, got %q", i, want, addr)
+			}
+		}
+	}
+	checkAddrs(t, "10.0.0.1:9090", "10.0.0.2:9090")
+
+	l.set(nil, errors.New("lookup failed"))
+	c.requestRefresh()
+	waitFor(t, "failed refresh", func() bool { return l.getCalls() == 2 })
+	checkAddrs(t, "10.0.0.1:9090", "10.0.0.2:9090")
+
+	l.set([]string{"10.0.0.3"}, nil)
+	c.requestRefresh()
+	waitFor(t, "successful refresh", func() bool {
+		addr, _ := c.generate()
+		return addr == "10.0.0.3:9090"
+	})
+}
+
+func TestDNSCacheSkipsLookup(t *testing.T) {
+	for _, addr := range []string{
+		"127.0.0.1:9090",
+		"[::1]:9090",
+		"unix:///var/run/thrift.socket",
+	} {
+		t.Run(addr, func(t *testing.T) {
+			l := &fakeDNSLookup{err: errors.New("should not be called")}
+			c := newDNSCache("dns-skip", SingleAddressGenerator(addr), l.lookup, time.Hour, time.Second)
+			defer c.close()
+			got, err := c.generate()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != addr {
+				t.Errorf("Expected address %q, got %q", addr, got)
+			}
+			if calls := l.getCalls(); calls != 0 {
+				t.Errorf("Expected no lookups, got %d", calls)
+			}
+		})
+	}
+}
+
+func TestNewClientConnectErrorRefreshesDNS(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	// Close the listener so the connection will be refused.
+	ln.Close()
+
+	l := &fakeDNSLookup{ips: []string{"127.0.0.1"}}
+	dns := newDNSCache("dns-connect-error", SingleAddressGenerator("upstream:"+port), l.lookup, time.Hour, time.Second)
+	defer dns.close()
+
+	_, err = newClient(
+		&thrift.TConfiguration{ConnectTimeout: time.Second},
+		"dns-connect-error",
+		0,
+		0,
+		SingleAddressGenerator("upstream:"+port),
+		thrift.NewTHeaderProtocolFactoryConf(nil),
+		nil,
+		dns,
+	)
+	var ce ConnectError
+	if !errors.As(err, &ce) {
+		t.Fatalf("Expected ConnectError, got %#v", err)
+	}
+	if want := net.JoinHostPort("127.0.0.1", port); ce.Addr != want {
+		t.Errorf("Expected Addr %q, got %q", want, ce.Addr)
+	}
+	waitFor(t, "refresh after connect error", func() bool { return l.getCalls() == 2 })
+}
+
+func TestInitialConnectionShortfall(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	for _, c := range []struct {
+		label     string
+		required  int
+		successes int
+		expected  float64
+	}{
+		{
+			label:     "met",
+			successes: 3,
+			expected:  0,
+		},
+		{
+			label:     "best-effort",
+			successes: 1,
+			expected:  2,
+		},
+		{
+			label:     "required",
+			required:  2,
+			successes: 1,
+			expected:  3,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			slug := "initial-shortfall-" + c.label
+			labels := prometheus.Labels{
+				"thrift_pool": slug,
+			}
+			defer promtest.NewPrometheusMetricTest(t, "shortfall", clientPoolInitialConnectionShortfallCounter, labels).CheckDelta(c.expected)
+
+			var calls int
+			genAddr := func() (string, error) {
+				calls++
+				if calls > c.successes {
+					return "", errors.New("error")
+				}
+				return ln.Addr().String(), nil
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			cfg := ClientPoolConfig{
+				ServiceSlug:                slug,
+				InitialConnections:         3,
+				RequiredInitialConnections: c.required,
+				MaxConnections:             5,
+			}
+			pool, err := NewCustomClientPoolWithContext(
+				ctx,
+				cfg,
+				genAddr,
+				thrift.NewTHeaderProtocolFactoryConf(cfg.ToTConfiguration()),
+			)
+			if c.required > c.successes {
+				if err == nil {
+					t.Error("Expected error, got nil")
+				}
+			} else {
+				if err != nil {
+					t.Fatal(err)
+				}
+				pool.Close()
+			}
+
+			if got := testutil.ToFloat64(clientPoolInitialConnectionsGauge.With(labels)); got != 3 {
+				t.Errorf("Expected initial connections gauge to be 3, got %v", got)
+			}
+		})
+	}
+}
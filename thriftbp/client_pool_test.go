@@ -359,3 +359,50 @@ func TestUDS(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestClientPoolConfigProtocolID(t *testing.T) {
+	binary := thrift.THeaderProtocolBinary
+	invalid := thrift.THeaderProtocolID(1)
+	for _, c := range []struct {
+		label    string
+		id       *thrift.THeaderProtocolID
+		expected thrift.THeaderProtocolID
+		invalid  bool
+	}{
+		{
+			label:    "default",
+			expected: thrift.THeaderProtocolCompact,
+		},
+		{
+			label:    "binary",
+			id:       &binary,
+			expected: thrift.THeaderProtocolBinary,
+		},
+		{
+			label:   "invalid",
+			id:      &invalid,
+			invalid: true,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			cfg := thriftbp.ClientPoolConfig{
+				ServiceSlug: "test",
+				Addr:        addr,
+				ProtocolID:  c.id,
+			}
+			err := thriftbp.BaseplateClientPoolConfig(cfg).Validate()
+			if c.invalid {
+				if !errors.Is(err, thriftbp.ErrConfigInvalidProtocolID) {
+					t.Errorf("Expected error %v, got %v", thriftbp.ErrConfigInvalidProtocolID, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := cfg.ToTConfiguration().GetTHeaderProtocolID(); got != c.expected {
+				t.Errorf("Expected protocol id %v, got %v", c.expected, got)
+			}
+		})
+	}
+}
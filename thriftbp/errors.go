@@ -30,6 +30,7 @@ var (
 	ErrConfigMissingServiceSlug = errors.New("`ServiceSlug` cannot be empty")
 	ErrConfigMissingAddr        = errors.New("`Addr` cannot be empty")
 	ErrConfigInvalidConnections = errors.New("`InitialConnections` cannot be bigger than `MaxConnections`")
+	ErrConfigInvalidProtocolID  = errors.New("`ProtocolID` must be either binary (0) or compact (2)")
 )
 
 // WithDefaultRetryableCodes returns a list including the given error codes and
@@ -71,7 +72,13 @@ func BaseplateErrorFilter(codes ...int32) retrybp.Filter {
 //
 // Note that if the exception is baseplate.Error,
 // this function will NOT suppress it if the code is in range [500, 600).
+//
+// The exceptions returned by the methods marked as failures by
+// MarkServerSpanIDLExceptionErrors are not suppressed either.
 func IDLExceptionSuppressor(err error) bool {
+	if errors.As(err, new(idlExceptionFailure)) {
+		return false
+	}
 	var te thrift.TException
 	if !errors.As(err, &te) {
 		return false
@@ -2,6 +2,7 @@ package thriftbp
 
 import (
 	"context"
+	"slices"
 	"strings"
 
 	"github.com/apache/thrift/lib/go/thrift"
@@ -21,6 +22,31 @@ var HeadersToForward = []string{
 	transport.HeaderTracingFlags,
 }
 
+// propagatedHeaders are all the baseplate reserved headers.
+var propagatedHeaders = append(
+	slices.Clone(HeadersToForward),
+	transport.HeaderUserAgent,
+	transport.HeaderDeadlineBudget,
+	transport.HeaderIdempotencyKey,
+)
+
+// PropagatedHeaders returns all the baseplate reserved headers (tracing,
+// edge request, deadline budget, etc.) present on the context object,
+// keyed by their canonical names (e.g. transport.HeaderTracingTrace).
+//
+// It's meant for introspection (e.g. logging or debugging header propagation)
+// only. The returned map is a copy, changing it doesn't affect the headers on
+// the context object. Use AddClientHeader to set headers instead.
+func PropagatedHeaders(ctx context.Context) map[string]string {
+	headers := make(map[string]string, len(propagatedHeaders))
+	for _, key := range propagatedHeaders {
+		if v, ok := header(ctx, key); ok {
+			headers[key] = v
+		}
+	}
+	return headers
+}
+
 // AttachEdgeRequestContext returns a context that has the header of the edge
 // context attached to ctx object set to forward using the "Edge-Request" header
 // on any Thrift calls made with that context object.
@@ -65,3 +91,33 @@ func header(ctx context.Context, key string) (v string, ok bool) {
 
 	return
 }
+
+// WithIdempotencyKey returns a context that sends key as the "Idempotency-Key"
+// (transport.HeaderIdempotencyKey) header on any Thrift calls made with that
+// context object.
+//
+// As the header is set on the context object, all the retries of the same
+// call made with the returned context share the same key.
+// The header is not in HeadersToForward, so it will not be forwarded by the
+// server to its upstream calls.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return AddClientHeader(ctx, transport.HeaderIdempotencyKey, key)
+}
+
+type idempotencyKeyType struct{}
+
+var idempotencyKey idempotencyKeyType
+
+// IdempotencyKey returns the idempotency key sent by the client via the
+// "Idempotency-Key" (transport.HeaderIdempotencyKey) header.
+//
+// It requires ExtractIdempotencyKey middleware,
+// which is included in BaseplateDefaultProcessorMiddlewares.
+// It returns false if the client didn't send the header.
+//
+// The deduplication of the requests with the same key is the responsibility of
+// the service.
+func IdempotencyKey(ctx context.Context) (key string, ok bool) {
+	key, ok = ctx.Value(idempotencyKey).(string)
+	return
+}
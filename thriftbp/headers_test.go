@@ -2,6 +2,7 @@ package thriftbp_test
 
 import (
 	"context"
+	"reflect"
 	"testing"
 
 	"github.com/apache/thrift/lib/go/thrift"
@@ -74,3 +75,31 @@ func TestAddClientHeader(t *testing.T) {
 	}
 	headerInWriteHeaderList(ctx, t, key)
 }
+
+func TestPropagatedHeaders(t *testing.T) {
+	ctx := context.Background()
+	ctx = thrift.SetHeader(ctx, transport.HeaderTracingTrace, "12345")
+	ctx = thrift.SetHeader(ctx, transport.HeaderEdgeRequest, "edge")
+	// Envoy sends lower-case header keys.
+	ctx = thrift.SetHeader(ctx, "deadline-budget", "50")
+	ctx = thrift.SetHeader(ctx, "X-Not-Baseplate", "foo")
+
+	want := map[string]string{
+		transport.HeaderTracingTrace:   "12345",
+		transport.HeaderEdgeRequest:    "edge",
+		transport.HeaderDeadlineBudget: "50",
+	}
+	got := thriftbp.PropagatedHeaders(ctx)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("PropagatedHeaders() got %#v, want %#v", got, want)
+	}
+
+	got[transport.HeaderTracingTrace] = "54321"
+	if v, _ := thrift.GetHeader(ctx, transport.HeaderTracingTrace); v != "12345" {
+		t.Errorf("Expected changes to the returned map not to affect the context, got header %q", v)
+	}
+
+	if got := thriftbp.PropagatedHeaders(context.Background()); len(got) != 0 {
+		t.Errorf("Expected no headers, got %#v", got)
+	}
+}
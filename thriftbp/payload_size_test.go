@@ -0,0 +1,163 @@
+package thriftbp_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apache/thrift/lib/go/thrift"
+
+	"github.com/reddit/baseplate.go/internal/gen-go/reddit/baseplate"
+	"github.com/reddit/baseplate.go/thriftbp"
+)
+
+const payloadSizeMethod = "is_healthy"
+
+// payloadSizeFrame returns a THeader framed is_healthy request encoded with
+// protoID.
+func payloadSizeFrame(tb testing.TB, protoID thrift.THeaderProtocolID) []byte {
+	tb.Helper()
+
+	ctx := context.Background()
+	buf := thrift.NewTMemoryBuffer()
+	cfg := &thrift.TConfiguration{
+		THeaderProtocolID: &protoID,
+	}
+	proto := thrift.NewTHeaderProtocolConf(buf, cfg)
+	if err := proto.WriteMessageBegin(ctx, payloadSizeMethod, thrift.CALL, 1); err != nil {
+		tb.Fatal(err)
+	}
+	args := baseplate.NewBaseplateServiceV2IsHealthyArgs()
+	args.Request = &baseplate.IsHealthyRequest{
+		Probe: baseplate.IsHealthyProbePtr(baseplate.IsHealthyProbe_READINESS),
+	}
+	if err := args.Write(ctx, proto); err != nil {
+		tb.Fatal(err)
+	}
+	if err := proto.WriteMessageEnd(ctx); err != nil {
+		tb.Fatal(err)
+	}
+	if err := proto.Flush(ctx); err != nil {
+		tb.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// isHealthyProcessor reads the is_healthy args from in and writes a successful
+// result to out.
+var isHealthyProcessor = thrift.WrappedTProcessorFunction{
+	Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+		args := baseplate.NewBaseplateServiceV2IsHealthyArgs()
+		if err := args.Read(ctx, in); err != nil {
+			return false, thrift.WrapTException(err)
+		}
+		if err := in.ReadMessageEnd(ctx); err != nil {
+			return false, thrift.WrapTException(err)
+		}
+		healthy := true
+		result := baseplate.NewBaseplateServiceV2IsHealthyResult()
+		result.Success = &healthy
+		if err := out.WriteMessageBegin(ctx, payloadSizeMethod, thrift.REPLY, seqID); err != nil {
+			return false, thrift.WrapTException(err)
+		}
+		if err := result.Write(ctx, out); err != nil {
+			return false, thrift.WrapTException(err)
+		}
+		if err := out.WriteMessageEnd(ctx); err != nil {
+			return false, thrift.WrapTException(err)
+		}
+		return true, thrift.WrapTException(out.Flush(ctx))
+	},
+}
+
+// payloadSizeServer holds reusable server side protocols to feed a request
+// frame through a processor function.
+type payloadSizeServer struct {
+	frame []byte
+	ibuf  *thrift.TMemoryBuffer
+	obuf  *thrift.TMemoryBuffer
+	in    thrift.TProtocol
+	out   thrift.TProtocol
+}
+
+func newPayloadSizeServer(tb testing.TB, protoID thrift.THeaderProtocolID) *payloadSizeServer {
+	tb.Helper()
+
+	s := &payloadSizeServer{
+		frame: payloadSizeFrame(tb, protoID),
+		ibuf:  thrift.NewTMemoryBuffer(),
+		obuf:  thrift.NewTMemoryBuffer(),
+	}
+	cfg := &thrift.TConfiguration{}
+	itrans := thrift.NewTHeaderTransportConf(s.ibuf, cfg)
+	s.in = thrift.NewTHeaderProtocolConf(itrans, cfg)
+	// Server side output uses the same transport as the input, the same way as
+	// thrift.TSimpleServer with THeaderProtocol.
+	s.out = s.in
+	return s
+}
+
+func (s *payloadSizeServer) process(tb testing.TB, processor thrift.TProcessorFunction) {
+	ctx := context.Background()
+	s.ibuf.Reset()
+	s.ibuf.Write(s.frame)
+	name, _, seqID, err := s.in.ReadMessageBegin(ctx)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if name != payloadSizeMethod {
+		tb.Fatalf("Expected method %q, got %q", payloadSizeMethod, name)
+	}
+	if _, err := processor.Process(ctx, seqID, s.in, s.out); err != nil {
+		tb.Fatal(err)
+	}
+}
+
+func TestReportPayloadSizeMetrics(t *testing.T) {
+	for _, c := range []struct {
+		label   string
+		protoID thrift.THeaderProtocolID
+	}{
+		{
+			label:   "binary",
+			protoID: thrift.THeaderProtocolBinary,
+		},
+		{
+			label:   "compact",
+			protoID: thrift.THeaderProtocolCompact,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			s := newPayloadSizeServer(t, c.protoID)
+			processor := thriftbp.ReportPayloadSizeMetrics(0)(payloadSizeMethod, isHealthyProcessor)
+			// Run it a few times to make sure the reused objects are reset properly.
+			for i := 0; i < 3; i++ {
+				s.process(t, processor)
+			}
+		})
+	}
+}
+
+func BenchmarkReportPayloadSizeMetrics(b *testing.B) {
+	for _, c := range []struct {
+		label     string
+		processor thrift.TProcessorFunction
+	}{
+		{
+			label:     "without-middleware",
+			processor: isHealthyProcessor,
+		},
+		{
+			label:     "with-middleware",
+			processor: thriftbp.ReportPayloadSizeMetrics(0)(payloadSizeMethod, isHealthyProcessor),
+		},
+	} {
+		b.Run(c.label, func(b *testing.B) {
+			s := newPayloadSizeServer(b, thrift.THeaderProtocolCompact)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				s.process(b, c.processor)
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package thriftbp
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/opentracing/opentracing-go"
+
+	"github.com/reddit/baseplate.go/tracing"
+)
+
+// tlsServerSocket is a thrift.TServerTransport serving TLS connections.
+//
+// Unlike thrift.TSSLServerSocket, the accepted transports keep the *tls.Conn,
+// so the peer certificates can be read from them.
+type tlsServerSocket struct {
+	addr    string
+	cfg     *tls.Config
+	timeout time.Duration
+
+	lock        sync.Mutex
+	listener    net.Listener
+	interrupted bool
+}
+
+func newTLSServerSocket(addr string, cfg *tls.Config, timeout time.Duration) *tlsServerSocket {
+	return &tlsServerSocket{
+		addr:    addr,
+		cfg:     cfg,
+		timeout: timeout,
+	}
+}
+
+func (s *tlsServerSocket) Listen() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.listener != nil {
+		return nil
+	}
+	l, err := tls.Listen("tcp", s.addr, s.cfg)
+	if err != nil {
+		return thrift.NewTTransportExceptionFromError(err)
+	}
+	s.listener = l
+	return nil
+}
+
+func (s *tlsServerSocket) Accept() (thrift.TTransport, error) {
+	s.lock.Lock()
+	listener, interrupted := s.listener, s.interrupted
+	s.lock.Unlock()
+
+	if interrupted {
+		return nil, thrift.NewTTransportException(thrift.NOT_OPEN, "Transport interrupted")
+	}
+	if listener == nil {
+		return nil, thrift.NewTTransportException(thrift.NOT_OPEN, "No underlying server socket")
+	}
+	conn, err := listener.Accept()
+	if err != nil {
+		return nil, thrift.NewTTransportExceptionFromError(err)
+	}
+	tlsConn, ok := conn.(*tls.Conn)
+	if !ok {
+		conn.Close()
+		return nil, thrift.NewTTransportExceptionFromError(errors.New("thriftbp: accepted connection is not TLS"))
+	}
+	return &tlsTransport{
+		TTransport: thrift.NewTSocketFromConnConf(tlsConn, &thrift.TConfiguration{
+			SocketTimeout: s.timeout,
+		}),
+		conn: tlsConn,
+	}, nil
+}
+
+func (s *tlsServerSocket) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.listener == nil {
+		return nil
+	}
+	err := s.listener.Close()
+	s.listener = nil
+	return err
+}
+
+func (s *tlsServerSocket) Interrupt() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.interrupted = true
+	if s.listener != nil {
+		return s.listener.Close()
+	}
+	return nil
+}
+
+// tlsTransport is the thrift.TTransport of a TLS connection accepted by
+// tlsServerSocket.
+type tlsTransport struct {
+	thrift.TTransport
+
+	conn *tls.Conn
+}
+
+// peerCertProcessorFactory is a thrift.TProcessorFactory attaching the peer
+// certificate identity of the TLS connections into the context objects of the
+// requests, to be read by PeerCertIdentity.
+type peerCertProcessorFactory struct {
+	processor thrift.TProcessor
+}
+
+func (f peerCertProcessorFactory) GetProcessor(trans thrift.TTransport) thrift.TProcessor {
+	for {
+		switch t := trans.(type) {
+		case *tlsTransport:
+			return &peerCertProcessor{
+				TProcessor: f.processor,
+				conn:       t.conn,
+			}
+		case transportWrapper:
+			trans = t.Unwrap()
+		default:
+			// Not a TLS connection.
+			return f.processor
+		}
+	}
+}
+
+// transportWrapper is implemented by the thrift.TTransport wrappers used by
+// the server, so the wrapped transports can be reached by
+// peerCertProcessorFactory.
+type transportWrapper interface {
+	Unwrap() thrift.TTransport
+}
+
+type peerCertProcessor struct {
+	thrift.TProcessor
+
+	conn *tls.Conn
+
+	once     sync.Once
+	identity string
+	ok       bool
+}
+
+func (p *peerCertProcessor) Process(ctx context.Context, in, out thrift.TProtocol) (bool, thrift.TException) {
+	p.once.Do(func() {
+		// The handshake is already done when reading the request, this is just to
+		// be safe.
+		if err := p.conn.HandshakeContext(ctx); err != nil {
+			return
+		}
+		p.identity, p.ok = peerCertIdentity(p.conn.ConnectionState())
+	})
+	if p.ok {
+		ctx = context.WithValue(ctx, peerCertIdentityCtxKey, p.identity)
+	}
+	return p.TProcessor.Process(ctx, in, out)
+}
+
+// peerCertIdentity returns the identity from the verified peer certificate,
+// which is the subject common name, or the first URI or DNS name from the
+// subject alternative names when the common name is empty.
+func peerCertIdentity(state tls.ConnectionState) (string, bool) {
+	if len(state.VerifiedChains) == 0 || len(state.PeerCertificates) == 0 {
+		return "", false
+	}
+	cert := state.PeerCertificates[0]
+	if cert.Subject.CommonName != "" {
+		return cert.Subject.CommonName, true
+	}
+	if len(cert.URIs) > 0 {
+		return cert.URIs[0].String(), true
+	}
+	if len(cert.DNSNames) > 0 {
+		return cert.DNSNames[0], true
+	}
+	return "", false
+}
+
+type peerCertIdentityCtxKeyType struct{}
+
+var peerCertIdentityCtxKey peerCertIdentityCtxKeyType
+
+// PeerCertIdentity returns the identity of the calling service from its
+// verified TLS client certificate.
+//
+// The identity is the subject common name of the certificate,
+// or the first URI or DNS name from its subject alternative names when the
+// common name is empty.
+//
+// It's only available for the servers created by NewServer or
+// NewBaseplateServer with ServerConfig.TLSConfig set,
+// when the client certificate is presented and verified,
+// which requires TLSConfig.ClientAuth to be tls.VerifyClientCertIfGiven or
+// tls.RequireAndVerifyClientCert.
+// For non-TLS connections it always returns false.
+//
+// Unlike the User-Agent header, the identity is authenticated.
+func PeerCertIdentity(ctx context.Context) (identity string, ok bool) {
+	identity, ok = ctx.Value(peerCertIdentityCtxKey).(string)
+	return identity, ok
+}
+
+// TagServerSpanPeerCertIdentity is a ProcessorMiddleware that sets the
+// PeerCertIdentity as the "peer.service" (tracing.TagKeyPeerService) tag on
+// the server span.
+//
+// It must come after InjectServerSpan. When used together with
+// TagServerSpanCallerService, put it after that one to prefer the
+// authenticated identity, e.g. in ServerConfig.Middlewares.
+// It's no-op when there's no span or PeerCertIdentity is not available.
+func TagServerSpanPeerCertIdentity(name string, next thrift.TProcessorFunction) thrift.TProcessorFunction {
+	return thrift.WrappedTProcessorFunction{
+		Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+			if span := opentracing.SpanFromContext(ctx); span != nil {
+				if identity, ok := PeerCertIdentity(ctx); ok {
+					span.SetTag(tracing.TagKeyPeerService, identity)
+				}
+			}
+			return next.Process(ctx, seqID, in, out)
+		},
+	}
+}
@@ -0,0 +1,129 @@
+package thriftbp
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/apache/thrift/lib/go/thrift"
+)
+
+func newTestCert(t *testing.T, template, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func TestPeerCertIdentity(t *testing.T) {
+	const identity = "test-client"
+
+	now := time.Now()
+	ca, caKey := newTestCert(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil, nil)
+	server, serverKey := newTestCert(t, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "test-server"},
+		DNSNames:     []string{"test-server"},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, ca, caKey)
+	client, clientKey := newTestCert(t, &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: identity},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, ca, caKey)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+
+	serverConn, clientConn := net.Pipe()
+	defer serverConn.Close()
+	defer clientConn.Close()
+	tlsServer := tls.Server(serverConn, &tls.Config{
+		Certificates: []tls.Certificate{{
+			Certificate: [][]byte{server.Raw},
+			PrivateKey:  serverKey,
+		}},
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  pool,
+	})
+	tlsClient := tls.Client(clientConn, &tls.Config{
+		Certificates: []tls.Certificate{{
+			Certificate: [][]byte{client.Raw},
+			PrivateKey:  clientKey,
+		}},
+		RootCAs:    pool,
+		ServerName: "test-server",
+	})
+	go tlsClient.Handshake()
+
+	check := func(expectedOK bool) processorFunc {
+		return func(ctx context.Context, in, out thrift.TProtocol) (bool, thrift.TException) {
+			got, ok := PeerCertIdentity(ctx)
+			if ok != expectedOK {
+				t.Errorf("Expected PeerCertIdentity ok to be %v, got %v", expectedOK, ok)
+			}
+			if expectedOK && got != identity {
+				t.Errorf("Expected PeerCertIdentity %q, got %q", identity, got)
+			}
+			return true, nil
+		}
+	}
+
+	t.Run("tls", func(t *testing.T) {
+		var trans thrift.TTransport = &tlsTransport{
+			TTransport: thrift.NewTSocketFromConnConf(tlsServer, nil),
+			conn:       tlsServer,
+		}
+		trans = &requestSizeLimitTransport{TTransport: trans}
+		trans = &receivedAtTransport{TTransport: trans}
+		processor := peerCertProcessorFactory{processor: check(true)}.GetProcessor(trans)
+		// Process twice to make sure the identity is kept for the connection.
+		for i := 0; i < 2; i++ {
+			if _, err := processor.Process(context.Background(), nil, nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+
+	t.Run("non-tls", func(t *testing.T) {
+		trans := thrift.NewTMemoryBuffer()
+		processor := peerCertProcessorFactory{processor: check(false)}.GetProcessor(trans)
+		if _, err := processor.Process(context.Background(), nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
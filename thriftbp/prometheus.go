@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/apache/thrift/lib/go/thrift"
 	"github.com/prometheus/client_golang/prometheus"
@@ -22,6 +24,7 @@ const (
 	baseplateStatusLabel     = "thrift_baseplate_status"
 	baseplateStatusCodeLabel = "thrift_baseplate_status_code"
 	clientNameLabel          = "thrift_client_name"
+	upstreamVersionLabel     = "thrift_upstream_version"
 )
 
 var (
@@ -59,6 +62,16 @@ var (
 	}, serverActiveRequestsLabels)
 )
 
+var (
+	serverQueueTime = promauto.With(prometheusbpint.GlobalRegistry).NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "thrift_server_queue_seconds",
+		Help:    "The time requests waited before being handled",
+		Buckets: prometheusbp.DefaultLatencyBuckets,
+	}, []string{
+		methodLabel,
+	})
+)
+
 var (
 	clientLatencyLabels = []string{
 		methodLabel,
@@ -103,6 +116,7 @@ const (
 	promNamespace = "thriftbp"
 
 	subsystemServer     = "server"
+	subsystemClient     = "client"
 	subsystemTTLClient  = "ttl_client"
 	subsystemClientPool = "client_pool"
 )
@@ -116,6 +130,32 @@ var (
 	})
 )
 
+var (
+	clientSlowCallsLabels = []string{
+		methodLabel,
+		clientNameLabel,
+	}
+
+	clientSlowCallsCounter = promauto.With(prometheusbpint.GlobalRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: promNamespace,
+		Subsystem: subsystemClient,
+		Name:      "slow_calls_total",
+		Help:      "The number of thrift client calls took longer than the configured slow call threshold",
+	}, clientSlowCallsLabels)
+
+	clientMaxAttemptsReachedLabels = []string{
+		methodLabel,
+		clientNameLabel,
+	}
+
+	clientMaxAttemptsReachedCounter = promauto.With(prometheusbpint.GlobalRegistry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: promNamespace,
+		Subsystem: subsystemClient,
+		Name:      "max_attempts_reached_total",
+		Help:      "The number of thrift client calls stopped retrying by the max attempts ceiling",
+	}, clientMaxAttemptsReachedLabels)
+)
+
 var (
 	ttlClientReplaceLabels = []string{
 		clientNameLabel,
@@ -177,6 +217,32 @@ var (
 	}, clientPayloadSizeLabels)
 )
 
+var (
+	serverBlockedRequests = promauto.With(prometheusbpint.GlobalRegistry).NewCounterVec(prometheus.CounterOpts{
+		Name: "thriftbp_server_blocked_requests_total",
+		Help: "The number of requests rejected by thriftbp.BlockMethods",
+	}, []string{
+		methodLabel,
+	})
+)
+
+var (
+	serverRequestTooLargeCounter = promauto.With(prometheusbpint.GlobalRegistry).NewCounter(prometheus.CounterOpts{
+		Name: "thriftbp_server_request_too_large_total",
+		Help: "The number of requests rejected for exceeding the max request size",
+	})
+)
+
+var (
+	clientUpstreamVersions = promauto.With(prometheusbpint.GlobalRegistry).NewCounterVec(prometheus.CounterOpts{
+		Name: "thriftbp_client_upstream_versions_total",
+		Help: "The number of responses by the bucketed version of the upstream server",
+	}, []string{
+		clientNameLabel,
+		upstreamVersionLabel,
+	})
+)
+
 var (
 	panicRecoverLabels = []string{
 		methodLabel,
@@ -198,6 +264,14 @@ var (
 		Help: "The number of pool exhaustions for a thrift client pool",
 	}, clientPoolLabels)
 
+	clientPoolMethodExhaustedCounter = promauto.With(prometheusbpint.GlobalRegistry).NewCounterVec(prometheus.CounterOpts{
+		Name: "thriftbp_client_pool_method_exhaustions_total",
+		Help: "The number of pool exhaustions for a thrift client pool by the waiting method",
+	}, []string{
+		"thrift_pool",
+		methodLabel,
+	})
+
 	clientPoolClosedConnectionsCounter = promauto.With(prometheusbpint.GlobalRegistry).NewCounterVec(prometheus.CounterOpts{
 		Name: "thriftbp_client_pool_closed_connections_total",
 		Help: "The number of times we closed the client after used it from the pool",
@@ -215,6 +289,14 @@ var (
 		"thrift_pool",
 	})
 
+	dnsRefreshCounter = promauto.With(prometheusbpint.GlobalRegistry).NewCounterVec(prometheus.CounterOpts{
+		Name: "thriftbp_client_pool_dns_refreshes_total",
+		Help: "The number of background DNS refreshes for a thriftbp client pool",
+	}, []string{
+		"thrift_pool",
+		"thrift_success",
+	})
+
 	clientPoolGetsCounter = promauto.With(prometheusbpint.GlobalRegistry).NewCounterVec(prometheus.CounterOpts{
 		Name: "thrift_client_pool_connection_gets_total",
 		Help: "The number of times we tried to lease(get) from a thrift client pool",
@@ -223,11 +305,42 @@ var (
 		"thrift_success",
 	})
 
+	clientPoolGetWaitHisto = promauto.With(prometheusbpint.GlobalRegistry).NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "thrift_client_pool_get_wait_seconds",
+		Help:    "The time spent getting a client from a thrift client pool, before a client is obtained or the attempt fails",
+		Buckets: prometheusbp.DefaultLatencyBuckets,
+	}, []string{
+		"thrift_pool",
+		"thrift_success",
+	})
+
+	clientPoolInitialConnectionsGauge = promauto.With(prometheusbpint.GlobalRegistry).NewGaugeVec(prometheus.GaugeOpts{
+		Name: "thriftbp_client_pool_initial_connections",
+		Help: "The configured number of initial connections requested by a thrift client pool",
+	}, clientPoolLabels)
+
+	clientPoolInitialConnectionShortfallCounter = promauto.With(prometheusbpint.GlobalRegistry).NewCounterVec(prometheus.CounterOpts{
+		Name: "thriftbp_client_pool_initial_connection_shortfall",
+		Help: "The number of requested initial connections failed to be established when creating a thrift client pool",
+	}, clientPoolLabels)
+
 	clientPoolMaxSizeGauge = promauto.With(prometheusbpint.GlobalRegistry).NewGaugeVec(prometheus.GaugeOpts{
 		Name: "thrift_client_pool_max_size",
 		Help: "The configured max size of a thrift client pool",
 	}, []string{"thrift_pool"})
 
+	clientPoolConfigInfoGauge = promauto.With(prometheusbpint.GlobalRegistry).NewGaugeVec(prometheus.GaugeOpts{
+		Name: "thrift_client_pool_config_info",
+		Help: "The effective configuration of a thrift client pool. Always 1",
+	}, []string{
+		"thrift_pool",
+		"thrift_initial_connections",
+		"thrift_max_connections",
+		"thrift_max_connection_age",
+		"thrift_connect_timeout",
+		"thrift_socket_timeout",
+	})
+
 	clientPoolPeakActiveConnectionsDesc = prometheus.NewDesc(
 		"thrift_client_pool_peak_active_connections",
 		"The lifetime max number of active (in-use) connections of a thrift client pool",
@@ -248,8 +361,22 @@ var (
 		[]string{"thrift_pool"},
 		nil, // const labels
 	)
+
+	clientPoolConnectionAgeDesc = prometheus.NewDesc(
+		"thrift_client_pool_connection_age_seconds",
+		"The ages of the current connections of a thrift client pool, sampled at scrape time",
+		[]string{"thrift_pool"},
+		nil, // const labels
+	)
 )
 
+// clientPoolConnectionAgeBuckets are the buckets used by
+// thrift_client_pool_connection_age_seconds histogram,
+// with finer granularity around DefaultMaxConnectionAge.
+var clientPoolConnectionAgeBuckets = []float64{
+	15, 30, 60, 120, 180, 240, 270, 300, 330, 360, 600, 1800, 3600,
+}
+
 const (
 	clientLabel = "thrift_client"
 )
@@ -269,9 +396,27 @@ var (
 	}, deadlineBudgetLabels)
 )
 
+// recordClientPoolConfigInfo exports the effective configuration of a client
+// pool via clientPoolConfigInfoGauge.
+func recordClientPoolConfigInfo(cfg ClientPoolConfig) {
+	maxConnectionAge := cfg.MaxConnectionAge
+	if maxConnectionAge == 0 {
+		maxConnectionAge = DefaultMaxConnectionAge
+	}
+	clientPoolConfigInfoGauge.With(prometheus.Labels{
+		"thrift_pool":                cfg.ServiceSlug,
+		"thrift_initial_connections": strconv.Itoa(cfg.InitialConnections),
+		"thrift_max_connections":     strconv.Itoa(cfg.MaxConnections),
+		"thrift_max_connection_age":  maxConnectionAge.String(),
+		"thrift_connect_timeout":     cfg.ConnectTimeout.String(),
+		"thrift_socket_timeout":      cfg.SocketTimeout.String(),
+	}).Set(1)
+}
+
 type clientPoolGaugeExporter struct {
 	slug string
 	pool clientpool.Pool
+	ages *connectionAgeTracker // optional
 
 	activeConnections prometheusbpint.HighWatermarkValue
 }
@@ -305,6 +450,38 @@ func (e *clientPoolGaugeExporter) Collect(ch chan<- prometheus.Metric) {
 		idle,
 		e.slug,
 	)
+
+	if e.ages != nil {
+		ch <- connectionAgeHistogram(e.slug, e.ages.ages(time.Now()))
+	}
+}
+
+// connectionAgeHistogram builds the thrift_client_pool_connection_age_seconds
+// histogram out of the given ages, in seconds.
+func connectionAgeHistogram(slug string, ages []float64) prometheus.Metric {
+	var sum float64
+	buckets := make(map[float64]uint64, len(clientPoolConnectionAgeBuckets))
+	for _, bound := range clientPoolConnectionAgeBuckets {
+		buckets[bound] = 0
+	}
+	for _, age := range ages {
+		sum += age
+		for _, bound := range clientPoolConnectionAgeBuckets {
+			// bucket counts are cumulative.
+			if age <= bound {
+				buckets[bound]++
+			}
+		}
+	}
+	// MustNewConstHistogram would only panic if there's a label mismatch, which
+	// we have a unit test to cover.
+	return prometheus.MustNewConstHistogram(
+		clientPoolConnectionAgeDesc,
+		uint64(len(ages)),
+		sum,
+		buckets,
+		slug,
+	)
 }
 
 func stringifyErrorType(err error) string {
@@ -7,9 +7,12 @@ import (
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/apache/thrift/lib/go/thrift"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
 
 	"github.com/reddit/baseplate.go/clientpool"
 	"github.com/reddit/baseplate.go/internal/gen-go/reddit/baseplate"
@@ -176,9 +179,12 @@ func TestClientPoolGaugeExporterRegister(t *testing.T) {
 }
 
 func TestClientPoolGaugeExporterCollect(t *testing.T) {
+	ages := newConnectionAgeTracker()
+	ages.set(&ttlClient{}, time.Now())
 	exporter := clientPoolGaugeExporter{
 		slug: "client_name",
 		pool: fakePool{},
+		ages: ages,
 	}
 	ch := make(chan prometheus.Metric)
 	var wg sync.WaitGroup
@@ -198,6 +204,41 @@ func TestClientPoolGaugeExporterCollect(t *testing.T) {
 	exporter.Collect(ch)
 }
 
+func TestConnectionAgeHistogram(t *testing.T) {
+	now := time.Now()
+	ages := newConnectionAgeTracker()
+	closed := &ttlClient{}
+	ages.set(&ttlClient{}, now.Add(-10*time.Second))
+	ages.set(&ttlClient{}, now.Add(-100*time.Second))
+	ages.set(&ttlClient{}, now.Add(-2*time.Hour))
+	ages.set(closed, now)
+	ages.remove(closed)
+
+	var m dto.Metric
+	if err := connectionAgeHistogram("client_name", ages.ages(now)).Write(&m); err != nil {
+		t.Fatal(err)
+	}
+	histogram := m.GetHistogram()
+	if got, want := histogram.GetSampleCount(), uint64(3); got != want {
+		t.Errorf("Expected sample count %d, got %d", want, got)
+	}
+	if got, want := histogram.GetSampleSum(), float64(10+100+7200); got != want {
+		t.Errorf("Expected sample sum %v, got %v", want, got)
+	}
+	for _, bucket := range histogram.GetBucket() {
+		var want uint64
+		switch bound := bucket.GetUpperBound(); {
+		case bound >= 120:
+			want = 2
+		case bound >= 15:
+			want = 1
+		}
+		if got := bucket.GetCumulativeCount(); got != want {
+			t.Errorf("Bucket %v: expected cumulative count %d, got %d", bucket.GetUpperBound(), want, got)
+		}
+	}
+}
+
 // This is an thrift.TException implementation that does not wrap any error.
 type customTException struct{}
 
@@ -278,3 +319,104 @@ func TestStringifyErrorType(t *testing.T) {
 		})
 	}
 }
+
+func TestRecordClientPoolConfigInfo(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		cfg    ClientPoolConfig
+		labels prometheus.Labels
+	}{
+		{
+			name: "explicit",
+			cfg: ClientPoolConfig{
+				ServiceSlug:        "config-info-explicit",
+				InitialConnections: 2,
+				MaxConnections:     10,
+				MaxConnectionAge:   time.Minute,
+				ConnectTimeout:     time.Millisecond * 5,
+				SocketTimeout:      time.Millisecond * 15,
+			},
+			labels: prometheus.Labels{
+				"thrift_pool":                "config-info-explicit",
+				"thrift_initial_connections": "2",
+				"thrift_max_connections":     "10",
+				"thrift_max_connection_age":  "1m0s",
+				"thrift_connect_timeout":     "5ms",
+				"thrift_socket_timeout":      "15ms",
+			},
+		},
+		{
+			name: "defaults",
+			cfg: ClientPoolConfig{
+				ServiceSlug:    "config-info-defaults",
+				MaxConnections: 5,
+			},
+			labels: prometheus.Labels{
+				"thrift_pool":                "config-info-defaults",
+				"thrift_initial_connections": "0",
+				"thrift_max_connections":     "5",
+				"thrift_max_connection_age":  DefaultMaxConnectionAge.String(),
+				"thrift_connect_timeout":     "0s",
+				"thrift_socket_timeout":      "0s",
+			},
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			recordClientPoolConfigInfo(c.cfg)
+			if got := testutil.ToFloat64(clientPoolConfigInfoGauge.With(c.labels)); got != 1 {
+				t.Errorf("Expected config info gauge with labels %v to be 1, got %v", c.labels, got)
+			}
+		})
+	}
+}
+
+func TestLogSlowCalls(t *testing.T) {
+	const (
+		slug      = "slow"
+		threshold = 10 * time.Millisecond
+	)
+
+	clientSlowCallsCounter.Reset()
+	defer clientSlowCallsCounter.Reset()
+
+	for _, c := range []struct {
+		method   string
+		duration time.Duration
+		slow     bool
+	}{
+		{
+			method:   "fast",
+			duration: 0,
+			slow:     false,
+		},
+		{
+			method:   "slow",
+			duration: threshold * 2,
+			slow:     true,
+		},
+	} {
+		t.Run(c.method, func(t *testing.T) {
+			metric := promtest.NewPrometheusMetricTest(t, "slow calls", clientSlowCallsCounter, prometheus.Labels{
+				methodLabel:     c.method,
+				clientNameLabel: slug,
+			})
+			client := thrift.WrapClient(
+				thrift.WrappedTClient{
+					Wrapped: func(ctx context.Context, method string, args, result thrift.TStruct) (thrift.ResponseMeta, error) {
+						time.Sleep(c.duration)
+						return thrift.ResponseMeta{}, nil
+					},
+				},
+				LogSlowCalls(slug, threshold),
+			)
+			if _, err := client.Call(context.Background(), c.method, nil, nil); err != nil {
+				t.Fatal(err)
+			}
+			if c.slow {
+				metric.CheckDelta(1)
+			} else {
+				metric.CheckDelta(0)
+			}
+		})
+	}
+}
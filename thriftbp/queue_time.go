@@ -0,0 +1,143 @@
+package thriftbp
+
+import (
+	"context"
+	"time"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type receivedAtCtxKeyType struct{}
+
+var receivedAtCtxKey receivedAtCtxKeyType
+
+// receivedAtServerTransport wraps a thrift.TServerTransport to track when the
+// requests are received on the accepted connections.
+type receivedAtServerTransport struct {
+	thrift.TServerTransport
+}
+
+func (t receivedAtServerTransport) Accept() (thrift.TTransport, error) {
+	trans, err := t.TServerTransport.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &receivedAtTransport{TTransport: trans}, nil
+}
+
+// receivedAtTransport records when the first bytes of the next request are
+// read from the connection.
+//
+// The requests on the same connection are read and processed sequentially by
+// the server, so it's not safe for concurrent use.
+type receivedAtTransport struct {
+	thrift.TTransport
+
+	// receivedAt is the time of the first read since the last request was
+	// processed, or zero if there's none.
+	receivedAt time.Time
+	// lastReadAt is the time of the last read.
+	lastReadAt time.Time
+}
+
+func (t *receivedAtTransport) Read(p []byte) (int, error) {
+	n, err := t.TTransport.Read(p)
+	if n > 0 {
+		t.lastReadAt = time.Now()
+		if t.receivedAt.IsZero() {
+			t.receivedAt = t.lastReadAt
+		}
+	}
+	return n, err
+}
+
+// Unwrap returns the wrapped transport.
+func (t *receivedAtTransport) Unwrap() thrift.TTransport {
+	return t.TTransport
+}
+
+// takeReceivedAt returns the time the current request was received,
+// and resets it for the next request.
+func (t *receivedAtTransport) takeReceivedAt() time.Time {
+	receivedAt := t.receivedAt
+	if receivedAt.IsZero() {
+		// The request was already buffered by the reads of the previous request.
+		receivedAt = t.lastReadAt
+	}
+	t.receivedAt = time.Time{}
+	return receivedAt
+}
+
+// receivedAtProcessorFactory is a thrift.TProcessorFactory wrapping the
+// processors of the connections accepted by receivedAtServerTransport with
+// receivedAtProcessor.
+type receivedAtProcessorFactory struct {
+	factory thrift.TProcessorFactory
+}
+
+func (f receivedAtProcessorFactory) GetProcessor(trans thrift.TTransport) thrift.TProcessor {
+	if t, ok := trans.(*receivedAtTransport); ok {
+		return receivedAtProcessor{
+			TProcessor: f.factory.GetProcessor(trans),
+			trans:      t,
+		}
+	}
+	return f.factory.GetProcessor(trans)
+}
+
+// receivedAtProcessor is a thrift.TProcessor wrapper that records the time
+// a request is received into the context object, to be used by
+// RecordQueueTime.
+type receivedAtProcessor struct {
+	thrift.TProcessor
+
+	trans *receivedAtTransport
+}
+
+func (p receivedAtProcessor) Process(ctx context.Context, in, out thrift.TProtocol) (bool, thrift.TException) {
+	if receivedAt := p.trans.takeReceivedAt(); !receivedAt.IsZero() {
+		ctx = context.WithValue(ctx, receivedAtCtxKey, receivedAt)
+	}
+	defer func() {
+		// The reads while processing the request, e.g. of unframed requests,
+		// belong to this request.
+		p.trans.receivedAt = time.Time{}
+	}()
+	return p.TProcessor.Process(ctx, in, out)
+}
+
+// RecordQueueTime is a ProcessorMiddleware that reports how long a request
+// waited before reaching it, via thrift_server_queue_seconds histogram with
+// thrift_method label.
+//
+// The wait time is measured from when the server received the request,
+// which is recorded by the servers created by NewServer with
+// ServerConfig.TrackReceiveTime set (NewBaseplateServer sets it when
+// ServerConfig.ReportQueueTime is true), to when RecordQueueTime is called,
+// so it should be added as early as possible in the middleware chain.
+//
+// The receive time is when the first bytes of the request are read from the
+// connection, so the wait time includes reading the rest of the request,
+// which the thrift server does before processing it.
+//
+// Limitation: the time a request sits in the socket buffer before being read,
+// for example while the previous request on the same connection is being
+// handled, is not included.
+// When the request was already read together with the previous request on the
+// same connection, the time of that read is used instead.
+// Without ServerConfig.TrackReceiveTime, or with servers not created by
+// NewServer or NewBaseplateServer, the receive time is not available,
+// and RecordQueueTime does not report anything.
+func RecordQueueTime(name string, next thrift.TProcessorFunction) thrift.TProcessorFunction {
+	return thrift.WrappedTProcessorFunction{
+		Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+			if receivedAt, ok := ctx.Value(receivedAtCtxKey).(time.Time); ok {
+				serverQueueTime.With(prometheus.Labels{
+					methodLabel: name,
+				}).Observe(time.Since(receivedAt).Seconds())
+			}
+			return next.Process(ctx, seqID, in, out)
+		},
+	}
+}
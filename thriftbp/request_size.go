@@ -0,0 +1,233 @@
+package thriftbp
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/apache/thrift/lib/go/thrift"
+)
+
+// RequestTooLargeError is the error returned when reading a request frame
+// exceeding the configured ServerConfig.MaxRequestSize.
+//
+// It's sent back to the client as the message of a thrift
+// TApplicationException with PROTOCOL_ERROR type.
+type RequestTooLargeError struct {
+	// Limit is the configured max request size, in bytes.
+	Limit int32
+
+	// Size is the frame size declared by the request, in bytes.
+	Size uint32
+}
+
+func (err RequestTooLargeError) Error() string {
+	return fmt.Sprintf(
+		"thriftbp: request frame of %d bytes exceeded max request size of %d bytes",
+		err.Size,
+		err.Limit,
+	)
+}
+
+// LimitRequestSize wraps a thrift.TServerTransport to reject the framed
+// requests larger than limit bytes based on their frame size,
+// before the frame is read into memory by the server.
+//
+// The oversized frames are skipped,
+// and the client gets a TApplicationException with RequestTooLargeError as the
+// message, so the connection can still be used for the following requests.
+// Only the first limit bytes of an oversized frame are kept in memory to read
+// the method name and sequence id of the request for the response.
+// If they can't be read, the connection fails with RequestTooLargeError
+// instead.
+// The rejections are reported via thriftbp_server_request_too_large_total
+// counter.
+//
+// Whether a connection is framed is decided once on its first request,
+// the same way as thrift.THeaderTransport does.
+// Unframed connections can't be checked this way,
+// those are only limited by the MaxMessageSize of the thrift.TConfiguration
+// used by the server's protocol.
+//
+// If you are using NewServer or NewBaseplateServer,
+// this is applied automatically when ServerConfig.MaxRequestSize is set.
+func LimitRequestSize(transport thrift.TServerTransport, limit int32) thrift.TServerTransport {
+	return requestSizeLimitServerTransport{
+		TServerTransport: transport,
+		limit:            limit,
+	}
+}
+
+type requestSizeLimitServerTransport struct {
+	thrift.TServerTransport
+
+	limit int32
+}
+
+func (t requestSizeLimitServerTransport) Accept() (thrift.TTransport, error) {
+	trans, err := t.TServerTransport.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &requestSizeLimitTransport{
+		TTransport: trans,
+		limit:      t.limit,
+	}, nil
+}
+
+// requestSizeLimitTransport tracks the frame boundaries of the bytes read
+// through it to check the size of every frame.
+type requestSizeLimitTransport struct {
+	thrift.TTransport
+
+	limit int32
+
+	// header is the size header of the next frame, or the first 4 bytes of the
+	// connection before detected, with headerN bytes read so far.
+	header  [4]byte
+	headerN int
+	// pending is the part of header not yet returned to the reader.
+	pending []byte
+	// remaining is the number of bytes of the current frame not yet read.
+	remaining uint32
+
+	detected bool
+	unframed bool
+	err      error
+}
+
+func (t *requestSizeLimitTransport) Read(p []byte) (int, error) {
+	if t.err != nil {
+		return 0, t.err
+	}
+	if t.unframed {
+		return t.TTransport.Read(p)
+	}
+	for len(t.pending) == 0 && t.remaining == 0 {
+		if err := t.readHeader(); err != nil {
+			return 0, err
+		}
+	}
+	if len(t.pending) > 0 {
+		n := copy(p, t.pending)
+		t.pending = t.pending[n:]
+		return n, nil
+	}
+	if uint32(len(p)) > t.remaining {
+		p = p[:t.remaining]
+	}
+	n, err := t.TTransport.Read(p)
+	t.remaining -= uint32(n)
+	return n, err
+}
+
+// Unwrap returns the wrapped transport.
+func (t *requestSizeLimitTransport) Unwrap() thrift.TTransport {
+	return t.TTransport
+}
+
+// readHeader reads the size header of the next frame.
+//
+// On the first frame of the connection it also detects whether the connection
+// is framed.
+// Oversized frames are rejected and skipped, leaving both pending and
+// remaining empty.
+func (t *requestSizeLimitTransport) readHeader() error {
+	// Partial headers are kept across the calls, as the reads could fail with
+	// timeouts to be retried by the caller.
+	for t.headerN < len(t.header) {
+		n, err := t.TTransport.Read(t.header[t.headerN:])
+		t.headerN += n
+		if t.headerN < len(t.header) && err != nil {
+			return err
+		}
+	}
+	t.headerN = 0
+	size := binary.BigEndian.Uint32(t.header[:])
+
+	if !t.detected {
+		t.detected = true
+		// Same detection as thrift.THeaderTransport.
+		if size&thrift.VERSION_MASK == thrift.VERSION_1 ||
+			(t.header[0] == thrift.COMPACT_PROTOCOL_ID && t.header[1]&thrift.COMPACT_VERSION_MASK == thrift.COMPACT_VERSION) {
+			// There are no frame boundaries to track for unframed requests.
+			t.unframed = true
+			t.pending = t.header[:]
+			return nil
+		}
+	}
+
+	if size > uint32(t.limit) {
+		return t.reject(size)
+	}
+	t.pending = t.header[:]
+	t.remaining = size
+	return nil
+}
+
+// reject skips the oversized frame of size bytes, and replies a
+// TApplicationException to the client.
+func (t *requestSizeLimitTransport) reject(size uint32) error {
+	serverRequestTooLargeCounter.Inc()
+	tooLarge := RequestTooLargeError{
+		Limit: t.limit,
+		Size:  size,
+	}
+
+	// Keep the first limit bytes of the frame as a frame on its own,
+	// to read the method name and sequence id of the request from it.
+	kept := uint32(t.limit)
+	frame := make([]byte, len(t.header)+int(kept))
+	binary.BigEndian.PutUint32(frame, kept)
+	if _, err := io.ReadFull(t.TTransport, frame[len(t.header):]); err != nil {
+		t.err = fmt.Errorf("%w: %w", tooLarge, err)
+		return t.err
+	}
+	if _, err := io.CopyN(io.Discard, t.TTransport, int64(size-kept)); err != nil {
+		t.err = fmt.Errorf("%w: %w", tooLarge, err)
+		return t.err
+	}
+
+	if err := t.replyTooLarge(frame, tooLarge); err != nil {
+		t.err = fmt.Errorf("%w: %w", tooLarge, err)
+		return t.err
+	}
+	return nil
+}
+
+// replyTooLarge writes the TApplicationException for the request in frame
+// back to the client, in the same protocol as the request.
+func (t *requestSizeLimitTransport) replyTooLarge(frame []byte, tooLarge RequestTooLargeError) error {
+	ctx := context.Background()
+	var response bytes.Buffer
+	cfg := &thrift.TConfiguration{
+		MaxFrameSize:   t.limit,
+		MaxMessageSize: t.limit,
+	}
+	proto := thrift.NewTHeaderProtocolConf(
+		thrift.NewStreamTransport(bytes.NewReader(frame), &response),
+		cfg,
+	)
+	name, _, seqID, err := proto.ReadMessageBegin(ctx)
+	if err != nil {
+		return err
+	}
+	if err := proto.WriteMessageBegin(ctx, name, thrift.EXCEPTION, seqID); err != nil {
+		return err
+	}
+	if err := thrift.NewTApplicationException(thrift.PROTOCOL_ERROR, tooLarge.Error()).Write(ctx, proto); err != nil {
+		return err
+	}
+	if err := proto.WriteMessageEnd(ctx); err != nil {
+		return err
+	}
+	if err := proto.Flush(ctx); err != nil {
+		return err
+	}
+	if _, err := t.TTransport.Write(response.Bytes()); err != nil {
+		return err
+	}
+	return t.TTransport.Flush(ctx)
+}
@@ -0,0 +1,178 @@
+package thriftbp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// oneByteTransport reads at most one byte at a time from the wrapped transport.
+type oneByteTransport struct {
+	thrift.TTransport
+}
+
+func (t oneByteTransport) Read(p []byte) (int, error) {
+	if len(p) > 1 {
+		p = p[:1]
+	}
+	return t.TTransport.Read(p)
+}
+
+func TestLimitRequestSize(t *testing.T) {
+	const (
+		limit  = 1024
+		method = "foo"
+	)
+	ctx := context.Background()
+
+	writeRequest := func(t *testing.T, proto thrift.TProtocol, seqID int32, payload string) {
+		t.Helper()
+		if err := proto.WriteMessageBegin(ctx, method, thrift.CALL, seqID); err != nil {
+			t.Fatal(err)
+		}
+		if err := proto.WriteString(ctx, payload); err != nil {
+			t.Fatal(err)
+		}
+		if err := proto.WriteMessageEnd(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if err := proto.Flush(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	readRequest := func(t *testing.T, proto thrift.TProtocol, expectedSeqID int32, expectedPayload string) {
+		t.Helper()
+		_, _, seqID, err := proto.ReadMessageBegin(ctx)
+		if err != nil {
+			t.Fatalf("Failed to read request %d: %v", expectedSeqID, err)
+		}
+		if seqID != expectedSeqID {
+			t.Errorf("Expected seq id %d, got %d", expectedSeqID, seqID)
+		}
+		payload, err := proto.ReadString(ctx)
+		if err != nil {
+			t.Fatalf("Failed to read request %d: %v", expectedSeqID, err)
+		}
+		if payload != expectedPayload {
+			t.Errorf("Expected payload %q, got %q", expectedPayload, payload)
+		}
+		if err := proto.ReadMessageEnd(ctx); err != nil {
+			t.Fatalf("Failed to read request %d: %v", expectedSeqID, err)
+		}
+	}
+
+	for _, c := range []struct {
+		label string
+		wrap  func(thrift.TTransport) thrift.TTransport
+	}{
+		{
+			label: "buffered",
+			wrap:  func(t thrift.TTransport) thrift.TTransport { return t },
+		},
+		{
+			label: "one-byte",
+			wrap:  func(t thrift.TTransport) thrift.TTransport { return oneByteTransport{t} },
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			t.Run("framed", func(t *testing.T) {
+				var requests, responses bytes.Buffer
+				client := thrift.NewTHeaderProtocolConf(thrift.NewStreamTransportW(&requests), nil)
+				writeRequest(t, client, 1, "small")
+				writeRequest(t, client, 2, strings.Repeat("a", limit*2))
+				writeRequest(t, client, 3, "small again")
+
+				before := testutil.ToFloat64(serverRequestTooLargeCounter)
+				trans := &requestSizeLimitTransport{
+					TTransport: c.wrap(thrift.NewStreamTransport(&requests, &responses)),
+					limit:      limit,
+				}
+				proto := thrift.NewTHeaderProtocolConf(trans, nil)
+
+				readRequest(t, proto, 1, "small")
+				// The oversized request should be skipped.
+				readRequest(t, proto, 3, "small again")
+				if delta := testutil.ToFloat64(serverRequestTooLargeCounter) - before; delta != 1 {
+					t.Errorf("Expected counter delta 1, got %v", delta)
+				}
+
+				response := thrift.NewTHeaderProtocolConf(thrift.NewStreamTransportR(&responses), nil)
+				name, typeID, seqID, err := response.ReadMessageBegin(ctx)
+				if err != nil {
+					t.Fatalf("Failed to read the response: %v", err)
+				}
+				if name != method || typeID != thrift.EXCEPTION || seqID != 2 {
+					t.Errorf(
+						"Expected exception response to %q #2, got %v response to %q #%d",
+						method,
+						typeID,
+						name,
+						seqID,
+					)
+				}
+				ex := thrift.NewTApplicationException(thrift.UNKNOWN_APPLICATION_EXCEPTION, "")
+				if err := ex.Read(ctx, response); err != nil {
+					t.Fatalf("Failed to read the exception: %v", err)
+				}
+				if ex.TypeId() != thrift.PROTOCOL_ERROR {
+					t.Errorf("Expected exception type %d, got %d", thrift.PROTOCOL_ERROR, ex.TypeId())
+				}
+				if !strings.Contains(ex.Error(), "exceeded max request size of 1024 bytes") {
+					t.Errorf("Unexpected exception message: %v", ex)
+				}
+			})
+
+			t.Run("framed-version-like-size", func(t *testing.T) {
+				var requests bytes.Buffer
+				client := thrift.NewTHeaderProtocolConf(thrift.NewStreamTransportW(&requests), nil)
+				writeRequest(t, client, 1, "small")
+				// A frame size looking like the version of unframed binary
+				// requests should still be checked as a frame size.
+				requests.Write([]byte{0x80, 0x01, 0x00, 0x01})
+				requests.WriteString("truncated")
+
+				trans := &requestSizeLimitTransport{
+					TTransport: c.wrap(thrift.NewStreamTransportR(&requests)),
+					limit:      limit,
+				}
+				proto := thrift.NewTHeaderProtocolConf(trans, nil)
+
+				readRequest(t, proto, 1, "small")
+				_, _, _, err := proto.ReadMessageBegin(ctx)
+				var tooLarge RequestTooLargeError
+				if !errors.As(err, &tooLarge) {
+					t.Fatalf("Expected RequestTooLargeError, got %v", err)
+				}
+				if tooLarge.Limit != limit {
+					t.Errorf("Expected limit %d, got %d", limit, tooLarge.Limit)
+				}
+				if tooLarge.Size != 0x80010001 {
+					t.Errorf("Expected size %d, got %d", 0x80010001, tooLarge.Size)
+				}
+			})
+
+			t.Run("unframed", func(t *testing.T) {
+				var requests bytes.Buffer
+				client := thrift.NewTBinaryProtocolConf(thrift.NewStreamTransportW(&requests), nil)
+				writeRequest(t, client, 1, "small")
+				large := strings.Repeat("a", limit*2)
+				writeRequest(t, client, 2, large)
+
+				trans := &requestSizeLimitTransport{
+					TTransport: c.wrap(thrift.NewStreamTransportR(&requests)),
+					limit:      limit,
+				}
+				proto := thrift.NewTHeaderProtocolConf(trans, nil)
+
+				readRequest(t, proto, 1, "small")
+				readRequest(t, proto, 2, large)
+			})
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package thriftbp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/apache/thrift/lib/go/thrift"
+)
+
+// ResponseTooLargeError is the error returned by the client calls when the
+// response from the upstream server exceeds the configured
+// ClientPoolConfig.MaxResponseSize.
+type ResponseTooLargeError struct {
+	// Limit is the configured max response size, in bytes.
+	Limit int32
+
+	// Cause is the size limit error returned by the thrift library.
+	Cause error
+}
+
+func (err ResponseTooLargeError) Error() string {
+	return fmt.Sprintf(
+		"thriftbp: response exceeded max response size of %d bytes: %v",
+		err.Limit,
+		err.Cause,
+	)
+}
+
+func (err ResponseTooLargeError) Unwrap() error {
+	return err.Cause
+}
+
+// LimitResponseSize is a ClientMiddleware that converts the size limit errors
+// returned by the thrift library when reading the response into
+// ResponseTooLargeError.
+//
+// It does not enforce the limit by itself, the limit is enforced by the thrift
+// transport and protocol via MaxFrameSize and MaxMessageSize of the
+// thrift.TConfiguration used to create them.
+//
+// If you are using a thrift ClientPool created by NewBaseplateClientPool,
+// this will be included automatically when ClientPoolConfig.MaxResponseSize is
+// set, and should not be passed in as a ClientMiddleware.
+// If you are using NewCustomClientPool, make sure the protoFactory passed in is
+// created with the TConfiguration returned by ClientPoolConfig.ToTConfiguration.
+func LimitResponseSize(limit int32) thrift.ClientMiddleware {
+	return func(next thrift.TClient) thrift.TClient {
+		return thrift.WrappedTClient{
+			Wrapped: func(ctx context.Context, method string, args, result thrift.TStruct) (thrift.ResponseMeta, error) {
+				meta, err := next.Call(ctx, method, args, result)
+				var te thrift.TProtocolException
+				if errors.As(err, &te) && te.TypeId() == thrift.SIZE_LIMIT {
+					err = ResponseTooLargeError{
+						Limit: limit,
+						Cause: err,
+					}
+				}
+				return meta, err
+			},
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package thriftbp_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/apache/thrift/lib/go/thrift"
+
+	baseplatethrift "github.com/reddit/baseplate.go/internal/gen-go/reddit/baseplate"
+	"github.com/reddit/baseplate.go/thriftbp"
+)
+
+func TestClientPoolConfigMaxResponseSize(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		cfg := thriftbp.ClientPoolConfig{}.ToTConfiguration()
+		if cfg.GetMaxFrameSize() != thrift.DEFAULT_MAX_FRAME_SIZE {
+			t.Errorf("Expected default max frame size %d, got %d", thrift.DEFAULT_MAX_FRAME_SIZE, cfg.GetMaxFrameSize())
+		}
+		if cfg.GetMaxMessageSize() != thrift.DEFAULT_MAX_MESSAGE_SIZE {
+			t.Errorf("Expected default max message size %d, got %d", thrift.DEFAULT_MAX_MESSAGE_SIZE, cfg.GetMaxMessageSize())
+		}
+	})
+
+	t.Run("set", func(t *testing.T) {
+		const limit = 1024
+		cfg := thriftbp.ClientPoolConfig{MaxResponseSize: limit}.ToTConfiguration()
+		if cfg.GetMaxFrameSize() != limit {
+			t.Errorf("Expected max frame size %d, got %d", limit, cfg.GetMaxFrameSize())
+		}
+		if cfg.GetMaxMessageSize() != limit {
+			t.Errorf("Expected max message size %d, got %d", limit, cfg.GetMaxMessageSize())
+		}
+	})
+}
+
+func TestLimitResponseSize(t *testing.T) {
+	const (
+		limit  = 1024
+		method = "isHealthy"
+	)
+	ctx := context.Background()
+
+	// Stub an oversized response from the upstream server.
+	response := thrift.NewTMemoryBuffer()
+	serverProto := thrift.NewTHeaderProtocolConf(response, nil)
+	if err := serverProto.WriteMessageBegin(ctx, method, thrift.REPLY, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := serverProto.WriteString(ctx, strings.Repeat("a", limit*2)); err != nil {
+		t.Fatal(err)
+	}
+	if err := serverProto.WriteMessageEnd(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := serverProto.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := thriftbp.ClientPoolConfig{MaxResponseSize: limit}.ToTConfiguration()
+	client := thrift.WrapClient(
+		thrift.NewTStandardClient(
+			thrift.NewTHeaderProtocolConf(response, cfg),
+			thrift.NewTHeaderProtocolConf(thrift.NewTMemoryBuffer(), cfg),
+		),
+		thriftbp.LimitResponseSize(limit),
+	)
+	_, err := client.Call(
+		ctx,
+		method,
+		&baseplatethrift.BaseplateServiceV2IsHealthyArgs{
+			Request: &baseplatethrift.IsHealthyRequest{},
+		},
+		&baseplatethrift.BaseplateServiceV2IsHealthyResult{},
+	)
+	var tooLarge thriftbp.ResponseTooLargeError
+	if !errors.As(err, &tooLarge) {
+		t.Fatalf("Expected ResponseTooLargeError, got %v", err)
+	}
+	if tooLarge.Limit != limit {
+		t.Errorf("Expected limit %d, got %d", limit, tooLarge.Limit)
+	}
+	var te thrift.TProtocolException
+	if !errors.As(err, &te) || te.TypeId() != thrift.SIZE_LIMIT {
+		t.Errorf("Expected the error to wrap a SIZE_LIMIT TProtocolException, got %v", err)
+	}
+}
@@ -2,6 +2,9 @@ package thriftbp
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/apache/thrift/lib/go/thrift"
@@ -52,6 +55,38 @@ type ServerConfig struct {
 	// regarding how it is used.
 	ErrorSpanSuppressor errorsbp.Suppressor
 
+	// Optional, used only by NewBaseplateServer.
+	//
+	// When it's true the OpenTelemetry semantic convention attributes are set on
+	// the server spans. Please refer to the documentation of
+	// DefaultProcessorMiddlewaresArgs.OTelSpanAttributes for more details.
+	OTelSpanAttributes bool
+
+	// Optional, used only by NewBaseplateServer.
+	//
+	// When it's set the caller service is set on the server spans.
+	// Please refer to the documentation of
+	// DefaultProcessorMiddlewaresArgs.OriginServiceFunc for more details.
+	OriginServiceFunc func(ctx context.Context) (name string, ok bool)
+
+	// Optional, used only by NewBaseplateServer.
+	//
+	// When it's true how long the requests waited before being processed is
+	// reported. Please refer to the documentation of RecordQueueTime for more
+	// details.
+	//
+	// NewBaseplateServer also sets TrackReceiveTime when it's true.
+	ReportQueueTime bool
+
+	// Optional, used only by NewServer.
+	// In NewBaseplateServer it's set when ReportQueueTime is true.
+	//
+	// When it's true the server records when the requests are received from the
+	// connections, to be reported by RecordQueueTime.
+	// It adds a small overhead to every read from the connections and every
+	// request, so only set it when RecordQueueTime is used.
+	TrackReceiveTime bool
+
 	// Optional, used only by NewBaseplateServer.
 	//
 	// Report the payload size metrics with this sample rate.
@@ -90,6 +125,31 @@ type ServerConfig struct {
 	// read or write operations will not time out.
 	SocketTimeout time.Duration
 
+	// Optional, used by both NewServer and NewBaseplateServer.
+	//
+	// The maximum size in bytes of a request the server would accept.
+	//
+	// When it's set to a positive value, it's used as both MaxFrameSize and
+	// MaxMessageSize of the thrift.TConfiguration used by the server,
+	// and the server transport is wrapped with LimitRequestSize,
+	// so oversized frames are skipped without being read into memory,
+	// and rejected with RequestTooLargeError as a TApplicationException.
+	//
+	// When <= 0 the thrift library defaults are used.
+	MaxRequestSize int32
+
+	// Optional, used by both NewServer and NewBaseplateServer.
+	//
+	// When it's non-nil the server serves TLS connections with this config,
+	// and the identity from the verified client certificates is available via
+	// PeerCertIdentity.
+	// To verify the client certificates, set ClientAuth to
+	// tls.VerifyClientCertIfGiven or tls.RequireAndVerifyClientCert and
+	// ClientCAs accordingly.
+	//
+	// This is ignored if Socket is non-nil.
+	TLSConfig *tls.Config
+
 	// Optional, used only by NewServer.
 	// In NewBaseplateServer the address and timeout set in bp.Config() will be
 	// used instead.
@@ -103,7 +163,9 @@ type ServerConfig struct {
 // given ProcessorMiddlewares.
 func NewServer(cfg ServerConfig) (*thrift.TSimpleServer, error) {
 	var transport thrift.TServerTransport
-	if cfg.Socket == nil {
+	if cfg.Socket == nil && cfg.TLSConfig != nil {
+		transport = newTLSServerSocket(cfg.Addr, cfg.TLSConfig, cfg.SocketTimeout)
+	} else if cfg.Socket == nil {
 		var err error
 		if cfg.SocketTimeout > 0 {
 			transport, err = thrift.NewTServerSocketTimeout(cfg.Addr, cfg.SocketTimeout)
@@ -117,15 +179,34 @@ func NewServer(cfg ServerConfig) (*thrift.TSimpleServer, error) {
 		transport = cfg.Socket
 	}
 
+	var tcfg *thrift.TConfiguration
+	if cfg.MaxRequestSize > 0 {
+		transport = LimitRequestSize(transport, cfg.MaxRequestSize)
+		tcfg = &thrift.TConfiguration{
+			MaxFrameSize:   cfg.MaxRequestSize,
+			MaxMessageSize: cfg.MaxRequestSize,
+		}
+	}
+
 	middlewares := make([]thrift.ProcessorMiddleware, 0, len(cfg.Middlewares)+1)
 	middlewares = append(middlewares, cfg.Middlewares...)
 	middlewares = append(middlewares, recoverPanik)
 
-	server := thrift.NewTSimpleServer4(
-		thrift.WrapProcessor(cfg.Processor, middlewares...),
+	processor := thrift.WrapProcessor(cfg.Processor, middlewares...)
+	processorFactory := thrift.NewTProcessorFactory(processor)
+	if cfg.Socket == nil && cfg.TLSConfig != nil {
+		processorFactory = peerCertProcessorFactory{processor: processor}
+	}
+	if cfg.TrackReceiveTime {
+		transport = receivedAtServerTransport{transport}
+		processorFactory = receivedAtProcessorFactory{factory: processorFactory}
+	}
+
+	server := thrift.NewTSimpleServerFactory4(
+		processorFactory,
 		transport,
-		thrift.NewTHeaderTransportFactoryConf(nil, nil),
-		thrift.NewTHeaderProtocolFactoryConf(nil),
+		thrift.NewTHeaderTransportFactoryConf(nil, tcfg),
+		thrift.NewTHeaderProtocolFactoryConf(tcfg),
 	)
 	server.SetForwardHeaders(HeadersToForward)
 	if cfg.LogContext != nil {
@@ -144,10 +225,16 @@ func NewBaseplateServer(
 		DefaultProcessorMiddlewaresArgs{
 			EdgeContextImpl:     bp.EdgeContextImpl(),
 			ErrorSpanSuppressor: cfg.ErrorSpanSuppressor,
+			OTelSpanAttributes:  cfg.OTelSpanAttributes,
+			OriginServiceFunc:   cfg.OriginServiceFunc,
+			ReportQueueTime:     cfg.ReportQueueTime,
 		},
 	)
 	middlewares = append(middlewares, cfg.Middlewares...)
 	cfg.Middlewares = middlewares
+	if cfg.ReportQueueTime {
+		cfg.TrackReceiveTime = true
+	}
 
 	cfg.Addr = bp.GetConfig().Addr
 	cfg.Socket = nil
@@ -167,6 +254,10 @@ func ApplyBaseplate(bp baseplate.Baseplate, server *thrift.TSimpleServer) basepl
 	return impl{bp: bp, srv: server}
 }
 
+// shutdownHooksTimeout is the timeout of the context passed into the shutdown
+// hooks, which are run after the server is stopped.
+const shutdownHooksTimeout = time.Second * 10
+
 type impl struct {
 	bp  baseplate.Baseplate
 	srv *thrift.TSimpleServer
@@ -179,11 +270,20 @@ func (s impl) Baseplate() baseplate.Baseplate {
 }
 
 func (s impl) Serve() error {
+	if err := baseplate.RunStartHooks(context.Background(), s.bp); err != nil {
+		return fmt.Errorf("thriftbp: start hooks failed: %w", err)
+	}
 	return s.srv.Serve()
 }
 
 func (s impl) Close() error {
-	return s.srv.Stop()
+	err := s.srv.Stop()
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownHooksTimeout)
+	defer cancel()
+	return errors.Join(
+		err,
+		baseplate.RunShutdownHooks(ctx, s.bp),
+	)
 }
 
 var (
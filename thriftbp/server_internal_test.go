@@ -0,0 +1,79 @@
+package thriftbp
+
+import (
+	"crypto/tls"
+	"testing"
+
+	"github.com/apache/thrift/lib/go/thrift"
+)
+
+func TestNewServerTrackReceiveTime(t *testing.T) {
+	for _, c := range []struct {
+		label    string
+		track    bool
+		expected bool
+	}{
+		{
+			label: "disabled",
+		},
+		{
+			label:    "enabled",
+			track:    true,
+			expected: true,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			socket, err := thrift.NewTServerSocket("localhost:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			server, err := NewServer(ServerConfig{
+				Processor:        thrift.NewTMultiplexedProcessor(),
+				Socket:           socket,
+				TrackReceiveTime: c.track,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := server.ServerTransport().(receivedAtServerTransport); ok != c.expected {
+				t.Errorf("Expected server transport wrapped to be %v, got %T", c.expected, server.ServerTransport())
+			}
+			if _, ok := server.ProcessorFactory().(receivedAtProcessorFactory); ok != c.expected {
+				t.Errorf("Expected processor factory wrapped to be %v, got %T", c.expected, server.ProcessorFactory())
+			}
+		})
+	}
+}
+
+func TestNewServerPeerCertProcessorFactory(t *testing.T) {
+	t.Run("tls", func(t *testing.T) {
+		server, err := NewServer(ServerConfig{
+			Addr:      "localhost:0",
+			Processor: thrift.NewTMultiplexedProcessor(),
+			TLSConfig: &tls.Config{},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := server.ProcessorFactory().(peerCertProcessorFactory); !ok {
+			t.Errorf("Expected peerCertProcessorFactory, got %T", server.ProcessorFactory())
+		}
+	})
+
+	t.Run("non-tls", func(t *testing.T) {
+		socket, err := thrift.NewTServerSocket("localhost:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		server, err := NewServer(ServerConfig{
+			Processor: thrift.NewTMultiplexedProcessor(),
+			Socket:    socket,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := server.ProcessorFactory().(peerCertProcessorFactory); ok {
+			t.Errorf("Expected no peerCertProcessorFactory for non-TLS server, got %T", server.ProcessorFactory())
+		}
+	})
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/opentracing/opentracing-go"
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/reddit/baseplate.go/ecinterface"
@@ -27,6 +28,8 @@ import (
 
 var (
 	_ thrift.ProcessorMiddleware = ExtractDeadlineBudget
+	_ thrift.ProcessorMiddleware = ExtractIdempotencyKey
+	_ thrift.ProcessorMiddleware = TagServerSpanExceptionType
 	_ thrift.ProcessorMiddleware = AbandonCanceledRequests
 )
 
@@ -56,6 +59,31 @@ type DefaultProcessorMiddlewaresArgs struct {
 	//
 	// If it's not set, the global one from ecinterface.Get will be used instead.
 	EdgeContextImpl ecinterface.Interface
+
+	// When OTelSpanAttributes is true, OTelServerSpanAttributes will be added
+	// right after InjectServerSpan. Optional.
+	OTelSpanAttributes bool
+
+	// OriginServiceFunc extracts the origin service name from the edge request
+	// context injected into the context object.
+	//
+	// When it's set, TagServerSpanCallerService will be added right after
+	// InjectEdgeContext. Optional.
+	OriginServiceFunc func(ctx context.Context) (name string, ok bool)
+
+	// When ReportQueueTime is true, RecordQueueTime will be added as the first
+	// middleware. Optional.
+	//
+	// The server must be created with ServerConfig.TrackReceiveTime for
+	// RecordQueueTime to report anything.
+	ReportQueueTime bool
+
+	// The methods with the IDL exceptions treated as failures instead of being
+	// suppressed.
+	//
+	// When it's non-empty, MarkServerSpanIDLExceptionErrors with these methods
+	// will be added right after TagServerSpanExceptionType. Optional.
+	IDLExceptionFailureMethods []string
 }
 
 // BaseplateDefaultProcessorMiddlewares returns the default processor
@@ -63,22 +91,90 @@ type DefaultProcessorMiddlewaresArgs struct {
 //
 // Currently they are (in order):
 //
-// 1. ExtractDeadlineBudget
+// 1. RecordQueueTime - Only if ReportQueueTime is true.
+//
+// 2. ExtractDeadlineBudget
+//
+// 3. InjectServerSpan
+//
+// 4. TagServerSpanDeadlineBudget
 //
-// 2. InjectServerSpan
+// 5. TagServerSpanExceptionType
 //
-// 3. InjectEdgeContext
+// 6. MarkServerSpanIDLExceptionErrors - Only if IDLExceptionFailureMethods is
+// non-empty.
 //
-// 4. ReportPayloadSizeMetrics
+// 7. OTelServerSpanAttributes - Only if OTelSpanAttributes is true.
 //
-// 5. PrometheusServerMiddleware
+// 8. InjectEdgeContext
+//
+// 9. TagServerSpanCallerService - Only if OriginServiceFunc is set.
+//
+// 10. ExtractIdempotencyKey
+//
+// 11. ReportPayloadSizeMetrics
+//
+// 12. PrometheusServerMiddleware
 func BaseplateDefaultProcessorMiddlewares(args DefaultProcessorMiddlewaresArgs) []thrift.ProcessorMiddleware {
-	return []thrift.ProcessorMiddleware{
+	var middlewares []thrift.ProcessorMiddleware
+	if args.ReportQueueTime {
+		middlewares = append(middlewares, RecordQueueTime)
+	}
+	middlewares = append(
+		middlewares,
 		ExtractDeadlineBudget,
 		InjectServerSpan(args.ErrorSpanSuppressor),
-		InjectEdgeContext(args.EdgeContextImpl),
+		TagServerSpanDeadlineBudget,
+		TagServerSpanExceptionType,
+	)
+	if len(args.IDLExceptionFailureMethods) > 0 {
+		middlewares = append(middlewares, MarkServerSpanIDLExceptionErrors(args.IDLExceptionFailureMethods...))
+	}
+	if args.OTelSpanAttributes {
+		middlewares = append(middlewares, OTelServerSpanAttributes)
+	}
+	middlewares = append(middlewares, InjectEdgeContext(args.EdgeContextImpl))
+	if args.OriginServiceFunc != nil {
+		middlewares = append(middlewares, TagServerSpanCallerService(args.OriginServiceFunc))
+	}
+	return append(
+		middlewares,
+		ExtractIdempotencyKey,
 		ReportPayloadSizeMetrics(0),
 		PrometheusServerMiddleware,
+	)
+}
+
+// MethodMiddlewares returns a ProcessorMiddleware that applies middlewares only
+// to the endpoints with names in methods, in the same order as
+// thrift.WrapProcessor.
+//
+// The endpoints not in methods are returned unwrapped,
+// so the middlewares add no overhead to them.
+//
+// For example, to only apply an idempotency check to the write methods:
+//
+//	cfg.Middlewares = append(
+//		cfg.Middlewares,
+//		thriftbp.MethodMiddlewares(
+//			[]string{"createFoo", "updateFoo"},
+//			idempotencyMiddleware,
+//		),
+//	)
+func MethodMiddlewares(methods []string, middlewares ...thrift.ProcessorMiddleware) thrift.ProcessorMiddleware {
+	set := make(map[string]bool, len(methods))
+	for _, method := range methods {
+		set[method] = true
+	}
+	return func(name string, next thrift.TProcessorFunction) thrift.TProcessorFunction {
+		if !set[name] {
+			return next
+		}
+		// Wrap in reverse order so the first middleware is the outermost one.
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			next = middlewares[i](name, next)
+		}
+		return next
 	}
 }
 
@@ -148,6 +244,186 @@ func InjectServerSpan(_ errorsbp.Suppressor) thrift.ProcessorMiddleware {
 	}
 }
 
+// SpanTagKeyThriftException is the span tag key set by
+// TagServerSpanExceptionType.
+const SpanTagKeyThriftException = "thrift.exception"
+
+// TagServerSpanExceptionType is a ProcessorMiddleware that sets the type name
+// of the exception returned by the endpoint as the "thrift.exception"
+// (SpanTagKeyThriftException) tag on the server span,
+// when the exception is defined in the thrift IDL.
+//
+// It only sets the tag, and doesn't affect whether the span is marked as
+// failed, so the tag is also set for the exceptions suppressed by the
+// ErrorSpanSuppressor.
+// It doesn't create any spans, so it must come after InjectServerSpan.
+// If there's no span in the context object, it's no-op.
+func TagServerSpanExceptionType(name string, next thrift.TProcessorFunction) thrift.TProcessorFunction {
+	return thrift.WrappedTProcessorFunction{
+		Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+			ok, err := next.Process(ctx, seqID, in, out)
+			if err != nil && isIDLException(err) {
+				if span := opentracing.SpanFromContext(ctx); span != nil {
+					span.SetTag(SpanTagKeyThriftException, stringifyErrorType(err))
+				}
+			}
+			return ok, err
+		},
+	}
+}
+
+// SpanTagKeyDeadlineBudget is the span tag key set by
+// TagServerSpanDeadlineBudget.
+const SpanTagKeyDeadlineBudget = "deadline_budget_ms"
+
+// TagServerSpanDeadlineBudget is a ProcessorMiddleware that sets the deadline
+// budget received from the client, in milliseconds,
+// as the "deadline_budget_ms" (SpanTagKeyDeadlineBudget) tag on the server
+// span.
+//
+// It helps to tell whether a timed out request was caused by a slow handler or
+// an unreasonably short budget given by the caller.
+// The distribution of the budgets is also reported by ExtractDeadlineBudget via
+// thriftbp_server_extracted_deadline_budget_seconds histogram.
+//
+// The tag is only set when the deadline budget header is present and is a
+// valid integer, and it's set even when the budget is too short to be applied
+// by ExtractDeadlineBudget.
+// It doesn't create any spans, so it must come after InjectServerSpan.
+// If there's no span in the context object, it's no-op.
+func TagServerSpanDeadlineBudget(name string, next thrift.TProcessorFunction) thrift.TProcessorFunction {
+	return thrift.WrappedTProcessorFunction{
+		Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+			if s, ok := header(ctx, transport.HeaderDeadlineBudget); ok {
+				if v, err := strconv.ParseInt(s, 10, 64); err == nil {
+					if span := opentracing.SpanFromContext(ctx); span != nil {
+						span.SetTag(SpanTagKeyDeadlineBudget, v)
+					}
+				}
+			}
+			return next.Process(ctx, seqID, in, out)
+		},
+	}
+}
+
+// MarkServerSpanIDLExceptionErrors returns a ProcessorMiddleware that marks
+// the server span as failed when one of the given methods returns an
+// exception defined in the thrift IDL.
+//
+// By default the IDL exceptions are suppressed (see IDLExceptionSuppressor) and
+// don't mark the server span as failed. Use this middleware to opt out of the
+// suppression for the methods where an IDL exception is a real failure,
+// e.g. relevant to the SLO. Other methods are not wrapped and keep the default
+// behavior.
+//
+// PrometheusServerMiddleware already reports all the exceptions, including the
+// IDL ones, with success="false" in thrift_server_requests_total,
+// with the exception type in the thrift_exception_type label.
+//
+// The IDL exceptions are returned wrapped to the middlewares before it,
+// so they are not suppressed by IDLExceptionSuppressor and are passed to the
+// Stop of the server span, and its OnPreStop hooks.
+// The original exception can still be accessed via errors.As.
+//
+// It doesn't create any spans, so it must come after InjectServerSpan.
+// If there's no span in the context object, it only wraps the exceptions.
+func MarkServerSpanIDLExceptionErrors(methods ...string) thrift.ProcessorMiddleware {
+	return MethodMiddlewares(methods, func(name string, next thrift.TProcessorFunction) thrift.TProcessorFunction {
+		return thrift.WrappedTProcessorFunction{
+			Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+				ok, err := next.Process(ctx, seqID, in, out)
+				if err != nil && isIDLException(err) {
+					if span := opentracing.SpanFromContext(ctx); span != nil {
+						span.SetTag(tracing.ZipkinBinaryAnnotationKeyError, true)
+					}
+					err = idlExceptionFailure{err: err}
+				}
+				return ok, err
+			},
+		}
+	})
+}
+
+// idlExceptionFailure wraps an IDL exception returned by the methods marked by
+// MarkServerSpanIDLExceptionErrors.
+//
+// Like the errors wrapped by thrift.WrapTException, its TExceptionType is
+// thrift.TExceptionTypeUnknown so it's not treated as an IDL exception itself.
+type idlExceptionFailure struct {
+	err thrift.TException
+}
+
+func (e idlExceptionFailure) Error() string {
+	return e.err.Error()
+}
+
+func (e idlExceptionFailure) TExceptionType() thrift.TExceptionType {
+	return thrift.TExceptionTypeUnknown
+}
+
+func (e idlExceptionFailure) Unwrap() error {
+	return e.err
+}
+
+// TagServerSpanCallerService returns a ProcessorMiddleware that sets the
+// caller service as the "peer.service" (tracing.TagKeyPeerService) tag on the
+// server span.
+//
+// The User-Agent header is preferred when the client sets it. Otherwise the
+// name returned by originService is used, which is usually the origin service
+// from the edge request context, for example:
+//
+//	func(ctx context.Context) (string, bool) {
+//		ec, ok := edgecontext.GetEdgeContext(ctx)
+//		if !ok {
+//			return "", false
+//		}
+//		return ec.OriginService().Name(), true
+//	}
+//
+// It doesn't create any spans, so it must come after InjectServerSpan, and
+// after InjectEdgeContext for originService to be able to read the edge request
+// context. If there's no span in the context object, or neither the header nor
+// the origin service is available, it's no-op.
+func TagServerSpanCallerService(originService func(ctx context.Context) (name string, ok bool)) thrift.ProcessorMiddleware {
+	return func(name string, next thrift.TProcessorFunction) thrift.TProcessorFunction {
+		return thrift.WrappedTProcessorFunction{
+			Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+				if span := opentracing.SpanFromContext(ctx); span != nil {
+					if caller, ok := callerService(ctx, originService); ok {
+						span.SetTag(tracing.TagKeyPeerService, caller)
+					}
+				}
+				return next.Process(ctx, seqID, in, out)
+			},
+		}
+	}
+}
+
+func callerService(ctx context.Context, originService func(ctx context.Context) (string, bool)) (string, bool) {
+	if client, ok := header(ctx, transport.HeaderUserAgent); ok && client != "" {
+		return client, true
+	}
+	if originService == nil {
+		return "", false
+	}
+	if name, ok := originService(ctx); ok && name != "" {
+		return name, true
+	}
+	return "", false
+}
+
+// isIDLException returns true if err, or any error it wraps, is an exception
+// defined in thrift IDL files.
+func isIDLException(err error) bool {
+	for ; err != nil; err = errors.Unwrap(err) {
+		if te, ok := err.(thrift.TException); ok && te.TExceptionType() == thrift.TExceptionTypeCompiled {
+			return true
+		}
+	}
+	return false
+}
+
 // InitializeEdgeContext sets an edge request context created from the Thrift
 // headers set on the context onto the context and configures Thrift to forward
 // the edge requent context header on any Thrift calls made by the server.
@@ -213,6 +489,20 @@ func ExtractDeadlineBudget(name string, next thrift.TProcessorFunction) thrift.T
 	}
 }
 
+// ExtractIdempotencyKey is a ProcessorMiddleware that reads the
+// "Idempotency-Key" (transport.HeaderIdempotencyKey) header sent by the client,
+// to be accessed via IdempotencyKey.
+func ExtractIdempotencyKey(name string, next thrift.TProcessorFunction) thrift.TProcessorFunction {
+	return thrift.WrappedTProcessorFunction{
+		Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+			if key, ok := header(ctx, transport.HeaderIdempotencyKey); ok {
+				ctx = context.WithValue(ctx, idempotencyKey, key)
+			}
+			return next.Process(ctx, seqID, in, out)
+		},
+	}
+}
+
 // AbandonCanceledRequests transforms context.Canceled errors into
 // thrift.ErrAbandonRequest errors.
 //
@@ -262,40 +552,28 @@ func AbandonCanceledRequests(name string, next thrift.TProcessorFunction) thrift
 func ReportPayloadSizeMetrics(_ float64) thrift.ProcessorMiddleware {
 	return func(name string, next thrift.TProcessorFunction) thrift.TProcessorFunction {
 		return thrift.WrappedTProcessorFunction{
-			Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+			Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (ok bool, err thrift.TException) {
 				// Only report for THeader requests
-				if ht, ok := in.Transport().(*thrift.THeaderTransport); ok {
+				if ht, isHeader := in.Transport().(*thrift.THeaderTransport); isHeader {
 					protoID := ht.Protocol()
-					cfg := &thrift.TConfiguration{
-						THeaderProtocolID: &protoID,
-					}
-					var itrans, otrans countingTransport
-					transport := thrift.NewTHeaderTransportConf(&itrans, cfg)
-					iproto := thrift.NewTHeaderProtocolConf(transport, cfg)
-					in = &thrift.TDuplicateToProtocol{
-						Delegate:    in,
-						DuplicateTo: iproto,
-					}
-					transport = thrift.NewTHeaderTransportConf(&otrans, cfg)
-					oproto := thrift.NewTHeaderProtocolConf(transport, cfg)
-					out = &thrift.TDuplicateToProtocol{
-						Delegate:    out,
-						DuplicateTo: oproto,
-					}
+					counter := getPayloadSizeCounter(protoID)
+					in, out = counter.wrap(in, out)
 
 					defer func() {
-						iproto.Flush(ctx)
-						oproto.Flush(ctx)
-						isize := float64(itrans.Size())
-						osize := float64(otrans.Size())
-
+						isize, osize := counter.sizes(ctx)
 						proto := "header-" + tHeaderProtocol2String(protoID)
 						labels := prometheus.Labels{
 							methodLabel: name,
 							protoLabel:  proto,
 						}
-						serverPayloadSizeRequestBytes.With(labels).Observe(isize)
-						serverPayloadSizeResponseBytes.With(labels).Observe(osize)
+						serverPayloadSizeRequestBytes.With(labels).Observe(float64(isize))
+						serverPayloadSizeResponseBytes.With(labels).Observe(float64(osize))
+
+						// Only reuse the counter when the request was fully processed,
+						// as the protocols could be left in a bad state otherwise.
+						if err == nil {
+							putPayloadSizeCounter(protoID, counter)
+						}
 					}()
 				}
 
@@ -305,13 +583,111 @@ func ReportPayloadSizeMetrics(_ float64) thrift.ProcessorMiddleware {
 	}
 }
 
-// countingTransport implements thrift.TTransport
+// payloadSizeCounter reconstructs the request and response of a THeader request
+// with countingTransports to count their sizes.
+//
+// payloadSizeCounters and their countingTransports are pooled to be reused,
+// see getPayloadSizeCounter.
+type payloadSizeCounter struct {
+	itrans, otrans *countingTransport
+	in, out        thrift.TDuplicateToProtocol
+}
+
+// wrap returns in and out duplicated to the counter.
+func (c *payloadSizeCounter) wrap(in, out thrift.TProtocol) (thrift.TProtocol, thrift.TProtocol) {
+	c.in.Delegate = in
+	c.out.Delegate = out
+	return &c.in, &c.out
+}
+
+// sizes flushes the reconstructed request and response and returns their
+// sizes.
+func (c *payloadSizeCounter) sizes(ctx context.Context) (request, response int64) {
+	return c.itrans.flush(ctx), c.otrans.flush(ctx)
+}
+
+var payloadSizeCounterPool iobp.Pool[*payloadSizeCounter]
+
+// getPayloadSizeCounter returns a payloadSizeCounter for protoID with
+// countingTransports from countingTransportPools.
+func getPayloadSizeCounter(protoID thrift.THeaderProtocolID) *payloadSizeCounter {
+	c := payloadSizeCounterPool.Get()
+	if c == nil {
+		c = new(payloadSizeCounter)
+	}
+	c.itrans = getCountingTransport(protoID)
+	c.otrans = getCountingTransport(protoID)
+	c.in.DuplicateTo = c.itrans.proto
+	c.out.DuplicateTo = c.otrans.proto
+	return c
+}
+
+func putPayloadSizeCounter(protoID thrift.THeaderProtocolID, c *payloadSizeCounter) {
+	putCountingTransport(protoID, c.itrans)
+	putCountingTransport(protoID, c.otrans)
+	*c = payloadSizeCounter{}
+	payloadSizeCounterPool.Put(c)
+}
+
+// countingTransport implements thrift.TTransport, counting the bytes written by
+// its THeaderProtocol.
+//
+// Creating the THeaderTransports allocates (e.g. their read buffers),
+// so countingTransports are pooled per protocol in countingTransportPools to
+// be reused.
 type countingTransport struct {
 	iobp.CountingSink
+
+	proto thrift.TProtocol
 }
 
 var _ thrift.TTransport = (*countingTransport)(nil)
 
+func newCountingTransport(protoID thrift.THeaderProtocolID) *countingTransport {
+	cfg := &thrift.TConfiguration{
+		THeaderProtocolID: &protoID,
+	}
+	t := new(countingTransport)
+	t.proto = thrift.NewTHeaderProtocolConf(thrift.NewTHeaderTransportConf(t, cfg), cfg)
+	return t
+}
+
+// flush flushes the protocol and returns the bytes written since the last
+// flush.
+func (t *countingTransport) flush(ctx context.Context) int64 {
+	t.CountingSink = 0
+	t.proto.Flush(ctx)
+	return t.Size()
+}
+
+// countingTransportPools are the pools of *countingTransport for the
+// protocols supported by THeaderProtocol.
+var countingTransportPools = map[thrift.THeaderProtocolID]*iobp.Pool[*countingTransport]{
+	thrift.THeaderProtocolBinary:  newCountingTransportPool(thrift.THeaderProtocolBinary),
+	thrift.THeaderProtocolCompact: newCountingTransportPool(thrift.THeaderProtocolCompact),
+}
+
+func newCountingTransportPool(protoID thrift.THeaderProtocolID) *iobp.Pool[*countingTransport] {
+	return &iobp.Pool[*countingTransport]{
+		New: func() *countingTransport {
+			return newCountingTransport(protoID)
+		},
+	}
+}
+
+func getCountingTransport(protoID thrift.THeaderProtocolID) *countingTransport {
+	if pool, ok := countingTransportPools[protoID]; ok {
+		return pool.Get()
+	}
+	return newCountingTransport(protoID)
+}
+
+func putCountingTransport(protoID thrift.THeaderProtocolID, t *countingTransport) {
+	if pool, ok := countingTransportPools[protoID]; ok {
+		pool.Put(t)
+	}
+}
+
 func (countingTransport) IsOpen() bool {
 	return true
 }
@@ -406,6 +782,10 @@ func recoverPanik(name string, next thrift.TProcessorFunction) thrift.TProcessor
 //     as a string if present (e.g. 404), or the empty string
 //   - thrift_baseplate_status_code: the human-readable status code, e.g.
 //     NOT_FOUND, or the empty string
+//
+// When exemplars are enabled via prometheusbp.EnableExemplars,
+// thrift_server_latency_seconds observations carry the trace id of the sampled
+// server span as an exemplar.
 func PrometheusServerMiddleware(method string, next thrift.TProcessorFunction) thrift.TProcessorFunction {
 	process := func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (success bool, err thrift.TException) {
 		start := time.Now()
@@ -433,7 +813,7 @@ func PrometheusServerMiddleware(method string, next thrift.TProcessorFunction) t
 				methodLabel:  method,
 				successLabel: success,
 			}
-			serverLatencyDistribution.With(latencyLabels).Observe(time.Since(start).Seconds())
+			prometheusbp.ObserveWithTrace(ctx, serverLatencyDistribution.With(latencyLabels), time.Since(start).Seconds())
 
 			totalRequestLabels := prometheus.Labels{
 				methodLabel:              method,
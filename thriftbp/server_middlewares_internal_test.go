@@ -1,12 +1,16 @@
 package thriftbp
 
 import (
+	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/reddit/baseplate.go/internal/gen-go/reddit/baseplate"
+	"github.com/reddit/baseplate.go/prometheusbp/promtest"
 )
 
 func TestWrapErrorForServerSpan(t *testing.T) {
@@ -65,3 +69,107 @@ func TestWrapErrorForServerSpan(t *testing.T) {
 		})
 	}
 }
+
+func TestPayloadSizeCounterReuse(t *testing.T) {
+	for _, protoID := range []thrift.THeaderProtocolID{
+		thrift.THeaderProtocolBinary,
+		thrift.THeaderProtocolCompact,
+	} {
+		t.Run(tHeaderProtocol2String(protoID), func(t *testing.T) {
+			ctx := context.Background()
+			delegate := thrift.NewTBinaryProtocolConf(thrift.NewTMemoryBuffer(), nil)
+			req := &baseplate.IsHealthyRequest{
+				Probe: baseplate.IsHealthyProbePtr(baseplate.IsHealthyProbe_LIVENESS),
+			}
+
+			var first int64
+			for i := 0; i < 3; i++ {
+				counter := getPayloadSizeCounter(protoID)
+				_, out := counter.wrap(delegate, delegate)
+				if err := req.Write(ctx, out); err != nil {
+					t.Fatal(err)
+				}
+				isize, osize := counter.sizes(ctx)
+				putPayloadSizeCounter(protoID, counter)
+
+				if isize != 0 {
+					t.Errorf("#%d: Expected request size 0, got %d", i, isize)
+				}
+				if i == 0 {
+					if osize <= 0 {
+						t.Fatalf("Expected positive response size, got %d", osize)
+					}
+					first = osize
+				} else if osize != first {
+					t.Errorf("#%d: Expected response size %d, got %d", i, first, osize)
+				}
+			}
+		})
+	}
+}
+
+type processorFunc func(ctx context.Context, in, out thrift.TProtocol) (bool, thrift.TException)
+
+func (f processorFunc) Process(ctx context.Context, in, out thrift.TProtocol) (bool, thrift.TException) {
+	return f(ctx, in, out)
+}
+
+func (processorFunc) ProcessorMap() map[string]thrift.TProcessorFunction { return nil }
+
+func (processorFunc) AddToProcessorMap(string, thrift.TProcessorFunction) {}
+
+func TestRecordQueueTime(t *testing.T) {
+	const method = "foo"
+	labels := prometheus.Labels{
+		methodLabel: method,
+	}
+	next := thrift.WrappedTProcessorFunction{
+		Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+			return true, nil
+		},
+	}
+	process := RecordQueueTime(method, next)
+
+	t.Run("received", func(t *testing.T) {
+		defer promtest.NewPrometheusMetricTest(t, "queue time", serverQueueTime, labels).CheckSampleCountDelta(2)
+
+		const wait = 10 * time.Millisecond
+		buf := thrift.NewTMemoryBuffer()
+		buf.WriteString("request")
+		trans := &receivedAtTransport{TTransport: buf}
+		// Only read the first byte, as the server reads the request before
+		// processing it.
+		if _, err := trans.Read(make([]byte, 1)); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(wait)
+
+		processor := receivedAtProcessorFactory{
+			factory: thrift.NewTProcessorFactory(processorFunc(func(ctx context.Context, in, out thrift.TProtocol) (bool, thrift.TException) {
+				receivedAt, ok := ctx.Value(receivedAtCtxKey).(time.Time)
+				if !ok {
+					t.Error("Expected receive time in context")
+				} else if waited := time.Since(receivedAt); waited < wait {
+					t.Errorf("Expected receive time to be at least %v ago, got %v", wait, waited)
+				}
+				return process.Process(ctx, 1, nil, nil)
+			})),
+		}.GetProcessor(trans)
+		if _, err := processor.Process(context.Background(), nil, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		// The next request was already read with the previous one.
+		if _, err := processor.Process(context.Background(), nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("not-received", func(t *testing.T) {
+		defer promtest.NewPrometheusMetricTest(t, "queue time", serverQueueTime, labels).CheckSampleCountDelta(0)
+
+		if _, err := process.Process(context.Background(), 1, nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
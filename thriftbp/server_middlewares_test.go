@@ -3,6 +3,7 @@ package thriftbp_test
 import (
 	"context"
 	"errors"
+	"slices"
 	"testing"
 	"time"
 
@@ -214,6 +215,63 @@ func TestExtractDeadlineBudget(t *testing.T) {
 	)
 }
 
+func TestIdempotencyKey(t *testing.T) {
+	const (
+		name = "test"
+		key  = "foo"
+	)
+
+	// Client side: the key is set on the write headers.
+	clientCtx := thriftbp.WithIdempotencyKey(context.Background(), key)
+	if v, ok := thrift.GetHeader(clientCtx, transport.HeaderIdempotencyKey); !ok || v != key {
+		t.Errorf("Expected header %q to be %q, got %q, %v", transport.HeaderIdempotencyKey, key, v, ok)
+	}
+	if !slices.Contains(thrift.GetWriteHeaderList(clientCtx), transport.HeaderIdempotencyKey) {
+		t.Errorf("Expected %q in write header list", transport.HeaderIdempotencyKey)
+	}
+
+	for _, c := range []struct {
+		label    string
+		ctx      context.Context
+		expected string
+		ok       bool
+	}{
+		{
+			label:    "set",
+			ctx:      thrift.SetHeader(context.Background(), transport.HeaderIdempotencyKey, key),
+			expected: key,
+			ok:       true,
+		},
+		{
+			label: "unset",
+			ctx:   context.Background(),
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			var got string
+			var ok bool
+			wrapped := thrift.WrapProcessor(
+				thrifttest.NewMockTProcessor(
+					t,
+					map[string]thrift.TProcessorFunction{
+						name: thrift.WrappedTProcessorFunction{
+							Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+								got, ok = thriftbp.IdempotencyKey(ctx)
+								return true, nil
+							},
+						},
+					},
+				),
+				thriftbp.ExtractIdempotencyKey,
+			)
+			wrapped.Process(thrifttest.SetMockTProcessorName(c.ctx, name), nil, nil)
+			if got != c.expected || ok != c.ok {
+				t.Errorf("Expected IdempotencyKey to return %q, %v, got %q, %v", c.expected, c.ok, got, ok)
+			}
+		})
+	}
+}
+
 func TestPanicMiddleware(t *testing.T) {
 	t.Run("error", func(t *testing.T) {
 		panicErr := errors.New("oops")
@@ -248,3 +306,46 @@ func TestPanicMiddleware(t *testing.T) {
 		}
 	})
 }
+
+func TestMethodMiddlewares(t *testing.T) {
+	var calls []string
+	recorder := func(label string) thrift.ProcessorMiddleware {
+		return func(name string, next thrift.TProcessorFunction) thrift.TProcessorFunction {
+			return thrift.WrappedTProcessorFunction{
+				Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+					calls = append(calls, label+":"+name)
+					return next.Process(ctx, seqID, in, out)
+				},
+			}
+		}
+	}
+	base := thrift.WrappedTProcessorFunction{
+		Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+			return true, nil
+		},
+	}
+	middleware := thriftbp.MethodMiddlewares([]string{"write"}, recorder("a"), recorder("b"))
+
+	for _, c := range []struct {
+		method   string
+		expected []string
+	}{
+		{
+			method:   "write",
+			expected: []string{"a:write", "b:write"},
+		},
+		{
+			method: "read",
+		},
+	} {
+		t.Run(c.method, func(t *testing.T) {
+			calls = nil
+			if _, err := middleware(c.method, base).Process(context.Background(), 1, nil, nil); err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(calls, c.expected) {
+				t.Errorf("Expected calls %v, got %v", c.expected, calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package thriftbp_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apache/thrift/lib/go/thrift"
+
+	"github.com/reddit/baseplate.go"
+	"github.com/reddit/baseplate.go/thriftbp"
+)
+
+func TestServerCloseTwice(t *testing.T) {
+	store := newSecretsStore(t)
+	defer store.Close()
+
+	bp := baseplate.NewTestBaseplate(baseplate.NewTestBaseplateArgs{
+		Store: store,
+	})
+	var calls int
+	bp.OnShutdown(func(ctx context.Context) error {
+		calls++
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("Expected shutdown hook context to have a deadline")
+		}
+		return nil
+	})
+
+	socket, err := thrift.NewTServerSocket("localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv, err := thriftbp.NewServer(thriftbp.ServerConfig{
+		Processor: thrift.NewTMultiplexedProcessor(),
+		Socket:    socket,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := thriftbp.ApplyBaseplate(bp, srv)
+
+	for i := 0; i < 2; i++ {
+		if err := server.Close(); err != nil {
+			t.Errorf("Close #%d returned error: %v", i+1, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Expected shutdown hook to be called once, got %d", calls)
+	}
+}
@@ -0,0 +1,121 @@
+package thrifttest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apache/thrift/lib/go/thrift"
+)
+
+// DefaultProcessMethod is the method name used by ProcessWithMiddlewares when
+// ProcessArgs.Method is not set.
+const DefaultProcessMethod = "test"
+
+// ProcessArgs are the args to be passed into ProcessWithMiddlewares.
+type ProcessArgs struct {
+	// Context is the parent context of the request.
+	//
+	// Optional, context.Background() will be used when it's nil.
+	Context context.Context
+
+	// Method is the name of the thrift method being called.
+	//
+	// Optional, DefaultProcessMethod will be used when it's empty.
+	Method string
+
+	// Headers are the THeaders of the request.
+	//
+	// They are added to the context the same way the server does,
+	// so they can be read via thrift.GetHeader and thrift.GetReadHeaderList.
+	//
+	// Optional.
+	Headers thrift.THeaderMap
+
+	// Handler is the implementation of the thrift method.
+	//
+	// The error returned by Handler is returned by the processor function the
+	// same way as the generated code, so the middlewares can see it.
+	//
+	// Optional, when it's nil the handler always succeeds.
+	Handler func(ctx context.Context) error
+
+	// Middlewares are the middlewares to wrap the processor with,
+	// in the same order as they would be passed into thriftbp.ServerConfig.
+	Middlewares []thrift.ProcessorMiddleware
+}
+
+// ProcessResult is the outcome of a ProcessWithMiddlewares call.
+type ProcessResult struct {
+	// Ctx is the context seen by the handler, after all the middlewares are
+	// applied.
+	//
+	// It's nil if the handler was not called,
+	// for example a middleware rejected the request.
+	Ctx context.Context
+
+	// Success and Err are the values returned by the wrapped processor.
+	Success bool
+	Err     thrift.TException
+}
+
+// HandlerCalled returns true if the handler was called.
+func (r ProcessResult) HandlerCalled() bool {
+	return r.Ctx != nil
+}
+
+// ProcessWithMiddlewares runs a single request through the given
+// ProcessorMiddlewares without starting a server,
+// and returns the outcome for assertions.
+//
+// The request is processed by a MockTProcessor against an in-memory
+// THeaderProtocol transport, so it can be used to unit test custom server
+// middlewares, e.g.:
+//
+//	result := thrifttest.ProcessWithMiddlewares(t, thrifttest.ProcessArgs{
+//	  Headers: thrift.THeaderMap{
+//	    transport.HeaderDeadlineBudget: "50",
+//	  },
+//	  Middlewares: []thrift.ProcessorMiddleware{
+//	    thriftbp.ExtractDeadlineBudget,
+//	  },
+//	})
+//	if _, ok := result.Ctx.Deadline(); !ok {
+//	  t.Error("Expected deadline to be set")
+//	}
+//
+// As the request is not serialized,
+// middlewares reading or writing the request or response payloads are not
+// supported.
+func ProcessWithMiddlewares(tb testing.TB, args ProcessArgs) ProcessResult {
+	tb.Helper()
+
+	method := args.Method
+	if method == "" {
+		method = DefaultProcessMethod
+	}
+	ctx := args.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var result ProcessResult
+	processor := NewMockTProcessor(tb, map[string]thrift.TProcessorFunction{
+		method: thrift.WrappedTProcessorFunction{
+			Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+				result.Ctx = ctx
+				if args.Handler == nil {
+					return true, nil
+				}
+				return true, thrift.WrapTException(args.Handler(ctx))
+			},
+		},
+	})
+
+	ctx = thrift.AddReadTHeaderToContext(ctx, args.Headers)
+	ctx = SetMockTProcessorName(ctx, method)
+	cfg := &thrift.TConfiguration{}
+	in := thrift.NewTHeaderProtocolConf(thrift.NewTMemoryBuffer(), cfg)
+	out := thrift.NewTHeaderProtocolConf(thrift.NewTMemoryBuffer(), cfg)
+	result.Success, result.Err = thrift.WrapProcessor(processor, args.Middlewares...).Process(ctx, in, out)
+	return result
+}
@@ -0,0 +1,79 @@
+package thrifttest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/apache/thrift/lib/go/thrift"
+
+	"github.com/reddit/baseplate.go/thriftbp"
+	"github.com/reddit/baseplate.go/thriftbp/thrifttest"
+	"github.com/reddit/baseplate.go/transport"
+)
+
+func TestProcessWithMiddlewares(t *testing.T) {
+	t.Run("headers", func(t *testing.T) {
+		result := thrifttest.ProcessWithMiddlewares(t, thrifttest.ProcessArgs{
+			Headers: thrift.THeaderMap{
+				transport.HeaderDeadlineBudget: "50",
+			},
+			Middlewares: []thrift.ProcessorMiddleware{
+				thriftbp.ExtractDeadlineBudget,
+			},
+		})
+		if !result.HandlerCalled() {
+			t.Fatal("Expected the handler to be called")
+		}
+		if !result.Success || result.Err != nil {
+			t.Errorf("Expected success, got %v, %v", result.Success, result.Err)
+		}
+		if _, ok := result.Ctx.Deadline(); !ok {
+			t.Error("Expected deadline to be set")
+		}
+	})
+
+	t.Run("handler-error", func(t *testing.T) {
+		handlerErr := errors.New("foo")
+		var method string
+		result := thrifttest.ProcessWithMiddlewares(t, thrifttest.ProcessArgs{
+			Method: "myMethod",
+			Handler: func(ctx context.Context) error {
+				return handlerErr
+			},
+			Middlewares: []thrift.ProcessorMiddleware{
+				func(name string, next thrift.TProcessorFunction) thrift.TProcessorFunction {
+					method = name
+					return next
+				},
+			},
+		})
+		if !errors.Is(result.Err, handlerErr) {
+			t.Errorf("Expected error %v, got %v", handlerErr, result.Err)
+		}
+		if method != "myMethod" {
+			t.Errorf("Expected method %q passed to the middleware, got %q", "myMethod", method)
+		}
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		rejectErr := thrift.NewTApplicationException(thrift.INTERNAL_ERROR, "rejected")
+		result := thrifttest.ProcessWithMiddlewares(t, thrifttest.ProcessArgs{
+			Middlewares: []thrift.ProcessorMiddleware{
+				func(name string, next thrift.TProcessorFunction) thrift.TProcessorFunction {
+					return thrift.WrappedTProcessorFunction{
+						Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+							return false, rejectErr
+						},
+					}
+				},
+			},
+		})
+		if result.HandlerCalled() {
+			t.Error("Expected the handler not to be called")
+		}
+		if result.Success || result.Err != rejectErr {
+			t.Errorf("Expected false, %v; got %v, %v", rejectErr, result.Success, result.Err)
+		}
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"strconv"
 
 	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/opentracing/opentracing-go"
 
 	"github.com/reddit/baseplate.go/tracing"
 	"github.com/reddit/baseplate.go/transport"
@@ -81,3 +82,66 @@ func CreateThriftContextFromSpan(ctx context.Context, span *tracing.Span) contex
 
 	return ctx
 }
+
+// OpenTelemetry semantic convention attribute keys set by
+// OTelServerSpanAttributes and OTelClientSpanAttributes.
+const (
+	OTelAttributeRPCSystem   = "rpc.system"
+	OTelAttributeRPCMethod   = "rpc.method"
+	OTelAttributePeerService = "peer.service"
+
+	// OTelRPCSystemThrift is the value of OTelAttributeRPCSystem.
+	OTelRPCSystemThrift = "apache_thrift"
+)
+
+var _ thrift.ProcessorMiddleware = OTelServerSpanAttributes
+
+// OTelServerSpanAttributes is a ProcessorMiddleware that sets OpenTelemetry
+// semantic convention attributes on the server span, in addition to the
+// existing baseplate tags.
+//
+// It doesn't create any spans, so it must come after InjectServerSpan.
+// The attributes set are:
+//
+//	rpc.system: "apache_thrift"
+//	rpc.method: the name of the thrift endpoint
+//
+// If there's no span in the context object, it's no-op.
+func OTelServerSpanAttributes(name string, next thrift.TProcessorFunction) thrift.TProcessorFunction {
+	return thrift.WrappedTProcessorFunction{
+		Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+			if span := opentracing.SpanFromContext(ctx); span != nil {
+				span.SetTag(OTelAttributeRPCSystem, OTelRPCSystemThrift)
+				span.SetTag(OTelAttributeRPCMethod, name)
+			}
+			return next.Process(ctx, seqID, in, out)
+		},
+	}
+}
+
+// OTelClientSpanAttributes returns a ClientMiddleware that sets OpenTelemetry
+// semantic convention attributes on the client span, in addition to the
+// existing baseplate tags.
+//
+// It doesn't create any spans, so it must come after MonitorClient.
+// The attributes set are:
+//
+//	rpc.system: "apache_thrift"
+//	rpc.method: the name of the thrift method called
+//	peer.service: slug, usually the ServiceSlug of the client pool
+//
+// If there's no span in the context object, it's no-op.
+func OTelClientSpanAttributes(slug string) thrift.ClientMiddleware {
+	return func(next thrift.TClient) thrift.TClient {
+		return thrift.WrappedTClient{
+			Wrapped: func(ctx context.Context, method string, args, result thrift.TStruct) (thrift.ResponseMeta, error) {
+				if span := opentracing.SpanFromContext(ctx); span != nil {
+					span.SetTag(OTelAttributeRPCSystem, OTelRPCSystemThrift)
+					span.SetTag(OTelAttributeRPCMethod, method)
+					span.SetTag(OTelAttributePeerService, slug)
+				}
+				return next.Call(ctx, method, args, result)
+			},
+		}
+	}
+}
@@ -2,14 +2,18 @@ package thriftbp_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"testing"
 
 	"github.com/apache/thrift/lib/go/thrift"
 
 	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
 
+	"github.com/reddit/baseplate.go/internal/gen-go/reddit/baseplate"
 	"github.com/reddit/baseplate.go/thriftbp"
+	"github.com/reddit/baseplate.go/thriftbp/thrifttest"
 	"github.com/reddit/baseplate.go/tracing"
 	"github.com/reddit/baseplate.go/transport"
 )
@@ -168,3 +172,262 @@ func TestCreateThriftContextFromSpan(t *testing.T) {
 		},
 	)
 }
+
+func TestOTelSpanAttributes(t *testing.T) {
+	checkTags := func(t *testing.T, span *mocktracer.MockSpan, expected map[string]interface{}) {
+		t.Helper()
+		tags := span.Tags()
+		for k, v := range expected {
+			if tags[k] != v {
+				t.Errorf("Expected tag %q to be %v, got %v", k, v, tags[k])
+			}
+		}
+	}
+
+	t.Run("server", func(t *testing.T) {
+		span := mocktracer.New().StartSpan("server").(*mocktracer.MockSpan)
+		ctx := opentracing.ContextWithSpan(context.Background(), span)
+		processor := thriftbp.OTelServerSpanAttributes("myEndpoint", thrift.WrappedTProcessorFunction{
+			Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+				return true, nil
+			},
+		})
+		processor.Process(ctx, 1, nil, nil)
+		checkTags(t, span, map[string]interface{}{
+			thriftbp.OTelAttributeRPCSystem: thriftbp.OTelRPCSystemThrift,
+			thriftbp.OTelAttributeRPCMethod: "myEndpoint",
+		})
+	})
+
+	t.Run("client", func(t *testing.T) {
+		span := mocktracer.New().StartSpan("client").(*mocktracer.MockSpan)
+		ctx := opentracing.ContextWithSpan(context.Background(), span)
+		client := thriftbp.OTelClientSpanAttributes("my-service")(thrift.WrappedTClient{
+			Wrapped: func(ctx context.Context, method string, args, result thrift.TStruct) (thrift.ResponseMeta, error) {
+				return thrift.ResponseMeta{}, nil
+			},
+		})
+		client.Call(ctx, "myMethod", nil, nil)
+		checkTags(t, span, map[string]interface{}{
+			thriftbp.OTelAttributeRPCSystem:   thriftbp.OTelRPCSystemThrift,
+			thriftbp.OTelAttributeRPCMethod:   "myMethod",
+			thriftbp.OTelAttributePeerService: "my-service",
+		})
+	})
+
+	t.Run("no-span", func(t *testing.T) {
+		var called bool
+		client := thriftbp.OTelClientSpanAttributes("my-service")(thrift.WrappedTClient{
+			Wrapped: func(ctx context.Context, method string, args, result thrift.TStruct) (thrift.ResponseMeta, error) {
+				called = true
+				return thrift.ResponseMeta{}, nil
+			},
+		})
+		client.Call(context.Background(), "myMethod", nil, nil)
+		if !called {
+			t.Error("Expected next client to be called")
+		}
+	})
+}
+
+func TestTagServerSpanExceptionType(t *testing.T) {
+	for _, c := range []struct {
+		label    string
+		err      thrift.TException
+		expected interface{}
+	}{
+		{
+			label:    "idl-exception",
+			err:      baseplate.NewError(),
+			expected: "baseplate.Error",
+		},
+		{
+			label: "non-idl-exception",
+			err:   thrift.NewTApplicationException(thrift.INTERNAL_ERROR, "error"),
+		},
+		{
+			label: "no-error",
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			span := mocktracer.New().StartSpan("server").(*mocktracer.MockSpan)
+			ctx := opentracing.ContextWithSpan(context.Background(), span)
+			processor := thriftbp.TagServerSpanExceptionType("myEndpoint", thrift.WrappedTProcessorFunction{
+				Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+					return c.err == nil, c.err
+				},
+			})
+			processor.Process(ctx, 1, nil, nil)
+			if got := span.Tag(thriftbp.SpanTagKeyThriftException); got != c.expected {
+				t.Errorf("Expected tag %q to be %v, got %v", thriftbp.SpanTagKeyThriftException, c.expected, got)
+			}
+			if got := span.Tag("error"); got != nil {
+				t.Errorf("Expected span not to be marked as error, got %v", got)
+			}
+		})
+	}
+}
+
+func TestTagServerSpanDeadlineBudget(t *testing.T) {
+	for _, c := range []struct {
+		label    string
+		headers  thrift.THeaderMap
+		expected interface{}
+	}{
+		{
+			label: "budget",
+			headers: thrift.THeaderMap{
+				transport.HeaderDeadlineBudget: "50",
+			},
+			expected: int64(50),
+		},
+		{
+			label: "too-short",
+			headers: thrift.THeaderMap{
+				transport.HeaderDeadlineBudget: "0",
+			},
+			expected: int64(0),
+		},
+		{
+			label: "malformed",
+			headers: thrift.THeaderMap{
+				transport.HeaderDeadlineBudget: "foo",
+			},
+		},
+		{
+			label: "no-header",
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			span := mocktracer.New().StartSpan("server").(*mocktracer.MockSpan)
+			result := thrifttest.ProcessWithMiddlewares(t, thrifttest.ProcessArgs{
+				Context: opentracing.ContextWithSpan(context.Background(), span),
+				Headers: c.headers,
+				Middlewares: []thrift.ProcessorMiddleware{
+					thriftbp.TagServerSpanDeadlineBudget,
+				},
+			})
+			if !result.HandlerCalled() {
+				t.Error("Expected the handler to be called")
+			}
+			if got := span.Tag(thriftbp.SpanTagKeyDeadlineBudget); got != c.expected {
+				t.Errorf("Expected tag %q to be %v, got %v", thriftbp.SpanTagKeyDeadlineBudget, c.expected, got)
+			}
+		})
+	}
+}
+
+func TestMarkServerSpanIDLExceptionErrors(t *testing.T) {
+	const failureMethod = "failureMethod"
+	for _, c := range []struct {
+		label    string
+		method   string
+		err      thrift.TException
+		expected interface{}
+		// suppressed is whether the returned error is suppressed by
+		// IDLExceptionSuppressor, so not passed to the Stop of the server span.
+		suppressed bool
+	}{
+		{
+			label:    "idl-exception",
+			method:   failureMethod,
+			err:      baseplate.NewError(),
+			expected: true,
+		},
+		{
+			label:      "idl-exception-other-method",
+			method:     "otherMethod",
+			err:        baseplate.NewError(),
+			suppressed: true,
+		},
+		{
+			label:  "non-idl-exception",
+			method: failureMethod,
+			err:    thrift.NewTApplicationException(thrift.INTERNAL_ERROR, "error"),
+		},
+		{
+			label:  "no-error",
+			method: failureMethod,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			span := mocktracer.New().StartSpan("server").(*mocktracer.MockSpan)
+			ctx := opentracing.ContextWithSpan(context.Background(), span)
+			processor := thriftbp.MarkServerSpanIDLExceptionErrors(failureMethod)(c.method, thrift.WrappedTProcessorFunction{
+				Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+					return c.err == nil, c.err
+				},
+			})
+			_, err := processor.Process(ctx, 1, nil, nil)
+			if c.err == nil {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+			} else if !errors.Is(err, c.err) {
+				t.Errorf("Expected error %v to be returned, got %v", c.err, err)
+			}
+			if got := span.Tag("error"); got != c.expected {
+				t.Errorf("Expected tag %q to be %v, got %v", "error", c.expected, got)
+			}
+			if got := thriftbp.IDLExceptionSuppressor(err); err != nil && got != c.suppressed {
+				t.Errorf("Expected IDLExceptionSuppressor to return %v on %v, got %v", c.suppressed, err, got)
+			}
+		})
+	}
+}
+
+func TestTagServerSpanCallerService(t *testing.T) {
+	originService := func(ctx context.Context) (string, bool) {
+		return "origin-service", true
+	}
+	noOriginService := func(ctx context.Context) (string, bool) {
+		return "", false
+	}
+	for _, c := range []struct {
+		label         string
+		userAgent     string
+		originService func(ctx context.Context) (string, bool)
+		expected      interface{}
+	}{
+		{
+			label:         "user-agent-preferred",
+			userAgent:     "client-service",
+			originService: originService,
+			expected:      "client-service",
+		},
+		{
+			label:         "origin-service-fallback",
+			originService: originService,
+			expected:      "origin-service",
+		},
+		{
+			label:         "neither",
+			originService: noOriginService,
+		},
+		{
+			label: "nil-func",
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			span := mocktracer.New().StartSpan("server").(*mocktracer.MockSpan)
+			ctx := opentracing.ContextWithSpan(context.Background(), span)
+			if c.userAgent != "" {
+				ctx = thrift.SetHeader(ctx, transport.HeaderUserAgent, c.userAgent)
+			}
+			var called bool
+			processor := thriftbp.TagServerSpanCallerService(c.originService)("myEndpoint", thrift.WrappedTProcessorFunction{
+				Wrapped: func(ctx context.Context, seqID int32, in, out thrift.TProtocol) (bool, thrift.TException) {
+					called = true
+					return true, nil
+				},
+			})
+			processor.Process(ctx, 1, nil, nil)
+			if !called {
+				t.Error("Expected next processor to be called")
+			}
+			if got := span.Tag(tracing.TagKeyPeerService); got != c.expected {
+				t.Errorf("Expected tag %q to be %v, got %v", tracing.TagKeyPeerService, c.expected, got)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package thriftbp
 
 import (
 	"context"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -47,6 +48,7 @@ type ttlClient struct {
 	generator ttlClientGenerator
 	ttl       time.Duration
 	slug      string
+	ages      *connectionAgeTracker
 
 	// state guarded by lock (buffer-1 channel)
 	state chan *ttlClientState
@@ -61,6 +63,7 @@ func (c *ttlClient) Close() error {
 		c.state <- state
 	}()
 	state.closed = true
+	c.ages.remove(c)
 	if state.timer != nil {
 		state.timer.Stop()
 	}
@@ -132,7 +135,9 @@ func (c *ttlClient) refresh() {
 		transport.Close()
 		return
 	}
-	state.renew(time.Now(), c)
+	now := time.Now()
+	state.renew(now, c)
+	c.ages.set(c, now)
 	state.client = client
 	if state.transport != nil {
 		// close the old transport before replacing it, to avoid connection leaks.
@@ -146,7 +151,10 @@ func (c *ttlClient) refresh() {
 }
 
 // newTTLClient creates a ttlClient with a thrift TTransport and ttl+jitter.
-func newTTLClient(generator ttlClientGenerator, ttl time.Duration, jitter float64, slug string) (*ttlClient, error) {
+//
+// ages is optional. When it's non-nil the connection creation times of the
+// client are recorded into it.
+func newTTLClient(generator ttlClientGenerator, ttl time.Duration, jitter float64, slug string, ages *connectionAgeTracker) (*ttlClient, error) {
 	client, transport, err := generator()
 	if err != nil {
 		return nil, err
@@ -160,6 +168,7 @@ func newTTLClient(generator ttlClientGenerator, ttl time.Duration, jitter float6
 		generator: generator,
 		ttl:       duration,
 		slug:      slug,
+		ages:      ages,
 
 		state: make(chan *ttlClientState, 1),
 	}
@@ -167,7 +176,9 @@ func newTTLClient(generator ttlClientGenerator, ttl time.Duration, jitter float6
 		client:    client,
 		transport: transport,
 	}
-	state.renew(time.Now(), c)
+	now := time.Now()
+	state.renew(now, c)
+	c.ages.set(c, now)
 	c.state <- state
 
 	// Register the error counter so it can be monitored
@@ -179,6 +190,59 @@ func newTTLClient(generator ttlClientGenerator, ttl time.Duration, jitter float6
 	return c, nil
 }
 
+// connectionAgeTracker tracks the creation times of the current connections of
+// the ttlClients in a client pool.
+//
+// It's guarded by its own lock instead of the states of the ttlClients,
+// so reading the ages never blocks nor is blocked by the in-flight calls.
+//
+// All methods are safe to be called on a nil *connectionAgeTracker,
+// which tracks nothing.
+type connectionAgeTracker struct {
+	lock    sync.Mutex
+	created map[*ttlClient]time.Time
+}
+
+func newConnectionAgeTracker() *connectionAgeTracker {
+	return &connectionAgeTracker{
+		created: make(map[*ttlClient]time.Time),
+	}
+}
+
+// set records the creation time of the current connection of c.
+func (t *connectionAgeTracker) set(c *ttlClient, created time.Time) {
+	if t == nil {
+		return
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.created[c] = created
+}
+
+// remove stops tracking c.
+func (t *connectionAgeTracker) remove(c *ttlClient) {
+	if t == nil {
+		return
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	delete(t.created, c)
+}
+
+// ages returns the ages of all the tracked connections, in seconds, at now.
+func (t *connectionAgeTracker) ages(now time.Time) []float64 {
+	if t == nil {
+		return nil
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	ages := make([]float64, 0, len(t.created))
+	for _, created := range t.created {
+		ages = append(ages, now.Sub(created).Seconds())
+	}
+	return ages
+}
+
 type countingDelegateTransport struct {
 	thrift.TTransport
 
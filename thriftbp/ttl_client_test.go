@@ -34,7 +34,7 @@ func TestTTLClient(t *testing.T) {
 	ttl := time.Millisecond
 	jitter := 0.1
 
-	client, err := newTTLClient(firstSuccessGenerator(transport), ttl, jitter, "")
+	client, err := newTTLClient(firstSuccessGenerator(transport), ttl, jitter, "", nil)
 	if err != nil {
 		t.Fatalf("newTTLClient returned error: %v", err)
 	}
@@ -47,7 +47,7 @@ func TestTTLClient(t *testing.T) {
 		t.Error("Expected IsOpen call after sleep to return false, got true.")
 	}
 
-	client, err = newTTLClient(firstSuccessGenerator(transport), ttl, -jitter, "")
+	client, err = newTTLClient(firstSuccessGenerator(transport), ttl, -jitter, "", nil)
 	if err != nil {
 		t.Fatalf("newTTLClient returned error: %v", err)
 	}
@@ -67,7 +67,7 @@ func TestTTLClientNegativeTTL(t *testing.T) {
 	}
 	ttl := time.Millisecond
 
-	client, err := newTTLClient(firstSuccessGenerator(transport), -ttl, 0.1, "")
+	client, err := newTTLClient(firstSuccessGenerator(transport), -ttl, 0.1, "", nil)
 	if err != nil {
 		t.Fatalf("newTTLClient returned error: %v", err)
 	}
@@ -166,7 +166,7 @@ func TestTTLClientRefresh(t *testing.T) {
 		g := alwaysSuccessGenerator{transport: &countingDelegateTransport{
 			TTransport: &transport,
 		}}
-		client, err := newTTLClient(g.generator(), ttl, jitter, "")
+		client, err := newTTLClient(g.generator(), ttl, jitter, "", nil)
 		if err != nil {
 			t.Fatalf("newTTLClient returned error: %v", err)
 		}
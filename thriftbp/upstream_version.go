@@ -0,0 +1,118 @@
+package thriftbp
+
+import (
+	"context"
+	"strings"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/opentracing/opentracing-go"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultUpstreamVersionHeader is the default response THeader read by
+// RecordUpstreamVersion.
+const DefaultUpstreamVersionHeader = "server-version"
+
+// SpanTagKeyUpstreamVersion is the client span tag key set by
+// RecordUpstreamVersion.
+const SpanTagKeyUpstreamVersion = "upstream.version"
+
+// UpstreamVersionOther is the bucket returned by DefaultUpstreamVersionBucket
+// for versions not in semantic versioning format.
+const UpstreamVersionOther = "other"
+
+// DefaultUpstreamVersionBucket is the default UpstreamVersionArgs.Bucket.
+//
+// It keeps the major and minor versions of versions in semantic versioning
+// format (with an optional "v" prefix), for example "v1.2.3-rc.1" becomes
+// "1.2", and returns UpstreamVersionOther for everything else,
+// for example git commit hashes.
+func DefaultUpstreamVersionBucket(version string) string {
+	parts := strings.SplitN(strings.TrimPrefix(version, "v"), ".", 3)
+	if len(parts) < 2 || !isDigits(parts[0]) {
+		return UpstreamVersionOther
+	}
+	minor, _, _ := strings.Cut(parts[1], "-")
+	if !isDigits(minor) {
+		return UpstreamVersionOther
+	}
+	return parts[0] + "." + minor
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// UpstreamVersionArgs are the args to be passed into RecordUpstreamVersion
+// function.
+type UpstreamVersionArgs struct {
+	// ServiceSlug is the slug of the upstream service, used as the
+	// thrift_client_name label.
+	//
+	// Required.
+	ServiceSlug string
+
+	// Header is the response THeader set by the upstream server with its
+	// version.
+	//
+	// Optional. Default to DefaultUpstreamVersionHeader.
+	Header string
+
+	// Bucket maps the version to the prometheus label value.
+	// It must return a small, bounded set of values to keep the cardinality of
+	// the metric low.
+	//
+	// Optional. Default to DefaultUpstreamVersionBucket.
+	Bucket func(version string) string
+}
+
+// RecordUpstreamVersion returns a ClientMiddleware that records the version of
+// the upstream server, read from a response THeader.
+//
+// The full version is set as the "upstream.version" (SpanTagKeyUpstreamVersion)
+// tag on the client span, so it must come after MonitorClient,
+// and the bucketed version is emitted as the
+// thriftbp_client_upstream_versions_total counter with labels:
+//
+//   - thrift_client_name: the ServiceSlug of the args
+//   - thrift_upstream_version: the bucketed version
+//
+// When the header is absent in the response it does nothing.
+func RecordUpstreamVersion(args UpstreamVersionArgs) thrift.ClientMiddleware {
+	slug := args.ServiceSlug
+	header := args.Header
+	if header == "" {
+		header = DefaultUpstreamVersionHeader
+	}
+	bucket := args.Bucket
+	if bucket == nil {
+		bucket = DefaultUpstreamVersionBucket
+	}
+	return func(next thrift.TClient) thrift.TClient {
+		return thrift.WrappedTClient{
+			Wrapped: func(ctx context.Context, method string, args, result thrift.TStruct) (thrift.ResponseMeta, error) {
+				meta, err := next.Call(ctx, method, args, result)
+				version, ok := meta.Headers[header]
+				if !ok || version == "" {
+					return meta, err
+				}
+				if span := opentracing.SpanFromContext(ctx); span != nil {
+					span.SetTag(SpanTagKeyUpstreamVersion, version)
+				}
+				clientUpstreamVersions.With(prometheus.Labels{
+					clientNameLabel:      slug,
+					upstreamVersionLabel: bucket(version),
+				}).Inc()
+				return meta, err
+			},
+		}
+	}
+}
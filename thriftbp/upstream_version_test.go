@@ -0,0 +1,65 @@
+package thriftbp_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+
+	"github.com/reddit/baseplate.go/thriftbp"
+)
+
+func TestRecordUpstreamVersion(t *testing.T) {
+	newClient := func(headers thrift.THeaderMap) thrift.TClient {
+		return thriftbp.RecordUpstreamVersion(thriftbp.UpstreamVersionArgs{
+			ServiceSlug: "my-service",
+		})(thrift.WrappedTClient{
+			Wrapped: func(ctx context.Context, method string, args, result thrift.TStruct) (thrift.ResponseMeta, error) {
+				return thrift.ResponseMeta{Headers: headers}, nil
+			},
+		})
+	}
+
+	t.Run("header", func(t *testing.T) {
+		span := mocktracer.New().StartSpan("client").(*mocktracer.MockSpan)
+		ctx := opentracing.ContextWithSpan(context.Background(), span)
+		client := newClient(thrift.THeaderMap{
+			thriftbp.DefaultUpstreamVersionHeader: "v1.2.3",
+		})
+		if _, err := client.Call(ctx, "myMethod", nil, nil); err != nil {
+			t.Fatal(err)
+		}
+		if got := span.Tag(thriftbp.SpanTagKeyUpstreamVersion); got != "v1.2.3" {
+			t.Errorf("Expected tag %q to be %q, got %v", thriftbp.SpanTagKeyUpstreamVersion, "v1.2.3", got)
+		}
+	})
+
+	t.Run("no-header", func(t *testing.T) {
+		span := mocktracer.New().StartSpan("client").(*mocktracer.MockSpan)
+		ctx := opentracing.ContextWithSpan(context.Background(), span)
+		if _, err := newClient(nil).Call(ctx, "myMethod", nil, nil); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := span.Tags()[thriftbp.SpanTagKeyUpstreamVersion]; ok {
+			t.Errorf("Expected tag %q to be absent", thriftbp.SpanTagKeyUpstreamVersion)
+		}
+	})
+}
+
+func TestDefaultUpstreamVersionBucket(t *testing.T) {
+	for version, expected := range map[string]string{
+		"1.2.3":       "1.2",
+		"v1.2.3-rc.1": "1.2",
+		"v2.0-beta":   "2.0",
+		"1":           thriftbp.UpstreamVersionOther,
+		"abcdef1234":  thriftbp.UpstreamVersionOther,
+		"v1.x.0":      thriftbp.UpstreamVersionOther,
+		"":            thriftbp.UpstreamVersionOther,
+	} {
+		if got := thriftbp.DefaultUpstreamVersionBucket(version); got != expected {
+			t.Errorf("DefaultUpstreamVersionBucket(%q) expected %q, got %q", version, expected, got)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package timebp
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	_ json.Unmarshaler         = (*Duration)(nil)
+	_ json.Marshaler           = Duration(0)
+	_ encoding.TextUnmarshaler = (*Duration)(nil)
+	_ encoding.TextMarshaler   = Duration(0)
+	_ yaml.Unmarshaler         = (*Duration)(nil)
+	_ yaml.Marshaler           = Duration(0)
+)
+
+// Duration implements json/yaml/text encoding/decoding using human readable
+// duration strings, e.g. "500ms" or "1m30s".
+//
+// For backward compatibility with configs using raw seconds,
+// bare numbers (e.g. 5 or 1.5) are also accepted while decoding,
+// and they are interpreted as seconds.
+// The same applies to strings containing only a number (e.g. "5"),
+// which time.ParseDuration would reject.
+//
+// It always encodes into duration strings.
+type Duration time.Duration
+
+func (d Duration) String() string {
+	return d.ToDuration().String()
+}
+
+// ToDuration converts Duration back to time.Duration.
+func (d Duration) ToDuration() time.Duration {
+	return time.Duration(d)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// MarshalYAML implements yaml.Marshaler.
+func (d Duration) MarshalYAML() (interface{}, error) {
+	return d.String(), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *Duration) UnmarshalText(data []byte) error {
+	// Empty/default
+	if len(data) == 0 {
+		*d = 0
+		return nil
+	}
+	parsed, err := parseDuration(string(data))
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		*d = 0
+		return nil
+	}
+	if strings.HasPrefix(s, `"`) {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+	}
+	return d.UnmarshalText([]byte(s))
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var v interface{}
+	if err := unmarshal(&v); err != nil {
+		return err
+	}
+	switch value := v.(type) {
+	case nil:
+		*d = 0
+		return nil
+	case int:
+		return d.setSeconds(float64(value))
+	case float64:
+		return d.setSeconds(value)
+	case string:
+		return d.UnmarshalText([]byte(value))
+	default:
+		return fmt.Errorf("timebp: cannot parse %v (%T) as duration", v, v)
+	}
+}
+
+func (d *Duration) setSeconds(sec float64) error {
+	parsed, err := secondsToDuration(sec)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+func parseDuration(s string) (Duration, error) {
+	if sec, err := strconv.ParseFloat(s, 64); err == nil {
+		return secondsToDuration(sec)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("timebp: cannot parse %q as duration: %w", s, err)
+	}
+	return Duration(parsed), nil
+}
+
+func secondsToDuration(sec float64) (Duration, error) {
+	ns := sec * float64(time.Second)
+	// math.MaxInt64 is rounded up to 2^63 as float64, which overflows.
+	if math.IsNaN(ns) || ns >= math.MaxInt64 || ns < math.MinInt64 {
+		return 0, fmt.Errorf("timebp: %v seconds is out of the range of duration", sec)
+	}
+	return Duration(time.Duration(ns)), nil
+}
@@ -0,0 +1,167 @@
+package timebp_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/reddit/baseplate.go/timebp"
+)
+
+type durationTestType struct {
+	Timeout timebp.Duration `json:"timeout" yaml:"timeout"`
+}
+
+func TestDurationDecode(t *testing.T) {
+	for _, c := range []struct {
+		label    string
+		json     string
+		yaml     string
+		expected time.Duration
+		err      bool
+	}{
+		{
+			label:    "string",
+			json:     `{"timeout":"500ms"}`,
+			yaml:     `timeout: 500ms`,
+			expected: 500 * time.Millisecond,
+		},
+		{
+			label:    "compound-string",
+			json:     `{"timeout":"1m30s"}`,
+			yaml:     `timeout: 1m30s`,
+			expected: 90 * time.Second,
+		},
+		{
+			label:    "int-seconds",
+			json:     `{"timeout":5}`,
+			yaml:     `timeout: 5`,
+			expected: 5 * time.Second,
+		},
+		{
+			label:    "float-seconds",
+			json:     `{"timeout":1.5}`,
+			yaml:     `timeout: 1.5`,
+			expected: 1500 * time.Millisecond,
+		},
+		{
+			// A quoted number is ambiguous, we treat it the same as a bare number.
+			label:    "quoted-seconds",
+			json:     `{"timeout":"5"}`,
+			yaml:     `timeout: "5"`,
+			expected: 5 * time.Second,
+		},
+		{
+			label: "zero",
+			json:  `{"timeout":0}`,
+			yaml:  `timeout: 0`,
+		},
+		{
+			label: "null",
+			json:  `{"timeout":null}`,
+			yaml:  `timeout: null`,
+		},
+		{
+			label: "empty",
+			json:  `{"timeout":""}`,
+			yaml:  `timeout: ""`,
+		},
+		{
+			label: "invalid",
+			json:  `{"timeout":"5 seconds"}`,
+			yaml:  `timeout: 5 seconds`,
+			err:   true,
+		},
+		{
+			label: "invalid-unit",
+			json:  `{"timeout":"5sec"}`,
+			yaml:  `timeout: 5sec`,
+			err:   true,
+		},
+		{
+			// 2^63 nanoseconds.
+			label: "overflow",
+			json:  `{"timeout":9223372036.854775808}`,
+			yaml:  `timeout: 9223372036.854775808`,
+			err:   true,
+		},
+	} {
+		c := c
+		t.Run(c.label, func(t *testing.T) {
+			t.Parallel()
+
+			check := func(t *testing.T, v durationTestType, err error) {
+				t.Helper()
+				if c.err {
+					if err == nil {
+						t.Errorf("Expected error, got %v", v.Timeout)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if got := v.Timeout.ToDuration(); got != c.expected {
+					t.Errorf("Expected %v, got %v", c.expected, got)
+				}
+			}
+
+			t.Run("json", func(t *testing.T) {
+				v := durationTestType{Timeout: timebp.Duration(time.Hour)}
+				err := json.Unmarshal([]byte(c.json), &v)
+				check(t, v, err)
+			})
+
+			t.Run("yaml", func(t *testing.T) {
+				v := durationTestType{Timeout: timebp.Duration(time.Hour)}
+				err := yaml.UnmarshalStrict([]byte(c.yaml), &v)
+				check(t, v, err)
+			})
+		})
+	}
+}
+
+func TestDurationEncode(t *testing.T) {
+	v := durationTestType{Timeout: timebp.Duration(1500 * time.Millisecond)}
+
+	s, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"timeout":"1.5s"}`; string(s) != expected {
+		t.Errorf("Encoded json expected to be %q, got %q", expected, s)
+	}
+
+	s, err = yaml.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "timeout: 1.5s\n"; string(s) != expected {
+		t.Errorf("Encoded yaml expected to be %q, got %q", expected, s)
+	}
+
+	s, err = json.Marshal(durationTestType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"timeout":"0s"}`; string(s) != expected {
+		t.Errorf("Encoded json expected to be %q, got %q", expected, s)
+	}
+	var decoded durationTestType
+	if err := json.Unmarshal(s, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Timeout != 0 {
+		t.Errorf("Decoded timeout expected to be 0, got %v", decoded.Timeout)
+	}
+
+	s, err = yaml.Marshal(durationTestType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "timeout: 0s\n"; string(s) != expected {
+		t.Errorf("Encoded yaml expected to be %q, got %q", expected, s)
+	}
+}
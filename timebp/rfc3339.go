@@ -0,0 +1,97 @@
+package timebp
+
+import (
+	"encoding"
+	"encoding/json"
+	"time"
+)
+
+var (
+	_ json.Unmarshaler         = (*TimestampRFC3339)(nil)
+	_ json.Marshaler           = TimestampRFC3339{}
+	_ encoding.TextUnmarshaler = (*TimestampRFC3339)(nil)
+	_ encoding.TextMarshaler   = TimestampRFC3339{}
+)
+
+// TimestampRFC3339 implements json/text encoding/decoding using RFC3339 strings
+// with optional fractional seconds.
+type TimestampRFC3339 time.Time
+
+func (ts TimestampRFC3339) String() string {
+	return ts.ToTime().String()
+}
+
+// ToTime converts TimestampRFC3339 back to time.Time.
+func (ts TimestampRFC3339) ToTime() time.Time {
+	return time.Time(ts)
+}
+
+// ToSecondF converts TimestampRFC3339 to TimestampSecondF.
+//
+// Note that TimestampSecondF only keeps the precision up to microseconds.
+func (ts TimestampRFC3339) ToSecondF() TimestampSecondF {
+	return TimestampSecondF(ts)
+}
+
+// ToRFC3339 converts TimestampSecondF to TimestampRFC3339.
+func (ts TimestampSecondF) ToRFC3339() TimestampRFC3339 {
+	return TimestampRFC3339(ts.ToTime())
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (ts *TimestampRFC3339) UnmarshalText(data []byte) error {
+	// Empty/default
+	if len(data) == 0 {
+		*ts = TimestampRFC3339{}
+		return nil
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, string(data))
+	if err != nil {
+		return err
+	}
+	*ts = TimestampRFC3339(t)
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+//
+// Both null and empty string are decoded as zero time.
+func (ts *TimestampRFC3339) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*ts = TimestampRFC3339{}
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	return ts.UnmarshalText([]byte(s))
+}
+
+// MarshalText implements encoding.TextMarshaler.
+//
+// The fractional seconds are only included when they are non-zero.
+func (ts TimestampRFC3339) MarshalText() ([]byte, error) {
+	t := ts.ToTime()
+	if t.IsZero() {
+		return nil, nil
+	}
+
+	return []byte(t.Format(time.RFC3339Nano)), nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (ts TimestampRFC3339) MarshalJSON() ([]byte, error) {
+	t := ts.ToTime()
+	if t.IsZero() {
+		return []byte("null"), nil
+	}
+
+	text, err := ts.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(string(text))
+}
@@ -0,0 +1,95 @@
+package timebp_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/reddit/baseplate.go/timebp"
+)
+
+type jsonTestTypeRFC3339 struct {
+	Timestamp timebp.TimestampRFC3339 `json:"timestamp"`
+}
+
+func TestTimestampRFC3339JSON(t *testing.T) {
+	for _, c := range []struct {
+		label    string
+		json     string
+		expected time.Time
+		encoded  string
+	}{
+		{
+			label:   "null",
+			json:    `{"timestamp":null}`,
+			encoded: `{"timestamp":null}`,
+		},
+		{
+			label:   "empty",
+			json:    `{"timestamp":""}`,
+			encoded: `{"timestamp":null}`,
+		},
+		{
+			label:    "seconds",
+			json:     `{"timestamp":"2019-12-31T23:59:59Z"}`,
+			expected: time.Date(2019, time.December, 31, 23, 59, 59, 0, time.UTC),
+			encoded:  `{"timestamp":"2019-12-31T23:59:59Z"}`,
+		},
+		{
+			label:    "fractional",
+			json:     `{"timestamp":"2019-12-31T23:59:59.123456Z"}`,
+			expected: time.Date(2019, time.December, 31, 23, 59, 59, 123456000, time.UTC),
+			encoded:  `{"timestamp":"2019-12-31T23:59:59.123456Z"}`,
+		},
+		{
+			label:    "offset",
+			json:     `{"timestamp":"2019-12-31T15:59:59.5-08:00"}`,
+			expected: time.Date(2019, time.December, 31, 23, 59, 59, 500000000, time.UTC),
+			encoded:  `{"timestamp":"2019-12-31T15:59:59.5-08:00"}`,
+		},
+	} {
+		c := c
+		t.Run(c.label, func(t *testing.T) {
+			t.Parallel()
+
+			v := jsonTestTypeRFC3339{Timestamp: timebp.TimestampRFC3339(time.Now())}
+			if err := json.Unmarshal([]byte(c.json), &v); err != nil {
+				t.Fatal(err)
+			}
+			if got := v.Timestamp.ToTime(); !got.Equal(c.expected) {
+				t.Errorf("Timestamp expected %v, got %v", c.expected, got)
+			}
+
+			s, err := json.Marshal(v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(s) != c.encoded {
+				t.Errorf("Encoded json expected to be %q, got %q", c.encoded, s)
+			}
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		var v jsonTestTypeRFC3339
+		if err := json.Unmarshal([]byte(`{"timestamp":"2019-12-31 23:59:59"}`), &v); err == nil {
+			t.Error("Expected error for malformed timestamp, got nil")
+		}
+	})
+}
+
+func TestTimestampRFC3339SecondFConversion(t *testing.T) {
+	ts := time.Date(2019, time.December, 31, 23, 59, 59, 123456789, time.UTC)
+	expected := ts.Round(time.Microsecond)
+
+	secondF := timebp.TimestampRFC3339(ts).ToSecondF()
+	if got := secondF.ToTime(); !got.Equal(expected) {
+		t.Errorf("ToSecondF expected %v, got %v", expected, got)
+	}
+	if got := secondF.ToRFC3339().ToTime(); !got.Equal(expected) {
+		t.Errorf("ToRFC3339 expected %v, got %v", expected, got)
+	}
+	if !(timebp.TimestampRFC3339{}).ToSecondF().ToTime().IsZero() {
+		t.Error("Expected zero TimestampRFC3339 to convert to zero TimestampSecondF")
+	}
+}
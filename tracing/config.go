@@ -26,6 +26,36 @@ type Config struct {
 	// headers from the client.
 	SampleRate float64 `yaml:"sampleRate"`
 
+	// OperationSampleRates are the per-operation overrides of SampleRate,
+	// keyed by the span (endpoint) names.
+	// Each rate should be in the range of [0, 1].
+	//
+	// They are used to sample the new top level spans of the operations
+	// differently from SampleRate, for example to sample health check endpoints
+	// at 0 and critical endpoints at 1.
+	// Operations not in OperationSampleRates fall back to SampleRate.
+	//
+	// The precedence of the sampling decision of a server span is:
+	//
+	// 1. The sampled flag from the upstream headers, if present.
+	//
+	// 2. The per-operation rate in OperationSampleRates, if present.
+	//
+	// 3. SampleRate, only for the new top level spans.
+	OperationSampleRates map[string]float64 `yaml:"operationSampleRates"`
+
+	// UntrustedDebugSampleRate should be in the range of [0, 1].
+	//
+	// It's the capped sample rate applied to the server spans started from
+	// untrusted upstream headers with either the debug flag or the sampled flag
+	// set, via StartSpanFromUntrustedHeaders.
+	// Those spans are never sampled unconditionally,
+	// so untrusted clients can't force unlimited sampling.
+	//
+	// When UntrustedDebugSampleRate <= 0 (default),
+	// the flags from untrusted headers are ignored.
+	UntrustedDebugSampleRate float64 `yaml:"untrustedDebugSampleRate"`
+
 	// Logger, if non-nil, will be used to log additional informations Record
 	// returned certain errors.
 	Logger log.Wrapper `yaml:"logger"`
@@ -65,6 +95,15 @@ type Config struct {
 	// can handle hex trace ids (Baseplate.go v0.8.0+ or Baseplate.py v2.0.0+).
 	UseHex bool `yaml:"useHex"`
 
+	// Clock is the function used to get the current time for the start and
+	// stop time of the spans, and the timestamps of their time annotations.
+	//
+	// It's mainly useful in tests, to assert exact span durations with a fake
+	// clock.
+	//
+	// Optional. Default to time.Now.
+	Clock func() time.Time `yaml:"-"`
+
 	// In test code,
 	// this field can be used to set the message queue the tracer publishes to,
 	// usually an *mqsend.MockMessageQueue.
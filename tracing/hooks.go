@@ -53,6 +53,17 @@ type AddSpanCounterHook interface {
 	OnAddCounter(span *Span, key string, delta float64) error
 }
 
+// SampledSpanHook allows you to inject functionality into the spans that are
+// sampled.
+type SampledSpanHook interface {
+	// OnSampled is called once for each sampled Span, after the sampling
+	// decision is made and the create Hooks are called, before any OnPostStart
+	// Hooks are called.
+	//
+	// It's not called for Spans that are not sampled.
+	OnSampled(span *Span) error
+}
+
 var (
 	createServerSpanHooks []CreateServerSpanHook
 )
@@ -72,6 +83,9 @@ func IsSpanHook(hook interface{}) bool {
 	if _, ok := hook.(AddSpanCounterHook); ok {
 		return ok
 	}
+	if _, ok := hook.(SampledSpanHook); ok {
+		return ok
+	}
 	return false
 }
 
@@ -130,3 +130,40 @@ func TestHookFailures(t *testing.T) {
 		t.Fatalf("Expected %v:\nGot: %v", expected, hook.Calls.Calls)
 	}
 }
+
+type TestOnSampledHook struct {
+	Calls *CallContainer
+}
+
+func (h TestOnSampledHook) OnCreateServerSpan(span *tracing.Span) error {
+	span.AddHooks(h)
+	return nil
+}
+
+func (h TestOnSampledHook) OnSampled(span *tracing.Span) error {
+	return h.Calls.AddCall("on-sampled", false)
+}
+
+var _ tracing.SampledSpanHook = TestOnSampledHook{}
+
+func TestSampledSpanHook(t *testing.T) {
+	for _, sampled := range []bool{true, false} {
+		hook := TestOnSampledHook{Calls: &CallContainer{}}
+		tracing.RegisterCreateServerSpanHooks(hook)
+
+		_, span := tracing.StartSpanFromHeaders(context.Background(), "foo", tracing.Headers{
+			TraceID: "1",
+			Sampled: &sampled,
+		})
+		span.SetTag("foo", "bar")
+		tracing.ResetHooks()
+
+		var expected []string
+		if sampled {
+			expected = []string{"on-sampled"}
+		}
+		if !reflect.DeepEqual(hook.Calls.Calls, expected) {
+			t.Errorf("sampled=%v: Expected calls %v, got %v", sampled, expected, hook.Calls.Calls)
+		}
+	}
+}
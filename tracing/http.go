@@ -0,0 +1,112 @@
+package tracing
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/opentracing/opentracing-go"
+)
+
+// The HTTP headers used by HTTPTransport to propagate the span to the server.
+//
+// They are the same as the ones defined in httpbp package.
+const (
+	httpHeaderTraceID  = "X-Trace"
+	httpHeaderSpanID   = "X-Span"
+	httpHeaderParentID = "X-Parent"
+	httpHeaderFlags    = "X-Flags"
+	httpHeaderSampled  = "X-Sampled"
+
+	httpSampledTrue = "1"
+)
+
+// Span tag keys set by HTTPTransport.
+const (
+	TagKeyHTTPMethod     = "http.method"
+	TagKeyHTTPURL        = "http.url"
+	TagKeyHTTPStatusCode = "http.status_code"
+)
+
+// HTTPTransport wraps next to create a client span around each outbound
+// request, using this package directly.
+//
+// The span is created as a child of the span in the context object of the
+// request, so it follows the sampling decision of the current server span.
+// The tracing headers of the span are injected into the request sent, and the
+// span is finished with the status code of the response.
+// Responses with status code >= 500 are reported as errors on the span.
+//
+// It's meant to be used by the services not using the injected v2 tracing http
+// client middleware (see httpbp.MonitorClient), for example:
+//
+//	client := &http.Client{
+//		Transport: tracing.HTTPTransport(http.DefaultTransport),
+//	}
+//
+// If next is nil, http.DefaultTransport will be used instead.
+func HTTPTransport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return httpTransport{next: next}
+}
+
+type httpTransport struct {
+	next http.RoundTripper
+}
+
+func (t httpTransport) RoundTrip(req *http.Request) (resp *http.Response, err error) {
+	name := req.Method + " " + req.URL.Host
+	otSpan, ctx := opentracing.StartSpanFromContext(
+		req.Context(),
+		name,
+		SpanTypeOption{Type: SpanTypeClient},
+	)
+	span := AsSpan(otSpan)
+	span.SetTag(TagKeyHTTPMethod, req.Method)
+	span.SetTag(TagKeyHTTPURL, redactedURL(req.URL))
+	defer func() {
+		spanErr := err
+		if resp != nil {
+			span.SetTag(TagKeyHTTPStatusCode, resp.StatusCode)
+			if spanErr == nil && resp.StatusCode >= 500 {
+				spanErr = fmt.Errorf("tracing: http response status code %d", resp.StatusCode)
+			}
+		}
+		span.FinishWithOptions(FinishOptions{
+			Ctx: ctx,
+			Err: spanErr,
+		}.Convert())
+	}()
+
+	// RoundTripper should not modify the request.
+	req = req.Clone(ctx)
+	req.Header.Set(httpHeaderTraceID, span.TraceID())
+	req.Header.Set(httpHeaderSpanID, span.ID())
+	req.Header.Set(httpHeaderFlags, strconv.FormatInt(span.Flags(), 10))
+	if span.ParentID() != "" {
+		req.Header.Set(httpHeaderParentID, span.ParentID())
+	} else {
+		req.Header.Del(httpHeaderParentID)
+	}
+	if span.Sampled() {
+		req.Header.Set(httpHeaderSampled, httpSampledTrue)
+	} else {
+		req.Header.Del(httpHeaderSampled)
+	}
+	return t.next.RoundTrip(req)
+}
+
+// redactedURL returns the string form of u to be used as the span tag,
+// without the user info, query and fragment as they might contain secrets.
+func redactedURL(u *url.URL) string {
+	redacted := *u
+	redacted.User = nil
+	redacted.RawQuery = ""
+	redacted.ForceQuery = false
+	redacted.Fragment = ""
+	redacted.RawFragment = ""
+	return redacted.String()
+}
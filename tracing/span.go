@@ -12,6 +12,7 @@ import (
 	otlog "github.com/opentracing/opentracing-go/log"
 
 	"github.com/reddit/baseplate.go/log"
+	"github.com/reddit/baseplate.go/randbp"
 )
 
 const maxIDLength = 1024
@@ -101,6 +102,9 @@ type Span struct {
 }
 
 func (s *Span) onStart() {
+	if s.Sampled() {
+		s.onSampled()
+	}
 	for _, h := range s.hooks {
 		if hook, ok := h.(StartStopSpanHook); ok {
 			if err := hook.OnPostStart(s); err != nil {
@@ -110,6 +114,16 @@ func (s *Span) onStart() {
 	}
 }
 
+func (s *Span) onSampled() {
+	for _, h := range s.hooks {
+		if hook, ok := h.(SampledSpanHook); ok {
+			if err := hook.OnSampled(s); err != nil {
+				s.logError(context.Background(), "OnSampled hook error: ", err)
+			}
+		}
+	}
+}
+
 // ID returns the ID for the Span.
 func (s Span) ID() string {
 	return s.trace.spanID
@@ -145,6 +159,12 @@ func (s Span) Sampled() bool {
 	return s.trace.sampled
 }
 
+// ShouldSample returns if the current span will be sampled,
+// which is when it's sampled or has the debug flag set.
+func (s Span) ShouldSample() bool {
+	return s.trace.shouldSample()
+}
+
 // StartTime the time that the span was started.
 func (s Span) StartTime() time.Time {
 	return s.trace.start
@@ -275,7 +295,7 @@ func (s *Span) Stop(ctx context.Context, err error) error {
 		}
 	}
 	if s.trace.stop.IsZero() {
-		s.trace.stop = time.Now()
+		s.trace.stop = s.trace.tracer.now()
 	}
 	return s.trace.publish(ctx)
 }
@@ -417,7 +437,8 @@ func (s *Span) Log(data opentracing.LogData) {}
 // StartTopLevelServerSpan initializes a new, top level server span.
 //
 // This span will have a new TraceID and will be sampled based on your configured
-// sample rate.
+// per-operation sample rate of name, or the global sample rate if there's no
+// per-operation sample rate configured for name.
 func StartTopLevelServerSpan(ctx context.Context, name string) (context.Context, *Span) {
 	otSpan, ctx := opentracing.StartSpanFromContext(
 		ctx,
@@ -430,6 +451,42 @@ func StartTopLevelServerSpan(ctx context.Context, name string) (context.Context,
 	}), span
 }
 
+// WithChildSpan creates a child span of the span in ctx named name,
+// runs fn with the context containing the child span,
+// and finishes the child span with the error returned by fn.
+//
+// The child span is a local span by default,
+// a SpanTypeOption can be passed in opts to override it.
+//
+// If fn panics, the child span is finished with an error describing the panic
+// before the panic is re-raised, so it's always finished.
+//
+// It returns the error returned by fn as-is.
+func WithChildSpan(
+	ctx context.Context,
+	name string,
+	fn func(ctx context.Context) error,
+	opts ...opentracing.StartSpanOption,
+) (err error) {
+	opts = append([]opentracing.StartSpanOption{SpanTypeOption{Type: SpanTypeLocal}}, opts...)
+	span, ctx := opentracing.StartSpanFromContext(ctx, name, opts...)
+	defer func() {
+		spanErr := err
+		r := recover()
+		if r != nil {
+			spanErr = fmt.Errorf("tracing: panic in %q: %v", name, r)
+		}
+		span.FinishWithOptions(FinishOptions{
+			Ctx: ctx,
+			Err: spanErr,
+		}.Convert())
+		if r != nil {
+			panic(r)
+		}
+	}()
+	return fn(ctx)
+}
+
 // Headers is the argument struct for starting a Span from upstream headers.
 type Headers struct {
 	// TraceID is the trace ID passed via upstream headers.
@@ -539,6 +596,10 @@ func (h Headers) ParseSampled() (sampled bool, ok bool) {
 // Please note that "Sampled" header is default to false according to baseplate
 // spec, so if the headers are incorrect, this span (and all its child-spans)
 // will never be sampled, unless debug flag was set explicitly later.
+// The exception is when there's a per-operation sample rate configured for name
+// via Config.OperationSampleRates, which will be used to make the sampling
+// decision when the "Sampled" header is missing.
+// A "Sampled" header from upstream always takes precedence.
 //
 // If any headers are missing or malformed, they will be ignored.
 // Malformed headers will be logged if InitGlobalTracer was last called with a
@@ -570,6 +631,8 @@ func StartSpanFromHeaders(ctx context.Context, name string, headers Headers) (co
 
 	if sampled, ok := headers.ParseSampled(); ok {
 		span.trace.sampled = sampled
+	} else if rate, ok := span.trace.tracer.operationSampleRate(name); ok {
+		span.trace.sampled = randbp.ShouldSampleWithRate(rate)
 	}
 
 	ctx = initRootSpan(ctx, span)
@@ -577,6 +640,42 @@ func StartSpanFromHeaders(ctx context.Context, name string, headers Headers) (co
 	return ctx, span
 }
 
+// StartSpanFromUntrustedHeaders creates a new, top level server span for a
+// request with headers that are not trusted.
+//
+// Like StartTopLevelServerSpan, the span will have a new TraceID and will be
+// sampled based on your configured sample rate,
+// and the trace and span ids from the headers are ignored.
+// But if the headers have either the debug flag or the sampled flag set,
+// instead of being trusted fully, the span is downgraded to be sampled with the
+// capped Config.UntrustedDebugSampleRate, and will be tagged with
+// TagKeyForcedUntrusted when sampled because of that.
+// The debug flag is never set on the span.
+func StartSpanFromUntrustedHeaders(ctx context.Context, name string, headers Headers) (context.Context, *Span) {
+	ctx, span := StartTopLevelServerSpan(ctx, name)
+	if span.trace.sampled || !headers.forcesSampling() {
+		return ctx, span
+	}
+	if randbp.ShouldSampleWithRate(span.trace.tracer.untrustedRate) {
+		span.trace.sampled = true
+		span.SetTag(TagKeyForcedUntrusted, true)
+		// The span was not sampled when it was started,
+		// so OnSampled hooks were not called yet.
+		span.onSampled()
+	}
+	return ctx, span
+}
+
+// forcesSampling returns true if either the debug flag or the sampled flag is
+// set in the headers.
+func (h Headers) forcesSampling() bool {
+	if flags, ok := h.ParseFlags(); ok && flags&FlagMaskDebug != 0 {
+		return true
+	}
+	sampled, ok := h.ParseSampled()
+	return ok && sampled
+}
+
 // initRootSpan is the other half of initChildSpan.
 //
 // One of initRootSpan and initChildSpan MUST be called for every span created.
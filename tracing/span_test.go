@@ -1,6 +1,8 @@
 package tracing
 
 import (
+	"context"
+	"errors"
 	"math/rand"
 	"reflect"
 	"strings"
@@ -280,6 +282,76 @@ func TestChildSpan(t *testing.T) {
 	}
 }
 
+func TestWithChildSpan(t *testing.T) {
+	parent := AsSpan(opentracing.StartSpan("parent", SpanTypeOption{Type: SpanTypeServer}))
+	parentCtx := opentracing.ContextWithSpan(context.Background(), parent)
+	fnErr := errors.New("foo")
+
+	for _, c := range []struct {
+		label        string
+		opts         []opentracing.StartSpanOption
+		err          error
+		panics       bool
+		expectedType SpanType
+	}{
+		{
+			label:        "success",
+			expectedType: SpanTypeLocal,
+		},
+		{
+			label:        "error",
+			err:          fnErr,
+			expectedType: SpanTypeLocal,
+		},
+		{
+			label:        "panic",
+			panics:       true,
+			expectedType: SpanTypeLocal,
+		},
+		{
+			label:        "client",
+			opts:         []opentracing.StartSpanOption{SpanTypeOption{Type: SpanTypeClient}},
+			expectedType: SpanTypeClient,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			var child *Span
+			var err error
+			func() {
+				defer func() {
+					if r := recover(); (r != nil) != c.panics {
+						t.Errorf("Expected panic %v, got %v", c.panics, r)
+					}
+				}()
+				err = WithChildSpan(parentCtx, "child", func(ctx context.Context) error {
+					child = AsSpan(opentracing.SpanFromContext(ctx))
+					if c.panics {
+						panic("oops")
+					}
+					return c.err
+				}, c.opts...)
+			}()
+
+			if !errors.Is(err, c.err) {
+				t.Errorf("Expected error %v, got %v", c.err, err)
+			}
+			if child.ParentID() != parent.ID() {
+				t.Errorf("Expected parent id %q, got %q", parent.ID(), child.ParentID())
+			}
+			if child.spanType != c.expectedType {
+				t.Errorf("Expected span type %v, got %v", c.expectedType, child.spanType)
+			}
+			if child.StopTime().IsZero() {
+				t.Error("Expected the child span to be finished")
+			}
+			_, failed := child.trace.tags[ZipkinBinaryAnnotationKeyError]
+			if expected := c.err != nil || c.panics; failed != expected {
+				t.Errorf("Expected span failed to be %v, got %v", expected, failed)
+			}
+		})
+	}
+}
+
 func TestSpanTypeStrings(t *testing.T) {
 	t.Parallel()
 
@@ -774,3 +846,187 @@ func TestSpanMetricsTags(t *testing.T) {
 		t.Errorf("Expected %v, got %v", expected, tags)
 	}
 }
+
+func TestStartSpanFromUntrustedHeaders(t *testing.T) {
+	defer func() {
+		CloseTracer()
+		InitGlobalTracer(Config{})
+	}()
+
+	sampledTrue := true
+	debugHeaders := Headers{
+		TraceID: "1234",
+		Flags:   "1",
+	}
+	sampledHeaders := Headers{
+		TraceID: "1234",
+		Sampled: &sampledTrue,
+	}
+	const n = 1000
+
+	for _, c := range []struct {
+		label    string
+		rate     float64
+		headers  Headers
+		min, max int
+	}{
+		{
+			label:   "no-flags",
+			rate:    1,
+			headers: Headers{TraceID: "1234"},
+			min:     0,
+			max:     0,
+		},
+		{
+			label:   "debug-disabled",
+			rate:    0,
+			headers: debugHeaders,
+			min:     0,
+			max:     0,
+		},
+		{
+			label:   "debug-full",
+			rate:    1,
+			headers: debugHeaders,
+			min:     n,
+			max:     n,
+		},
+		{
+			label:   "debug-capped",
+			rate:    0.1,
+			headers: debugHeaders,
+			min:     n * 0.05,
+			max:     n * 0.2,
+		},
+		{
+			label:   "sampled-capped",
+			rate:    0.1,
+			headers: sampledHeaders,
+			min:     n * 0.05,
+			max:     n * 0.2,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			InitGlobalTracer(Config{
+				UntrustedDebugSampleRate: c.rate,
+			})
+			var count int
+			for i := 0; i < n; i++ {
+				_, span := StartSpanFromUntrustedHeaders(context.Background(), "foo", c.headers)
+				if span.trace.isDebugSet() {
+					t.Fatal("Expected debug flag to be downgraded")
+				}
+				if span.TraceID() == c.headers.TraceID {
+					t.Fatal("Expected untrusted trace id to be ignored")
+				}
+				_, tagged := span.trace.tags[TagKeyForcedUntrusted]
+				if span.Sampled() != tagged {
+					t.Fatalf(
+						"Expected %q tag only on sampled spans, sampled: %v, tags: %v",
+						TagKeyForcedUntrusted,
+						span.Sampled(),
+						span.trace.tags,
+					)
+				}
+				if span.Sampled() {
+					count++
+				}
+			}
+			if count < c.min || count > c.max {
+				t.Errorf("Expected sampled spans in [%d, %d], got %d", c.min, c.max, count)
+			}
+		})
+	}
+}
+
+func TestOperationSampleRates(t *testing.T) {
+	defer func() {
+		CloseTracer()
+		InitGlobalTracer(Config{})
+	}()
+
+	if err := InitGlobalTracer(Config{
+		SampleRate: 0.5,
+		OperationSampleRates: map[string]float64{
+			"health":   0,
+			"checkout": 1,
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	sampledTrue := true
+	sampledFalse := false
+	const n = 100
+
+	for _, c := range []struct {
+		label    string
+		name     string
+		headers  Headers
+		expected bool
+		fallback bool
+	}{
+		{
+			label:    "top-level-health",
+			name:     "health",
+			expected: false,
+		},
+		{
+			label:    "top-level-checkout",
+			name:     "checkout",
+			expected: true,
+		},
+		{
+			label:    "top-level-fallback",
+			name:     "other",
+			fallback: true,
+		},
+		{
+			label:    "headers-no-decision",
+			name:     "checkout",
+			headers:  Headers{TraceID: "1234"},
+			expected: true,
+		},
+		{
+			label:    "headers-no-decision-no-override",
+			name:     "other",
+			headers:  Headers{TraceID: "1234"},
+			expected: false,
+		},
+		{
+			label:    "headers-upstream-sampled",
+			name:     "health",
+			headers:  Headers{TraceID: "1234", Sampled: &sampledTrue},
+			expected: true,
+		},
+		{
+			label:    "headers-upstream-not-sampled",
+			name:     "checkout",
+			headers:  Headers{TraceID: "1234", Sampled: &sampledFalse},
+			expected: false,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			var count int
+			for i := 0; i < n; i++ {
+				_, span := StartSpanFromHeaders(context.Background(), c.name, c.headers)
+				if span.Sampled() {
+					count++
+				}
+			}
+			if c.fallback {
+				if count == 0 || count == n {
+					t.Errorf("Expected some but not all spans sampled with global rate, got %d/%d", count, n)
+				}
+				return
+			}
+			expected := 0
+			if c.expected {
+				expected = n
+			}
+			if count != expected {
+				t.Errorf("Expected %d/%d spans sampled, got %d", expected, n, count)
+			}
+		})
+	}
+}
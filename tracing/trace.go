@@ -15,6 +15,12 @@ const (
 	TagKeyEndpoint    = "endpoint"
 	TagKeySuccess     = "success"
 	TagKeyPeerService = "peer.service"
+
+	// TagKeyForcedUntrusted is set to true on the server spans sampled because
+	// of the debug or sampled flag from untrusted upstream headers.
+	//
+	// See StartSpanFromUntrustedHeaders for more details.
+	TagKeyForcedUntrusted = "forced_but_untrusted"
 )
 
 // FlagMask values.
@@ -59,7 +65,7 @@ func newTrace(tracer *Tracer, name string) *trace {
 		name:    name,
 		traceID: tracer.newTraceID(),
 		spanID:  tracer.newSpanID(),
-		start:   time.Now(),
+		start:   tracer.now(),
 
 		counters: make(map[string]float64),
 		tags: map[string]string{
@@ -86,7 +92,7 @@ func (t *trace) toZipkinSpan() ZipkinSpan {
 	}
 	end := t.stop
 	if end.IsZero() {
-		end = time.Now()
+		end = t.tracer.now()
 	}
 	zs.Duration = timebp.DurationMicrosecond(end.Sub(t.start))
 
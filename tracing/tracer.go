@@ -70,11 +70,14 @@ var globalTracer = Tracer{logger: log.NopWrapper}
 // A Tracer creates and manages spans.
 type Tracer struct {
 	sampleRate       float64
+	operationRates   map[string]float64
+	untrustedRate    float64
 	recorder         mqsend.MessageQueue
 	logger           log.Wrapper
 	endpoint         ZipkinEndpointInfo
 	maxRecordTimeout time.Duration
 	useHex           bool
+	clock            func() time.Time
 }
 
 // InitGlobalTracer initializes opentracing's global tracer.
@@ -107,7 +110,10 @@ func InitGlobalTracer(cfg Config) error {
 	}
 
 	tracer.sampleRate = cfg.SampleRate
+	tracer.operationRates = cfg.OperationSampleRates
+	tracer.untrustedRate = cfg.UntrustedDebugSampleRate
 	tracer.useHex = cfg.UseHex
+	tracer.clock = cfg.Clock
 
 	logger := cfg.Logger
 	if logger == nil {
@@ -236,7 +242,11 @@ func (t *Tracer) StartSpan(operationName string, opts ...opentracing.StartSpanOp
 		parent.initChildSpan(span)
 	} else {
 		span.trace.traceID = t.newTraceID()
-		span.trace.sampled = randbp.ShouldSampleWithRate(t.sampleRate)
+		rate, ok := t.operationSampleRate(operationName)
+		if !ok {
+			rate = t.sampleRate
+		}
+		span.trace.sampled = randbp.ShouldSampleWithRate(rate)
 		initRootSpan(context.Background(), span)
 	}
 
@@ -253,6 +263,13 @@ func (t *Tracer) StartSpan(operationName string, opts ...opentracing.StartSpanOp
 	return span
 }
 
+// operationSampleRate returns the per-operation sample rate override of the
+// operation, if any.
+func (t *Tracer) operationSampleRate(name string) (rate float64, ok bool) {
+	rate, ok = t.operationRates[name]
+	return rate, ok
+}
+
 // Inject implements opentracing.Tracer.
 //
 // Currently it always return opentracing.ErrInvalidCarrier as the error.
@@ -267,6 +284,14 @@ func (t *Tracer) Extract(format interface{}, carrier interface{}) (opentracing.S
 	return nil, opentracing.ErrInvalidCarrier
 }
 
+// now returns the current time from the configured clock.
+func (t *Tracer) now() time.Time {
+	if t == nil || t.clock == nil {
+		return time.Now()
+	}
+	return t.clock()
+}
+
 func (t *Tracer) newTraceID() string {
 	if t.useHex {
 		// For traces we just combine two 64-bit hex ids to get a 128-bit hex id.
@@ -2,6 +2,7 @@ package tracing
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"testing"
 	"testing/quick"
@@ -139,6 +140,65 @@ func TestTracer(t *testing.T) {
 	)
 }
 
+func TestTracerClock(t *testing.T) {
+	const duration = 1500 * time.Microsecond
+	start := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	recorder := mqsend.OpenMockMessageQueue(mqsend.MessageQueueConfig{
+		MaxQueueSize:   1,
+		MaxMessageSize: MaxSpanSize,
+	})
+	defer func() {
+		CloseTracer()
+		InitGlobalTracer(Config{})
+	}()
+	InitGlobalTracer(Config{
+		SampleRate:               1,
+		TestOnlyMockMessageQueue: recorder,
+		Clock: func() time.Time {
+			return now
+		},
+	})
+
+	span := AsSpan(opentracing.StartSpan(
+		"server",
+		SpanTypeOption{Type: SpanTypeServer},
+	))
+	if !span.StartTime().Equal(start) {
+		t.Errorf("Expected start time %v, got %v", start, span.StartTime())
+	}
+	now = now.Add(duration)
+	if err := span.Stop(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+	msg, err := recorder.Receive(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var zs ZipkinSpan
+	if err := json.Unmarshal(msg, &zs); err != nil {
+		t.Fatal(err)
+	}
+	if got := time.Duration(zs.Duration); got != duration {
+		t.Errorf("Expected duration %v, got %v", duration, got)
+	}
+	expected := map[string]time.Time{
+		ZipkinTimeAnnotationKeyServerReceive: start,
+		ZipkinTimeAnnotationKeyServerSend:    start.Add(duration),
+	}
+	if len(zs.TimeAnnotations) != len(expected) {
+		t.Fatalf("Expected %d time annotations, got %+v", len(expected), zs.TimeAnnotations)
+	}
+	for _, annotation := range zs.TimeAnnotations {
+		if got, want := time.Time(annotation.Timestamp), expected[annotation.Key]; !got.Equal(want) {
+			t.Errorf("Expected annotation %q timestamp %v, got %v", annotation.Key, want, got)
+		}
+	}
+}
+
 func TestHexID64Quick(t *testing.T) {
 	const expectedLength = 16
 	f := func() bool {
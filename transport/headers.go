@@ -25,4 +25,7 @@ const (
 	HeaderTracingSampledTrue = "1"
 	// Number of milliseconds, 64-bit integer encoded in decimal.
 	HeaderDeadlineBudget = "Deadline-Budget"
+	// A caller-provided key identifying a logical request across retries,
+	// used by the server to deduplicate non-idempotent operations.
+	HeaderIdempotencyKey = "Idempotency-Key"
 )